@@ -0,0 +1,69 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"text/tabwriter"
+	"time"
+
+	"org-charm/analytics"
+
+	"github.com/charmbracelet/log"
+)
+
+// analyticsSaveInterval is how often the view counts are saved
+const analyticsSaveInterval = time.Minute
+
+// countViews opens the view counts saved at path, or keeps them in memory
+// with an empty path, and saves them every analyticsSaveInterval in the
+// background
+func countViews(path string) *analytics.Views {
+	views, err := analytics.Open(path)
+	if err != nil {
+		log.Fatal("Failed to open the view counts", "path", path, "error", err)
+	}
+	log.Info("Counting document views", "path", path)
+	go func() {
+		for range time.Tick(analyticsSaveInterval) {
+			saveViews(views)
+		}
+	}()
+	return views
+}
+
+// saveViews saves the view counts, if they changed
+func saveViews(views *analytics.Views) {
+	if err := views.Save(); err != nil {
+		log.Error("Failed to save the view counts", "error", err)
+	}
+}
+
+// runStats runs org-charm stats with args, printing the view counts a
+// server saved, for operators to see what's read
+func runStats(args []string) {
+	flags := flag.NewFlagSet("stats", flag.ExitOnError)
+	path := flags.String("analytics-file", "", "File the server saves the view counts to (its -analytics-file, required)")
+	limit := flags.Int("n", 20, "Number of documents to list, the most viewed first (0 lists all)")
+	flags.Parse(args)
+
+	// Servers keep the counts in memory unless given a file, so there's no
+	// file to default to
+	if *path == "" {
+		log.Fatal("Invalid -analytics-file flag", "error", "give the file the server saves the view counts to with its -analytics-file")
+	}
+
+	stats, err := analytics.Load(*path)
+	if err != nil {
+		log.Fatal("Failed to read the view counts", "error", err)
+	}
+	if *limit > 0 && len(stats) > *limit {
+		stats = stats[:*limit]
+	}
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', tabwriter.AlignRight)
+	fmt.Fprintln(w, "Viewers\tViews\tLast viewed\t\tDocument")
+	for _, s := range stats {
+		fmt.Fprintf(w, "%d\t%d\t%s\t\t%s\n", s.Viewers, s.Views, s.LastViewed.Local().Format("2006-01-02 15:04"), s.Path)
+	}
+	w.Flush()
+}
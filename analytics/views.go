@@ -0,0 +1,177 @@
+// Package analytics counts the views of the documents, for operators who
+// opt in to see what's read. Viewers are only kept as salted hashes of the
+// ids of their public keys, to tell them apart without knowing who they
+// are; the salt is random and never leaves the counts.
+package analytics
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+	"time"
+)
+
+// viewerHashLength is the length in hex digits of the hashes viewers are
+// kept as, enough to tell the viewers of a server apart
+const viewerHashLength = 16
+
+// Views counts the views and the distinct viewers of each document. It is
+// safe for concurrent use.
+type Views struct {
+	mu    sync.Mutex
+	path  string // File the counts are saved to, or empty
+	data  viewsFile
+	dirty bool // Changed since last saved
+}
+
+// viewsFile is the encoding of the counts, as saved
+type viewsFile struct {
+	Salt      string                `json:"salt"`
+	Documents map[string]*docCounts `json:"documents"`
+}
+
+// docCounts are the counts of a document
+type docCounts struct {
+	Views      int             `json:"views"`
+	Viewers    map[string]bool `json:"viewers"` // Hashes of the viewers
+	LastViewed time.Time       `json:"last_viewed"`
+}
+
+// Stats are the counts of a document
+type Stats struct {
+	Path       string    `json:"path"` // Slash separated, relative to the org directory
+	Views      int       `json:"views"`
+	Viewers    int       `json:"viewers"` // Distinct viewers with a public key
+	LastViewed time.Time `json:"last_viewed"`
+}
+
+// Open loads the counts saved at path, or starts counting if there are
+// none. With an empty path, the counts are kept in memory only.
+func Open(path string) (*Views, error) {
+	v := &Views{path: path}
+	if path != "" {
+		data, err := os.ReadFile(path)
+		if err != nil && !errors.Is(err, os.ErrNotExist) {
+			return nil, err
+		}
+		if err == nil {
+			if err := json.Unmarshal(data, &v.data); err != nil {
+				return nil, err
+			}
+		}
+	}
+	if v.data.Salt == "" {
+		salt := make([]byte, 16)
+		rand.Read(salt)
+		v.data.Salt = hex.EncodeToString(salt)
+		v.dirty = path != ""
+	}
+	if v.data.Documents == nil {
+		v.data.Documents = map[string]*docCounts{}
+	}
+	return v, nil
+}
+
+// Record counts a view of the document at rel by the user with the given
+// id, the hash of their public key; anonymous users, with an empty id,
+// only count as views
+func (v *Views) Record(rel, userID string, now time.Time) {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+	doc := v.data.Documents[rel]
+	if doc == nil {
+		doc = &docCounts{Viewers: map[string]bool{}}
+		v.data.Documents[rel] = doc
+	}
+	doc.Views++
+	doc.LastViewed = now.UTC()
+	if userID != "" {
+		sum := sha256.Sum256([]byte(v.data.Salt + userID))
+		doc.Viewers[hex.EncodeToString(sum[:])[:viewerHashLength]] = true
+	}
+	v.dirty = true
+}
+
+// Stats returns the counts of the documents viewed, the most viewed first
+func (v *Views) Stats() []Stats {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+	return v.data.stats()
+}
+
+// stats returns the counts of the documents, ranked by distinct viewers,
+// then views, then path
+func (f viewsFile) stats() []Stats {
+	stats := make([]Stats, 0, len(f.Documents))
+	for rel, doc := range f.Documents {
+		stats = append(stats, Stats{Path: rel, Views: doc.Views, Viewers: len(doc.Viewers), LastViewed: doc.LastViewed})
+	}
+	sort.Slice(stats, func(i, j int) bool {
+		a, b := stats[i], stats[j]
+		if a.Viewers != b.Viewers {
+			return a.Viewers > b.Viewers
+		}
+		if a.Views != b.Views {
+			return a.Views > b.Views
+		}
+		return a.Path < b.Path
+	})
+	return stats
+}
+
+// Popular returns the paths of the documents viewed, the most viewed
+// first
+func (v *Views) Popular() []string {
+	stats := v.Stats()
+	paths := make([]string, len(stats))
+	for i, s := range stats {
+		paths[i] = s.Path
+	}
+	return paths
+}
+
+// Save writes the counts to their file if they changed since last saved,
+// through a temporary file so a crash never leaves a torn one
+func (v *Views) Save() error {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+	if v.path == "" || !v.dirty {
+		return nil
+	}
+	data, err := json.Marshal(v.data)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(v.path), 0o700); err != nil {
+		return err
+	}
+	tmp := v.path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0o600); err != nil {
+		return err
+	}
+	if err := os.Rename(tmp, v.path); err != nil {
+		return err
+	}
+	v.dirty = false
+	return nil
+}
+
+// Load returns the counts saved at path by a server, the most viewed
+// document first, for operators to look at
+func Load(path string) ([]Stats, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var f viewsFile
+	if err := json.Unmarshal(data, &f); err != nil {
+		return nil, err
+	}
+	return f.stats(), nil
+}
@@ -0,0 +1,64 @@
+package analytics
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestViews(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "views.json")
+	v, err := Open(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	now := time.Date(2024, 3, 4, 9, 0, 0, 0, time.UTC)
+	v.Record("notes/b.org", "ada-key", now)
+	v.Record("notes/b.org", "ada-key", now)
+	v.Record("a.org", "ada-key", now)
+	v.Record("a.org", "grace-key", now)
+	v.Record("c.org", "", now.Add(time.Hour))
+	v.Record("c.org", "", now.Add(time.Hour))
+	v.Record("c.org", "", now.Add(time.Hour))
+
+	want := []Stats{
+		{Path: "a.org", Views: 2, Viewers: 2, LastViewed: now},
+		{Path: "notes/b.org", Views: 2, Viewers: 1, LastViewed: now},
+		{Path: "c.org", Views: 3, Viewers: 0, LastViewed: now.Add(time.Hour)},
+	}
+	if got := v.Stats(); !reflect.DeepEqual(got, want) {
+		t.Errorf("stats = %+v, want %+v", got, want)
+	}
+	if got := v.Popular(); !reflect.DeepEqual(got, []string{"a.org", "notes/b.org", "c.org"}) {
+		t.Errorf("popular = %v", got)
+	}
+
+	// Viewers are saved hashed, and counted the same once reopened
+	if err := v.Save(); err != nil {
+		t.Fatal(err)
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.Contains(string(data), "ada-key") {
+		t.Errorf("saved counts name a viewer: %s", data)
+	}
+	v, err = Open(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	v.Record("notes/b.org", "ada-key", now)
+	if got := v.Stats()[1]; got.Path != "notes/b.org" || got.Views != 3 || got.Viewers != 1 {
+		t.Errorf("reopened stats = %+v", got)
+	}
+	if err := v.Save(); err != nil {
+		t.Fatal(err)
+	}
+	if stats, err := Load(path); err != nil || len(stats) != 3 || stats[1].Views != 3 {
+		t.Errorf("Load = %+v, %v", stats, err)
+	}
+}
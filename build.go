@@ -0,0 +1,46 @@
+package main
+
+import (
+	"flag"
+	"time"
+
+	"org-charm/export"
+	"org-charm/org"
+	"org-charm/ui"
+
+	"github.com/charmbracelet/log"
+)
+
+// runBuild runs org-charm build with args, building a browsable static
+// site of the org directory: pages, directory indexes, tag pages and a
+// feed
+func runBuild(args []string) {
+	flags := flag.NewFlagSet("build", flag.ExitOnError)
+	orgDir := flags.String("dir", "./orgfiles", "Directory containing org files")
+	outDir := flags.String("out", "./site", "Directory to write the site to")
+	title := flags.String("title", "", "Title of the site (empty uses the name of the org directory)")
+	baseURL := flags.String("base-url", "", "URL the site is published at, for the links of its feed (empty keeps them relative)")
+	chromaStyle := flags.String("chroma-style", "", "Syntax highlighting style for source blocks (empty matches the theme)")
+	flags.Parse(args)
+
+	if *chromaStyle != "" {
+		if err := ui.CheckChromaStyle(*chromaStyle); err != nil {
+			log.Fatal("Invalid -chroma-style flag", "error", err)
+		}
+	}
+	tree, err := org.BuildFileTree(*orgDir)
+	if err != nil {
+		log.Fatal("Failed to build file tree", "error", err)
+	}
+
+	start := time.Now()
+	pages, err := export.Site(*orgDir, tree, *outDir, export.SiteOptions{
+		HTMLOptions: export.HTMLOptions{ChromaStyle: *chromaStyle},
+		Title:       *title,
+		BaseURL:     *baseURL,
+	})
+	if err != nil {
+		log.Fatal("Failed to build site", "error", err)
+	}
+	log.Info("Built static site", "pages", pages, "out", *outDir, "took", time.Since(start))
+}
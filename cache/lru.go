@@ -0,0 +1,121 @@
+// Package cache provides the size-bounded caches the server keeps parsed
+// and rendered documents in.
+package cache
+
+import (
+	"container/list"
+	"sync"
+)
+
+// Stats describes the use of a cache
+type Stats struct {
+	Hits      int64 // Lookups that found a value
+	Misses    int64 // Lookups that didn't
+	Evictions int64 // Values dropped to stay within the budget
+	Entries   int   // Values held
+	Bytes     int64 // Estimated size of the values held
+	Budget    int64 // Size the values are kept within, or 0 for no limit
+}
+
+// HitRate returns the fraction of lookups that found a value, or 0 before
+// any lookup
+func (s Stats) HitRate() float64 {
+	if s.Hits+s.Misses == 0 {
+		return 0
+	}
+	return float64(s.Hits) / float64(s.Hits+s.Misses)
+}
+
+// LRU is a cache holding values up to a budget of bytes, as estimated by
+// its size function, dropping the least recently used values beyond it.
+// It is safe for concurrent use.
+type LRU[K comparable, V any] struct {
+	mu    sync.Mutex
+	size  func(V) int64
+	items map[K]*list.Element
+	order *list.List // Most recently used first
+	stats Stats
+}
+
+// entry is a value in the cache with its key and estimated size
+type entry[K comparable, V any] struct {
+	key   K
+	value V
+	size  int64
+}
+
+// New creates a cache keeping the values it holds within budget bytes as
+// estimated by size. A budget of 0 or less doesn't limit it.
+func New[K comparable, V any](budget int64, size func(V) int64) *LRU[K, V] {
+	return &LRU[K, V]{
+		size:  size,
+		items: make(map[K]*list.Element),
+		order: list.New(),
+		stats: Stats{Budget: max(0, budget)},
+	}
+}
+
+// Get returns the value cached under key, if any, marking it as used
+func (c *LRU[K, V]) Get(key K) (V, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	el, ok := c.items[key]
+	if !ok {
+		c.stats.Misses++
+		var zero V
+		return zero, false
+	}
+	c.stats.Hits++
+	c.order.MoveToFront(el)
+	return el.Value.(*entry[K, V]).value, true
+}
+
+// Add caches value under key, replacing any value cached under it, and
+// evicts the least recently used values beyond the budget. Values larger
+// than the whole budget aren't cached.
+func (c *LRU[K, V]) Add(key K, value V) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if el, ok := c.items[key]; ok {
+		c.remove(el)
+	}
+	size := c.size(value)
+	if c.stats.Budget > 0 && size > c.stats.Budget {
+		return
+	}
+	c.items[key] = c.order.PushFront(&entry[K, V]{key, value, size})
+	c.stats.Entries++
+	c.stats.Bytes += size
+	c.evict()
+}
+
+// SetBudget changes the budget, evicting values beyond a smaller one
+func (c *LRU[K, V]) SetBudget(budget int64) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.stats.Budget = max(0, budget)
+	c.evict()
+}
+
+// Stats returns the current use of the cache
+func (c *LRU[K, V]) Stats() Stats {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.stats
+}
+
+// evict drops the least recently used values until the rest fit the budget
+func (c *LRU[K, V]) evict() {
+	for c.stats.Budget > 0 && c.stats.Bytes > c.stats.Budget {
+		c.remove(c.order.Back())
+		c.stats.Evictions++
+	}
+}
+
+// remove drops the value of el
+func (c *LRU[K, V]) remove(el *list.Element) {
+	e := c.order.Remove(el).(*entry[K, V])
+	delete(c.items, e.key)
+	c.stats.Entries--
+	c.stats.Bytes -= e.size
+}
@@ -0,0 +1,43 @@
+package cache
+
+import "testing"
+
+func TestLRU(t *testing.T) {
+	c := New[string, string](10, func(v string) int64 { return int64(len(v)) })
+	c.Add("a", "aaaa")
+	c.Add("b", "bbbb")
+	if _, ok := c.Get("a"); !ok {
+		t.Fatal("a not cached")
+	}
+
+	// b is the least recently used, so it makes room for c
+	c.Add("c", "cccc")
+	if _, ok := c.Get("b"); ok {
+		t.Error("b not evicted")
+	}
+	if v, ok := c.Get("a"); !ok || v != "aaaa" {
+		t.Errorf("Get(a) = %q, %t", v, ok)
+	}
+
+	// Values larger than the budget aren't cached
+	c.Add("d", "ddddddddddd")
+	if _, ok := c.Get("d"); ok {
+		t.Error("value over budget cached")
+	}
+
+	// Replacing a value counts its new size
+	c.Add("a", "a")
+	stats := c.Stats()
+	want := Stats{Hits: 2, Misses: 2, Evictions: 1, Entries: 2, Bytes: 5, Budget: 10}
+	if stats != want {
+		t.Errorf("Stats() = %+v, want %+v", stats, want)
+	}
+	if rate := stats.HitRate(); rate != 0.5 {
+		t.Errorf("HitRate() = %v, want 0.5", rate)
+	}
+
+	c.SetBudget(3)
+	if stats := c.Stats(); stats.Entries != 1 || stats.Bytes != 1 {
+		t.Errorf("after shrinking the budget: %+v", stats)
+	}
+}
@@ -0,0 +1,221 @@
+// Package caldav publishes the SCHEDULED and DEADLINE entries of the org
+// files to a calendar collection of a CalDAV server, such as a Nextcloud,
+// Fastmail or Radicale calendar, for calendar apps to sync with. The org
+// files are the source of truth: events the publisher put in the
+// collection are replaced or removed as the entries change, and changes
+// made to them in the calendar are overwritten. Other events of the
+// collection are left alone.
+package caldav
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"path"
+	"strings"
+	"time"
+
+	"org-charm/export"
+)
+
+// requestTimeout bounds how long a listing, upload or removal may take
+const requestTimeout = time.Minute
+
+// resourcePrefix starts the names of the events the publisher puts in the
+// collection, to tell them from others
+const resourcePrefix = "org-charm-"
+
+// propfindBody asks for the names of the resources of the collection only
+const propfindBody = `<?xml version="1.0" encoding="utf-8"?>
+<d:propfind xmlns:d="DAV:"><d:prop><d:resourcetype/></d:prop></d:propfind>`
+
+// Publisher keeps the events of a calendar collection in step with the
+// entries of the org files
+type Publisher struct {
+	url      *url.URL // Collection, with a trailing slash
+	user     string
+	password string
+	client   *http.Client
+
+	// Hashes of the events as last uploaded, by resource name
+	uploaded map[string]string
+}
+
+// Result counts the changes a Publish made to the collection
+type Result struct {
+	Uploaded int
+	Removed  int
+}
+
+// New creates a publisher to the calendar collection at rawURL,
+// authenticating with user and password unless user is empty
+func New(rawURL, user, password string) (*Publisher, error) {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return nil, err
+	}
+	if (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return nil, fmt.Errorf("%s: want an http or https URL", rawURL)
+	}
+	if !strings.HasSuffix(u.Path, "/") {
+		u.Path += "/"
+		u.RawPath = ""
+	}
+	return &Publisher{
+		url:      u,
+		user:     user,
+		password: password,
+		client:   &http.Client{Timeout: requestTimeout},
+		uploaded: map[string]string{},
+	}, nil
+}
+
+// Publish uploads the events added or changed since the last Publish, and
+// removes the events it uploaded before that aren't among events anymore.
+// Every event is uploaded on the first Publish, as the publisher doesn't
+// know what a previous run left in the collection. Events sharing a UID,
+// as entries given the same :ID: do, would overwrite each other, and fail
+// the Publish before anything is uploaded.
+func (p *Publisher) Publish(ctx context.Context, events []export.Event) (Result, error) {
+	var result Result
+	current := make(map[string]bool, len(events))
+	for _, event := range events {
+		name := resourceName(event.UID)
+		if current[name] {
+			return result, fmt.Errorf("%s: UID shared by several events", event.UID)
+		}
+		current[name] = true
+	}
+	for _, event := range events {
+		name := resourceName(event.UID)
+		sum := sha256.Sum256(event.ICal)
+		hash := hex.EncodeToString(sum[:])
+		if p.uploaded[name] == hash {
+			continue
+		}
+		err := p.do(ctx, http.MethodPut, name, bytes.NewReader(event.ICal), map[string]string{
+			"Content-Type": "text/calendar; charset=utf-8",
+		})
+		if err != nil {
+			return result, fmt.Errorf("%s: %w", event.UID, err)
+		}
+		p.uploaded[name] = hash
+		result.Uploaded++
+	}
+
+	// Drop the events of entries gone, or done, including those a previous
+	// run uploaded
+	names, err := p.list(ctx)
+	if err != nil {
+		return result, err
+	}
+	for _, name := range names {
+		if current[name] || !strings.HasPrefix(name, resourcePrefix) || !strings.HasSuffix(name, ".ics") {
+			continue
+		}
+		if err := p.do(ctx, http.MethodDelete, name, nil, nil); err != nil {
+			return result, fmt.Errorf("%s: %w", name, err)
+		}
+		delete(p.uploaded, name)
+		result.Removed++
+	}
+	return result, nil
+}
+
+// resourceName returns the name of the resource of the event with the
+// given UID in the collection. UIDs are hashed, as they may have
+// characters that aren't safe in names.
+func resourceName(uid string) string {
+	sum := sha256.Sum256([]byte(uid))
+	return resourcePrefix + hex.EncodeToString(sum[:12]) + ".ics"
+}
+
+// multistatus is the response to a PROPFIND request
+type multistatus struct {
+	Responses []struct {
+		Href string `xml:"DAV: href"`
+	} `xml:"DAV: response"`
+}
+
+// list returns the names of the resources of the collection
+func (p *Publisher) list(ctx context.Context) ([]string, error) {
+	ctx, cancel := context.WithTimeout(ctx, requestTimeout)
+	defer cancel()
+	req, err := p.request(ctx, "PROPFIND", p.url, strings.NewReader(propfindBody))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Depth", "1")
+	req.Header.Set("Content-Type", "application/xml; charset=utf-8")
+	resp, err := p.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode >= 300 {
+		return nil, fmt.Errorf("PROPFIND %s: %s", p.url.Redacted(), resp.Status)
+	}
+
+	var ms multistatus
+	if err := xml.NewDecoder(resp.Body).Decode(&ms); err != nil {
+		return nil, fmt.Errorf("listing %s: %w", p.url.Redacted(), err)
+	}
+	var names []string
+	for _, r := range ms.Responses {
+		u, err := url.Parse(r.Href)
+		if err != nil {
+			continue
+		}
+		u = p.url.ResolveReference(u)
+		if u.Host != p.url.Host || path.Dir(u.Path)+"/" != p.url.Path {
+			// The collection itself, or somewhere else
+			continue
+		}
+		names = append(names, path.Base(u.Path))
+	}
+	return names, nil
+}
+
+// do sends a request for the resource of the collection named name, and
+// drains the response. Removing a resource that's already gone succeeds.
+func (p *Publisher) do(ctx context.Context, method, name string, body io.Reader, header map[string]string) error {
+	ctx, cancel := context.WithTimeout(ctx, requestTimeout)
+	defer cancel()
+	target := p.url.ResolveReference(&url.URL{Path: name})
+	req, err := p.request(ctx, method, target, body)
+	if err != nil {
+		return err
+	}
+	for k, v := range header {
+		req.Header.Set(k, v)
+	}
+	resp, err := p.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	io.Copy(io.Discard, resp.Body)
+	if resp.StatusCode >= 300 && !(method == http.MethodDelete && resp.StatusCode == http.StatusNotFound) {
+		return fmt.Errorf("%s %s: %s", method, target.Redacted(), resp.Status)
+	}
+	return nil
+}
+
+// request creates an authenticated request to target
+func (p *Publisher) request(ctx context.Context, method string, target *url.URL, body io.Reader) (*http.Request, error) {
+	req, err := http.NewRequestWithContext(ctx, method, target.String(), body)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("User-Agent", "org-charm")
+	if p.user != "" {
+		req.SetBasicAuth(p.user, p.password)
+	}
+	return req, nil
+}
@@ -0,0 +1,136 @@
+package caldav
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sort"
+	"strings"
+	"sync"
+	"testing"
+
+	"org-charm/export"
+)
+
+// calServer serves a calendar collection under /cal/ada/org/
+type calServer struct {
+	mu     sync.Mutex
+	events map[string]string // Content by resource name
+	puts   int
+}
+
+func (s *calServer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if user, password, ok := r.BasicAuth(); !ok || user != "ada" || password != "secret" {
+		w.WriteHeader(http.StatusUnauthorized)
+		return
+	}
+	const root = "/cal/ada/org/"
+	name := strings.TrimPrefix(r.URL.Path, root)
+
+	switch r.Method {
+	case http.MethodPut:
+		if r.Header.Get("Content-Type") != "text/calendar; charset=utf-8" {
+			w.WriteHeader(http.StatusUnsupportedMediaType)
+			return
+		}
+		body, _ := io.ReadAll(r.Body)
+		s.events[name] = string(body)
+		s.puts++
+		w.WriteHeader(http.StatusCreated)
+	case http.MethodDelete:
+		if _, ok := s.events[name]; !ok {
+			http.NotFound(w, r)
+			return
+		}
+		delete(s.events, name)
+		w.WriteHeader(http.StatusNoContent)
+	case "PROPFIND":
+		var b strings.Builder
+		b.WriteString(`<?xml version="1.0"?><d:multistatus xmlns:d="DAV:">`)
+		fmt.Fprintf(&b, `<d:response><d:href>%s</d:href></d:response>`, root)
+		for name := range s.events {
+			fmt.Fprintf(&b, `<d:response><d:href>%s</d:href></d:response>`, root+name)
+		}
+		b.WriteString(`</d:multistatus>`)
+		w.WriteHeader(http.StatusMultiStatus)
+		fmt.Fprint(w, b.String())
+	default:
+		w.WriteHeader(http.StatusMethodNotAllowed)
+	}
+}
+
+func (s *calServer) names() []string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	var names []string
+	for name := range s.events {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+func TestPublish(t *testing.T) {
+	cal := &calServer{events: map[string]string{"birthday.ics": "BEGIN:VCALENDAR"}}
+	srv := httptest.NewServer(cal)
+	defer srv.Close()
+
+	publisher, err := New(srv.URL+"/cal/ada/org", "ada", "secret")
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx := context.Background()
+	publish := func(want Result, events ...export.Event) {
+		t.Helper()
+		result, err := publisher.Publish(ctx, events)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if result != want {
+			t.Errorf("Publish() = %+v, want %+v", result, want)
+		}
+	}
+	report := export.Event{UID: "DL-report@org-charm", ICal: []byte("BEGIN:VCALENDAR\r\nSUMMARY:DL: Report\r\n")}
+	plants := export.Event{UID: "S-plants@org-charm", ICal: []byte("BEGIN:VCALENDAR\r\nSUMMARY:S: Water plants\r\n")}
+
+	publish(Result{Uploaded: 2}, report, plants)
+	if names := cal.names(); len(names) != 3 || cal.events[resourceName(report.UID)] != string(report.ICal) {
+		t.Errorf("collection after publishing = %v", names)
+	}
+	publish(Result{}, report, plants)
+
+	report.ICal = []byte("BEGIN:VCALENDAR\r\nSUMMARY:DL: Final report\r\n")
+	publish(Result{Uploaded: 1, Removed: 1}, report)
+	if names := cal.names(); len(names) != 2 || cal.events[resourceName(report.UID)] != string(report.ICal) {
+		t.Errorf("collection after changing and removing events = %v", names)
+	}
+
+	// A new publisher, as after a restart, uploads everything once and
+	// removes what the previous one left
+	publisher, _ = New(srv.URL+"/cal/ada/org/", "ada", "secret")
+	puts := cal.puts
+	publish(Result{Uploaded: 1, Removed: 1}, plants)
+	publish(Result{}, plants)
+	if names := cal.names(); len(names) != 2 || cal.puts != puts+1 {
+		t.Errorf("collection after a restart = %v", names)
+	}
+	if _, ok := cal.events["birthday.ics"]; !ok {
+		t.Error("removed an event of the calendar it didn't upload")
+	}
+
+	// Events sharing a UID would overwrite each other, and upload again on
+	// every Publish
+	puts = cal.puts
+	duplicate := export.Event{UID: plants.UID, ICal: []byte("BEGIN:VCALENDAR\r\nSUMMARY:S: Water more plants\r\n")}
+	if _, err := publisher.Publish(ctx, []export.Event{plants, duplicate}); err == nil || cal.puts != puts {
+		t.Errorf("published events sharing a UID: error %v, %d uploads", err, cal.puts-puts)
+	}
+
+	if _, err := New("webcal://example.com/", "", ""); err == nil {
+		t.Error("accepted a URL other than http or https")
+	}
+}
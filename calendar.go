@@ -0,0 +1,83 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"net/http"
+	"os"
+	"time"
+
+	"org-charm/caldav"
+	"org-charm/export"
+	"org-charm/org"
+
+	"github.com/charmbracelet/log"
+)
+
+// caldavPasswordEnv is the environment variable holding the password of
+// the CalDAV user, kept out of the command line
+const caldavPasswordEnv = "ORG_CHARM_CALDAV_PASSWORD"
+
+// calendarPath is where the calendar is served, for calendar apps to
+// subscribe to
+const calendarPath = "/calendar.ics"
+
+// serveCalendar serves the SCHEDULED and DEADLINE entries of the org
+// files in library as an iCalendar at calendarPath on addr, from the
+// latest listing on every request
+func serveCalendar(addr string, library *org.Library) {
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET "+calendarPath, func(w http.ResponseWriter, r *http.Request) {
+		tree, _ := library.Tree()
+		var buf bytes.Buffer
+		if _, err := export.ICal(&buf, tree, time.Now()); err != nil {
+			log.Error("Failed to export calendar", "error", err)
+			http.Error(w, "failed to export calendar", http.StatusInternalServerError)
+			return
+		}
+		w.Header().Set("Content-Type", "text/calendar; charset=utf-8")
+		w.Write(buf.Bytes())
+	})
+
+	log.Info("Serving calendar", "addr", addr, "path", calendarPath)
+	go func() {
+		if err := http.ListenAndServe(addr, mux); err != nil {
+			log.Error("Calendar server error", "error", err)
+		}
+	}()
+}
+
+// publishCalendar publishes the SCHEDULED and DEADLINE entries of the org
+// files in library to the CalDAV calendar collection at url, at startup
+// and every interval, in the background
+func publishCalendar(url, user string, library *org.Library, interval time.Duration) {
+	publisher, err := caldav.New(url, user, os.Getenv(caldavPasswordEnv))
+	if err != nil {
+		log.Fatal("Invalid -caldav-url flag", "error", err)
+	}
+	publish := func() {
+		ctx, cancel := context.WithTimeout(context.Background(), interval)
+		defer cancel()
+		tree, _ := library.Tree()
+		events, err := export.ICalEvents(tree)
+		if err != nil {
+			log.Error("Failed to export calendar", "error", err)
+			return
+		}
+		result, err := publisher.Publish(ctx, events)
+		if err != nil {
+			log.Error("Failed to publish calendar", "url", url, "error", err)
+			return
+		}
+		if result.Uploaded > 0 || result.Removed > 0 {
+			log.Info("Published calendar", "url", url, "uploaded", result.Uploaded, "removed", result.Removed)
+		}
+	}
+
+	go func() {
+		publish()
+		for range time.Tick(interval) {
+			publish()
+		}
+	}()
+}
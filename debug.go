@@ -0,0 +1,64 @@
+package main
+
+import (
+	"errors"
+	"expvar"
+	"net"
+	"net/http"
+	"net/http/pprof"
+
+	"org-charm/cache"
+	"org-charm/org"
+	"org-charm/ui"
+
+	"github.com/charmbracelet/log"
+)
+
+// debugAddr returns addr with its host defaulting to localhost, and an
+// error unless the host is a loopback address: profiles and variables are
+// for operators on the server, not visitors
+func debugAddr(addr string) (string, error) {
+	host, port, err := net.SplitHostPort(addr)
+	if err != nil {
+		return "", err
+	}
+	if host == "" {
+		host = "localhost"
+	}
+	if ip := net.ParseIP(host); host != "localhost" && (ip == nil || !ip.IsLoopback()) {
+		return "", errors.New("debug endpoints must listen on localhost")
+	}
+	return net.JoinHostPort(host, port), nil
+}
+
+// serveDebug serves net/http/pprof under /debug/pprof/ and expvar, with
+// the use of the shared caches and of the sessions running, under
+// /debug/vars on addr
+func serveDebug(addr string) {
+	expvar.Publish("cache", expvar.Func(func() any {
+		documents, previews := ui.CacheStats()
+		return map[string]cache.Stats{
+			"parsed":   org.CacheStats(),
+			"rendered": documents,
+			"previews": previews,
+		}
+	}))
+	expvar.Publish("sessions", expvar.Func(func() any {
+		return ui.Sessions()
+	}))
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/debug/pprof/", pprof.Index)
+	mux.HandleFunc("/debug/pprof/cmdline", pprof.Cmdline)
+	mux.HandleFunc("/debug/pprof/profile", pprof.Profile)
+	mux.HandleFunc("/debug/pprof/symbol", pprof.Symbol)
+	mux.HandleFunc("/debug/pprof/trace", pprof.Trace)
+	mux.Handle("/debug/vars", expvar.Handler())
+
+	log.Info("Serving debug endpoints", "addr", addr)
+	go func() {
+		if err := http.ListenAndServe(addr, mux); err != nil {
+			log.Error("Debug server error", "error", err)
+		}
+	}()
+}
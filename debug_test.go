@@ -0,0 +1,32 @@
+package main
+
+import "testing"
+
+func TestDebugAddr(t *testing.T) {
+	tests := []struct {
+		addr string
+		want string // Empty if refused
+	}{
+		{"localhost:6060", "localhost:6060"},
+		{":6060", "localhost:6060"},
+		{"127.0.0.1:6060", "127.0.0.1:6060"},
+		{"[::1]:6060", "[::1]:6060"},
+		{"0.0.0.0:6060", ""},
+		{"[::]:6060", ""},
+		{"192.168.1.10:6060", ""},
+		{"example.com:6060", ""},
+		{"6060", ""},
+	}
+	for _, tt := range tests {
+		got, err := debugAddr(tt.addr)
+		if tt.want == "" {
+			if err == nil {
+				t.Errorf("debugAddr(%q) = %q, want an error", tt.addr, got)
+			}
+			continue
+		}
+		if err != nil || got != tt.want {
+			t.Errorf("debugAddr(%q) = %q, %v; want %q", tt.addr, got, err, tt.want)
+		}
+	}
+}
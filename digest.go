@@ -0,0 +1,47 @@
+package main
+
+import (
+	"os"
+	"time"
+
+	"org-charm/digest"
+	"org-charm/org"
+
+	"github.com/charmbracelet/log"
+)
+
+// smtpPasswordEnv is the environment variable holding the password of the
+// SMTP user, kept out of the command line
+const smtpPasswordEnv = "ORG_CHARM_SMTP_PASSWORD"
+
+// scheduleDigest emails a digest of the agenda of the next days days of
+// the org files in library to the addresses to, whenever schedule fires,
+// in the background
+func scheduleDigest(schedule *digest.Schedule, days int, addr, user, from, to string, library *org.Library) {
+	mailer, err := digest.New(addr, user, os.Getenv(smtpPasswordEnv), from, to)
+	if err != nil {
+		log.Fatal("Invalid -digest-to flag", "error", err)
+	}
+	send := func(now time.Time) {
+		tree, _ := library.Tree()
+		items, err := org.Agenda(tree, now, days)
+		if err != nil {
+			log.Error("Failed to gather the agenda", "error", err)
+			return
+		}
+		subject, body := digest.Render(items, now, days)
+		if err := mailer.Send(subject, body, now); err != nil {
+			log.Error("Failed to email the agenda digest", "smtp", addr, "error", err)
+			return
+		}
+		log.Info("Emailed the agenda digest", "to", to, "items", len(items))
+	}
+
+	go func() {
+		for {
+			next := schedule.Next(time.Now())
+			time.Sleep(time.Until(next))
+			send(next)
+		}
+	}()
+}
@@ -0,0 +1,214 @@
+// Package digest emails a digest of the agenda of the coming days, the
+// SCHEDULED and DEADLINE entries of the org files laid out day by day as
+// org agendas show them, over SMTP on a crontab schedule
+package digest
+
+import (
+	"bytes"
+	"crypto/tls"
+	"fmt"
+	"mime"
+	"mime/quotedprintable"
+	"net"
+	"net/mail"
+	"net/smtp"
+	"path"
+	"strings"
+	"time"
+
+	"org-charm/org"
+)
+
+// sendTimeout bounds how long sending a digest may take
+const sendTimeout = time.Minute
+
+// Render renders the agenda items of the days days from the day of from,
+// as org.Agenda returns them, as the subject and plain text body of a
+// digest. Every day is listed, with or without items.
+func Render(items []org.AgendaItem, from time.Time, days int) (subject, body string) {
+	first := time.Date(from.Year(), from.Month(), from.Day(), 0, 0, 0, 0, time.UTC)
+	last := first.AddDate(0, 0, max(1, days)-1)
+	subject = "Agenda for " + first.Format("Mon 2 Jan 2006")
+	if days > 1 {
+		subject = "Agenda for " + first.Format("Mon 2 Jan") + " – " + last.Format("Mon 2 Jan 2006")
+	}
+
+	// Align the entries past the categories, the file names, as org does
+	width := 0
+	for _, item := range items {
+		width = max(width, len([]rune(category(item.File))))
+	}
+
+	var b strings.Builder
+	next := 0
+	for day := first; !day.After(last); day = day.AddDate(0, 0, 1) {
+		if !day.Equal(first) {
+			b.WriteString("\n")
+		}
+		b.WriteString(day.Format("Monday 2 January 2006"))
+		b.WriteString("\n")
+		for ; next < len(items) && items[next].Day.Equal(day); next++ {
+			item := items[next]
+			clock := ""
+			if item.HasTime {
+				clock = item.Date.Format("15:04")
+			}
+			fmt.Fprintf(&b, "  %-*s %5s %-11s %s\n", width+1, category(item.File)+":", clock, label(item), title(item.Entry))
+		}
+	}
+	return subject, b.String()
+}
+
+// category returns the category org agendas show for an entry of the file
+// at rel, the name of the file
+func category(rel string) string {
+	return strings.TrimSuffix(path.Base(rel), ".org")
+}
+
+// label returns what an item is, as org agendas label them: Scheduled: or
+// Deadline:, or how long past due it is
+func label(item org.AgendaItem) string {
+	if item.Overdue() {
+		days := int(item.Day.Sub(time.Date(item.Date.Year(), item.Date.Month(), item.Date.Day(), 0, 0, 0, 0, time.UTC)).Hours() / 24)
+		if item.Kind == "DL" {
+			return fmt.Sprintf("%d d. ago:", days)
+		}
+		return fmt.Sprintf("Sched.%2dx:", days)
+	}
+	if item.Kind == "DL" {
+		return "Deadline:"
+	}
+	return "Scheduled:"
+}
+
+// title returns the headline of an entry as agendas show it, with its
+// keyword and tags
+func title(entry org.PlannedEntry) string {
+	s := entry.Title
+	if entry.Status != "" {
+		s = entry.Status + " " + s
+	}
+	if len(entry.Tags) > 0 {
+		s += "  :" + strings.Join(entry.Tags, ":") + ":"
+	}
+	return s
+}
+
+// Mailer sends digests from an address to others through an SMTP server
+type Mailer struct {
+	addr     string // host:port of the server
+	user     string
+	password string
+	from     *mail.Address
+	to       []*mail.Address
+}
+
+// New creates a mailer sending digests through the SMTP server at addr,
+// host:port, from the address from to the comma-separated addresses to,
+// authenticating with user and password unless user is empty. Servers on
+// port 465 are spoken to over TLS, others upgraded to it with STARTTLS
+// when they offer it.
+func New(addr, user, password, from, to string) (*Mailer, error) {
+	if _, _, err := net.SplitHostPort(addr); err != nil {
+		return nil, fmt.Errorf("%s: want host:port", addr)
+	}
+	sender, err := mail.ParseAddress(from)
+	if err != nil {
+		return nil, fmt.Errorf("sender %q: %w", from, err)
+	}
+	recipients, err := mail.ParseAddressList(to)
+	if err != nil {
+		return nil, fmt.Errorf("recipients %q: %w", to, err)
+	}
+	return &Mailer{addr: addr, user: user, password: password, from: sender, to: recipients}, nil
+}
+
+// Send emails a digest with the given subject and plain text body, dated
+// now
+func (m *Mailer) Send(subject, body string, now time.Time) error {
+	msg, err := m.message(subject, body, now)
+	if err != nil {
+		return err
+	}
+
+	host, port, _ := net.SplitHostPort(m.addr)
+	dialer := &net.Dialer{Timeout: sendTimeout}
+	var conn net.Conn
+	if port == "465" {
+		conn, err = tls.DialWithDialer(dialer, "tcp", m.addr, &tls.Config{ServerName: host})
+	} else {
+		conn, err = dialer.Dial("tcp", m.addr)
+	}
+	if err != nil {
+		return err
+	}
+	conn.SetDeadline(time.Now().Add(sendTimeout))
+	c, err := smtp.NewClient(conn, host)
+	if err != nil {
+		conn.Close()
+		return err
+	}
+	defer c.Close()
+
+	if ok, _ := c.Extension("STARTTLS"); ok {
+		if err := c.StartTLS(&tls.Config{ServerName: host}); err != nil {
+			return err
+		}
+	}
+	if m.user != "" {
+		// Refuses to send the password unencrypted, but to localhost
+		if err := c.Auth(smtp.PlainAuth("", m.user, m.password, host)); err != nil {
+			return err
+		}
+	}
+	if err := c.Mail(m.from.Address); err != nil {
+		return err
+	}
+	for _, to := range m.to {
+		if err := c.Rcpt(to.Address); err != nil {
+			return fmt.Errorf("%s: %w", to.Address, err)
+		}
+	}
+	w, err := c.Data()
+	if err != nil {
+		return err
+	}
+	if _, err := w.Write(msg); err != nil {
+		return err
+	}
+	if err := w.Close(); err != nil {
+		return err
+	}
+	return c.Quit()
+}
+
+// message returns the email of a digest, headers and quoted-printable
+// body, with CRLF line endings
+func (m *Mailer) message(subject, body string, now time.Time) ([]byte, error) {
+	to := make([]string, len(m.to))
+	for i, addr := range m.to {
+		to[i] = addr.String()
+	}
+	var b bytes.Buffer
+	header := func(name, value string) {
+		b.WriteString(name + ": " + value + "\r\n")
+	}
+	header("From", m.from.String())
+	header("To", strings.Join(to, ", "))
+	header("Subject", mime.QEncoding.Encode("utf-8", subject))
+	header("Date", now.Format(time.RFC1123Z))
+	header("MIME-Version", "1.0")
+	header("Content-Type", "text/plain; charset=utf-8")
+	header("Content-Transfer-Encoding", "quoted-printable")
+	header("Auto-Submitted", "auto-generated")
+	b.WriteString("\r\n")
+
+	qp := quotedprintable.NewWriter(&b)
+	if _, err := qp.Write([]byte(strings.ReplaceAll(body, "\n", "\r\n"))); err != nil {
+		return nil, err
+	}
+	if err := qp.Close(); err != nil {
+		return nil, err
+	}
+	return b.Bytes(), nil
+}
@@ -0,0 +1,179 @@
+package digest
+
+import (
+	"bufio"
+	"io"
+	"mime/quotedprintable"
+	"net"
+	"net/mail"
+	"strings"
+	"testing"
+	"time"
+
+	"org-charm/org"
+)
+
+func TestParseSchedule(t *testing.T) {
+	at := func(s string) time.Time {
+		t.Helper()
+		v, err := time.Parse("2006-01-02 15:04", s)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return v
+	}
+	tests := []struct {
+		spec, after, want string
+	}{
+		{"0 7 * * *", "2024-03-04 06:59", "2024-03-04 07:00"},
+		{"0 7 * * *", "2024-03-04 07:00", "2024-03-05 07:00"},
+		{"@daily", "2024-03-04 07:00", "2024-03-05 00:00"},
+		{"*/15 9-17 * * *", "2024-03-04 17:50", "2024-03-05 09:00"},
+		{"30 8 * * 1-5", "2024-03-08 09:00", "2024-03-11 08:30"}, // Friday to Monday
+		{"0 8 * * 7", "2024-03-04 09:00", "2024-03-10 08:00"},    // Sunday as 7
+		{"0 0 1,15 * 1", "2024-03-02 00:00", "2024-03-04 00:00"}, // Either day matches
+		{"0 0 */2 * 1", "2024-03-04 00:00", "2024-03-11 00:00"},  // Both match with a star
+		{"0 0 1 * */2", "2024-03-02 00:00", "2024-06-01 00:00"},
+		{"0 0 29 2 *", "2024-03-01 00:00", "2028-02-29 00:00"},
+	}
+	for _, tt := range tests {
+		s, err := ParseSchedule(tt.spec)
+		if err != nil {
+			t.Errorf("ParseSchedule(%q): %v", tt.spec, err)
+			continue
+		}
+		if got := s.Next(at(tt.after)); !got.Equal(at(tt.want)) {
+			t.Errorf("%q after %s = %s, want %s", tt.spec, tt.after, got.Format("2006-01-02 15:04"), tt.want)
+		}
+	}
+
+	for _, spec := range []string{"", "0 7 * *", "60 * * * *", "0 7 * * 8", "5-1 * * * *", "*/0 * * * *", "0 0 31 2 *"} {
+		if _, err := ParseSchedule(spec); err == nil {
+			t.Errorf("ParseSchedule(%q) succeeded", spec)
+		}
+	}
+}
+
+func TestRender(t *testing.T) {
+	day := func(d int) time.Time { return time.Date(2024, 3, d, 0, 0, 0, 0, time.UTC) }
+	items := []org.AgendaItem{
+		{Day: day(4), File: "work.org", Kind: "S", Date: day(4).Add(9 * time.Hour), HasTime: true,
+			Entry: org.PlannedEntry{Title: "Standup", Status: "TODO"}},
+		{Day: day(4), File: "sub/home.org", Kind: "DL", Date: day(1),
+			Entry: org.PlannedEntry{Title: "Rent", Status: "TODO", Tags: []string{"bills"}}},
+		{Day: day(6), File: "work.org", Kind: "DL", Date: day(6),
+			Entry: org.PlannedEntry{Title: "Report"}},
+	}
+	subject, body := Render(items, time.Date(2024, 3, 4, 7, 0, 0, 0, time.Local), 3)
+	if subject != "Agenda for Mon 4 Mar – Wed 6 Mar 2024" {
+		t.Errorf("subject = %q", subject)
+	}
+	want := `Monday 4 March 2024
+  work: 09:00 Scheduled:  TODO Standup
+  home:       3 d. ago:   TODO Rent  :bills:
+
+Tuesday 5 March 2024
+
+Wednesday 6 March 2024
+  work:       Deadline:   Report
+`
+	if body != want {
+		t.Errorf("body =\n%s\nwant\n%s", body, want)
+	}
+}
+
+func TestSend(t *testing.T) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ln.Close()
+
+	// A server speaking just enough SMTP to take one message
+	type received struct {
+		from string
+		to   []string
+		data string
+	}
+	got := make(chan received, 1)
+	go func() {
+		conn, err := ln.Accept()
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+		r := bufio.NewReader(conn)
+		reply := func(s string) { io.WriteString(conn, s+"\r\n") }
+		var msg received
+		reply("220 localhost ESMTP")
+		for {
+			line, err := r.ReadString('\n')
+			if err != nil {
+				return
+			}
+			line = strings.TrimRight(line, "\r\n")
+			switch cmd := strings.ToUpper(strings.SplitN(line, " ", 2)[0]); cmd {
+			case "EHLO", "HELO":
+				reply("250 localhost")
+			case "MAIL":
+				msg.from = line
+				reply("250 OK")
+			case "RCPT":
+				msg.to = append(msg.to, line)
+				reply("250 OK")
+			case "DATA":
+				reply("354 Go ahead")
+				var data strings.Builder
+				for {
+					l, err := r.ReadString('\n')
+					if err != nil || l == ".\r\n" {
+						break
+					}
+					data.WriteString(l)
+				}
+				msg.data = data.String()
+				reply("250 OK")
+			case "QUIT":
+				reply("221 Bye")
+				got <- msg
+				return
+			default:
+				reply("502 Not implemented")
+			}
+		}
+	}()
+
+	m, err := New(ln.Addr().String(), "", "", "org-charm <agenda@example.com>", "me@example.com, Team <team@example.com>")
+	if err != nil {
+		t.Fatal(err)
+	}
+	body := "Monday 4 March 2024\n  work: 09:00 Scheduled:  TODO Standup – café\n"
+	if err := m.Send("Agenda for Mon 4 Mar 2024", body, time.Date(2024, 3, 4, 7, 0, 0, 0, time.UTC)); err != nil {
+		t.Fatal(err)
+	}
+
+	msg := <-got
+	if msg.from != "MAIL FROM:<agenda@example.com>" {
+		t.Errorf("from = %q", msg.from)
+	}
+	if len(msg.to) != 2 || msg.to[0] != "RCPT TO:<me@example.com>" || msg.to[1] != "RCPT TO:<team@example.com>" {
+		t.Errorf("to = %q", msg.to)
+	}
+	parsed, err := mail.ReadMessage(strings.NewReader(msg.data))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if s := parsed.Header.Get("Subject"); s != "Agenda for Mon 4 Mar 2024" {
+		t.Errorf("subject = %q", s)
+	}
+	if to := parsed.Header.Get("To"); to != `<me@example.com>, "Team" <team@example.com>` {
+		t.Errorf("To = %q", to)
+	}
+	text, err := io.ReadAll(quotedprintable.NewReader(parsed.Body))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(text) != strings.ReplaceAll(body, "\n", "\r\n") {
+		t.Errorf("body = %q", text)
+	}
+}
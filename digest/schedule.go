@@ -0,0 +1,147 @@
+package digest
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// scheduleAliases are the shorthands of common schedules cron accepts
+var scheduleAliases = map[string]string{
+	"@hourly":  "0 * * * *",
+	"@daily":   "0 0 * * *",
+	"@weekly":  "0 0 * * 0",
+	"@monthly": "0 0 1 * *",
+}
+
+// Schedule is when a digest is sent, in the five fields of a crontab line:
+// minute, hour, day of month, month and day of week
+type Schedule struct {
+	minute, hour, dom, month, dow uint64 // Bit i set for value i
+	// As in cron, when both days are restricted either may match; a day
+	// field starting with *, as */2 does, doesn't restrict them
+	domAny, dowAny bool
+}
+
+// scheduleField is the range of values of a field of a schedule
+type scheduleField struct {
+	name     string
+	min, max int
+}
+
+var scheduleFields = [5]scheduleField{
+	{"minute", 0, 59},
+	{"hour", 0, 23},
+	{"day of month", 1, 31},
+	{"month", 1, 12},
+	{"day of week", 0, 7}, // Sunday is 0 or 7
+}
+
+// ParseSchedule parses a crontab schedule such as "0 7 * * 1-5", with
+// lists, ranges and steps, or one of @hourly, @daily, @weekly and @monthly
+func ParseSchedule(spec string) (*Schedule, error) {
+	if alias, ok := scheduleAliases[strings.TrimSpace(spec)]; ok {
+		spec = alias
+	}
+	fields := strings.Fields(spec)
+	if len(fields) != len(scheduleFields) {
+		return nil, fmt.Errorf("%q: want 5 fields (minute hour day-of-month month day-of-week)", spec)
+	}
+	var bits [5]uint64
+	for i, field := range fields {
+		var err error
+		if bits[i], err = parseScheduleField(field, scheduleFields[i]); err != nil {
+			return nil, err
+		}
+	}
+	s := &Schedule{
+		minute: bits[0],
+		hour:   bits[1],
+		dom:    bits[2],
+		month:  bits[3],
+		dow:    bits[4],
+		domAny: strings.HasPrefix(fields[2], "*"),
+		dowAny: strings.HasPrefix(fields[4], "*"),
+	}
+	if s.dow&(1<<7) != 0 {
+		s.dow |= 1
+	}
+	if s.Next(time.Now()).IsZero() {
+		return nil, fmt.Errorf("%q: never fires", spec)
+	}
+	return s, nil
+}
+
+// parseScheduleField parses a comma-separated list of values, ranges such
+// as 1-5 and * of a schedule field, each optionally stepped as in */15
+func parseScheduleField(s string, f scheduleField) (uint64, error) {
+	var bits uint64
+	for _, part := range strings.Split(s, ",") {
+		span, stepText, stepped := strings.Cut(part, "/")
+		step := 1
+		if stepped {
+			n, err := strconv.Atoi(stepText)
+			if err != nil || n < 1 {
+				return 0, fmt.Errorf("%s %q: invalid step", f.name, part)
+			}
+			step = n
+		}
+		lo, hi := f.min, f.max
+		if span != "*" {
+			first, last, isRange := strings.Cut(span, "-")
+			var err error
+			if lo, err = strconv.Atoi(first); err != nil {
+				return 0, fmt.Errorf("%s %q: invalid value", f.name, part)
+			}
+			hi = lo
+			if isRange {
+				if hi, err = strconv.Atoi(last); err != nil {
+					return 0, fmt.Errorf("%s %q: invalid value", f.name, part)
+				}
+			} else if stepped {
+				// 5/15 steps from 5 to the end of the range
+				hi = f.max
+			}
+		}
+		if lo < f.min || hi > f.max || lo > hi {
+			return 0, fmt.Errorf("%s %q: out of range %d-%d", f.name, part, f.min, f.max)
+		}
+		for v := lo; v <= hi; v += step {
+			bits |= 1 << v
+		}
+	}
+	return bits, nil
+}
+
+// Next returns the first time after after the schedule fires, in the
+// location of after, or the zero time if it doesn't within five years
+func (s *Schedule) Next(after time.Time) time.Time {
+	t := after.Truncate(time.Minute).Add(time.Minute)
+	limit := t.AddDate(5, 0, 0)
+	for t.Before(limit) {
+		switch {
+		case s.month&(1<<int(t.Month())) == 0:
+			t = time.Date(t.Year(), t.Month()+1, 1, 0, 0, 0, 0, t.Location())
+		case !s.dayMatches(t):
+			t = time.Date(t.Year(), t.Month(), t.Day()+1, 0, 0, 0, 0, t.Location())
+		case s.hour&(1<<t.Hour()) == 0:
+			t = time.Date(t.Year(), t.Month(), t.Day(), t.Hour()+1, 0, 0, 0, t.Location())
+		case s.minute&(1<<t.Minute()) == 0:
+			t = t.Add(time.Minute)
+		default:
+			return t
+		}
+	}
+	return time.Time{}
+}
+
+// dayMatches reports whether the schedule fires on the day of t
+func (s *Schedule) dayMatches(t time.Time) bool {
+	dom := s.dom&(1<<t.Day()) != 0
+	dow := s.dow&(1<<int(t.Weekday())) != 0
+	if s.domAny || s.dowAny {
+		return dom && dow
+	}
+	return dom || dow
+}
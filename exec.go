@@ -0,0 +1,130 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"runtime/debug"
+	"strings"
+	"time"
+
+	"org-charm/export"
+	"org-charm/org"
+	"org-charm/ui"
+
+	"github.com/charmbracelet/log"
+	"github.com/charmbracelet/ssh"
+	"github.com/charmbracelet/wish"
+)
+
+// execUsage describes the commands sessions can run instead of the TUI
+const execUsage = `Commands:
+  markdown FILE.org    Print an org file converted to Markdown
+  json FILE.org        Print the structure of an org file as JSON
+  ical                 Print the SCHEDULED and DEADLINE entries as an iCalendar
+  render [-width N] [-color truecolor|256|16|none] FILE.org
+                       Print an org file as the TUI shows it, e.g. | less -R
+
+FILE.org is relative to the org directory, e.g. notes/todo.org.`
+
+// execMiddleware runs the command a session is started with, as in
+// ssh -p 2222 host markdown notes.org, printing its output instead of
+// serving the TUI, rendering documents with the settings of render.
+// Sessions without a command go on to the TUI.
+func execMiddleware(library *org.Library, render ui.RenderOptions) wish.Middleware {
+	render.Root = library.Root()
+	return func(next ssh.Handler) ssh.Handler {
+		return func(sess ssh.Session) {
+			args := sess.Command()
+			if len(args) == 0 {
+				next(sess)
+				return
+			}
+			log.Info("SSH command", "user", sess.User(), "command", strings.Join(args, " "))
+			// A command failing on a document mustn't take the server down
+			defer func() {
+				if r := recover(); r != nil {
+					log.Error("SSH command panicked", "command", strings.Join(args, " "), "panic", r, "stack", string(debug.Stack()))
+					fmt.Fprintln(sess.Stderr(), "internal error")
+					sess.Exit(1)
+				}
+			}()
+			// Render at the width of the terminal, for ssh -t
+			render := render
+			if pty, _, ok := sess.Pty(); ok {
+				render.Width = pty.Window.Width
+			}
+			if err := runCommand(sess.Context(), library, render, args, sess); err != nil {
+				fmt.Fprintln(sess.Stderr(), err)
+				sess.Exit(1)
+				return
+			}
+			sess.Exit(0)
+		}
+	}
+}
+
+// runCommand runs a session command, writing its output to w
+func runCommand(ctx context.Context, library *org.Library, render ui.RenderOptions, args []string, w io.Writer) error {
+	switch args[0] {
+	case "markdown", "md":
+		if len(args) != 2 {
+			return errors.New("usage: markdown FILE.org")
+		}
+		doc, err := library.Open(args[1])
+		if err != nil {
+			return err
+		}
+		markdown, err := export.Markdown(doc)
+		if err != nil {
+			return err
+		}
+		_, err = io.WriteString(w, markdown)
+		return err
+	case "json":
+		if len(args) != 2 {
+			return errors.New("usage: json FILE.org")
+		}
+		doc, err := library.Open(args[1])
+		if err != nil {
+			return err
+		}
+		return export.JSON(w, doc)
+	case "ical":
+		if len(args) != 1 {
+			return errors.New("usage: ical")
+		}
+		tree, _ := library.Tree()
+		_, err := export.ICal(w, tree, time.Now())
+		return err
+	case "render":
+		const usage = "usage: render [-width N] [-color truecolor|256|16|none] FILE.org"
+		flags := flag.NewFlagSet("render", flag.ContinueOnError)
+		flags.SetOutput(io.Discard)
+		width := flags.String("width", "", "")
+		color := flags.String("color", "", "")
+		if err := flags.Parse(args[1:]); err != nil || flags.NArg() != 1 {
+			return errors.New(usage)
+		}
+		options, err := renderOptions(render, *width, *color)
+		if err != nil {
+			return err
+		}
+		doc, err := library.Open(flags.Arg(0))
+		if err != nil {
+			return err
+		}
+		ansi, err := ui.RenderANSI(ctx, doc, options)
+		if err != nil {
+			return err
+		}
+		_, err = io.WriteString(w, ansi)
+		return err
+	case "help":
+		_, err := fmt.Fprintln(w, execUsage)
+		return err
+	}
+	return fmt.Errorf("unknown command %q\n\n%s", args[0], execUsage)
+}
@@ -0,0 +1,54 @@
+package main
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"org-charm/ui"
+)
+
+func TestRunCommand(t *testing.T) {
+	library := testLibrary(t, map[string]string{
+		"notes.org": "#+TITLE: Notes\n* TODO Report\nDEADLINE: <2024-03-08 Fri>\nSome *bold* text.\n-----\n",
+	})
+	run := func(args ...string) (string, error) {
+		var b strings.Builder
+		err := runCommand(context.Background(), library, ui.RenderOptions{}, args, &b)
+		return b.String(), err
+	}
+
+	tests := []struct {
+		args []string
+		want string // Part of the output
+	}{
+		{[]string{"markdown", "notes.org"}, "**bold**"},
+		{[]string{"md", "notes.org"}, "Report"},
+		{[]string{"json", "notes.org"}, `"title"`},
+		{[]string{"ical"}, "BEGIN:VEVENT"},
+		{[]string{"render", "-color", "none", "notes.org"}, "Report"},
+		{[]string{"render", "-width", "40", "notes.org"}, "Report"},
+		{[]string{"help"}, "Commands:"},
+	}
+	for _, tt := range tests {
+		out, err := run(tt.args...)
+		if err != nil || !strings.Contains(out, tt.want) {
+			t.Errorf("%v = %q, %v; want it to contain %q", tt.args, out, err, tt.want)
+		}
+	}
+
+	for _, args := range [][]string{
+		{"markdown"},
+		{"json", "missing.org"},
+		{"ical", "extra"},
+		{"render"},
+		{"render", "-width", "1", "notes.org"},
+		{"render", "-color", "sepia", "notes.org"},
+		{"render", "../outside.org"},
+		{"rm", "-rf"},
+	} {
+		if _, err := run(args...); err == nil {
+			t.Errorf("%v succeeded", args)
+		}
+	}
+}
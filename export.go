@@ -0,0 +1,145 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"time"
+
+	"org-charm/export"
+	"org-charm/org"
+	"org-charm/ui"
+
+	"github.com/charmbracelet/log"
+)
+
+// exportUsage describes the export subcommand
+const exportUsage = `Usage: org-charm export <format> [flags]
+
+Formats:
+  html        Render the org files to a static HTML site
+  markdown    Print an org file converted to Markdown
+  json        Print the structure of an org file as JSON
+  ical        Print the SCHEDULED and DEADLINE entries as an iCalendar
+
+Run org-charm export <format> -h for the flags of a format.`
+
+// runExport runs org-charm export with args, writing the org files in
+// another format instead of serving them
+func runExport(args []string) {
+	if len(args) == 0 {
+		fmt.Fprintln(os.Stderr, exportUsage)
+		os.Exit(2)
+	}
+	switch args[0] {
+	case "html":
+		exportHTML(args[1:])
+	case "markdown", "md":
+		exportMarkdown(args[1:])
+	case "json":
+		exportJSON(args[1:])
+	case "ical":
+		exportICal(args[1:])
+	case "-h", "-help", "--help":
+		fmt.Println(exportUsage)
+	default:
+		fmt.Fprintf(os.Stderr, "Unknown export format %q\n\n%s\n", args[0], exportUsage)
+		os.Exit(2)
+	}
+}
+
+// exportHTML renders the org directory to a static site
+func exportHTML(args []string) {
+	flags := flag.NewFlagSet("export html", flag.ExitOnError)
+	orgDir := flags.String("dir", "./orgfiles", "Directory containing org files")
+	outDir := flags.String("out", "./site", "Directory to write the site to")
+	chromaStyle := flags.String("chroma-style", "", "Syntax highlighting style for source blocks (empty matches the theme)")
+	flags.Parse(args)
+
+	if *chromaStyle != "" {
+		if err := ui.CheckChromaStyle(*chromaStyle); err != nil {
+			log.Fatal("Invalid -chroma-style flag", "error", err)
+		}
+	}
+	tree, err := org.BuildFileTree(*orgDir)
+	if err != nil {
+		log.Fatal("Failed to build file tree", "error", err)
+	}
+
+	start := time.Now()
+	pages, err := export.HTML(*orgDir, tree, *outDir, export.HTMLOptions{ChromaStyle: *chromaStyle})
+	if err != nil {
+		log.Fatal("Failed to export HTML", "error", err)
+	}
+	log.Info("Exported HTML site", "pages", pages, "out", *outDir, "took", time.Since(start))
+}
+
+// exportMarkdown prints org files converted to Markdown
+func exportMarkdown(args []string) {
+	flags := flag.NewFlagSet("export markdown", flag.ExitOnError)
+	flags.Usage = func() {
+		fmt.Fprintln(flags.Output(), "Usage: org-charm export markdown FILE.org...")
+		flags.PrintDefaults()
+	}
+	flags.Parse(args)
+	if flags.NArg() == 0 {
+		flags.Usage()
+		os.Exit(2)
+	}
+
+	for i, path := range flags.Args() {
+		if i > 0 {
+			fmt.Println()
+		}
+		doc, err := org.ParseFile(path)
+		if err != nil {
+			log.Fatal("Failed to parse org file", "file", path, "error", err)
+		}
+		markdown, err := export.Markdown(doc)
+		if err != nil {
+			log.Fatal("Failed to export Markdown", "file", path, "error", err)
+		}
+		fmt.Print(markdown)
+	}
+}
+
+// exportJSON prints the structure of org files as JSON, one document after
+// the other when given several, as tools like jq read them
+func exportJSON(args []string) {
+	flags := flag.NewFlagSet("export json", flag.ExitOnError)
+	flags.Usage = func() {
+		fmt.Fprintln(flags.Output(), "Usage: org-charm export json FILE.org...")
+		flags.PrintDefaults()
+	}
+	flags.Parse(args)
+	if flags.NArg() == 0 {
+		flags.Usage()
+		os.Exit(2)
+	}
+
+	for _, path := range flags.Args() {
+		doc, err := org.ParseFile(path)
+		if err != nil {
+			log.Fatal("Failed to parse org file", "file", path, "error", err)
+		}
+		if err := export.JSON(os.Stdout, doc); err != nil {
+			log.Fatal("Failed to export JSON", "file", path, "error", err)
+		}
+	}
+}
+
+// exportICal prints the SCHEDULED and DEADLINE entries of the org
+// directory as an iCalendar
+func exportICal(args []string) {
+	flags := flag.NewFlagSet("export ical", flag.ExitOnError)
+	orgDir := flags.String("dir", "./orgfiles", "Directory containing org files")
+	flags.Parse(args)
+
+	tree, err := org.BuildFileTree(*orgDir)
+	if err != nil {
+		log.Fatal("Failed to build file tree", "error", err)
+	}
+	if _, err := export.ICal(os.Stdout, tree, time.Now()); err != nil {
+		log.Fatal("Failed to export calendar", "error", err)
+	}
+}
@@ -0,0 +1,288 @@
+// Package export writes the org files served over SSH in other formats,
+// for publishing and sharing them elsewhere
+package export
+
+import (
+	"bytes"
+	_ "embed"
+	"fmt"
+	"html"
+	"html/template"
+	"io"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"org-charm/org"
+
+	"github.com/alecthomas/chroma/v2"
+	chromahtml "github.com/alecthomas/chroma/v2/formatters/html"
+	"github.com/alecthomas/chroma/v2/lexers"
+	"github.com/alecthomas/chroma/v2/styles"
+	goorg "github.com/niklasfasching/go-org/org"
+)
+
+// defaultChromaStyle highlights source blocks like the terminal theme does
+const defaultChromaStyle = "tokyonight-storm"
+
+//go:embed style.css
+var styleCSS string
+
+// HTMLOptions holds the settings of an HTML export
+type HTMLOptions struct {
+	// ChromaStyle is the syntax highlighting style of source blocks; empty
+	// uses the style of the terminal theme
+	ChromaStyle string
+}
+
+// page is what the page template shows
+type page struct {
+	Title  string
+	Author string
+	Date   string
+	Home   string // Relative URL of the index page
+	Body   template.HTML
+	Files  template.HTML // File listing, on the generated index page
+	CSS    template.CSS
+	Parent bool   // Whether there's an index page to go back to
+	Feed   string // Relative URL of the site's feed, if it has one
+}
+
+var pageTemplate = template.Must(template.New("page").Parse(`<!DOCTYPE html>
+<html lang="en">
+<head>
+<meta charset="utf-8">
+<meta name="viewport" content="width=device-width, initial-scale=1">
+<title>{{.Title}}</title>
+<style>{{.CSS}}</style>
+{{with .Feed}}<link rel="alternate" type="application/atom+xml" href="{{.}}">
+{{end}}</head>
+<body>
+{{if .Parent}}<nav><a href="{{.Home}}">← Index</a></nav>
+{{end}}<main>
+{{if .Files}}<h1 class="title">{{.Title}}</h1>
+{{.Files}}{{else}}{{.Body}}{{end}}
+</main>
+{{if or .Author .Date}}<footer>{{with .Author}}By {{.}}{{end}}{{if and .Author .Date}} · {{end}}{{.Date}}</footer>
+{{end}}</body>
+</html>
+`))
+
+// HTML renders the org files of tree, listed from root, to a static site
+// in outDir: a page per file at its path with .html for .org, links
+// between files included, and the images they show. Unless root has an
+// index.org, index.html lists the files with their titles. It returns the
+// number of pages written.
+func HTML(root string, tree []*org.FileEntry, outDir string, options HTMLOptions) (int, error) {
+	written, err := writePages(root, tree, outDir, options)
+	if err != nil {
+		return len(written), err
+	}
+	pages := len(written)
+	if !slices.ContainsFunc(written, func(p writtenPage) bool { return p.entry.RelPath == "index.org" }) {
+		var files strings.Builder
+		writeListing(&files, tree)
+		p := page{
+			Title: filepath.Base(filepath.Clean(root)),
+			Files: template.HTML(files.String()),
+			CSS:   template.CSS(styleCSS),
+		}
+		if err := writeTemplate(filepath.Join(outDir, "index.html"), p); err != nil {
+			return pages, err
+		}
+		pages++
+	}
+	return pages, nil
+}
+
+// writtenPage is the page of an org file, as written by writePages
+type writtenPage struct {
+	entry *org.FileEntry
+	doc   *org.OrgFile
+	body  string // HTML of the document, without the page around it
+}
+
+// writePages renders every org file of tree to its page under outDir,
+// with the images it shows, and returns the pages written
+func writePages(root string, tree []*org.FileEntry, outDir string, options HTMLOptions) ([]writtenPage, error) {
+	style, ok := styles.Registry[options.ChromaStyle]
+	if !ok {
+		style = styles.Get(defaultChromaStyle)
+	}
+
+	var written []writtenPage
+	for _, entry := range org.FlattenTree(expandAll(tree)) {
+		if entry.IsDir {
+			continue
+		}
+		p, err := writePage(root, entry, outDir, style)
+		if err != nil {
+			return written, fmt.Errorf("%s: %w", entry.RelPath, err)
+		}
+		written = append(written, p)
+	}
+	return written, nil
+}
+
+// writePage renders the org file of entry to its page under outDir and
+// copies the images it shows next to it
+func writePage(root string, entry *org.FileEntry, outDir string, style *chroma.Style) (writtenPage, error) {
+	doc, err := entry.GetOrgFile()
+	if err != nil {
+		return writtenPage{}, err
+	}
+	w := &htmlWriter{HTMLWriter: goorg.NewHTMLWriter()}
+	w.ExtendingWriter = w
+	w.HighlightCodeBlock = func(source, lang string, inline bool, params map[string]string) string {
+		return highlightHTML(source, lang, style)
+	}
+	body, err := doc.Document.Write(w)
+	if err != nil {
+		return writtenPage{}, err
+	}
+
+	depth := strings.Count(filepath.ToSlash(entry.RelPath), "/")
+	p := page{
+		Title:  doc.Title(),
+		Author: doc.Author(),
+		Date:   doc.Date(),
+		Home:   strings.Repeat("../", depth) + "index.html",
+		Body:   template.HTML(body),
+		CSS:    template.CSS(styleCSS),
+		Parent: entry.RelPath != "index.org",
+	}
+	if err := writeTemplate(filepath.Join(outDir, pagePath(entry.RelPath)), p); err != nil {
+		return writtenPage{}, err
+	}
+
+	for _, image := range w.images {
+		if err := copyImage(root, filepath.Dir(entry.RelPath), image, outDir); err != nil {
+			return writtenPage{}, err
+		}
+	}
+	return writtenPage{entry: entry, doc: doc, body: body}, nil
+}
+
+// pagePath returns the path of the page of the org file at relPath
+func pagePath(relPath string) string {
+	return strings.TrimSuffix(relPath, filepath.Ext(relPath)) + ".html"
+}
+
+// htmlWriter is go-org's HTML writer collecting the images pages show
+type htmlWriter struct {
+	*goorg.HTMLWriter
+	images []string // Relative paths of the images, from the page
+}
+
+// WriteRegularLink writes a link, noting the images among them
+func (w *htmlWriter) WriteRegularLink(l goorg.RegularLink) {
+	if l.Kind() == "image" && (l.Protocol == "file" || l.Protocol == "") {
+		w.images = append(w.images, strings.TrimPrefix(l.URL, "file:"))
+	}
+	w.HTMLWriter.WriteRegularLink(l)
+}
+
+// highlightHTML highlights the source of a block in lang with inline
+// styles, leaving it plain when the language isn't known
+func highlightHTML(source, lang string, style *chroma.Style) string {
+	lexer := lexers.Get(lang)
+	if lexer == nil {
+		return "<pre>" + html.EscapeString(source) + "</pre>"
+	}
+	iterator, err := chroma.Coalesce(lexer).Tokenise(nil, source)
+	if err != nil {
+		return "<pre>" + html.EscapeString(source) + "</pre>"
+	}
+	var buf bytes.Buffer
+	if err := chromahtml.New().Format(&buf, style, iterator); err != nil {
+		return "<pre>" + html.EscapeString(source) + "</pre>"
+	}
+	return buf.String()
+}
+
+// copyImage copies the image at the relative path image, as linked from a
+// page in dir, to the same place under outDir. Images outside of root and
+// missing ones are left out; the page shows them as broken.
+func copyImage(root, dir, image, outDir string) error {
+	rel := filepath.Join(dir, filepath.FromSlash(image))
+	if filepath.IsAbs(image) || !filepath.IsLocal(rel) {
+		return nil
+	}
+	src, err := os.Open(filepath.Join(root, rel))
+	if err != nil {
+		return nil
+	}
+	defer src.Close()
+
+	dst := filepath.Join(outDir, rel)
+	if err := os.MkdirAll(filepath.Dir(dst), 0o755); err != nil {
+		return err
+	}
+	f, err := os.Create(dst)
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(f, src); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
+
+// writeListing writes the entries of a tree as nested lists of links to
+// their pages, with their titles and dates
+func writeListing(b *strings.Builder, entries []*org.FileEntry) {
+	b.WriteString("<ul class=\"files\">\n")
+	for _, e := range entries {
+		if e.IsDir {
+			fmt.Fprintf(b, "<li>📁 %s\n", html.EscapeString(e.Name))
+			writeListing(b, e.Children)
+			b.WriteString("</li>\n")
+			continue
+		}
+		href := filepath.ToSlash(pagePath(e.RelPath))
+		writeFileItem(b, e, href)
+	}
+	b.WriteString("</ul>\n")
+}
+
+// writeFileItem writes the list item of a file linking to its page at
+// href, with its title, date and tags
+func writeFileItem(b *strings.Builder, e *org.FileEntry, href string) {
+	fmt.Fprintf(b, `<li><a href="%s">%s</a>`, html.EscapeString(href), html.EscapeString(e.Title()))
+	if date := e.Header().Date; date != "" {
+		fmt.Fprintf(b, ` <span class="meta">%s</span>`, html.EscapeString(date))
+	}
+	if tags := e.Header().FileTags; len(tags) > 0 {
+		fmt.Fprintf(b, ` <span class="tags">:%s:</span>`, html.EscapeString(strings.Join(tags, ":")))
+	}
+	b.WriteString("</li>\n")
+}
+
+// writeTemplate writes a page to path, creating its directory
+func writeTemplate(path string, p page) error {
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return err
+	}
+	f, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	if err := pageTemplate.Execute(f, p); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
+
+// expandAll expands the directories of tree, for listing all its files
+func expandAll(tree []*org.FileEntry) []*org.FileEntry {
+	for _, e := range tree {
+		if e.IsDir {
+			e.Expanded = true
+			expandAll(e.Children)
+		}
+	}
+	return tree
+}
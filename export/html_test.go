@@ -0,0 +1,74 @@
+package export
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"org-charm/org"
+)
+
+// writeFiles writes files, by path relative to dir
+func writeFiles(t *testing.T, dir string, files map[string]string) {
+	t.Helper()
+	for name, content := range files {
+		path := filepath.Join(dir, name)
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+func TestHTML(t *testing.T) {
+	root, out := t.TempDir(), t.TempDir()
+	writeFiles(t, root, map[string]string{
+		"a.org": "#+TITLE: First <note>\n* Heading\nText.\n",
+		"sub/b.org": "#+TITLE: Second\n#+AUTHOR: Ann\n" +
+			"See [[file:../a.org][the first]] and [[file:pic.png]], not [[file:../../secret.png]].\n" +
+			"#+begin_src go\nfunc main() {}\n#+end_src\n",
+		"sub/pic.png": "PNG",
+	})
+	tree, err := org.BuildFileTree(root)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	pages, err := HTML(root, tree, out, HTMLOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if pages != 3 {
+		t.Errorf("wrote %d pages, want 3", pages)
+	}
+
+	read := func(name string) string {
+		t.Helper()
+		content, err := os.ReadFile(filepath.Join(out, name))
+		if err != nil {
+			t.Fatal(err)
+		}
+		return string(content)
+	}
+	index := read("index.html")
+	for _, want := range []string{`href="a.html">First &lt;note&gt;</a>`, `href="sub/b.html">Second</a>`} {
+		if !strings.Contains(index, want) {
+			t.Errorf("index lacks %s:\n%s", want, index)
+		}
+	}
+	page := read("sub/b.html")
+	for _, want := range []string{`href="../index.html"`, `href="../a.html"`, `<img src="pic.png"`, "By Ann", `style="color`} {
+		if !strings.Contains(page, want) {
+			t.Errorf("page lacks %s:\n%s", want, page)
+		}
+	}
+	if read("sub/pic.png") != "PNG" {
+		t.Error("image not copied")
+	}
+	if _, err := os.Stat(filepath.Join(out, "..", "secret.png")); err == nil {
+		t.Error("image outside of the root copied")
+	}
+}
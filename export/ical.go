@@ -0,0 +1,216 @@
+package export
+
+import (
+	"bytes"
+	"crypto/sha1"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"org-charm/org"
+)
+
+// icalLineLimit is the length in octets lines of a calendar are folded at
+const icalLineLimit = 75
+
+// icalFrequencies maps the units of org repeaters to iCalendar recurrence
+// frequencies
+var icalFrequencies = map[byte]string{
+	'h': "HOURLY",
+	'd': "DAILY",
+	'w': "WEEKLY",
+	'm': "MONTHLY",
+	'y': "YEARLY",
+}
+
+// ICal writes the SCHEDULED and DEADLINE entries of the org files in tree
+// as an iCalendar, an event per timestamp. Entries in a done state or
+// closed are left out; repeaters become recurrence rules. now stamps the
+// events. It returns the number of events written.
+func ICal(w io.Writer, tree []*org.FileEntry, now time.Time) (int, error) {
+	c := &calendar{w: w}
+	c.begin()
+	c.line("X-WR-CALNAME:org-charm")
+
+	stamp := now.UTC().Format("20060102T150405Z")
+	events := 0
+	err := plannedEvents(tree, func(entry *org.FileEntry, planned org.PlannedEntry, kind string, ts *org.Timestamp) {
+		c.event(entry.RelPath, planned, kind, ts, stamp)
+		events++
+	})
+	if err != nil {
+		return events, err
+	}
+
+	c.line("END:VCALENDAR")
+	return events, c.err
+}
+
+// Event is one event of the calendar ICal writes, as a calendar of its
+// own, the way calendar servers store them
+type Event struct {
+	UID  string
+	ICal []byte
+}
+
+// ICalEvents returns the events ICal writes of the org files in tree, a
+// calendar each. The events are stamped with the modification time of
+// their file, so they only change with it.
+func ICalEvents(tree []*org.FileEntry) ([]Event, error) {
+	var events []Event
+	err := plannedEvents(tree, func(entry *org.FileEntry, planned org.PlannedEntry, kind string, ts *org.Timestamp) {
+		var b bytes.Buffer
+		c := &calendar{w: &b}
+		c.begin()
+		c.event(entry.RelPath, planned, kind, ts, entry.ModTime.UTC().Format("20060102T150405Z"))
+		c.line("END:VCALENDAR")
+		events = append(events, Event{UID: eventUID(entry.RelPath, planned, kind), ICal: b.Bytes()})
+	})
+	return events, err
+}
+
+// plannedEvents calls fn with each SCHEDULED and DEADLINE timestamp of
+// the entries of the org files in tree that aren't done; kind is S for
+// SCHEDULED and DL for DEADLINE, as in org agendas
+func plannedEvents(tree []*org.FileEntry, fn func(entry *org.FileEntry, planned org.PlannedEntry, kind string, ts *org.Timestamp)) error {
+	for _, entry := range org.FlattenTree(expandAll(tree)) {
+		if entry.IsDir {
+			continue
+		}
+		doc, err := entry.GetOrgFile()
+		if err != nil {
+			return fmt.Errorf("%s: %w", entry.RelPath, err)
+		}
+		for _, planned := range doc.Planned() {
+			if planned.Done {
+				continue
+			}
+			if ts := planned.Planning.Scheduled; ts != nil {
+				fn(entry, planned, "S", ts)
+			}
+			if ts := planned.Planning.Deadline; ts != nil {
+				fn(entry, planned, "DL", ts)
+			}
+		}
+	}
+	return nil
+}
+
+// calendar writes the lines of an iCalendar, keeping the first error
+type calendar struct {
+	w   io.Writer
+	err error
+}
+
+// begin writes the lines a calendar starts with
+func (c *calendar) begin() {
+	c.line("BEGIN:VCALENDAR")
+	c.line("VERSION:2.0")
+	c.line("PRODID:-//org-charm//org-charm//EN")
+	c.line("CALSCALE:GREGORIAN")
+}
+
+// eventUID returns the UID of the event of a timestamp of an entry of the
+// file at relPath: its ID, or a hash of where it is without one, its
+// outline path and position telling apart headlines of the same title
+func eventUID(relPath string, entry org.PlannedEntry, kind string) string {
+	uid := entry.ID
+	if uid == "" {
+		where := append([]string{relPath}, entry.Outline...)
+		where = append(where, entry.Title, fmt.Sprint(entry.Position))
+		uid = fmt.Sprintf("%x", sha1.Sum([]byte(strings.Join(where, "\x00"))))
+	}
+	return kind + "-" + uid + "@org-charm"
+}
+
+// event writes the event of one timestamp of an entry of the file at
+// relPath, stamped with stamp; kind is S for SCHEDULED and DL for
+// DEADLINE, as in org agendas
+func (c *calendar) event(relPath string, entry org.PlannedEntry, kind string, ts *org.Timestamp, stamp string) {
+	c.line("BEGIN:VEVENT")
+	c.line("UID:" + eventUID(relPath, entry, kind))
+	c.line("DTSTAMP:" + stamp)
+	if ts.HasTime {
+		c.line("DTSTART:" + ts.Start.Format("20060102T150405"))
+		if ts.IsRange() {
+			c.line("DTEND:" + ts.End.Format("20060102T150405"))
+		}
+	} else {
+		c.line("DTSTART;VALUE=DATE:" + ts.Start.Format("20060102"))
+		if ts.IsRange() {
+			// The end of all-day events is exclusive
+			c.line("DTEND;VALUE=DATE:" + ts.End.AddDate(0, 0, 1).Format("20060102"))
+		}
+	}
+	if rule := recurrence(ts.Repeater); rule != "" {
+		c.line("RRULE:" + rule)
+	}
+	summary := kind + ": " + entry.Title
+	if entry.Status != "" {
+		summary = kind + ": " + entry.Status + " " + entry.Title
+	}
+	c.line("SUMMARY:" + escapeText(summary))
+	c.line("DESCRIPTION:" + escapeText(relPath))
+	if len(entry.Tags) > 0 {
+		tags := make([]string, len(entry.Tags))
+		for i, tag := range entry.Tags {
+			tags[i] = escapeText(tag)
+		}
+		c.line("CATEGORIES:" + strings.Join(tags, ","))
+	}
+	c.line("END:VEVENT")
+}
+
+// line writes a content line, folded to icalLineLimit octets
+func (c *calendar) line(s string) {
+	if c.err != nil {
+		return
+	}
+	var b strings.Builder
+	limit := icalLineLimit
+	for len(s) > limit {
+		// Fold between characters, not within one
+		cut := limit
+		for cut > 0 && !utf8.RuneStart(s[cut]) {
+			cut--
+		}
+		b.WriteString(s[:cut])
+		b.WriteString("\r\n ")
+		s = s[cut:]
+		limit = icalLineLimit - 1 // Continuation lines start with a space
+	}
+	b.WriteString(s)
+	b.WriteString("\r\n")
+	_, c.err = io.WriteString(c.w, b.String())
+}
+
+// recurrence converts an org repeater such as +1w, .+2d or ++1m to an
+// iCalendar recurrence rule. The catch-up and restart variants only
+// differ once an entry is marked done, so they repeat the same way.
+func recurrence(repeater string) string {
+	// Habits add a maximum interval, as in .+2d/4d, which calendars can't show
+	value, _, _ := strings.Cut(strings.TrimLeft(repeater, "+."), "/")
+	if len(value) < 2 {
+		return ""
+	}
+	freq, ok := icalFrequencies[value[len(value)-1]]
+	interval, err := strconv.Atoi(value[:len(value)-1])
+	if !ok || err != nil || interval < 1 {
+		return ""
+	}
+	if interval == 1 {
+		return "FREQ=" + freq
+	}
+	return "FREQ=" + freq + ";INTERVAL=" + strconv.Itoa(interval)
+}
+
+// escapeText escapes a TEXT value of a calendar
+var escapeText = strings.NewReplacer(
+	`\`, `\\`,
+	`;`, `\;`,
+	`,`, `\,`,
+	"\n", `\n`,
+).Replace
@@ -0,0 +1,137 @@
+package export
+
+import (
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"org-charm/org"
+)
+
+func TestICal(t *testing.T) {
+	dir := t.TempDir()
+	writeFiles(t, dir, map[string]string{
+		"todo.org": `* TODO Write report, draft :work:
+DEADLINE: <2024-03-08 Fri> SCHEDULED: <2024-03-04 Mon 09:00-10:30>
+:PROPERTIES:
+:ID: report
+:END:
+* DONE Shipped
+SCHEDULED: <2024-03-01 Fri>
+`,
+		"sub/habits.org": `* Water plants
+SCHEDULED: <2024-03-02 Sat .+2w>
+`,
+	})
+	tree, err := org.BuildFileTree(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var b strings.Builder
+	now := time.Date(2024, 3, 1, 12, 0, 0, 0, time.UTC)
+	events, err := ICal(&b, tree, now)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if events != 3 {
+		t.Errorf("events = %d, want 3", events)
+	}
+	got := b.String()
+
+	for _, want := range []string{
+		"BEGIN:VCALENDAR\r\nVERSION:2.0\r\n",
+		"UID:DL-report@org-charm\r\nDTSTAMP:20240301T120000Z\r\nDTSTART;VALUE=DATE:20240308\r\n",
+		"UID:S-report@org-charm\r\nDTSTAMP:20240301T120000Z\r\nDTSTART:20240304T090000\r\nDTEND:20240304T103000\r\n",
+		"SUMMARY:S: TODO Write report\\, draft\r\n",
+		"CATEGORIES:work\r\n",
+		"DTSTART;VALUE=DATE:20240302\r\nRRULE:FREQ=WEEKLY;INTERVAL=2\r\nSUMMARY:S: Water plants\r\nDESCRIPTION:" + filepath.Join("sub", "habits.org") + "\r\n",
+		"END:VCALENDAR\r\n",
+	} {
+		if !strings.Contains(got, want) {
+			t.Errorf("calendar missing %q:\n%s", want, got)
+		}
+	}
+	if strings.Contains(got, "Shipped") {
+		t.Errorf("done entry exported:\n%s", got)
+	}
+}
+
+func TestICalFolding(t *testing.T) {
+	var b strings.Builder
+	c := &calendar{w: &b}
+	c.line("SUMMARY:" + strings.Repeat("é", 50))
+	for line := range strings.SplitSeq(strings.TrimSuffix(b.String(), "\r\n"), "\r\n") {
+		if len(line) > icalLineLimit {
+			t.Errorf("line of %d octets: %q", len(line), line)
+		}
+	}
+	unfolded := strings.ReplaceAll(b.String(), "\r\n ", "")
+	if unfolded != "SUMMARY:"+strings.Repeat("é", 50)+"\r\n" {
+		t.Errorf("unfolded = %q", unfolded)
+	}
+}
+
+func TestICalEvents(t *testing.T) {
+	dir := t.TempDir()
+	writeFiles(t, dir, map[string]string{
+		"todo.org": `* TODO Write report
+DEADLINE: <2024-03-08 Fri> SCHEDULED: <2024-03-04 Mon>
+:PROPERTIES:
+:ID: report
+:END:
+`,
+	})
+	tree, err := org.BuildFileTree(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	events, err := ICalEvents(tree)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(events) != 2 || events[0].UID != "S-report@org-charm" || events[1].UID != "DL-report@org-charm" {
+		t.Fatalf("events = %+v", events)
+	}
+	stamp := "DTSTAMP:" + tree[0].ModTime.UTC().Format("20060102T150405Z") + "\r\n"
+	for _, event := range events {
+		got := string(event.ICal)
+		if !strings.HasPrefix(got, "BEGIN:VCALENDAR\r\n") || !strings.HasSuffix(got, "END:VEVENT\r\nEND:VCALENDAR\r\n") ||
+			strings.Count(got, "BEGIN:VEVENT") != 1 || !strings.Contains(got, stamp) {
+			t.Errorf("calendar of %s:\n%s", event.UID, got)
+		}
+	}
+}
+
+func TestICalUniqueUIDs(t *testing.T) {
+	dir := t.TempDir()
+	writeFiles(t, dir, map[string]string{
+		"todo.org": `* TODO Call
+SCHEDULED: <2024-03-04 Mon>
+* TODO Call
+SCHEDULED: <2024-03-05 Tue>
+* Project
+** TODO Call
+SCHEDULED: <2024-03-06 Wed>
+`,
+	})
+	tree, err := org.BuildFileTree(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	events, err := ICalEvents(tree)
+	if err != nil {
+		t.Fatal(err)
+	}
+	seen := map[string]bool{}
+	for _, event := range events {
+		if seen[event.UID] {
+			t.Errorf("UID %s shared by headlines of the same title", event.UID)
+		}
+		seen[event.UID] = true
+	}
+	if len(seen) != 3 {
+		t.Errorf("events = %+v, want 3", events)
+	}
+}
@@ -0,0 +1,251 @@
+package export
+
+import (
+	"encoding/json"
+	"io"
+	"strings"
+
+	"org-charm/org"
+
+	goorg "github.com/niklasfasching/go-org/org"
+)
+
+// Document is the structure of a parsed document, as other tools consume
+// it
+type Document struct {
+	File       string      `json:"file"`
+	Title      string      `json:"title"`
+	Author     string      `json:"author,omitempty"`
+	Date       string      `json:"date,omitempty"`
+	Tags       []string    `json:"tags,omitempty"` // #+FILETAGS
+	Body       string      `json:"body,omitempty"` // Org source before the first heading, settings left out
+	Timestamps []Timestamp `json:"timestamps,omitempty"`
+	Links      []Link      `json:"links,omitempty"`
+	Headings   []Heading   `json:"headings"`
+}
+
+// Heading is a heading of a Document with its section and subheadings
+type Heading struct {
+	Level      int               `json:"level"`
+	Title      string            `json:"title"`
+	Status     string            `json:"status,omitempty"`
+	Priority   string            `json:"priority,omitempty"`
+	Tags       []string          `json:"tags,omitempty"`
+	Properties map[string]string `json:"properties,omitempty"`
+	Scheduled  *Timestamp        `json:"scheduled,omitempty"`
+	Deadline   *Timestamp        `json:"deadline,omitempty"`
+	Closed     *Timestamp        `json:"closed,omitempty"`
+	Body       string            `json:"body,omitempty"` // Org source of the section, without planning and properties
+	Timestamps []Timestamp       `json:"timestamps,omitempty"`
+	Links      []Link            `json:"links,omitempty"`
+	Children   []Heading         `json:"children,omitempty"`
+}
+
+// Timestamp is a timestamp of a Document, with its dates as 2006-01-02 or
+// 2006-01-02T15:04 when they have a time of day
+type Timestamp struct {
+	Text     string `json:"text,omitempty"` // Org source, except for planning
+	Active   bool   `json:"active"`
+	Start    string `json:"start"`
+	End      string `json:"end,omitempty"`
+	Repeater string `json:"repeater,omitempty"`
+	Warning  string `json:"warning,omitempty"`
+}
+
+// Link is a link of a Document, with its kind as go-org tells it:
+// regular, image or video
+type Link struct {
+	URL         string `json:"url"`
+	Description string `json:"description,omitempty"`
+	Kind        string `json:"kind"`
+}
+
+// JSON writes the Structure of a parsed document as indented JSON
+func JSON(w io.Writer, doc *org.OrgFile) error {
+	encoder := json.NewEncoder(w)
+	encoder.SetEscapeHTML(false)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(Structure(doc))
+}
+
+// Structure returns the structure of a parsed document: its settings, and
+// its headings nested as in the outline with their keywords, tags,
+// properties, planning, timestamps, links and the org source of their
+// sections. Commented out headings and subtrees excluded from export are
+// left out, as the viewer hides them.
+func Structure(doc *org.OrgFile) Document {
+	d := doc.Document
+	out := Document{
+		File:     doc.Name,
+		Title:    doc.Title(),
+		Author:   doc.Author(),
+		Date:     doc.Date(),
+		Headings: []Heading{},
+	}
+	for _, tag := range strings.Split(d.Get("FILETAGS"), ":") {
+		if tag = strings.TrimSpace(tag); tag != "" {
+			out.Tags = append(out.Tags, tag)
+		}
+	}
+
+	var preamble []goorg.Node
+	for _, node := range d.Nodes {
+		h, ok := node.(goorg.Headline)
+		if !ok {
+			preamble = append(preamble, node)
+			continue
+		}
+		if !h.IsExcluded(d) {
+			out.Headings = append(out.Headings, headingStructure(d, h))
+		}
+	}
+	out.Body = sectionSource(preamble)
+	out.Timestamps = findTimestamps(out.Body)
+	out.Links = collectLinks(preamble, nil)
+	return out
+}
+
+// headingStructure converts a headline and its subheadings
+func headingStructure(d *goorg.Document, h goorg.Headline) Heading {
+	heading := Heading{
+		Level:    h.Lvl,
+		Title:    strings.TrimSpace(goorg.String(h.Title...)),
+		Status:   h.Status,
+		Priority: h.Priority,
+		Tags:     h.Tags,
+	}
+	if pd := org.HeadlineProperties(h); pd != nil && len(pd.Properties) > 0 {
+		heading.Properties = map[string]string{}
+		for _, kv := range pd.Properties {
+			heading.Properties[kv[0]] = kv[1]
+		}
+	}
+	planning, planned := org.HeadlinePlanning(h)
+	if planned {
+		heading.Scheduled = plannedTimestamp(planning.Scheduled)
+		heading.Deadline = plannedTimestamp(planning.Deadline)
+		heading.Closed = plannedTimestamp(planning.Closed)
+	}
+
+	var section []goorg.Node
+	for i, child := range h.Children {
+		switch child := child.(type) {
+		case goorg.Headline:
+			if !child.IsExcluded(d) {
+				heading.Children = append(heading.Children, headingStructure(d, child))
+			}
+		case goorg.PropertyDrawer:
+			// The headline's own drawer, left after the planning line,
+			// is its properties
+			if i > 1 {
+				section = append(section, child)
+			}
+		default:
+			section = append(section, child)
+		}
+	}
+	heading.Body = sectionSource(section)
+	if planned {
+		_, heading.Body, _ = strings.Cut(heading.Body, "\n")
+		heading.Body = strings.TrimSpace(heading.Body)
+	}
+	heading.Timestamps = findTimestamps(goorg.String(h.Title...) + "\n" + heading.Body)
+	heading.Links = collectLinks(section, collectLinks(h.Title, nil))
+	return heading
+}
+
+// sectionSource returns the org source of the nodes of a section, without
+// the keywords setting up the document
+func sectionSource(nodes []goorg.Node) string {
+	var kept []goorg.Node
+	for _, node := range nodes {
+		if _, ok := node.(goorg.Keyword); !ok {
+			kept = append(kept, node)
+		}
+	}
+	if len(kept) == 0 {
+		return ""
+	}
+	return strings.TrimSpace(goorg.String(kept...))
+}
+
+// plannedTimestamp converts a planning timestamp, if set
+func plannedTimestamp(ts *org.Timestamp) *Timestamp {
+	if ts == nil {
+		return nil
+	}
+	t := timestampOf(*ts, "")
+	return &t
+}
+
+// findTimestamps returns the timestamps found in the org source of a
+// section
+func findTimestamps(source string) []Timestamp {
+	var timestamps []Timestamp
+	for _, text := range org.TimestampRegexp.FindAllString(source, -1) {
+		if ts, ok := org.ParseTimestamp(text); ok {
+			timestamps = append(timestamps, timestampOf(ts, text))
+		}
+	}
+	return timestamps
+}
+
+// timestampOf converts a parsed timestamp, with text its org source if kept
+func timestampOf(ts org.Timestamp, text string) Timestamp {
+	format := func(hasTime bool) string {
+		if hasTime {
+			return "2006-01-02T15:04"
+		}
+		return "2006-01-02"
+	}
+	t := Timestamp{
+		Text:     text,
+		Active:   ts.Active,
+		Start:    ts.Start.Format(format(ts.HasTime)),
+		Repeater: ts.Repeater,
+		Warning:  ts.Warning,
+	}
+	if ts.IsRange() {
+		t.End = ts.End.Format(format(ts.EndTime))
+	}
+	return t
+}
+
+// collectLinks appends the links among nodes, in order, to links
+func collectLinks(nodes []goorg.Node, links []Link) []Link {
+	for _, node := range nodes {
+		switch n := node.(type) {
+		case goorg.RegularLink:
+			links = append(links, Link{
+				URL:         n.URL,
+				Description: goorg.String(n.Description...),
+				Kind:        n.Kind(),
+			})
+		case goorg.Paragraph:
+			links = collectLinks(n.Children, links)
+		case goorg.Emphasis:
+			links = collectLinks(n.Content, links)
+		case goorg.List:
+			links = collectLinks(n.Items, links)
+		case goorg.ListItem:
+			links = collectLinks(n.Children, links)
+		case goorg.DescriptiveListItem:
+			links = collectLinks(n.Details, collectLinks(n.Term, links))
+		case goorg.Block:
+			links = collectLinks(n.Children, links)
+		case goorg.Table:
+			for _, row := range n.Rows {
+				for _, col := range row.Columns {
+					links = collectLinks(col.Children, links)
+				}
+			}
+		case goorg.FootnoteDefinition:
+			links = collectLinks(n.Children, links)
+		case goorg.NodeWithMeta:
+			links = collectLinks([]goorg.Node{n.Node}, links)
+		case goorg.NodeWithName:
+			links = collectLinks([]goorg.Node{n.Node}, links)
+		}
+	}
+	return links
+}
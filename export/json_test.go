@@ -0,0 +1,70 @@
+package export
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"org-charm/org"
+
+	goorg "github.com/niklasfasching/go-org/org"
+)
+
+func TestJSON(t *testing.T) {
+	input := `#+TITLE: Plans
+#+FILETAGS: :home:
+
+Intro with [[https://example.com][a link]].
+
+* TODO [#A] Taxes :money:
+DEADLINE: <2024-04-15 Mon -1w>
+:PROPERTIES:
+:ID: taxes
+:END:
+Gather receipts by <2024-04-01 Mon 18:00-19:00>.
+** Receipts
+See [[file:receipts.org]].
+* Private :noexport:
+Hidden.
+`
+	doc := &org.OrgFile{Name: "plans.org", Document: goorg.New().Parse(strings.NewReader(input), "plans.org")}
+	var b strings.Builder
+	if err := JSON(&b, doc); err != nil {
+		t.Fatal(err)
+	}
+
+	var got Document
+	if err := json.Unmarshal([]byte(b.String()), &got); err != nil {
+		t.Fatalf("invalid JSON: %v\n%s", err, b.String())
+	}
+	if got.File != "plans.org" || got.Title != "Plans" || strings.Join(got.Tags, ",") != "home" {
+		t.Errorf("document = %+v", got)
+	}
+	if got.Body != "Intro with [[https://example.com][a link]]." {
+		t.Errorf("body = %q", got.Body)
+	}
+	if len(got.Links) != 1 || got.Links[0].URL != "https://example.com" || got.Links[0].Description != "a link" {
+		t.Errorf("links = %+v", got.Links)
+	}
+	if len(got.Headings) != 1 {
+		t.Fatalf("headings = %+v, want the excluded one left out", got.Headings)
+	}
+
+	taxes := got.Headings[0]
+	if taxes.Title != "Taxes" || taxes.Status != "TODO" || taxes.Priority != "A" || taxes.Properties["ID"] != "taxes" {
+		t.Errorf("heading = %+v", taxes)
+	}
+	if d := taxes.Deadline; d == nil || d.Start != "2024-04-15" || d.Warning != "-1w" || !d.Active {
+		t.Errorf("deadline = %+v", d)
+	}
+	if taxes.Body != "Gather receipts by <2024-04-01 Mon 18:00-19:00>." {
+		t.Errorf("heading body = %q", taxes.Body)
+	}
+	if len(taxes.Timestamps) != 1 || taxes.Timestamps[0].Start != "2024-04-01T18:00" || taxes.Timestamps[0].End != "2024-04-01T19:00" {
+		t.Errorf("timestamps = %+v", taxes.Timestamps)
+	}
+	if len(taxes.Children) != 1 || taxes.Children[0].Level != 2 || len(taxes.Children[0].Links) != 1 ||
+		taxes.Children[0].Links[0].URL != "file:receipts.org" {
+		t.Errorf("children = %+v", taxes.Children)
+	}
+}
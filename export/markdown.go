@@ -0,0 +1,430 @@
+package export
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+	"unicode"
+
+	"org-charm/org"
+
+	goorg "github.com/niklasfasching/go-org/org"
+)
+
+// Markdown converts an org document to GitHub flavored Markdown, under a
+// heading with its title: headings, emphasis, links (to other org files
+// as .md), lists, tables, source blocks, quotes and footnotes. Drawers,
+// comments, settings and subtrees excluded from export are left out.
+func Markdown(doc *org.OrgFile) (string, error) {
+	w := newMarkdownWriter()
+	body, err := doc.Document.Write(w)
+	if err != nil {
+		return "", err
+	}
+
+	var b strings.Builder
+	b.WriteString("# " + doc.Title() + "\n\n")
+	if meta := strings.Trim(doc.Author()+" · "+doc.Date(), " ·"); meta != "" {
+		b.WriteString("_" + meta + "_\n\n")
+	}
+	b.WriteString(strings.TrimSpace(body) + "\n")
+	return b.String(), nil
+}
+
+// markdownWriter writes org documents as Markdown. It extends go-org's org
+// writer, which already writes what the two have in common, such as text,
+// timestamps and macros.
+type markdownWriter struct {
+	*goorg.OrgWriter
+	indent      string   // Indentation of the content of list items
+	excludeTags []string // Tags of the headlines left out
+
+	// Footnote definitions by name, wherever they are, and the names of
+	// the footnotes referenced, in order, for writing them at the end
+	notes      map[string][]goorg.Node
+	referenced []string
+}
+
+func newMarkdownWriter() *markdownWriter {
+	w := &markdownWriter{OrgWriter: goorg.NewOrgWriter()}
+	w.ExtendingWriter = w
+	return w
+}
+
+// Before notes the settings and footnote definitions of the document
+func (w *markdownWriter) Before(d *goorg.Document) {
+	w.excludeTags = strings.Fields(d.Get("EXCLUDE_TAGS"))
+	w.notes = map[string][]goorg.Node{}
+	var collect func(nodes []goorg.Node)
+	collect = func(nodes []goorg.Node) {
+		for _, n := range nodes {
+			switch n := n.(type) {
+			case goorg.FootnoteDefinition:
+				w.notes[n.Name] = n.Children
+			case goorg.Headline:
+				collect(n.Children)
+			}
+		}
+	}
+	collect(d.Nodes)
+}
+
+// After writes the definitions of the footnotes referenced. Definitions
+// can reference more footnotes, which are written after them.
+func (w *markdownWriter) After(d *goorg.Document) {
+	for i := 0; i < len(w.referenced); i++ {
+		name := w.referenced[i]
+		if i == 0 {
+			w.blankLine()
+		}
+		content := strings.TrimSpace(w.nested("    ", w.notes[name]))
+		w.WriteString("[^" + name + "]: " + content + "\n")
+	}
+}
+
+// blankLine ends what was written with an empty line, for the block
+// written next to start on its own
+func (w *markdownWriter) blankLine() {
+	out := w.String()
+	if out == "" || strings.HasSuffix(out, "\n\n") {
+		return
+	}
+	if !strings.HasSuffix(out, "\n") {
+		w.WriteString("\n")
+	}
+	w.WriteString("\n")
+}
+
+// nested writes nodes indented by indent, as the content of a list item
+func (w *markdownWriter) nested(indent string, nodes []goorg.Node) string {
+	outer := w.indent
+	w.indent = indent
+	content := w.WriteNodesAsString(nodes...)
+	w.indent = outer
+	return content
+}
+
+// WriteHeadline writes a headline one level below the title, unless it's
+// commented out or tagged for exclusion
+func (w *markdownWriter) WriteHeadline(h goorg.Headline) {
+	if h.IsComment || slices.ContainsFunc(h.Tags, func(tag string) bool {
+		return slices.Contains(w.excludeTags, tag)
+	}) {
+		return
+	}
+	w.blankLine()
+	w.WriteString(strings.Repeat("#", min(h.Lvl+1, 6)) + " ")
+	if h.Status != "" {
+		w.WriteString(h.Status + " ")
+	}
+	if h.Priority != "" {
+		w.WriteString("[#" + h.Priority + "] ")
+	}
+	w.WriteString(w.WriteNodesAsString(h.Title...))
+	if len(h.Tags) > 0 {
+		w.WriteString(" `:" + strings.Join(h.Tags, ":") + ":`")
+	}
+	w.WriteString("\n\n")
+	goorg.WriteNodes(w, h.Children...)
+}
+
+// WriteBlock writes source and example blocks as fenced code, quotes as
+// block quotes, Markdown export blocks as they are and other blocks as
+// their content
+func (w *markdownWriter) WriteBlock(b goorg.Block) {
+	switch b.Name {
+	case "SRC", "EXAMPLE":
+		exports := b.ParameterMap()[":exports"]
+		if b.Name == "SRC" && (exports == "results" || exports == "none") {
+			break
+		}
+		lang := ""
+		if b.Name == "SRC" && len(b.Parameters) > 0 {
+			lang = b.Parameters[0]
+		}
+		w.fence(lang, w.WriteNodesAsString(b.Children...))
+	case "QUOTE":
+		w.blankLine()
+		content := strings.TrimSpace(w.nested("", b.Children))
+		for line := range strings.SplitSeq(content, "\n") {
+			w.WriteString(strings.TrimRight(w.indent+"> "+line, " ") + "\n")
+		}
+	case "EXPORT":
+		if len(b.Parameters) > 0 && (strings.EqualFold(b.Parameters[0], "markdown") || strings.EqualFold(b.Parameters[0], "md")) {
+			w.blankLine()
+			w.WriteString(w.WriteNodesAsString(b.Children...))
+		}
+	default:
+		w.blankLine()
+		goorg.WriteNodes(w, b.Children...)
+	}
+	if b.Result != nil && b.ParameterMap()[":exports"] != "code" && b.ParameterMap()[":exports"] != "none" {
+		goorg.WriteNodes(w, b.Result)
+	}
+}
+
+// fence writes code as a fenced block in lang, with a fence longer than
+// the backticks in it
+func (w *markdownWriter) fence(lang, code string) {
+	fence := "```"
+	for strings.Contains(code, fence) {
+		fence += "`"
+	}
+	w.blankLine()
+	w.WriteString(w.indent + fence + lang + "\n")
+	for line := range strings.SplitSeq(strings.TrimSuffix(code, "\n"), "\n") {
+		w.WriteString(strings.TrimRight(w.indent+line, " ") + "\n")
+	}
+	w.WriteString(w.indent + fence + "\n")
+}
+
+// WriteResult writes the results of a source block after it
+func (w *markdownWriter) WriteResult(r goorg.Result) {
+	goorg.WriteNodes(w, r.Node)
+}
+
+// WriteExample writes fixed-width lines as fenced code
+func (w *markdownWriter) WriteExample(e goorg.Example) {
+	lines := make([]string, len(e.Children))
+	for i, n := range e.Children {
+		lines[i] = w.WriteNodesAsString(n)
+	}
+	w.fence("", strings.Join(lines, "\n"))
+}
+
+// WriteLatexBlock writes a LaTeX environment as display math
+func (w *markdownWriter) WriteLatexBlock(b goorg.LatexBlock) {
+	w.blankLine()
+	w.WriteString(w.indent + "$$\n" + w.WriteNodesAsString(b.Content...) + "\n" + w.indent + "$$\n")
+}
+
+// WriteInlineBlock writes inline source as code, and inline Markdown
+// exports as they are
+func (w *markdownWriter) WriteInlineBlock(b goorg.InlineBlock) {
+	switch b.Name {
+	case "src":
+		w.WriteString(codeSpan(w.WriteNodesAsString(b.Children...)))
+	case "export":
+		if len(b.Parameters) > 0 && (b.Parameters[0] == "markdown" || b.Parameters[0] == "md") {
+			goorg.WriteNodes(w, b.Children...)
+		}
+	}
+}
+
+// Settings, comments and drawers aren't part of the content
+func (w *markdownWriter) WriteKeyword(goorg.Keyword)               {}
+func (w *markdownWriter) WriteInclude(goorg.Include)               {}
+func (w *markdownWriter) WriteComment(goorg.Comment)               {}
+func (w *markdownWriter) WriteDrawer(goorg.Drawer)                 {}
+func (w *markdownWriter) WritePropertyDrawer(goorg.PropertyDrawer) {}
+
+// WriteNodeWithMeta writes a node followed by its caption
+func (w *markdownWriter) WriteNodeWithMeta(n goorg.NodeWithMeta) {
+	goorg.WriteNodes(w, n.Node)
+	for _, caption := range n.Meta.Caption {
+		w.blankLine()
+		w.WriteString(w.indent + "_" + w.WriteNodesAsString(caption...) + "_\n")
+	}
+}
+
+// WriteNodeWithName writes a named node, the name being for org links
+func (w *markdownWriter) WriteNodeWithName(n goorg.NodeWithName) {
+	goorg.WriteNodes(w, n.Node)
+}
+
+// WriteParagraph writes a paragraph at the indentation of the list item
+// it's in
+func (w *markdownWriter) WriteParagraph(p goorg.Paragraph) {
+	content := w.WriteNodesAsString(p.Children...)
+	if content != "" && content[0] != '\n' {
+		w.WriteString(w.indent)
+	}
+	w.WriteString(content + "\n")
+}
+
+// WriteLineBreak writes line breaks, keeping the indentation of the list
+// item they're in
+func (w *markdownWriter) WriteLineBreak(l goorg.LineBreak) {
+	w.WriteString(strings.Repeat("\n"+w.indent, l.Count))
+}
+
+// WriteExplicitLineBreak writes a hard line break
+func (w *markdownWriter) WriteExplicitLineBreak(goorg.ExplicitLineBreak) {
+	w.WriteString("\\\n" + w.indent)
+}
+
+// WriteHorizontalRule writes a thematic break
+func (w *markdownWriter) WriteHorizontalRule(goorg.HorizontalRule) {
+	w.blankLine()
+	w.WriteString(w.indent + "---\n")
+}
+
+// WriteListItem writes a list item, with its checkbox as a task list item
+func (w *markdownWriter) WriteListItem(li goorg.ListItem) {
+	bullet := li.Bullet
+	switch {
+	case bullet == "*" || bullet == "+":
+		bullet = "-"
+	case li.Value != "":
+		bullet = li.Value + bullet[len(bullet)-1:]
+	}
+	prefix := w.indent + bullet + " "
+	switch li.Status {
+	case "X":
+		prefix += "[x] "
+	case " ", "-":
+		prefix += "[ ] "
+	}
+	indent := w.indent + strings.Repeat(" ", len(bullet)+1)
+	content := strings.TrimPrefix(w.nested(indent, li.Children), indent)
+	w.WriteString(prefix + strings.TrimPrefix(content, "\n"))
+}
+
+// WriteDescriptiveListItem writes a description list item as a list item
+// starting with the term in bold
+func (w *markdownWriter) WriteDescriptiveListItem(di goorg.DescriptiveListItem) {
+	indent := w.indent + "  "
+	term := w.WriteNodesAsString(di.Term...)
+	details := strings.TrimPrefix(w.nested(indent, di.Details), indent)
+	w.WriteString(w.indent + "- **" + term + "**: " + strings.TrimPrefix(details, "\n"))
+}
+
+// WriteTable writes a table with its first row as the header, which
+// Markdown tables need, and the alignment of its columns
+func (w *markdownWriter) WriteTable(t goorg.Table) {
+	w.blankLine()
+	header := true
+	for _, row := range t.Rows {
+		if len(row.Columns) == 0 || row.IsSpecial {
+			continue
+		}
+		w.WriteString(w.indent + "|")
+		for i := range t.ColumnInfos {
+			content := ""
+			if i < len(row.Columns) {
+				content = w.WriteNodesAsString(row.Columns[i].Children...)
+			}
+			w.WriteString(" " + strings.ReplaceAll(content, "|", `\|`) + " |")
+		}
+		w.WriteString("\n")
+		if header {
+			w.WriteString(w.indent + "|")
+			for _, info := range t.ColumnInfos {
+				switch info.Align {
+				case "center":
+					w.WriteString(" :---: |")
+				case "right":
+					w.WriteString(" ---: |")
+				default:
+					w.WriteString(" --- |")
+				}
+			}
+			w.WriteString("\n")
+			header = false
+		}
+	}
+}
+
+// WriteEmphasis writes emphasis in the Markdown markup of its kind;
+// underlines have none and are left plain
+func (w *markdownWriter) WriteEmphasis(e goorg.Emphasis) {
+	content := w.WriteNodesAsString(e.Content...)
+	switch e.Kind {
+	case "*":
+		w.WriteString("**" + content + "**")
+	case "/":
+		w.WriteString("*" + content + "*")
+	case "+":
+		w.WriteString("~~" + content + "~~")
+	case "=", "~":
+		w.WriteString(codeSpan(content))
+	case "_{}":
+		w.WriteString("<sub>" + content + "</sub>")
+	case "^{}":
+		w.WriteString("<sup>" + content + "</sup>")
+	default:
+		w.WriteString(content)
+	}
+}
+
+// codeSpan returns code as an inline code span, delimited by more
+// backticks than it holds in a row
+func codeSpan(code string) string {
+	longest, run := 0, 0
+	for _, r := range code {
+		if r == '`' {
+			run++
+			longest = max(longest, run)
+		} else {
+			run = 0
+		}
+	}
+	fence := strings.Repeat("`", longest+1)
+	if longest > 0 {
+		return fence + " " + code + " " + fence
+	}
+	return fence + code + fence
+}
+
+// WriteRegularLink writes a link or an image. Links to org files point
+// to their Markdown exports, and links to headings to their anchors.
+func (w *markdownWriter) WriteRegularLink(l goorg.RegularLink) {
+	url := l.URL
+	local := l.Protocol == "file" || l.Protocol == ""
+	if l.Protocol == "file" {
+		url = strings.TrimPrefix(url, "file:")
+	}
+	switch {
+	case local && strings.HasPrefix(url, "*"):
+		url = "#" + headingAnchor(url[1:])
+	case local && strings.HasSuffix(url, ".org"):
+		url = strings.TrimSuffix(url, ".org") + ".md"
+	}
+
+	switch {
+	case l.Kind() == "image" && l.Description == nil:
+		w.WriteString("![](" + url + ")")
+	case l.AutoLink:
+		w.WriteString(url)
+	case l.Description == nil && local:
+		w.WriteString("[" + strings.TrimPrefix(l.URL, "file:") + "](" + url + ")")
+	case l.Description == nil:
+		w.WriteString("<" + url + ">")
+	default:
+		w.WriteString("[" + w.WriteNodesAsString(l.Description...) + "](" + url + ")")
+	}
+}
+
+// headingAnchor returns the anchor GitHub gives a heading with title
+func headingAnchor(title string) string {
+	var b strings.Builder
+	for _, r := range strings.ToLower(strings.TrimSpace(title)) {
+		switch {
+		case r == ' ':
+			b.WriteRune('-')
+		case r == '-' || r == '_' || unicode.IsLetter(r) || unicode.IsDigit(r):
+			b.WriteRune(r)
+		}
+	}
+	return b.String()
+}
+
+// WriteFootnoteLink writes a footnote reference, noting the footnote for
+// its definition to be written at the end
+func (w *markdownWriter) WriteFootnoteLink(l goorg.FootnoteLink) {
+	name := l.Name
+	if l.Definition != nil {
+		if name == "" {
+			name = fmt.Sprintf("inline-%d", len(w.referenced)+1)
+		}
+		w.notes[name] = l.Definition.Children
+	}
+	if !slices.Contains(w.referenced, name) {
+		w.referenced = append(w.referenced, name)
+	}
+	w.WriteString("[^" + name + "]")
+}
+
+// WriteFootnoteDefinition writes nothing, definitions being written at
+// the end
+func (w *markdownWriter) WriteFootnoteDefinition(goorg.FootnoteDefinition) {}
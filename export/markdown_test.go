@@ -0,0 +1,71 @@
+package export
+
+import (
+	"strings"
+	"testing"
+
+	"org-charm/org"
+
+	goorg "github.com/niklasfasching/go-org/org"
+)
+
+func TestMarkdown(t *testing.T) {
+	input := `#+TITLE: Notes
+#+AUTHOR: Ann
+
+Some *bold*, /italic/, =code= and +gone+ text.[fn:1]
+See [[file:other.org][other]] and [[*Next steps][the steps]].
+
+* TODO First :work:
+- [X] done
+- open
+  - nested
+
+| Name | Qty |
+|------+-----|
+| a    |   1 |
+
+#+begin_src go
+func main() {}
+#+end_src
+
+#+begin_quote
+Quoted.
+#+end_quote
+* Next steps
+:PROPERTIES:
+:ID: x
+:END:
+** Private :noexport:
+Secret.
+
+[fn:1] The note.
+`
+	doc := &org.OrgFile{Name: "notes.org", Document: goorg.New().Parse(strings.NewReader(input), "notes.org")}
+	got, err := Markdown(doc)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, want := range []string{
+		"# Notes\n\n_Ann_\n",
+		"Some **bold**, *italic*, `code` and ~~gone~~ text.[^1]",
+		"See [other](other.md) and [the steps](#next-steps).",
+		"## TODO First `:work:`\n",
+		"- [x] done\n- open\n  - nested\n",
+		"| Name | Qty |\n| --- | ---: |\n| a | 1 |\n",
+		"```go\nfunc main() {}\n```\n",
+		"> Quoted.\n",
+		"## Next steps\n",
+		"[^1]: The note.\n",
+	} {
+		if !strings.Contains(got, want) {
+			t.Errorf("Markdown lacks %q:\n%s", want, got)
+		}
+	}
+	for _, unwanted := range []string{"#+", ":ID:", "Secret", "Private"} {
+		if strings.Contains(got, unwanted) {
+			t.Errorf("Markdown has %q:\n%s", unwanted, got)
+		}
+	}
+}
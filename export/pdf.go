@@ -0,0 +1,120 @@
+package export
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+)
+
+// PDF converts the org file at input to a PDF at output by running
+// command, the converter the operator set up, such as
+// pandoc {input} -o {output}. {input} and {output} in its arguments are
+// replaced by the paths of the files. Converters without {output} are
+// expected to write the PDF next to their input, as Emacs'
+// org-latex-export-to-pdf does: they're given a copy of the input in a
+// directory of its own, so they never overwrite a PDF of the org
+// directory or race other exports, and the PDF is moved to output. The
+// converter runs in the directory of the input, so relative links resolve.
+func PDF(ctx context.Context, command []string, input, output string) error {
+	if len(command) == 0 {
+		return errors.New("no PDF converter set up")
+	}
+	input, err := filepath.Abs(input)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(output), 0o755); err != nil {
+		return err
+	}
+
+	// The PDF is written aside and moved in place once done, so sessions
+	// exporting the same document don't get each other's partial files
+	tmp, err := os.CreateTemp(filepath.Dir(output), ".*.pdf")
+	if err != nil {
+		return err
+	}
+	tmp.Close()
+	defer os.Remove(tmp.Name())
+	written, err := filepath.Abs(tmp.Name())
+	if err != nil {
+		return err
+	}
+
+	hasOutput := false
+	for _, arg := range command {
+		hasOutput = hasOutput || strings.Contains(arg, "{output}")
+	}
+	converted := input
+	if !hasOutput {
+		dir, err := os.MkdirTemp("", "org-charm-pdf-")
+		if err != nil {
+			return err
+		}
+		defer os.RemoveAll(dir)
+		converted = filepath.Join(dir, filepath.Base(input))
+		if err := copyFile(input, converted); err != nil {
+			return err
+		}
+		written = strings.TrimSuffix(converted, filepath.Ext(converted)) + ".pdf"
+	}
+	args := make([]string, len(command))
+	for i, arg := range command {
+		args[i] = strings.NewReplacer("{input}", converted, "{output}", written).Replace(arg)
+	}
+
+	cmd := exec.CommandContext(ctx, args[0], args[1:]...)
+	cmd.Dir = filepath.Dir(input)
+	if out, err := cmd.CombinedOutput(); err != nil {
+		return fmt.Errorf("%s: %w%s", filepath.Base(args[0]), err, lastLine(out))
+	}
+	if info, err := os.Stat(written); err != nil || info.Size() == 0 {
+		return fmt.Errorf("%s wrote no PDF", filepath.Base(args[0]))
+	}
+	if hasOutput {
+		return os.Rename(written, output)
+	}
+	// The copy may be on another file system than output
+	return copyFile(written, output)
+}
+
+// copyFile copies the file at src to dst, through a temporary file so
+// readers never see dst half written
+func copyFile(src, dst string) error {
+	in, err := os.Open(src)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+	tmp, err := os.CreateTemp(filepath.Dir(dst), ".*"+filepath.Ext(dst))
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+	if _, err := io.Copy(tmp, in); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), dst)
+}
+
+// lastLine returns the last line of a converter's output, which usually
+// says what went wrong, after a colon, or "" without output
+func lastLine(out []byte) string {
+	out = bytes.TrimSpace(out)
+	if len(out) == 0 {
+		return ""
+	}
+	if i := bytes.LastIndexByte(out, '\n'); i >= 0 {
+		out = out[i+1:]
+	}
+	return ": " + string(out)
+}
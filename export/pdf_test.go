@@ -0,0 +1,48 @@
+package export
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestPDF(t *testing.T) {
+	root, out := t.TempDir(), t.TempDir()
+	input := filepath.Join(root, "notes.org")
+	writeFiles(t, root, map[string]string{"notes.org": "* Notes\n"})
+	ctx := context.Background()
+
+	// A converter writing where it's told to
+	output := filepath.Join(out, "sub", "notes.pdf")
+	if err := PDF(ctx, []string{"cp", "{input}", "{output}"}, input, output); err != nil {
+		t.Fatal(err)
+	}
+	if content, err := os.ReadFile(output); err != nil || string(content) != "* Notes\n" {
+		t.Errorf("output = %q, %v", content, err)
+	}
+
+	// A converter writing next to its input, as Emacs does, is given a
+	// copy so a PDF of the org directory is left alone
+	writeFiles(t, root, map[string]string{"notes.pdf": "committed"})
+	output = filepath.Join(out, "beside.pdf")
+	beside := []string{"sh", "-c", `cp "$1" "${1%.org}.pdf"`, "sh", "{input}"}
+	if err := PDF(ctx, beside, input, output); err != nil {
+		t.Fatal(err)
+	}
+	if content, err := os.ReadFile(output); err != nil || string(content) != "* Notes\n" {
+		t.Errorf("PDF written next to the input not moved to the output: %q, %v", content, err)
+	}
+	if content, err := os.ReadFile(filepath.Join(root, "notes.pdf")); err != nil || string(content) != "committed" {
+		t.Errorf("PDF of the org directory changed: %q, %v", content, err)
+	}
+
+	err := PDF(ctx, []string{"sh", "-c", "echo working; echo no LaTeX found >&2; exit 1"}, input, output)
+	if err == nil || !strings.HasSuffix(err.Error(), ": no LaTeX found") {
+		t.Errorf("failed conversion error = %v, want the converter's last line", err)
+	}
+	if entries, _ := os.ReadDir(out); len(entries) != 2 {
+		t.Errorf("exports directory has %d entries after a failure, want 2", len(entries))
+	}
+}
@@ -0,0 +1,302 @@
+package export
+
+import (
+	"encoding/xml"
+	"fmt"
+	"html"
+	"html/template"
+	"net/url"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"org-charm/org"
+)
+
+// feedEntries is how many of the newest files the feed of a site lists
+const feedEntries = 20
+
+// SiteOptions holds the settings of a static site build
+type SiteOptions struct {
+	HTMLOptions
+
+	// Title is the title of the site, the name of the org directory when
+	// empty
+	Title string
+	// BaseURL is the URL the site is published at, for the links and IDs
+	// of its feed; they're relative to the feed without it
+	BaseURL string
+}
+
+// Site builds a browsable static site of the org files of tree, listed
+// from root, in outDir: the pages of HTML, an index page per directory
+// listing its files and subdirectories (unless it has an index.org), a
+// page per tag listing the files using it under tags/, and an Atom feed of
+// the newest files in feed.xml. It returns the number of pages written.
+func Site(root string, tree []*org.FileEntry, outDir string, options SiteOptions) (int, error) {
+	if options.Title == "" {
+		options.Title = filepath.Base(filepath.Clean(root))
+	}
+	written, err := writePages(root, tree, outDir, options.HTMLOptions)
+	if err != nil {
+		return len(written), err
+	}
+	pages := len(written)
+
+	indexes, err := writeIndexes(tree, "", outDir, options.Title)
+	pages += indexes
+	if err != nil {
+		return pages, err
+	}
+	tagPages, err := writeTagPages(written, outDir, options.Title)
+	pages += tagPages
+	if err != nil {
+		return pages, err
+	}
+	return pages, writeFeed(written, filepath.Join(outDir, "feed.xml"), options)
+}
+
+// writeIndexes writes the index page of the directory at dir, relative to
+// the root, and of its subdirectories, and returns how many it wrote
+func writeIndexes(entries []*org.FileEntry, dir, outDir, title string) (int, error) {
+	pages := 0
+	hasIndex := false
+	var b strings.Builder
+	depth := 0
+	if dir != "" {
+		depth = strings.Count(filepath.ToSlash(dir), "/") + 1
+		b.WriteString(`<p class="meta"><a href="../index.html">↑ Up</a></p>` + "\n")
+	} else {
+		b.WriteString(`<p class="meta"><a href="tags/index.html">Tags</a> · <a href="feed.xml">Feed</a></p>` + "\n")
+	}
+	b.WriteString("<ul class=\"files\">\n")
+	for _, e := range entries {
+		if e.IsDir {
+			fmt.Fprintf(&b, "<li>📁 <a href=\"%s/index.html\">%s</a></li>\n", html.EscapeString(url.PathEscape(e.Name)), html.EscapeString(e.Name))
+			n, err := writeIndexes(e.Children, e.RelPath, outDir, title)
+			pages += n
+			if err != nil {
+				return pages, err
+			}
+			continue
+		}
+		if e.Name == "index.org" {
+			hasIndex = true
+		}
+		writeFileItem(&b, e, url.PathEscape(pagePath(e.Name)))
+	}
+	b.WriteString("</ul>\n")
+	if hasIndex {
+		return pages, nil
+	}
+
+	home := strings.Repeat("../", depth) + "index.html"
+	p := page{
+		Title:  title,
+		Home:   home,
+		Files:  template.HTML(b.String()),
+		CSS:    template.CSS(styleCSS),
+		Parent: dir != "",
+		Feed:   strings.Repeat("../", depth) + "feed.xml",
+	}
+	if dir != "" {
+		p.Title = filepath.ToSlash(dir)
+	}
+	if err := writeTemplate(filepath.Join(outDir, dir, "index.html"), p); err != nil {
+		return pages, err
+	}
+	return pages + 1, nil
+}
+
+// writeTagPages writes a page per tag used by the written pages, file and
+// headline tags alike, listing the files using it, and an index of the
+// tags, and returns how many pages it wrote
+func writeTagPages(written []writtenPage, outDir, title string) (int, error) {
+	tagged := map[string][]*org.FileEntry{}
+	for _, p := range written {
+		for _, tag := range p.doc.Stats().Tags {
+			tagged[tag] = append(tagged[tag], p.entry)
+		}
+	}
+	tags := make([]string, 0, len(tagged))
+	for tag := range tagged {
+		tags = append(tags, tag)
+	}
+	sort.Strings(tags)
+
+	dir := filepath.Join(outDir, "tags")
+	var index strings.Builder
+	index.WriteString("<ul class=\"files\">\n")
+	for _, tag := range tags {
+		href := url.PathEscape(tag) + ".html"
+		fmt.Fprintf(&index, "<li><a href=\"%s\">:%s:</a> <span class=\"meta\">%d</span></li>\n",
+			html.EscapeString(href), html.EscapeString(tag), len(tagged[tag]))
+
+		var files strings.Builder
+		files.WriteString("<ul class=\"files\">\n")
+		for _, e := range tagged[tag] {
+			writeFileItem(&files, e, "../"+(&url.URL{Path: filepath.ToSlash(pagePath(e.RelPath))}).EscapedPath())
+		}
+		files.WriteString("</ul>\n")
+		p := page{
+			Title:  ":" + tag + ":",
+			Home:   "../index.html",
+			Files:  template.HTML(files.String()),
+			CSS:    template.CSS(styleCSS),
+			Parent: true,
+			Feed:   "../feed.xml",
+		}
+		if err := writeTemplate(filepath.Join(dir, tag+".html"), p); err != nil {
+			return 0, err
+		}
+	}
+	index.WriteString("</ul>\n")
+
+	p := page{
+		Title:  title + " · Tags",
+		Home:   "../index.html",
+		Files:  template.HTML(index.String()),
+		CSS:    template.CSS(styleCSS),
+		Parent: true,
+		Feed:   "../feed.xml",
+	}
+	if err := writeTemplate(filepath.Join(dir, "index.html"), p); err != nil {
+		return len(tags), err
+	}
+	return len(tags) + 1, nil
+}
+
+// atomFeed is an Atom feed, as written to feed.xml
+type atomFeed struct {
+	XMLName xml.Name    `xml:"http://www.w3.org/2005/Atom feed"`
+	Title   string      `xml:"title"`
+	ID      string      `xml:"id"`
+	Updated string      `xml:"updated"`
+	Links   []atomLink  `xml:"link"`
+	Entries []atomEntry `xml:"entry"`
+}
+
+type atomLink struct {
+	Href string `xml:"href,attr"`
+	Rel  string `xml:"rel,attr,omitempty"`
+	Type string `xml:"type,attr,omitempty"`
+}
+
+type atomEntry struct {
+	Base       string         `xml:"http://www.w3.org/XML/1998/namespace base,attr,omitempty"` // URL the links of the content resolve against
+	Title      string         `xml:"title"`
+	ID         string         `xml:"id"`
+	Updated    string         `xml:"updated"`
+	Link       atomLink       `xml:"link"`
+	Author     *atomPerson    `xml:"author,omitempty"`
+	Categories []atomCategory `xml:"category"`
+	Content    atomContent    `xml:"content"`
+}
+
+type atomPerson struct {
+	Name string `xml:"name"`
+}
+
+type atomCategory struct {
+	Term string `xml:"term,attr"`
+}
+
+type atomContent struct {
+	Type string `xml:"type,attr"`
+	Body string `xml:",chardata"`
+}
+
+// writeFeed writes an Atom feed of the newest feedEntries written pages
+// to file, with their content
+func writeFeed(written []writtenPage, file string, options SiteOptions) error {
+	dated := make([]writtenPage, len(written))
+	copy(dated, written)
+	dates := map[*org.FileEntry]time.Time{}
+	for _, p := range dated {
+		dates[p.entry] = pageDate(p)
+	}
+	sort.SliceStable(dated, func(i, j int) bool {
+		return dates[dated[i].entry].After(dates[dated[j].entry])
+	})
+	dated = dated[:min(len(dated), feedEntries)]
+
+	base := strings.TrimSuffix(options.BaseURL, "/")
+	link := func(rel string) string {
+		escaped := (&url.URL{Path: rel}).EscapedPath()
+		if base == "" {
+			return escaped
+		}
+		return base + "/" + escaped
+	}
+	id := func(rel string) string {
+		if base == "" {
+			return "urn:org-charm:" + url.PathEscape(options.Title) + ":" + (&url.URL{Path: rel}).EscapedPath()
+		}
+		return link(rel)
+	}
+
+	feed := atomFeed{
+		Title: options.Title,
+		ID:    id(""),
+		Links: []atomLink{
+			{Href: link("index.html")},
+			{Href: link("feed.xml"), Rel: "self", Type: "application/atom+xml"},
+		},
+		Updated: time.Now().UTC().Format(time.RFC3339),
+	}
+	if len(dated) > 0 {
+		feed.Updated = dates[dated[0].entry].UTC().Format(time.RFC3339)
+	}
+	for _, p := range dated {
+		rel := filepath.ToSlash(pagePath(p.entry.RelPath))
+		entry := atomEntry{
+			Title:   p.doc.Title(),
+			ID:      id(rel),
+			Updated: dates[p.entry].UTC().Format(time.RFC3339),
+			Link:    atomLink{Href: link(rel)},
+			Content: atomContent{Type: "html", Body: p.body},
+		}
+		if dir := path.Dir(rel); dir != "." {
+			entry.Base = link(dir) + "/"
+		}
+		if author := p.doc.Author(); author != "" {
+			entry.Author = &atomPerson{Name: author}
+		}
+		for _, tag := range p.doc.Stats().Tags {
+			entry.Categories = append(entry.Categories, atomCategory{Term: tag})
+		}
+		feed.Entries = append(feed.Entries, entry)
+	}
+
+	f, err := os.Create(file)
+	if err != nil {
+		return err
+	}
+	if _, err := f.WriteString(xml.Header); err != nil {
+		f.Close()
+		return err
+	}
+	encoder := xml.NewEncoder(f)
+	encoder.Indent("", "  ")
+	if err := encoder.Encode(feed); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
+
+// pageDate returns the date of a page for the feed: its #+DATE, as an org
+// timestamp or a plain date, or else when its file was last modified
+func pageDate(p writtenPage) time.Time {
+	date := strings.TrimSpace(p.doc.Date())
+	if ts, ok := org.ParseTimestamp(date); ok {
+		return ts.Start
+	}
+	if t, err := time.Parse("2006-01-02", date); err == nil {
+		return t
+	}
+	return p.entry.ModTime
+}
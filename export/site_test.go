@@ -0,0 +1,69 @@
+package export
+
+import (
+	"encoding/xml"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"org-charm/org"
+)
+
+func TestSite(t *testing.T) {
+	root, out := t.TempDir(), t.TempDir()
+	writeFiles(t, root, map[string]string{
+		"a.org":     "#+TITLE: Old\n#+DATE: 2024-01-01\n#+FILETAGS: :go:\n* Heading\n",
+		"sub/b.org": "#+TITLE: New\n#+DATE: <2025-06-01 Sun>\n* Work :work:go:\n",
+	})
+	tree, err := org.BuildFileTree(root)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	pages, err := Site(root, tree, out, SiteOptions{Title: "Notes", BaseURL: "https://example.com/notes/"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Two files, two directory indexes, two tags and the tag index
+	if pages != 7 {
+		t.Errorf("wrote %d pages, want 7", pages)
+	}
+
+	read := func(name string) string {
+		t.Helper()
+		content, err := os.ReadFile(filepath.Join(out, name))
+		if err != nil {
+			t.Fatal(err)
+		}
+		return string(content)
+	}
+	for name, wants := range map[string][]string{
+		"index.html":      {"<title>Notes</title>", `href="sub/index.html">sub</a>`, `href="a.html">Old</a>`, `href="tags/index.html"`},
+		"sub/index.html":  {`href="b.html">New</a>`, `href="../index.html"`},
+		"tags/go.html":    {`href="../a.html">Old</a>`, `href="../sub/b.html">New</a>`},
+		"tags/work.html":  {`href="../sub/b.html">New</a>`},
+		"tags/index.html": {`href="go.html">:go:</a> <span class="meta">2</span>`},
+	} {
+		page := read(name)
+		for _, want := range wants {
+			if !strings.Contains(page, want) {
+				t.Errorf("%s lacks %s:\n%s", name, want, page)
+			}
+		}
+	}
+
+	var feed atomFeed
+	if err := xml.Unmarshal([]byte(read("feed.xml")), &feed); err != nil {
+		t.Fatal(err)
+	}
+	if len(feed.Entries) != 2 || feed.Entries[0].Title != "New" || feed.Entries[1].Title != "Old" {
+		t.Fatalf("feed entries = %+v, want newest first", feed.Entries)
+	}
+	if e := feed.Entries[0]; e.Link.Href != "https://example.com/notes/sub/b.html" || e.Updated != "2025-06-01T00:00:00Z" || e.Base != "https://example.com/notes/sub/" {
+		t.Errorf("entry = %+v", e)
+	}
+	if feed.Updated != "2025-06-01T00:00:00Z" {
+		t.Errorf("feed updated = %s", feed.Updated)
+	}
+}
@@ -0,0 +1,31 @@
+package main
+
+import (
+	"net"
+
+	"org-charm/org"
+	"org-charm/rpc"
+	"org-charm/ui"
+
+	"github.com/charmbracelet/log"
+	"google.golang.org/grpc"
+)
+
+// serveGRPC serves the OrgCharm gRPC service for the org files of library
+// on addr, rendering documents with the settings of render unless
+// requests set them
+func serveGRPC(addr string, library *org.Library, render ui.RenderOptions) {
+	lis, err := net.Listen("tcp", addr)
+	if err != nil {
+		log.Fatal("Failed to listen for gRPC", "addr", addr, "error", err)
+	}
+	srv := grpc.NewServer()
+	rpc.RegisterOrgCharmServer(srv, rpc.NewServer(library, render))
+
+	log.Info("Serving gRPC", "addr", addr)
+	go func() {
+		if err := srv.Serve(lis); err != nil {
+			log.Error("gRPC server error", "error", err)
+		}
+	}()
+}
@@ -2,17 +2,29 @@ package main
 
 import (
 	"context"
+	"crypto/sha256"
 	_ "embed"
+	"encoding/hex"
 	"errors"
 	"flag"
+	"fmt"
 	"net"
+	"net/url"
 	"os"
 	"os/signal"
+	"runtime"
+	"strings"
 	"syscall"
 	"time"
 
+	"org-charm/analytics"
+	"org-charm/cache"
+	"org-charm/digest"
 	"org-charm/org"
+	"org-charm/remind"
+	"org-charm/state"
 	"org-charm/ui"
+	"org-charm/webhook"
 
 	tea "github.com/charmbracelet/bubbletea"
 	"github.com/charmbracelet/log"
@@ -22,17 +34,85 @@ import (
 	"github.com/charmbracelet/wish/bubbletea"
 	"github.com/charmbracelet/wish/logging"
 	"github.com/muesli/termenv"
+	gossh "golang.org/x/crypto/ssh"
 )
 
 //go:embed CHANGELOG.md
 var changelog string
 
 func main() {
+	// org-charm export writes the org files in other formats instead of
+	// serving them
+	if len(os.Args) > 1 && os.Args[1] == "export" {
+		runExport(os.Args[2:])
+		return
+	}
+	// org-charm stats prints the view counts a server saved
+	if len(os.Args) > 1 && os.Args[1] == "stats" {
+		runStats(os.Args[2:])
+		return
+	}
+	// org-charm build writes a static site of the org files
+	if len(os.Args) > 1 && os.Args[1] == "build" {
+		runBuild(os.Args[2:])
+		return
+	}
+
 	// Command line flags
 	host := flag.String("host", "localhost", "Host to listen on")
 	port := flag.String("port", "2222", "Port to listen on")
 	orgDir := flag.String("dir", "./orgfiles", "Directory containing org files")
 	keyPath := flag.String("key", ".ssh/id_ed25519", "Path to host key")
+	hyperlinks := flag.Bool("hyperlinks", false, "Emit OSC 8 hyperlinks (clickable links in supporting terminals)")
+	statusTemplate := flag.String("status-bar", ui.DefaultStatusTemplate, "Document status bar template ({file}, {title}, {scroll}, {words}, {reading}, {heading}, {time})")
+	stateDir := flag.String("state-dir", "", "Directory to persist per-user state in, a JSON file per user (empty keeps it in memory)")
+	stateDB := flag.String("state-db", "", "File to persist the per-user state of all users in instead of -state-dir, as one file to back up or sync (empty uses -state-dir)")
+	sortFlag := flag.String("sort", "name", "Initial file list order: name, title, date or modified, optionally suffixed with :desc")
+	motionFlag := flag.String("motion", "full", "Animations: full, reduced (short, low frame rate) or off")
+	chromaStyle := flag.String("chroma-style", "", "Syntax highlighting style for source blocks (empty matches the theme)")
+	showExcluded := flag.Bool("show-excluded", false, "Show COMMENT headlines and :noexport: subtrees dimmed instead of hiding them")
+	zebraTables := flag.Bool("zebra-tables", false, "Stripe alternate table rows")
+	foldCode := flag.Int("fold-code", 40, "Fold source blocks longer than this many lines to a preview (0 never folds)")
+	guessLanguage := flag.Bool("guess-language", true, "Guess the language of source blocks without one for highlighting")
+	latexUnicode := flag.Bool("latex-unicode", false, "Approximate LaTeX math environments with unicode symbols")
+	cacheMB := flag.Int64("cache-mb", 512, "Memory budget in MiB of the parsed and rendered documents kept for sessions to share, split evenly between the two (0 for no limit)")
+	eagerParse := flag.Bool("eager-parse", false, "Parse every org file at startup, in parallel, instead of on first open")
+	debugFlag := flag.String("debug-addr", "", "Serve pprof and expvar on this localhost address, e.g. :6060 (empty disables)")
+	pdfCommand := flag.String("pdf-command", "", "Command converting a document to PDF, split on spaces, with {input} and {output} for the paths, e.g. \"pandoc {input} -o {output}\" (empty disables PDF export)")
+	exportsDir := flag.String("exports-dir", "./exports", "Directory PDF exports are written to")
+	calendarAddr := flag.String("calendar-addr", "", "Serve the SCHEDULED and DEADLINE entries as an iCalendar at /calendar.ics on this address, e.g. :8080 (empty disables)")
+	caldavURL := flag.String("caldav-url", "", "Publish the SCHEDULED and DEADLINE entries as events of this CalDAV calendar collection, replacing and removing them as the entries change (empty disables)")
+	caldavUser := flag.String("caldav-user", "", "User to authenticate to the CalDAV server as, with the password in $"+caldavPasswordEnv)
+	caldavInterval := flag.Duration("caldav-interval", 15*time.Minute, "How often the entries are published to the CalDAV calendar")
+	digestTo := flag.String("digest-to", "", "Email a digest of the agenda of the next -digest-days days to these comma-separated addresses on the -digest-schedule, through the -smtp-addr server (empty disables)")
+	digestSchedule := flag.String("digest-schedule", "0 7 * * *", "When the agenda digest is emailed, as a crontab schedule (minute hour day-of-month month day-of-week) in local time, or @daily, @weekly")
+	digestDays := flag.Int("digest-days", 7, "Number of days of the agenda the digest covers, from the day it's sent")
+	smtpAddr := flag.String("smtp-addr", "localhost:25", "SMTP server the agenda digest is sent through, as host:port; port 465 uses TLS, others STARTTLS when offered")
+	smtpUser := flag.String("smtp-user", "", "User to authenticate to the SMTP server as, with the password in $"+smtpPasswordEnv+" (empty sends without authenticating)")
+	smtpFrom := flag.String("smtp-from", "org-charm@localhost", "Sender address of the agenda digest")
+	var sinks []remind.Sink
+	flag.Func("remind", "Send a reminder of each DEADLINE entry coming within its warning period to this sink: slack=URL (incoming webhook), matrix=URL (homeserver and room ID, as https://matrix.example.com/!room:example.com, posting with the access token in $"+matrixTokenEnv+") or http=URL (JSON POST); repeat for several", func(spec string) error {
+		sink, err := remind.ParseSink(spec, os.Getenv(matrixTokenEnv))
+		if err != nil {
+			return err
+		}
+		sinks = append(sinks, sink)
+		return nil
+	})
+	remindWarning := flag.Int("remind-warning", 14, "Days before a deadline its reminder is sent, for deadlines without a warning period of their own such as -3d (org-deadline-warning-days)")
+	remindFile := flag.String("remind-file", "./reminders.json", "File the reminders sent are saved to, so a restart doesn't send them again (empty keeps them in memory)")
+	analyticsFlag := flag.Bool("analytics", false, "Count the views of each document, with viewers only kept as salted hashes of their keys, for a Popular section in the file list and org-charm stats (off: nothing is counted)")
+	analyticsFile := flag.String("analytics-file", "", "File -analytics saves the view counts to, for them to last across restarts and for org-charm stats to read (empty keeps them in memory)")
+	renderAddr := flag.String("render-addr", "", "Serve the org files rendered to ANSI text as the TUI shows them on this address, e.g. :8081, for curl host:8081/notes.org?width=120 (empty disables)")
+	grpcAddr := flag.String("grpc-addr", "", "Serve the gRPC API listing, parsing and rendering documents on this address, e.g. localhost:50051 (empty disables)")
+	webhookURL := flag.String("webhook-url", "", "POST a JSON event (path, title, lines added and removed) to this URL for every org file added or changed (empty disables)")
+	repoURL := flag.String("repo", "", "Serve the org files of this git repository instead of -dir, cloned into a cache directory at startup and fetched every -repo-interval (empty serves -dir)")
+	repoInterval := flag.Duration("repo-interval", 5*time.Minute, "How often the -repo repository is fetched")
+	webdavURL := flag.String("webdav-url", "", "Mirror the org files of this WebDAV collection, e.g. a Nextcloud or Fastmail folder, into a cache directory and serve them read-only instead of -dir (empty serves -dir)")
+	webdavUser := flag.String("webdav-user", "", "User to authenticate to the WebDAV server as, with the password in $"+webdavPasswordEnv)
+	webdavInterval := flag.Duration("webdav-interval", 5*time.Minute, "How often the WebDAV collection is mirrored again")
+	roamDB := flag.String("roam-db", "", "Resolve id: and roam: links and count backlinks with the org-roam database at this path (org-roam-db-location), read with the sqlite3 command (empty disables)")
+	graphicsFlag := flag.String("graphics", "auto", "Image drawing: auto (detect per session), kitty, blocks (half-block art) or none (placeholders)")
 	flag.Parse()
 
 	// Setup logging with charm's log library
@@ -40,6 +120,78 @@ func main() {
 	log.SetReportTimestamp(true)
 	log.SetReportCaller(false)
 
+	sortOrder, err := org.ParseSortOrder(*sortFlag)
+	if err != nil {
+		log.Fatal("Invalid -sort flag", "error", err)
+	}
+	motion, err := ui.ParseMotion(*motionFlag)
+	if err != nil {
+		log.Fatal("Invalid -motion flag", "error", err)
+	}
+	graphics, err := ui.ParseGraphics(*graphicsFlag)
+	if err != nil {
+		log.Fatal("Invalid -graphics flag", "error", err)
+	}
+	if *chromaStyle != "" {
+		if err := ui.CheckChromaStyle(*chromaStyle); err != nil {
+			log.Fatal("Invalid -chroma-style flag", "error", err)
+		}
+	}
+	if *pdfCommand != "" && !strings.Contains(*pdfCommand, "{input}") {
+		log.Fatal("Invalid -pdf-command flag", "error", "the command must take the document as {input}")
+	}
+	if *webhookURL != "" {
+		if u, err := url.Parse(*webhookURL); err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			log.Fatal("Invalid -webhook-url flag", "error", "want an http or https URL")
+		}
+	}
+	if *caldavURL != "" && *caldavInterval <= 0 {
+		log.Fatal("Invalid -caldav-interval flag", "error", "the interval must be positive")
+	}
+	var schedule *digest.Schedule
+	if *digestTo != "" {
+		if schedule, err = digest.ParseSchedule(*digestSchedule); err != nil {
+			log.Fatal("Invalid -digest-schedule flag", "error", err)
+		}
+		if *digestDays <= 0 {
+			log.Fatal("Invalid -digest-days flag", "error", "the digest must cover at least a day")
+		}
+	}
+	if *analyticsFile != "" && !*analyticsFlag {
+		log.Fatal("Invalid -analytics-file flag", "error", "views are only counted with -analytics")
+	}
+	if *remindWarning < 0 {
+		log.Fatal("Invalid -remind-warning flag", "error", "the warning period can't be negative")
+	}
+	if *stateDB != "" && *stateDir != "" {
+		log.Fatal("Invalid -state-db flag", "error", "state is kept in either a directory or a file")
+	}
+	if *repoURL != "" && *webdavURL != "" {
+		log.Fatal("Invalid -repo flag", "error", "the org files come from either a repository or a WebDAV collection")
+	}
+	if *repoURL != "" && *repoInterval <= 0 {
+		log.Fatal("Invalid -repo-interval flag", "error", "the interval must be positive")
+	}
+	if *webdavURL != "" && *webdavInterval <= 0 {
+		log.Fatal("Invalid -webdav-interval flag", "error", "the interval must be positive")
+	}
+	if *debugFlag != "" {
+		addr, err := debugAddr(*debugFlag)
+		if err != nil {
+			log.Fatal("Invalid -debug-addr flag", "error", err)
+		}
+		serveDebug(addr)
+	}
+
+	// Serve a clone of the repository or a mirror of the WebDAV
+	// collection, if given one
+	if *repoURL != "" {
+		*orgDir = mirrorRepository(*repoURL, *repoInterval)
+	}
+	if *webdavURL != "" {
+		*orgDir = mirrorWebDAV(*webdavURL, *webdavUser, *webdavInterval)
+	}
+
 	// Verify org directory exists
 	if _, err := os.Stat(*orgDir); os.IsNotExist(err) {
 		log.Warn("Org directory does not exist, creating it", "dir", *orgDir)
@@ -48,11 +200,60 @@ func main() {
 		}
 	}
 
-	// Build file tree (recursive)
-	tree, err := org.BuildFileTree(*orgDir)
+	// List the org files for all sessions to share, and keep listing them
+	// for sessions to follow changes
+	library, err := org.NewLibrary(*orgDir)
 	if err != nil {
 		log.Fatal("Failed to build file tree", "error", err)
 	}
+	var notifier *webhook.Notifier
+	if *webhookURL != "" {
+		notifier = webhook.New(*webhookURL, library)
+	}
+	var roam *org.Roam
+	if *roamDB != "" {
+		roam = openRoam(*roamDB, library)
+	}
+	go func() {
+		for range time.Tick(libraryRefreshInterval) {
+			if changes, err := library.Refresh(); err != nil {
+				log.Error("Failed to refresh file tree", "error", err)
+			} else if len(changes) > 0 {
+				log.Debug("Org files changed", "version", library.Version(), "changes", len(changes))
+				if notifier != nil {
+					notifier.Notify(changes)
+				}
+			}
+			if roam != nil {
+				refreshRoam(roam)
+			}
+		}
+	}()
+	if *calendarAddr != "" {
+		serveCalendar(*calendarAddr, library)
+	}
+	if *caldavURL != "" {
+		publishCalendar(*caldavURL, *caldavUser, library, *caldavInterval)
+	}
+	if *digestTo != "" {
+		scheduleDigest(schedule, *digestDays, *smtpAddr, *smtpUser, *smtpFrom, *digestTo, library)
+	}
+	if len(sinks) > 0 {
+		sendReminders(sinks, *remindWarning, *remindFile, library)
+	}
+	render := ui.RenderOptions{
+		ChromaStyle:   *chromaStyle,
+		ShowExcluded:  *showExcluded,
+		GuessLanguage: *guessLanguage,
+		LatexUnicode:  *latexUnicode,
+	}
+	if *grpcAddr != "" {
+		serveGRPC(*grpcAddr, library, render)
+	}
+	if *renderAddr != "" {
+		serveRender(*renderAddr, library, render)
+	}
+	tree, _ := library.Tree()
 
 	// Count total org files
 	flatList := org.FlattenTree(tree)
@@ -64,18 +265,86 @@ func main() {
 	}
 	log.Info("Found org files", "count", fileCount)
 
+	// Keep the shared caches within the budget, and report how they and
+	// the sessions do
+	budget := *cacheMB << 20
+	org.SetCacheBudget(budget / 2)
+	ui.SetCacheBudget(budget / 2)
+	go func() {
+		for range time.Tick(cacheStatsInterval) {
+			logCacheStats()
+			logSessionStats()
+		}
+	}()
+
+	// Parse everything up front if asked to, for sessions to share, logging
+	// progress every tenth
+	if *eagerParse {
+		start := time.Now()
+		parsed := org.ParseTree(tree, runtime.NumCPU(), func(done, total int) {
+			if done == total || done*10/total != (done-1)*10/total {
+				log.Debug("Parsing org files", "done", done, "total", total)
+			}
+		})
+		log.Info("Parsed org files", "count", len(parsed), "workers", runtime.NumCPU(), "took", time.Since(start))
+	}
+
+	// Per-user state, shared by all sessions
+	var store *state.Store
+	if *stateDB != "" {
+		db, err := state.OpenDB(*stateDB)
+		if err != nil {
+			log.Fatal("Failed to open state database", "error", err)
+		}
+		defer db.Close()
+		store = state.NewBackendStore(db)
+	} else if store, err = state.NewStore(*stateDir); err != nil {
+		log.Fatal("Failed to open state directory", "error", err)
+	}
+
+	// View counts, only kept when asked for
+	var views *analytics.Views
+	if *analyticsFlag {
+		views = countViews(*analyticsFile)
+	}
+
 	// Create the bubbletea handler
-	teaHandler := makeTeaHandler(*orgDir)
+	teaHandler := makeTeaHandler(*orgDir, ui.Options{
+		Hyperlinks:     *hyperlinks,
+		StatusTemplate: *statusTemplate,
+		SortOrder:      sortOrder,
+		Motion:         motion,
+		ChromaStyle:    *chromaStyle,
+		ShowExcluded:   *showExcluded,
+		ZebraTables:    *zebraTables,
+		FoldCode:       *foldCode,
+		LatexUnicode:   *latexUnicode,
+		GuessLanguage:  *guessLanguage,
+		Graphics:       graphics,
+		PDFCommand:     strings.Fields(*pdfCommand),
+		ExportsDir:     *exportsDir,
+		Library:        library,
+		Roam:           roam,
+		Views:          views,
+		Store:          store,
+	})
 
 	// Create SSH server with wish
 	srv, err := wish.NewServer(
 		wish.WithAddress(net.JoinHostPort(*host, *port)),
 		wish.WithHostKeyPath(*keyPath),
+		// Accept everyone; a public key, when offered, identifies the user
+		// for per-user state
+		wish.WithPublicKeyAuth(func(ssh.Context, ssh.PublicKey) bool { return true }),
+		wish.WithKeyboardInteractiveAuth(func(ssh.Context, gossh.KeyboardInteractiveChallenge) bool { return true }),
 		wish.WithMiddleware(
 			// Bubbletea middleware with forced TrueColor - serves the TUI to each SSH session
 			bubbletea.MiddlewareWithColorProfile(teaHandler, termenv.TrueColor),
 			// Require an active terminal
 			activeterm.Middleware(),
+			// Commands given to ssh run without a terminal, before it's
+			// required
+			execMiddleware(library, render),
 			// Logging middleware using charm's log
 			logging.Middleware(),
 		),
@@ -113,11 +382,72 @@ func main() {
 		log.Error("Failed to shutdown server gracefully", "error", err)
 	}
 
+	if views != nil {
+		saveViews(views)
+	}
+	logCacheStats()
 	log.Info("Server stopped")
 }
 
+// libraryRefreshInterval is how often the org directory is listed again
+// for sessions to see files added, removed or modified
+const libraryRefreshInterval = 5 * time.Second
+
+// cacheStatsInterval is how often the use of the shared caches is logged
+const cacheStatsInterval = 10 * time.Minute
+
+// logCacheStats logs the hit rate and memory use of the shared caches
+func logCacheStats() {
+	documents, previews := ui.CacheStats()
+	for _, c := range []struct {
+		name  string
+		stats cache.Stats
+	}{
+		{"parsed", org.CacheStats()},
+		{"rendered", documents},
+		{"previews", previews},
+	} {
+		log.Info("Cache use",
+			"cache", c.name,
+			"entries", c.stats.Entries,
+			"mib", fmt.Sprintf("%.1f/%.0f", float64(c.stats.Bytes)/(1<<20), float64(c.stats.Budget)/(1<<20)),
+			"hit_rate", fmt.Sprintf("%.0f%%", c.stats.HitRate()*100),
+			"evictions", c.stats.Evictions,
+		)
+	}
+}
+
+// sessionStatsLogged is how many of the sessions spending the most time
+// rendering are logged with the cache use
+const sessionStatsLogged = 5
+
+// logSessionStats logs how many sessions are running and the resource use
+// of the most expensive of them
+func logSessionStats() {
+	stats := ui.Sessions()
+	log.Info("Sessions", "count", len(stats))
+	for _, s := range stats[:min(len(stats), sessionStatsLogged)] {
+		logSession("Session use", s)
+	}
+}
+
+// logSession logs the resource use of a session
+func logSession(msg string, s ui.SessionStats) {
+	log.Info(msg,
+		"session", s.ID,
+		"user", s.User,
+		"duration", time.Since(s.Started).Round(time.Second),
+		"document", s.Document,
+		"held_kib", s.HeldBytes>>10,
+		"renders", s.Renders,
+		"render_time", s.RenderTime.Round(time.Millisecond),
+		"slowest", s.SlowestRender.Round(time.Millisecond),
+		"slowest_document", s.SlowestDocument,
+	)
+}
+
 // makeTeaHandler creates a bubbletea handler function for wish
-func makeTeaHandler(orgDir string) bubbletea.Handler {
+func makeTeaHandler(orgDir string, options ui.Options) bubbletea.Handler {
 	return func(sess ssh.Session) (tea.Model, []tea.ProgramOption) {
 		// Get the renderer for this SSH session and force TrueColor
 		renderer := bubbletea.MakeRenderer(sess)
@@ -126,15 +456,30 @@ func makeTeaHandler(orgDir string) bubbletea.Handler {
 		// Get PTY info for window size
 		pty, _, _ := sess.Pty()
 
+		// Pick the image protocol of the client's terminal
+		sessionOptions := options
+		if sessionOptions.Graphics == ui.GraphicsAuto {
+			sessionOptions.Graphics = ui.DetectGraphics(pty.Term, sess.Environ())
+		}
+
 		log.Info("New SSH session",
 			"user", sess.User(),
 			"term", pty.Term,
+			"graphics", sessionOptions.Graphics,
 			"width", pty.Window.Width,
 			"height", pty.Window.Height,
 		)
 
 		// Create the model with session-specific renderer
-		model := ui.NewModel(renderer, orgDir, changelog)
+		sessionOptions.UserID = userID(sess)
+		model := ui.NewModel(renderer, orgDir, changelog, sessionOptions)
+
+		// Account for the session until it ends
+		end := ui.TrackSession(model, sess.User(), pty.Term)
+		go func() {
+			<-sess.Context().Done()
+			logSession("Session ended", end())
+		}()
 
 		return model, []tea.ProgramOption{
 			tea.WithAltScreen(),
@@ -142,3 +487,14 @@ func makeTeaHandler(orgDir string) bubbletea.Handler {
 		}
 	}
 }
+
+// userID identifies the user of a session by their public key. Sessions
+// without a key are anonymous and get an empty id.
+func userID(sess ssh.Session) string {
+	key := sess.PublicKey()
+	if key == nil {
+		return ""
+	}
+	sum := sha256.Sum256(key.Marshal())
+	return hex.EncodeToString(sum[:])
+}
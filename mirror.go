@@ -0,0 +1,117 @@
+package main
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"os"
+	"path"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"time"
+
+	"org-charm/org"
+	"org-charm/webdav"
+
+	"github.com/charmbracelet/log"
+)
+
+// webdavPasswordEnv is the environment variable holding the password of
+// the WebDAV user, kept out of the command line
+const webdavPasswordEnv = "ORG_CHARM_WEBDAV_PASSWORD"
+
+// mirrorWebDAV mirrors the org files of the WebDAV collection at url into
+// a cache directory before the server lists them, and again every interval
+// in the background, and returns the directory, for the server to serve.
+// The mirror gets a directory of its own rather than -dir as it removes
+// the org files gone from the collection. The server goes on with the
+// files mirrored last if the collection can't be reached.
+func mirrorWebDAV(url, user string, interval time.Duration) string {
+	dir := mirrorCacheDir("webdav", url)
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		log.Fatal("Failed to create WebDAV mirror directory", "dir", dir, "error", err)
+	}
+	mirror, err := webdav.New(url, user, os.Getenv(webdavPasswordEnv), dir)
+	if err != nil {
+		log.Fatal("Invalid -webdav-url flag", "error", err)
+	}
+	sync := func() {
+		ctx, cancel := context.WithTimeout(context.Background(), interval)
+		defer cancel()
+		start := time.Now()
+		changed, err := mirror.Sync(ctx)
+		if err != nil {
+			log.Error("Failed to mirror WebDAV collection", "url", url, "error", err)
+			return
+		}
+		if changed > 0 {
+			log.Info("Mirrored WebDAV collection", "url", url, "dir", dir, "changed", changed, "took", time.Since(start))
+		}
+	}
+
+	sync()
+	go func() {
+		for range time.Tick(interval) {
+			sync()
+		}
+	}()
+	return dir
+}
+
+// mirrorRepository clones the git repository at url into a cache
+// directory, or updates the clone a previous run left there, and fetches
+// it again every interval in the background. It returns the directory of
+// the clone, for the server to serve. The server goes on with the commit
+// fetched last if the repository can't be reached, unless it was never
+// cloned.
+func mirrorRepository(url string, interval time.Duration) string {
+	dir := mirrorCacheDir("repos", url)
+	sync := func() error {
+		ctx, cancel := context.WithTimeout(context.Background(), interval)
+		defer cancel()
+		start := time.Now()
+		changed, err := org.MirrorRepository(ctx, url, dir)
+		if err != nil {
+			return err
+		}
+		if changed {
+			log.Info("Updated repository", "url", url, "dir", dir, "took", time.Since(start))
+		}
+		return nil
+	}
+
+	if err := sync(); err != nil {
+		if _, statErr := os.Stat(filepath.Join(dir, ".git")); statErr != nil {
+			log.Fatal("Failed to clone repository", "url", url, "error", err)
+		}
+		log.Error("Failed to update repository", "url", url, "error", err)
+	}
+	go func() {
+		for range time.Tick(interval) {
+			if err := sync(); err != nil {
+				log.Error("Failed to update repository", "url", url, "error", err)
+			}
+		}
+	}()
+	return dir
+}
+
+// unsafeNameChars matches the characters of source names left out of the
+// names of their cache directories
+var unsafeNameChars = regexp.MustCompile(`[^A-Za-z0-9._-]+`)
+
+// mirrorCacheDir returns the directory the repository or collection at url
+// is mirrored into: a directory of the user's cache under kind, named
+// after the repository or collection and told apart from others of the
+// same name by a hash of url
+func mirrorCacheDir(kind, url string) string {
+	base, err := os.UserCacheDir()
+	if err != nil {
+		base = os.TempDir()
+	}
+	name := path.Base(strings.TrimSuffix(strings.TrimRight(url, "/"), ".git"))
+	name = strings.Trim(unsafeNameChars.ReplaceAllString(name, "-"), "-.")
+	sum := sha256.Sum256([]byte(url))
+	return filepath.Join(base, "org-charm", kind, name+"-"+hex.EncodeToString(sum[:6]))
+}
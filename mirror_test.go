@@ -0,0 +1,22 @@
+package main
+
+import (
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestMirrorCacheDir(t *testing.T) {
+	t.Setenv("XDG_CACHE_HOME", t.TempDir())
+	repo := mirrorCacheDir("repos", "https://github.com/ada/notes.git")
+	if !strings.HasPrefix(filepath.Base(repo), "notes-") || filepath.Base(filepath.Dir(repo)) != "repos" {
+		t.Errorf("repository cache dir = %s", repo)
+	}
+	dav := mirrorCacheDir("webdav", "https://cloud.example.com/remote.php/dav/files/ada/My Notes/")
+	if !strings.HasPrefix(filepath.Base(dav), "My-Notes-") || filepath.Base(filepath.Dir(dav)) != "webdav" {
+		t.Errorf("WebDAV cache dir = %s", dav)
+	}
+	if other := mirrorCacheDir("webdav", "https://other.example.com/dav/My Notes/"); other == dav {
+		t.Error("collections of the same name share a cache dir")
+	}
+}
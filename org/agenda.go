@@ -0,0 +1,291 @@
+package org
+
+import (
+	"fmt"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	goorg "github.com/niklasfasching/go-org/org"
+)
+
+// PlannedEntry is a headline with a SCHEDULED or DEADLINE timestamp
+type PlannedEntry struct {
+	Title    string   // Headline text, without its keyword, priority and tags
+	Status   string   // TODO keyword, if any
+	Done     bool     // Whether the keyword is a done state, or the entry was closed
+	Tags     []string // Tags of the headline itself
+	ID       string   // :ID: property, if any
+	Planning Planning
+
+	// Where the headline is: the titles of its ancestors, outermost first,
+	// and its index among the headlines of its parent, and theirs
+	Outline  []string
+	Position []int
+}
+
+// Planned returns the headlines of the document that are scheduled or
+// have a deadline, in document order. Excluded subtrees, COMMENT ones and
+// those tagged with an EXCLUDE_TAGS tag such as :noexport:, are left out,
+// as exports leave them out.
+func (f *OrgFile) Planned() []PlannedEntry {
+	done := doneKeywords(f.Document.Get("TODO"))
+	var entries []PlannedEntry
+	var walk func(nodes []goorg.Node, outline []string, position []int)
+	walk = func(nodes []goorg.Node, outline []string, position []int) {
+		index := -1
+		for _, node := range nodes {
+			h, ok := node.(goorg.Headline)
+			if !ok {
+				continue
+			}
+			// Excluded headlines count, so excluding one moves no other
+			index++
+			if h.IsExcluded(f.Document) {
+				continue
+			}
+			title := strings.TrimSpace(goorg.String(h.Title...))
+			position := append(slices.Clip(position), index)
+			if planning, ok := HeadlinePlanning(h); ok && (planning.Scheduled != nil || planning.Deadline != nil) {
+				entry := PlannedEntry{
+					Title:    title,
+					Status:   h.Status,
+					Done:     done[h.Status] || planning.Closed != nil,
+					Tags:     h.Tags,
+					ID:       headlineID(h),
+					Planning: planning,
+					Outline:  outline,
+					Position: position,
+				}
+				entries = append(entries, entry)
+			}
+			walk(h.Children, append(slices.Clip(outline), title), position)
+		}
+	}
+	walk(f.Document.Nodes, nil, nil)
+	return entries
+}
+
+// EachPlanned calls fn with each planned entry of the org files in tree,
+// loading them as needed, whether the directories are expanded or not
+func EachPlanned(tree []*FileEntry, fn func(entry *FileEntry, planned PlannedEntry)) error {
+	for _, entry := range tree {
+		if entry.IsDir {
+			if err := EachPlanned(entry.Children, fn); err != nil {
+				return err
+			}
+			continue
+		}
+		doc, err := entry.GetOrgFile()
+		if err != nil {
+			return fmt.Errorf("%s: %w", entry.RelPath, err)
+		}
+		for _, planned := range doc.Planned() {
+			fn(entry, planned)
+		}
+	}
+	return nil
+}
+
+// AgendaItem is a line of an agenda: a SCHEDULED or DEADLINE timestamp of
+// an entry falling on a day, or past due on the first day
+type AgendaItem struct {
+	Day   time.Time // Day the item shows on, at midnight UTC like timestamp dates
+	File  string    // Path of the file, slash separated, relative to the org directory
+	Entry PlannedEntry
+	Kind  string    // S for SCHEDULED, DL for DEADLINE, as in org agendas
+	Date  time.Time // Date of the timestamp, or of its repetition on Day
+	// HasTime reports whether Date includes a time of day
+	HasTime bool
+}
+
+// Overdue reports whether the item is shown past its date
+func (it AgendaItem) Overdue() bool {
+	return it.Date.Before(it.Day)
+}
+
+// Agenda returns the items of the days days from the day of from, of the
+// entries of the org files in tree that aren't done, the way org agendas
+// show them: repeating timestamps on each day they repeat to, and those
+// past due on the first day. Items are sorted by day, timed items first by
+// time, then in file order.
+func Agenda(tree []*FileEntry, from time.Time, days int) ([]AgendaItem, error) {
+	// Timestamps are parsed as UTC, so days are compared as such
+	first := time.Date(from.Year(), from.Month(), from.Day(), 0, 0, 0, 0, time.UTC)
+	end := first.AddDate(0, 0, days)
+
+	var items []AgendaItem
+	add := func(entry *FileEntry, planned PlannedEntry, kind string, ts *Timestamp) {
+		item := AgendaItem{File: entry.RelPath, Entry: planned, Kind: kind, HasTime: ts.HasTime}
+		if ts.Start.Before(first) {
+			overdue := item
+			overdue.Day, overdue.Date = first, ts.Start
+			items = append(items, overdue)
+		}
+		for _, date := range repetitions(*ts, first, end) {
+			item.Day = time.Date(date.Year(), date.Month(), date.Day(), 0, 0, 0, 0, time.UTC)
+			item.Date = date
+			items = append(items, item)
+		}
+	}
+	err := EachPlanned(tree, func(entry *FileEntry, planned PlannedEntry) {
+		if planned.Done {
+			return
+		}
+		if ts := planned.Planning.Scheduled; ts != nil {
+			add(entry, planned, "S", ts)
+		}
+		if ts := planned.Planning.Deadline; ts != nil {
+			add(entry, planned, "DL", ts)
+		}
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	sort.SliceStable(items, func(i, j int) bool {
+		a, b := items[i], items[j]
+		if !a.Day.Equal(b.Day) {
+			return a.Day.Before(b.Day)
+		}
+		if a.HasTime != b.HasTime {
+			return a.HasTime
+		}
+		if a.HasTime {
+			return clock(a.Date) < clock(b.Date)
+		}
+		return false
+	})
+	return items, nil
+}
+
+// maxRepetitions bounds the repetitions of a timestamp an agenda lists,
+// for hourly repeaters over long spans
+const maxRepetitions = 1000
+
+// repetitions returns the dates a timestamp falls on from first until
+// end: its own, and those its repeater moves it to
+func repetitions(ts Timestamp, first, end time.Time) []time.Time {
+	step := repeaterStep(ts.Repeater)
+	var dates []time.Time
+	for date := ts.Start; date.Before(end) && len(dates) < maxRepetitions; date = step(date) {
+		if !date.Before(first) {
+			dates = append(dates, date)
+		}
+		if step == nil {
+			break
+		}
+	}
+	return dates
+}
+
+// repeaterStep returns a function moving a date to the next repetition of
+// a repeater such as +1w, .+2d or ++1m, or nil for no repeater
+func repeaterStep(repeater string) func(time.Time) time.Time {
+	// Habits add a maximum interval, as in .+2d/4d, which agendas ignore
+	value, _, _ := strings.Cut(strings.TrimLeft(repeater, "+."), "/")
+	return cookieShift(value, 1)
+}
+
+// cookieShift returns a function moving a date by sign times the interval
+// of a cookie value such as 2d or 1w, or nil if it isn't one
+func cookieShift(value string, sign int) func(time.Time) time.Time {
+	if len(value) < 2 {
+		return nil
+	}
+	n, err := strconv.Atoi(value[:len(value)-1])
+	if err != nil || n < 1 {
+		return nil
+	}
+	n *= sign
+	switch value[len(value)-1] {
+	case 'h':
+		return func(t time.Time) time.Time { return t.Add(time.Duration(n) * time.Hour) }
+	case 'd':
+		return func(t time.Time) time.Time { return t.AddDate(0, 0, n) }
+	case 'w':
+		return func(t time.Time) time.Time { return t.AddDate(0, 0, 7*n) }
+	case 'm':
+		return func(t time.Time) time.Time { return t.AddDate(0, n, 0) }
+	case 'y':
+		return func(t time.Time) time.Time { return t.AddDate(n, 0, 0) }
+	}
+	return nil
+}
+
+// WarningStart returns when agendas start warning of the deadline at a
+// timestamp: the start of its day less its warning period, such as -3d,
+// or less fallback days without one, as org-deadline-warning-days
+func (t Timestamp) WarningStart(fallback int) time.Time {
+	day := time.Date(t.Start.Year(), t.Start.Month(), t.Start.Day(), 0, 0, 0, 0, t.Start.Location())
+	if shift := cookieShift(strings.TrimLeft(t.Warning, "-"), -1); shift != nil {
+		return shift(day)
+	}
+	return day.AddDate(0, 0, -fallback)
+}
+
+// clock returns the time of day of t, as a duration since midnight
+func clock(t time.Time) time.Duration {
+	return time.Duration(t.Hour())*time.Hour + time.Duration(t.Minute())*time.Minute
+}
+
+// HeadlinePlanning parses the planning line right below a headline, which
+// go-org leaves at the start of its first paragraph
+func HeadlinePlanning(h goorg.Headline) (Planning, bool) {
+	if len(h.Children) == 0 {
+		return Planning{}, false
+	}
+	p, ok := h.Children[0].(goorg.Paragraph)
+	if !ok {
+		return Planning{}, false
+	}
+	first, _, _ := strings.Cut(strings.TrimSpace(goorg.String(p)), "\n")
+	return ParsePlanning(first)
+}
+
+// HeadlineProperties returns the property drawer of a headline, or nil.
+// go-org only attaches the drawer to the headline when no planning line
+// sits between them, so it's looked for among the first children too.
+func HeadlineProperties(h goorg.Headline) *goorg.PropertyDrawer {
+	if h.Properties != nil {
+		return h.Properties
+	}
+	for _, child := range h.Children[:min(2, len(h.Children))] {
+		if pd, ok := child.(goorg.PropertyDrawer); ok {
+			return &pd
+		}
+	}
+	return nil
+}
+
+// headlineID returns the :ID: property of a headline
+func headlineID(h goorg.Headline) string {
+	if pd := HeadlineProperties(h); pd != nil {
+		id, _ := pd.Get("ID")
+		return id
+	}
+	return ""
+}
+
+// doneKeywords returns the done states of a TODO keyword setting such as
+// "TODO NEXT | DONE CANCELED": those after the bar, or the last keyword
+// without one
+func doneKeywords(setting string) map[string]bool {
+	done := map[string]bool{}
+	keywords := strings.Fields(setting)
+	if _, after, found := strings.Cut(setting, "|"); found {
+		keywords = strings.Fields(after)
+	} else if len(keywords) > 0 {
+		keywords = keywords[len(keywords)-1:]
+	}
+	for _, kw := range keywords {
+		// Strip fast-access keys like DONE(d)
+		if i := strings.Index(kw, "("); i > 0 {
+			kw = kw[:i]
+		}
+		done[kw] = true
+	}
+	return done
+}
@@ -0,0 +1,44 @@
+package org
+
+import "org-charm/cache"
+
+// parsedSizeFactor estimates the memory a parsed file takes as a multiple
+// of its source: the source itself plus the syntax tree
+const parsedSizeFactor = 6
+
+// documents keeps parsed org files by path, shared by all sessions, so a
+// file is parsed once until it changes rather than once per session
+var documents = cache.New[string, *OrgFile](0, func(f *OrgFile) int64 {
+	return int64(len(f.RawContent)) * parsedSizeFactor
+})
+
+// SetCacheBudget limits the memory of the parsed files kept for sessions
+// to share to about budget bytes, dropping the least recently used files
+// beyond it. 0 doesn't limit it.
+func SetCacheBudget(budget int64) {
+	documents.SetBudget(budget)
+}
+
+// CacheStats returns the use of the cache of parsed files
+func CacheStats() cache.Stats {
+	return documents.Stats()
+}
+
+// Load returns the parsed org file at path, parsing it unless it was
+// parsed before and hasn't changed since. The file may be shared with
+// other sessions and must not be modified.
+func Load(path string) (*OrgFile, error) {
+	if cached, ok := documents.Get(path); ok && !cached.Changed() {
+		return cached, nil
+	}
+
+	orgFile, err := ParseFile(path)
+	if err != nil {
+		return nil, err
+	}
+	// Stats are cached on first use, so compute them before the file is
+	// shared
+	orgFile.Stats()
+	documents.Add(path, orgFile)
+	return orgFile, nil
+}
@@ -0,0 +1,74 @@
+package org
+
+import (
+	"regexp"
+	"strings"
+	"time"
+)
+
+// DenoteName is the metadata in the name of a file following the Denote
+// convention, IDENTIFIER==SIGNATURE--TITLE__KEYWORDS.org, as in
+// 20240101T101010--meeting-notes__work_plans.org. Only the identifier is
+// required.
+type DenoteName struct {
+	Identifier string    // Creation time, as in 20240101T101010
+	Date       time.Time // The identifier as a local time
+	Signature  string
+	Title      string // Words of the title, separated by spaces
+	Keywords   []string
+}
+
+// denoteNameRegexp matches the parts of Denote file names, extension and
+// all. Titles are slugs of lowercase words joined by hyphens, and keywords
+// are joined by underscores.
+var denoteNameRegexp = regexp.MustCompile(`^(\d{8}T\d{6})(?:==([^-_.]+))?(?:--([^_.]+))?(?:__([^.]+))?(?:\..*)?$`)
+
+// ParseDenoteName reads the Denote metadata of the file named name, and
+// reports whether the name follows the convention
+func ParseDenoteName(name string) (DenoteName, bool) {
+	m := denoteNameRegexp.FindStringSubmatch(name)
+	if m == nil {
+		return DenoteName{}, false
+	}
+	date, err := time.ParseInLocation("20060102T150405", m[1], time.Local)
+	if err != nil {
+		return DenoteName{}, false
+	}
+	d := DenoteName{
+		Identifier: m[1],
+		Date:       date,
+		Signature:  strings.ReplaceAll(m[2], "=", " "),
+		Title:      strings.Join(strings.FieldsFunc(m[3], func(r rune) bool { return r == '-' }), " "),
+	}
+	for _, keyword := range strings.Split(m[4], "_") {
+		if keyword != "" {
+			d.Keywords = append(d.Keywords, keyword)
+		}
+	}
+	return d, true
+}
+
+// DateString returns the identifier as Denote writes the #+date of its
+// org files, an inactive timestamp such as [2024-01-01 Mon 10:10]
+func (d DenoteName) DateString() string {
+	return d.Date.Format("[2006-01-02 Mon 15:04]")
+}
+
+// withDenoteName fills the metadata a header doesn't set from the Denote
+// name of its file, if it has one
+func withDenoteName(h Header, name string) Header {
+	d, ok := ParseDenoteName(name)
+	if !ok {
+		return h
+	}
+	if h.Title == "" {
+		h.Title = d.Title
+	}
+	if h.Date == "" {
+		h.Date = d.DateString()
+	}
+	if len(h.FileTags) == 0 {
+		h.FileTags = d.Keywords
+	}
+	return h
+}
@@ -0,0 +1,88 @@
+package org
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"slices"
+	"testing"
+	"time"
+)
+
+func TestDenoteName(t *testing.T) {
+	for _, tt := range []struct {
+		name string
+		want DenoteName
+		ok   bool
+	}{
+		{"20240101T101010--meeting-notes__work_plans.org", DenoteName{
+			Identifier: "20240101T101010",
+			Date:       time.Date(2024, 1, 1, 10, 10, 10, 0, time.Local),
+			Title:      "meeting notes",
+			Keywords:   []string{"work", "plans"},
+		}, true},
+		{"20231224T080000==1=a--gifts.org.gpg", DenoteName{
+			Identifier: "20231224T080000",
+			Date:       time.Date(2023, 12, 24, 8, 0, 0, 0, time.Local),
+			Signature:  "1 a",
+			Title:      "gifts",
+		}, true},
+		{"20240101T101010.org", DenoteName{
+			Identifier: "20240101T101010",
+			Date:       time.Date(2024, 1, 1, 10, 10, 10, 0, time.Local),
+		}, true},
+		{"notes.org", DenoteName{}, false},
+		{"2024-01-01--notes.org", DenoteName{}, false},
+	} {
+		got, ok := ParseDenoteName(tt.name)
+		if ok != tt.ok || !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("ParseDenoteName(%q) = %+v, %v, want %+v, %v", tt.name, got, ok, tt.want, tt.ok)
+		}
+	}
+
+	// Files without keywords get the title, date and tags of their name
+	dir := t.TempDir()
+	write := func(name, content string) string {
+		path := filepath.Join(dir, name)
+		if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+		return path
+	}
+	bare := write("20240301T090000--trip-ideas__travel_family.org", "* Lisbon\n")
+	titled := write("20240201T090000--draft__work.org", "#+TITLE: Final Title\n#+FILETAGS: :done:\n* Text\n")
+	header, err := ScanHeader(bare)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if header.Title != "trip ideas" || header.Date != "[2024-03-01 Fri 09:00]" || !slices.Equal(header.FileTags, []string{"travel", "family"}) {
+		t.Errorf("header of a bare Denote file = %+v", header)
+	}
+	if header, _ := ScanHeader(titled); header.Title != "Final Title" || !slices.Equal(header.FileTags, []string{"done"}) {
+		t.Errorf("keywords overridden by the Denote name: %+v", header)
+	}
+
+	file, err := Load(bare)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if file.Title() != "trip ideas" || file.Date() != "[2024-03-01 Fri 09:00]" {
+		t.Errorf("parsed bare Denote file: title %q, date %q", file.Title(), file.Date())
+	}
+	if tags := file.Stats().Tags; !slices.Equal(tags, []string{"family", "travel"}) {
+		t.Errorf("tags of a bare Denote file = %v", tags)
+	}
+
+	tree, err := BuildFileTree(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	SortTree(tree, SortOrder{Field: SortByTitle})
+	if tree[0].Title() != "Final Title" || tree[1].Title() != "trip ideas" {
+		t.Errorf("sorted by title: %s, %s", tree[0].Title(), tree[1].Title())
+	}
+	SortTree(tree, SortOrder{Field: SortByDate, Descending: true})
+	if tree[0].Path != bare {
+		t.Errorf("sorted by date, newest first: %s first", tree[0].Name)
+	}
+}
@@ -0,0 +1,235 @@
+package org
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+)
+
+// ErrNoHistory is returned for files that aren't in a git repository, or
+// when git isn't installed
+var ErrNoHistory = errors.New("no git history")
+
+// ErrNoGit is returned when repositories are to be mirrored without git
+// installed
+var ErrNoGit = errors.New("git not installed")
+
+// Commit is a commit of a git repository that changed a file
+type Commit struct {
+	Hash    string
+	Author  string
+	Email   string
+	Date    time.Time // Author date
+	Subject string    // First line of the commit message
+	Path    string    // Path of the file in the commit, relative to the top of the repository
+}
+
+// ShortHash returns the abbreviated hash of the commit, as git shows it
+func (c Commit) ShortHash() string {
+	return c.Hash[:min(len(c.Hash), 7)]
+}
+
+// Separators of the fields and records of the log FileHistory reads
+const (
+	fieldSep  = "\x1f"
+	recordSep = "\x1e"
+)
+
+// FileHistory returns up to limit of the commits changing the file at
+// path, newest first, following it across renames; no limit lists them
+// all. It returns ErrNoHistory if the file isn't in a git repository.
+func FileHistory(ctx context.Context, path string, limit int) ([]Commit, error) {
+	args := []string{"log", "--follow", "--name-only",
+		"--format=" + recordSep + strings.Join([]string{"%H", "%an", "%ae", "%aI", "%s"}, fieldSep)}
+	if limit > 0 {
+		args = append(args, fmt.Sprintf("--max-count=%d", limit))
+	}
+	out, err := git(ctx, filepath.Dir(path), append(args, "--", filepath.Base(path))...)
+	if err != nil {
+		return nil, err
+	}
+
+	var commits []Commit
+	for record := range strings.SplitSeq(out, recordSep) {
+		header, names, _ := strings.Cut(record, "\n")
+		fields := strings.Split(header, fieldSep)
+		if len(fields) != 5 {
+			continue
+		}
+		date, err := time.Parse(time.RFC3339, fields[3])
+		if err != nil {
+			return nil, fmt.Errorf("commit %s: %w", fields[0], err)
+		}
+		commit := Commit{
+			Hash:    fields[0],
+			Author:  fields[1],
+			Email:   fields[2],
+			Date:    date,
+			Subject: fields[4],
+		}
+		// The name of the file at the commit follows the header
+		for name := range strings.SplitSeq(names, "\n") {
+			if name != "" {
+				commit.Path = name
+				break
+			}
+		}
+		commits = append(commits, commit)
+	}
+	return commits, nil
+}
+
+// FileAt parses the file at path as it was at commit, which must be one
+// of its FileHistory. The OrgFile keeps path, so relative links and
+// images resolve as in the file today, and the date of the commit as its
+// modification time.
+func FileAt(ctx context.Context, path string, commit Commit) (*OrgFile, error) {
+	content, err := git(ctx, filepath.Dir(path), "show", commit.Hash+":"+commit.Path)
+	if err != nil {
+		return nil, err
+	}
+	return parse(path, content, commit.Date), nil
+}
+
+// modificationTTL is how long the last modification of a file is kept
+// once looked up, unless the file changes: commits don't touch the files
+// they're of, so they're only noticed after that
+const modificationTTL = time.Minute
+
+// Modification tells who last modified a file and when
+type Modification struct {
+	Time        time.Time // Author date of the last commit, or modification time of the file
+	Author      string    // Author of the last commit changing the file, if any
+	Commit      string    // Abbreviated hash of that commit
+	Uncommitted bool      // The file has changes not committed yet, as of Time
+}
+
+// modifications keeps the last modifications looked up, by path, shared by
+// all sessions
+var modifications = struct {
+	sync.Mutex
+	byPath map[string]cachedModification
+}{byPath: map[string]cachedModification{}}
+
+// cachedModification is a modification as looked up at checked, when the
+// file was modified at modTime
+type cachedModification struct {
+	Modification
+	modTime time.Time
+	checked time.Time
+}
+
+// LastModification returns who last modified the file at path and when:
+// the author and date of the last commit changing it if it's in a git
+// repository and committed as is, or else when the file was modified.
+// The result is kept for others to get with CachedModification.
+func LastModification(ctx context.Context, path string) (Modification, error) {
+	info, err := os.Stat(path)
+	if err != nil {
+		return Modification{}, err
+	}
+	mod := Modification{Time: info.ModTime()}
+
+	commits, err := FileHistory(ctx, path, 1)
+	switch {
+	case errors.Is(err, ErrNoHistory):
+		// Not in a repository; the file tells
+	case err != nil:
+		return Modification{}, err
+	default:
+		status, err := git(ctx, filepath.Dir(path), "status", "--porcelain", "--", filepath.Base(path))
+		if err != nil {
+			return Modification{}, err
+		}
+		// Ignored files are neither committed nor listed
+		switch {
+		case strings.TrimSpace(status) != "":
+			mod.Uncommitted = true
+		case len(commits) > 0:
+			mod.Time = commits[0].Date
+			mod.Author = commits[0].Author
+			mod.Commit = commits[0].ShortHash()
+		}
+	}
+
+	modifications.Lock()
+	modifications.byPath[path] = cachedModification{mod, info.ModTime(), time.Now()}
+	modifications.Unlock()
+	return mod, nil
+}
+
+// CachedModification returns the last modification of the file at path,
+// modified at modTime, as LastModification last looked it up, unless the
+// file changed since or it was looked up too long ago
+func CachedModification(path string, modTime time.Time) (Modification, bool) {
+	modifications.Lock()
+	defer modifications.Unlock()
+	cached, ok := modifications.byPath[path]
+	if !ok || !cached.modTime.Equal(modTime) || time.Since(cached.checked) > modificationTTL {
+		return Modification{}, false
+	}
+	return cached.Modification, true
+}
+
+// MirrorRepository clones the git repository at url into dir, or updates
+// the clone in dir to the latest commit of its branch, discarding any
+// local changes. It reports whether the files changed, or returns ErrNoGit
+// if git isn't installed.
+func MirrorRepository(ctx context.Context, url, dir string) (bool, error) {
+	if _, err := exec.LookPath("git"); err != nil {
+		return false, ErrNoGit
+	}
+	if _, err := os.Stat(filepath.Join(dir, ".git")); errors.Is(err, os.ErrNotExist) {
+		if err := os.MkdirAll(filepath.Dir(dir), 0o755); err != nil {
+			return false, err
+		}
+		_, err := git(ctx, filepath.Dir(dir), "clone", "--quiet", "--", url, filepath.Base(dir))
+		return err == nil, err
+	}
+
+	before, err := git(ctx, dir, "rev-parse", "HEAD")
+	if err != nil {
+		return false, err
+	}
+	if _, err := git(ctx, dir, "fetch", "--quiet", "origin"); err != nil {
+		return false, err
+	}
+	if _, err := git(ctx, dir, "reset", "--quiet", "--hard", "@{upstream}"); err != nil {
+		return false, err
+	}
+	after, err := git(ctx, dir, "rev-parse", "HEAD")
+	if err != nil {
+		return false, err
+	}
+	return before != after, nil
+}
+
+// git runs git in dir and returns its output, or ErrNoHistory if dir
+// isn't in a git repository. Paths are output as they are, not quoted as
+// git quotes names with non-ASCII characters.
+func git(ctx context.Context, dir string, args ...string) (string, error) {
+	cmd := exec.CommandContext(ctx, "git", append([]string{"-C", dir, "-c", "core.quotePath=false"}, args...)...)
+	var stdout, stderr bytes.Buffer
+	cmd.Stdout, cmd.Stderr = &stdout, &stderr
+	err := cmd.Run()
+	var exitErr *exec.ExitError
+	switch {
+	case errors.Is(err, exec.ErrNotFound):
+		return "", ErrNoHistory
+	case errors.As(err, &exitErr) && strings.Contains(stderr.String(), "not a git repository"):
+		return "", ErrNoHistory
+	case err != nil:
+		if msg := strings.TrimSpace(stderr.String()); msg != "" {
+			return "", fmt.Errorf("git %s: %s", args[0], msg)
+		}
+		return "", err
+	}
+	return stdout.String(), nil
+}
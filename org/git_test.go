@@ -0,0 +1,215 @@
+package org
+
+import (
+	"context"
+	"errors"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestFileHistory(t *testing.T) {
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git not installed")
+	}
+	dir := t.TempDir()
+	t.Setenv("GIT_CONFIG_GLOBAL", os.DevNull)
+	t.Setenv("GIT_AUTHOR_NAME", "Ada")
+	t.Setenv("GIT_AUTHOR_EMAIL", "ada@example.com")
+	t.Setenv("GIT_COMMITTER_NAME", "Ada")
+	t.Setenv("GIT_COMMITTER_EMAIL", "ada@example.com")
+	run := func(args ...string) {
+		t.Helper()
+		if out, err := exec.Command("git", append([]string{"-C", dir}, args...)...).CombinedOutput(); err != nil {
+			t.Fatalf("git %s: %v: %s", args[0], err, out)
+		}
+	}
+	write := func(name, content string) {
+		t.Helper()
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	ctx := context.Background()
+	if _, err := FileHistory(ctx, filepath.Join(dir, "notes.org"), 0); !errors.Is(err, ErrNoHistory) {
+		t.Errorf("history outside a repository: err = %v, want ErrNoHistory", err)
+	}
+
+	run("init", "-q")
+	write("notes.org", "#+TITLE: First\n")
+	run("add", "notes.org")
+	run("commit", "-q", "-m", "Start notes")
+	write("notes.org", "#+TITLE: Second\n")
+	run("commit", "-q", "-am", "Retitle notes")
+	run("mv", "notes.org", "journal.org")
+	run("commit", "-q", "-m", "Rename notes")
+
+	path := filepath.Join(dir, "journal.org")
+	commits, err := FileHistory(ctx, path, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var subjects []string
+	for _, c := range commits {
+		subjects = append(subjects, c.Subject)
+	}
+	if want := []string{"Rename notes", "Retitle notes", "Start notes"}; !reflect.DeepEqual(subjects, want) {
+		t.Fatalf("subjects = %q, want %q", subjects, want)
+	}
+	first := commits[2]
+	if first.Author != "Ada" || first.Email != "ada@example.com" || first.Path != "notes.org" || len(first.ShortHash()) != 7 {
+		t.Errorf("first commit = %+v", first)
+	}
+	if limited, _ := FileHistory(ctx, path, 1); len(limited) != 1 {
+		t.Errorf("limited to 1 commit, got %d", len(limited))
+	}
+
+	old, err := FileAt(ctx, path, first)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if old.Title() != "First" || old.Path != path || !old.ModTime.Equal(first.Date) {
+		t.Errorf("file at first commit: title %q, path %s, modified %v", old.Title(), old.Path, old.ModTime)
+	}
+
+	// Names git would quote, such as non-ASCII ones, come out as they are
+	write("café.org", "#+TITLE: Café\n")
+	run("add", "café.org")
+	run("commit", "-q", "-m", "Add café")
+	path = filepath.Join(dir, "café.org")
+	commits, err = FileHistory(ctx, path, 0)
+	if err != nil || len(commits) != 1 || commits[0].Path != "café.org" {
+		t.Fatalf("history of café.org = %+v, %v", commits, err)
+	}
+	if cafe, err := FileAt(ctx, path, commits[0]); err != nil || cafe.Title() != "Café" {
+		t.Errorf("café.org at its commit: %v", err)
+	}
+}
+
+func TestLastModification(t *testing.T) {
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git not installed")
+	}
+	dir := t.TempDir()
+	path := filepath.Join(dir, "notes.org")
+	if err := os.WriteFile(path, []byte("* Notes\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	ctx := context.Background()
+	info, err := os.Stat(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	mod, err := LastModification(ctx, path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !mod.Time.Equal(info.ModTime()) || mod.Author != "" || mod.Uncommitted {
+		t.Errorf("outside a repository: %+v", mod)
+	}
+	if cached, ok := CachedModification(path, info.ModTime()); !ok || cached != mod {
+		t.Errorf("cached = %+v, %v", cached, ok)
+	}
+	if _, ok := CachedModification(path, info.ModTime().Add(time.Second)); ok {
+		t.Error("cached modification of another version of the file")
+	}
+
+	t.Setenv("GIT_CONFIG_GLOBAL", os.DevNull)
+	t.Setenv("GIT_AUTHOR_DATE", "2024-03-01T10:00:00Z")
+	for _, args := range [][]string{
+		{"init", "-q"},
+		{"add", "notes.org"},
+		{"-c", "user.name=Ada", "-c", "user.email=ada@example.com", "commit", "-q", "-m", "Start notes"},
+	} {
+		if out, err := exec.Command("git", append([]string{"-C", dir}, args...)...).CombinedOutput(); err != nil {
+			t.Fatalf("git %v: %v: %s", args, err, out)
+		}
+	}
+	mod, err = LastModification(ctx, path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if mod.Author != "Ada" || len(mod.Commit) != 7 || !mod.Time.Equal(time.Date(2024, 3, 1, 10, 0, 0, 0, time.UTC)) || mod.Uncommitted {
+		t.Errorf("committed: %+v", mod)
+	}
+
+	if err := os.WriteFile(path, []byte("* Notes\nMore.\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if mod, err = LastModification(ctx, path); err != nil || mod.Author != "" || !mod.Uncommitted {
+		t.Errorf("changed since committed: %+v, %v", mod, err)
+	}
+}
+
+func TestMirrorRepository(t *testing.T) {
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git not installed")
+	}
+	origin := t.TempDir()
+	t.Setenv("GIT_CONFIG_GLOBAL", os.DevNull)
+	t.Setenv("GIT_AUTHOR_NAME", "Ada")
+	t.Setenv("GIT_AUTHOR_EMAIL", "ada@example.com")
+	t.Setenv("GIT_COMMITTER_NAME", "Ada")
+	t.Setenv("GIT_COMMITTER_EMAIL", "ada@example.com")
+	commit := func(content, message string) {
+		t.Helper()
+		if err := os.WriteFile(filepath.Join(origin, "notes.org"), []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+		for _, args := range [][]string{{"add", "notes.org"}, {"commit", "-q", "-m", message}} {
+			if out, err := exec.Command("git", append([]string{"-C", origin}, args...)...).CombinedOutput(); err != nil {
+				t.Fatalf("git %s: %v: %s", args[0], err, out)
+			}
+		}
+	}
+	if out, err := exec.Command("git", "-C", origin, "init", "-q").CombinedOutput(); err != nil {
+		t.Fatalf("git init: %v: %s", err, out)
+	}
+	commit("* First\n", "Start notes")
+
+	ctx := context.Background()
+	clone := filepath.Join(t.TempDir(), "cache", "notes")
+	read := func() string {
+		t.Helper()
+		content, err := os.ReadFile(filepath.Join(clone, "notes.org"))
+		if err != nil {
+			t.Fatal(err)
+		}
+		return string(content)
+	}
+
+	if changed, err := MirrorRepository(ctx, origin, clone); err != nil || !changed {
+		t.Fatalf("clone: changed %v, err %v", changed, err)
+	}
+	if got := read(); got != "* First\n" {
+		t.Errorf("cloned notes.org = %q", got)
+	}
+	if changed, err := MirrorRepository(ctx, origin, clone); err != nil || changed {
+		t.Errorf("update without commits: changed %v, err %v", changed, err)
+	}
+
+	commit("* Second\n", "Edit notes")
+	if err := os.WriteFile(filepath.Join(clone, "notes.org"), []byte("* Local\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if changed, err := MirrorRepository(ctx, origin, clone); err != nil || !changed {
+		t.Errorf("update: changed %v, err %v", changed, err)
+	}
+	if got := read(); got != "* Second\n" {
+		t.Errorf("updated notes.org = %q", got)
+	}
+
+	if _, err := MirrorRepository(ctx, "--upload-pack=touch", filepath.Join(t.TempDir(), "bad")); err == nil {
+		t.Error("cloned an option")
+	}
+
+	t.Setenv("PATH", t.TempDir())
+	if _, err := MirrorRepository(ctx, origin, clone); !errors.Is(err, ErrNoGit) {
+		t.Errorf("mirroring without git: err %v, want %v", err, ErrNoGit)
+	}
+}
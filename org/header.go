@@ -0,0 +1,111 @@
+package org
+
+import (
+	"bufio"
+	"io"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+)
+
+// Header is the metadata an org file sets with keywords before its first
+// headline, read without parsing the file
+type Header struct {
+	Title    string
+	Author   string
+	Date     string
+	FileTags []string
+}
+
+// headerMaxBytes bounds how much of a file is read for its header, for
+// files with long preambles or no headlines
+const headerMaxBytes = 64 << 10
+
+// headerKeywordRegexp matches keyword lines like #+TITLE: Notes, as go-org
+// lexes them
+var headerKeywordRegexp = regexp.MustCompile(`^\s*#\+([^:]+):(?:\s+(.*)|$)`)
+
+// ScanHeader reads the header of the org file at path. Only the lines
+// before the first headline are read, so it's cheap enough to do for
+// every file when listing them. Files named by the Denote convention get
+// the title, date and tags of their name unless their keywords set them.
+func ScanHeader(path string) (Header, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return Header{}, err
+	}
+	defer f.Close()
+	return withDenoteName(scanHeader(io.LimitReader(f, headerMaxBytes)), filepath.Base(path)), nil
+}
+
+// scanHeader reads the header keywords from r. Keywords set more than once
+// are joined by newlines, like go-org does.
+func scanHeader(r io.Reader) Header {
+	settings := map[string]string{}
+	inBlock := false
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(nil, headerMaxBytes)
+	for scanner.Scan() {
+		line := scanner.Text()
+		trimmed := strings.ToLower(strings.TrimSpace(line))
+		switch {
+		case strings.HasPrefix(trimmed, "#+begin_"):
+			inBlock = true
+		case strings.HasPrefix(trimmed, "#+end_"):
+			inBlock = false
+		case inBlock:
+		case headlineLevel(line) > 0:
+			return newHeader(settings)
+		default:
+			m := headerKeywordRegexp.FindStringSubmatch(line)
+			if m == nil {
+				continue
+			}
+			key, value := strings.ToUpper(m[1]), strings.TrimSpace(m[2])
+			if prev, ok := settings[key]; ok {
+				value = prev + "\n" + value
+			}
+			settings[key] = value
+		}
+	}
+	return newHeader(settings)
+}
+
+// newHeader picks the header metadata from keyword settings
+func newHeader(settings map[string]string) Header {
+	h := Header{
+		Title:  settings["TITLE"],
+		Author: settings["AUTHOR"],
+		Date:   settings["DATE"],
+	}
+	h.FileTags = strings.FieldsFunc(settings["FILETAGS"], func(r rune) bool {
+		return r == ':' || r == '\n' || r == ' '
+	})
+	return h
+}
+
+// Header returns the header of the file, scanning it on first access. The
+// header of a file that can't be read is empty.
+func (fe *FileEntry) Header() Header {
+	if fe.IsDir {
+		return Header{}
+	}
+	if fe.header == nil {
+		h, _ := ScanHeader(fe.Path)
+		fe.header = &h
+	}
+	return *fe.header
+}
+
+// Title returns the title of the file: its #+TITLE: once parsed, or from
+// its header until then, falling back to the file name
+func (fe *FileEntry) Title() string {
+	if fe.OrgFile != nil {
+		return fe.OrgFile.Title()
+	}
+	if title := fe.Header().Title; title != "" {
+		return title
+	}
+	return strings.TrimSuffix(fe.Name, ".org")
+}
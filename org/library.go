@@ -0,0 +1,180 @@
+package org
+
+import (
+	"errors"
+	"fmt"
+	"path/filepath"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+)
+
+// ErrNotOrgFile is returned by Open for paths outside of the library,
+// hidden files and files other than org files
+var ErrNotOrgFile = errors.New("not an org file in the org directory")
+
+// Library is the listing of the org files in a directory, shared by all
+// sessions. A refresh replaces the listing as a whole rather than
+// modifying it, so sessions take copies of it to expand, sort and open
+// files in without locking. Parsed files are shared through Load.
+type Library struct {
+	root string
+
+	mu      sync.RWMutex
+	tree    []*FileEntry // Root level entries, not modified once listed
+	listed  []listedFile // What the listing was made of, to detect changes
+	version uint64       // Increased whenever the listing changes
+}
+
+// listedFile is an entry of a listing as far as telling whether the
+// directory changed goes
+type listedFile struct {
+	relPath string
+	isDir   bool
+	modTime time.Time
+}
+
+// NewLibrary lists the org files in root. A directory that can't be read
+// gives an empty library along with the error; it's listed again on
+// Refresh.
+func NewLibrary(root string) (*Library, error) {
+	l := &Library{root: root}
+	_, err := l.Refresh()
+	return l, err
+}
+
+// Root returns the directory of the library
+func (l *Library) Root() string {
+	return l.root
+}
+
+// Version returns the number of the current listing, which changes
+// whenever files are added, removed or modified
+func (l *Library) Version() uint64 {
+	l.mu.RLock()
+	defer l.mu.RUnlock()
+	return l.version
+}
+
+// Tree returns a copy of the current listing for a session to use as its
+// own, and its version. Directories are collapsed; parsed files are
+// shared.
+func (l *Library) Tree() ([]*FileEntry, uint64) {
+	l.mu.RLock()
+	defer l.mu.RUnlock()
+	return cloneEntries(l.tree, nil), l.version
+}
+
+// Open loads the org file at rel, a slash separated path relative to the
+// directory, refusing paths outside of it and hidden files, which aren't
+// listed
+func (l *Library) Open(rel string) (*OrgFile, error) {
+	path := filepath.FromSlash(rel)
+	hidden := strings.HasPrefix(rel, ".") || strings.Contains(rel, "/.")
+	if !filepath.IsLocal(path) || hidden || !strings.HasSuffix(strings.ToLower(path), ".org") {
+		return nil, fmt.Errorf("%s: %w", rel, ErrNotOrgFile)
+	}
+	return Load(filepath.Join(l.root, path))
+}
+
+// ChangeKind tells how an entry changed between two listings
+type ChangeKind int
+
+const (
+	// ChangeAdded is an entry that wasn't listed before
+	ChangeAdded ChangeKind = iota
+	// ChangeModified is an entry modified since it was listed
+	ChangeModified
+	// ChangeRemoved is an entry no longer listed
+	ChangeRemoved
+)
+
+// String returns the name of the kind, as in "added"
+func (k ChangeKind) String() string {
+	switch k {
+	case ChangeAdded:
+		return "added"
+	case ChangeModified:
+		return "modified"
+	}
+	return "removed"
+}
+
+// Change is an entry added, modified or removed by a refresh
+type Change struct {
+	RelPath string
+	IsDir   bool
+	Kind    ChangeKind
+}
+
+// Refresh lists the directory again and returns what changed since the
+// last listing, in listing order with removals last; nothing when the
+// listing is the same. On error the listing is kept as it was.
+func (l *Library) Refresh() ([]Change, error) {
+	tree, err := BuildFileTree(l.root)
+	if err != nil {
+		return nil, err
+	}
+	listed := listFiles(tree, nil)
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if l.version > 0 && slices.Equal(listed, l.listed) {
+		return nil, nil
+	}
+	changes := diffListings(l.listed, listed)
+	l.tree, l.listed = tree, listed
+	l.version++
+	return changes, nil
+}
+
+// diffListings returns the changes from the listing before to after
+func diffListings(before, after []listedFile) []Change {
+	previous := make(map[string]listedFile, len(before))
+	for _, f := range before {
+		previous[f.relPath] = f
+	}
+	var changes []Change
+	for _, f := range after {
+		prev, ok := previous[f.relPath]
+		delete(previous, f.relPath)
+		switch {
+		case !ok || prev.isDir != f.isDir:
+			changes = append(changes, Change{f.relPath, f.isDir, ChangeAdded})
+		case !prev.modTime.Equal(f.modTime):
+			changes = append(changes, Change{f.relPath, f.isDir, ChangeModified})
+		}
+	}
+	for _, f := range before {
+		if _, ok := previous[f.relPath]; ok {
+			changes = append(changes, Change{f.relPath, f.isDir, ChangeRemoved})
+		}
+	}
+	return changes
+}
+
+// listFiles appends the entries of tree, depth first, to listed
+func listFiles(tree []*FileEntry, listed []listedFile) []listedFile {
+	for _, e := range tree {
+		listed = append(listed, listedFile{e.RelPath, e.IsDir, e.ModTime})
+		listed = listFiles(e.Children, listed)
+	}
+	return listed
+}
+
+// cloneEntries copies entries and their children, recursively, under
+// parent
+func cloneEntries(entries []*FileEntry, parent *FileEntry) []*FileEntry {
+	if entries == nil {
+		return nil
+	}
+	clones := make([]*FileEntry, len(entries))
+	for i, e := range entries {
+		clone := *e
+		clone.Parent = parent
+		clone.Children = cloneEntries(e.Children, &clone)
+		clones[i] = &clone
+	}
+	return clones
+}
@@ -0,0 +1,102 @@
+package org
+
+import (
+	"regexp"
+	"strings"
+	"time"
+)
+
+// LogKind is the kind of a LOGBOOK entry
+type LogKind int
+
+const (
+	// LogOther is an entry of another kind, such as a refile note
+	LogOther LogKind = iota
+	// LogState is a TODO state change
+	LogState
+	// LogNote is a note taken with org-add-note
+	LogNote
+	// LogClock is a CLOCK line
+	LogClock
+)
+
+// LogEntry is an entry of a LOGBOOK drawer
+type LogEntry struct {
+	Kind     LogKind
+	Time     Timestamp     // When it was logged; the clocked time for clock lines
+	From     string        // Previous state of a state change, empty if none
+	To       string        // New state of a state change
+	Duration time.Duration // Clocked time of a closed clock line
+	Text     string        // Note text, or the whole entry for other kinds
+}
+
+var (
+	logStateRegexp = regexp.MustCompile(`^State\s+"([^"]*)"\s+from(?:\s+"([^"]*)")?\s+(\[[^\]]+\])`)
+	logNoteRegexp  = regexp.MustCompile(`^Note taken on\s+(\[[^\]]+\])`)
+	logClockRegexp = regexp.MustCompile(`^CLOCK:\s*(\[[^\]]+\](?:--\[[^\]]+\])?)`)
+	logBreakRegexp = regexp.MustCompile(`\s*\\\\\s*$`)
+)
+
+// ParseLogbook parses the lines of a LOGBOOK drawer, without its :LOGBOOK:
+// and :END: lines, into entries in the order they appear (org logs the
+// newest first). Indented lines continue the entry above.
+func ParseLogbook(text string) []LogEntry {
+	var entries []LogEntry
+	var lines []string // Lines of the entry being read
+	flush := func() {
+		if len(lines) > 0 {
+			entries = append(entries, parseLogEntry(lines))
+			lines = nil
+		}
+	}
+
+	for _, line := range strings.Split(text, "\n") {
+		trimmed := strings.TrimSpace(line)
+		switch {
+		case trimmed == "":
+		case strings.HasPrefix(trimmed, "- "), strings.HasPrefix(trimmed, "CLOCK:"):
+			flush()
+			lines = append(lines, strings.TrimPrefix(trimmed, "- "))
+		case len(lines) > 0:
+			lines = append(lines, trimmed)
+		default:
+			lines = append(lines, trimmed)
+			flush()
+		}
+	}
+	flush()
+	return entries
+}
+
+// parseLogEntry parses the lines of a single LOGBOOK entry
+func parseLogEntry(lines []string) LogEntry {
+	first := logBreakRegexp.ReplaceAllString(lines[0], "")
+	note := make([]string, 0, len(lines)-1)
+	for _, line := range lines[1:] {
+		note = append(note, logBreakRegexp.ReplaceAllString(line, ""))
+	}
+	entry := LogEntry{Text: strings.Join(note, " ")}
+
+	if m := logStateRegexp.FindStringSubmatch(first); m != nil {
+		if ts, ok := ParseTimestamp(m[3]); ok {
+			entry.Kind, entry.To, entry.From, entry.Time = LogState, m[1], m[2], ts
+			return entry
+		}
+	}
+	if m := logNoteRegexp.FindStringSubmatch(first); m != nil {
+		if ts, ok := ParseTimestamp(m[1]); ok {
+			entry.Kind, entry.Time = LogNote, ts
+			return entry
+		}
+	}
+	if m := logClockRegexp.FindStringSubmatch(first); m != nil {
+		if ts, ok := ParseTimestamp(m[1]); ok {
+			entry.Kind, entry.Time = LogClock, ts
+			if ts.IsRange() {
+				entry.Duration = ts.End.Sub(ts.Start)
+			}
+			return entry
+		}
+	}
+	return LogEntry{Kind: LogOther, Text: strings.Join(append([]string{first}, note...), " ")}
+}
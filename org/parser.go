@@ -5,6 +5,8 @@ import (
 	"path/filepath"
 	"sort"
 	"strings"
+	"sync"
+	"time"
 
 	goorg "github.com/niklasfasching/go-org/org"
 )
@@ -19,6 +21,9 @@ type FileEntry struct {
 	Children []*FileEntry // Child entries (for directories)
 	OrgFile  *OrgFile     // Parsed org file (for .org files)
 	Expanded bool         // Is directory expanded in view?
+	ModTime  time.Time    // Last modification time
+
+	header *Header // Cached result of Header
 }
 
 // OrgFile represents a parsed org file
@@ -27,13 +32,20 @@ type OrgFile struct {
 	Path       string
 	Document   *goorg.Document
 	RawContent string
+	ModTime    time.Time // Modification time of the file when parsed
+
+	stats *FileStats // Cached result of Stats
 }
 
-// Title returns the document title from #+TITLE: or the filename
+// Title returns the document title from #+TITLE:, the title of a Denote
+// file name, or the file name
 func (f *OrgFile) Title() string {
 	if title := f.Document.Get("TITLE"); title != "" {
 		return title
 	}
+	if d, ok := ParseDenoteName(f.Name); ok && d.Title != "" {
+		return d.Title
+	}
 	return strings.TrimSuffix(f.Name, ".org")
 }
 
@@ -42,27 +54,126 @@ func (f *OrgFile) Author() string {
 	return f.Document.Get("AUTHOR")
 }
 
-// Date returns the document date from #+DATE:
+// Date returns the document date from #+DATE:, or from the identifier of
+// a Denote file name
 func (f *OrgFile) Date() string {
-	return f.Document.Get("DATE")
+	if date := f.Document.Get("DATE"); date != "" {
+		return date
+	}
+	if d, ok := ParseDenoteName(f.Name); ok {
+		return d.DateString()
+	}
+	return ""
+}
+
+// Property returns the value of a #+PROPERTY: setting, matching name
+// case-insensitively. Later settings override earlier ones.
+func (f *OrgFile) Property(name string) string {
+	value := ""
+	for _, line := range strings.Split(f.Document.Get("PROPERTY"), "\n") {
+		key, v, _ := strings.Cut(strings.TrimSpace(line), " ")
+		if strings.EqualFold(key, name) {
+			value = strings.TrimSpace(v)
+		}
+	}
+	return value
+}
+
+// Option returns the value of an export option set with #+OPTIONS:, such
+// as "nil" for -:nil, or "" if the document doesn't set it. Later
+// settings override earlier ones.
+func (f *OrgFile) Option(key string) string {
+	value := ""
+	for _, field := range strings.Fields(f.Document.Get("OPTIONS")) {
+		if k, v, ok := strings.Cut(field, ":"); ok && k == key {
+			value = v
+		}
+	}
+	return value
+}
+
+// Subtree returns the raw source of the n-th headline (0-based, in document
+// order) including its body and all nested headlines
+func (f *OrgFile) Subtree(n int) string {
+	headlines := f.HeadlineLines()
+	if n < 0 || n >= len(headlines) {
+		return ""
+	}
+	lines := strings.Split(f.RawContent, "\n")
+	start := headlines[n]
+	level := headlineLevel(lines[start])
+	for _, i := range headlines[n+1:] {
+		if headlineLevel(lines[i]) <= level {
+			return strings.Join(lines[start:i], "\n")
+		}
+	}
+	return strings.TrimRight(strings.Join(lines[start:], "\n"), "\n")
+}
+
+// HeadlineLines returns the source line (0-based) of each headline, in
+// document order. Lines of blocks that look like headlines are skipped.
+func (f *OrgFile) HeadlineLines() []int {
+	var headlines []int
+	inBlock := false
+	for i, line := range strings.Split(f.RawContent, "\n") {
+		trimmed := strings.ToLower(strings.TrimSpace(line))
+		switch {
+		case strings.HasPrefix(trimmed, "#+begin_"):
+			inBlock = true
+		case strings.HasPrefix(trimmed, "#+end_"):
+			inBlock = false
+		case !inBlock && headlineLevel(line) > 0:
+			headlines = append(headlines, i)
+		}
+	}
+	return headlines
+}
+
+// headlineLevel returns the number of leading stars if line is an org
+// headline, or 0 otherwise
+func headlineLevel(line string) int {
+	lvl := 0
+	for lvl < len(line) && line[lvl] == '*' {
+		lvl++
+	}
+	if lvl == 0 || lvl >= len(line) || (line[lvl] != ' ' && line[lvl] != '\t') {
+		return 0
+	}
+	return lvl
+}
+
+// Changed reports whether the file was modified or removed since it was
+// parsed
+func (f *OrgFile) Changed() bool {
+	info, err := os.Stat(f.Path)
+	return err != nil || !info.ModTime().Equal(f.ModTime)
 }
 
 // ParseFile reads and parses an org file using go-org
 func ParseFile(path string) (*OrgFile, error) {
+	info, err := os.Stat(path)
+	if err != nil {
+		return nil, err
+	}
 	content, err := os.ReadFile(path)
 	if err != nil {
 		return nil, err
 	}
+	return parse(path, string(content), info.ModTime()), nil
+}
 
+// parse parses content as the org file at path, modified at modTime
+func parse(path, content string, modTime time.Time) *OrgFile {
 	config := goorg.New()
-	doc := config.Parse(strings.NewReader(string(content)), path)
+	doc := config.Parse(strings.NewReader(content), path)
 
 	return &OrgFile{
 		Name:       filepath.Base(path),
 		Path:       path,
 		Document:   doc,
-		RawContent: string(content),
-	}, nil
+		RawContent: content,
+		ModTime:    modTime,
+	}
 }
 
 // ListOrgFiles returns all .org files in a directory (non-recursive, for backwards compatibility)
@@ -114,6 +225,9 @@ func buildFileTreeRecursive(rootDir, currentDir string, parent *FileEntry) ([]*F
 			IsDir:   entry.IsDir(),
 			Parent:  parent,
 		}
+		if info, err := entry.Info(); err == nil {
+			fe.ModTime = info.ModTime()
+		}
 
 		if entry.IsDir() {
 			// Recursively process directory
@@ -187,7 +301,7 @@ func (fe *FileEntry) GetDepth() int {
 	return depth
 }
 
-// GetOrgFile returns the parsed org file, parsing it on first access
+// GetOrgFile returns the parsed org file, loading it on first access
 func (fe *FileEntry) GetOrgFile() (*OrgFile, error) {
 	if fe.IsDir {
 		return nil, nil
@@ -195,10 +309,61 @@ func (fe *FileEntry) GetOrgFile() (*OrgFile, error) {
 	if fe.OrgFile != nil {
 		return fe.OrgFile, nil
 	}
-	orgFile, err := ParseFile(fe.Path)
+	orgFile, err := Load(fe.Path)
 	if err != nil {
 		return nil, err
 	}
 	fe.OrgFile = orgFile
 	return fe.OrgFile, nil
 }
+
+// ParseTree loads the org files of a tree that aren't loaded yet, up to
+// workers of them at once, and returns the loaded files by path. progress,
+// if not nil, is called after each file with the number of files done and
+// the total; calls don't overlap. Files that fail to parse are skipped.
+func ParseTree(entries []*FileEntry, workers int, progress func(done, total int)) map[string]*OrgFile {
+	var pending []*FileEntry
+	var collect func(entries []*FileEntry)
+	collect = func(entries []*FileEntry) {
+		for _, e := range entries {
+			if e.IsDir {
+				collect(e.Children)
+			} else if e.OrgFile == nil {
+				pending = append(pending, e)
+			}
+		}
+	}
+	collect(entries)
+
+	var (
+		mu     sync.Mutex
+		wg     sync.WaitGroup
+		done   int
+		parsed = make(map[string]*OrgFile, len(pending))
+	)
+	jobs := make(chan *FileEntry)
+	for range max(1, workers) {
+		wg.Go(func() {
+			for e := range jobs {
+				orgFile, err := Load(e.Path)
+
+				mu.Lock()
+				if err == nil {
+					e.OrgFile = orgFile
+					parsed[e.Path] = orgFile
+				}
+				done++
+				if progress != nil {
+					progress(done, len(pending))
+				}
+				mu.Unlock()
+			}
+		})
+	}
+	for _, e := range pending {
+		jobs <- e
+	}
+	close(jobs)
+	wg.Wait()
+	return parsed
+}
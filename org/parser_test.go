@@ -0,0 +1,582 @@
+package org
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	goorg "github.com/niklasfasching/go-org/org"
+)
+
+func TestSubtree(t *testing.T) {
+	f := &OrgFile{RawContent: `#+TITLE: Test
+
+* One
+Body one
+** One A
+Nested body
+* Two
+Body two
+** Two A
+*** Two A i
+* Three`}
+
+	tests := []struct {
+		n    int
+		want string
+	}{
+		{0, "* One\nBody one\n** One A\nNested body"},
+		{1, "** One A\nNested body"},
+		{3, "** Two A\n*** Two A i"},
+		{5, "* Three"},
+		{6, ""},
+	}
+
+	for _, tt := range tests {
+		if got := f.Subtree(tt.n); got != tt.want {
+			t.Errorf("Subtree(%d) = %q, want %q", tt.n, got, tt.want)
+		}
+	}
+}
+
+func TestHeadlineLines(t *testing.T) {
+	f := &OrgFile{RawContent: `* One
+#+BEGIN_EXAMPLE
+* Not a headline
+#+END_EXAMPLE
+** One A
+*bold* text
+* Two`}
+
+	got := f.HeadlineLines()
+	want := []int{0, 4, 6}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("HeadlineLines() = %v, want %v", got, want)
+	}
+	if got := f.Subtree(0); got != "* One\n#+BEGIN_EXAMPLE\n* Not a headline\n#+END_EXAMPLE\n** One A\n*bold* text" {
+		t.Errorf("Subtree(0) = %q", got)
+	}
+}
+
+func TestChanged(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "notes.org")
+	if err := os.WriteFile(path, []byte("* Notes\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	f, err := ParseFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if f.Changed() {
+		t.Error("Changed() = true right after parsing")
+	}
+
+	later := f.ModTime.Add(time.Minute)
+	if err := os.Chtimes(path, later, later); err != nil {
+		t.Fatal(err)
+	}
+	if !f.Changed() {
+		t.Error("Changed() = false after the file was modified")
+	}
+
+	if err := os.Remove(path); err != nil {
+		t.Fatal(err)
+	}
+	if !f.Changed() {
+		t.Error("Changed() = false after the file was removed")
+	}
+}
+
+func TestLoad(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "shared.org")
+	if err := os.WriteFile(path, []byte("#+TITLE: First\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	first, err := Load(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if again, _ := Load(path); again != first {
+		t.Error("unchanged file parsed again")
+	}
+
+	if err := os.WriteFile(path, []byte("#+TITLE: Second\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	later := first.ModTime.Add(time.Minute)
+	if err := os.Chtimes(path, later, later); err != nil {
+		t.Fatal(err)
+	}
+	second, err := Load(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if second == first || second.Title() != "Second" {
+		t.Errorf("changed file not parsed again: %q", second.Title())
+	}
+}
+
+func TestScanHeader(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "notes.org")
+	raw := `#+title: Field Notes
+#+AUTHOR: Ada
+#+DATE: <2024-03-01 Fri>
+#+FILETAGS: :work:draft:
+#+begin_example
+#+TITLE: Not the title
+#+end_example
+#+FILETAGS: :go:
+
+* Heading
+#+TITLE: Too late
+`
+	if err := os.WriteFile(path, []byte(raw), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	header, err := ScanHeader(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if header.Title != "Field Notes" || header.Author != "Ada" || header.Date != "<2024-03-01 Fri>" {
+		t.Errorf("header = %+v", header)
+	}
+	if got := strings.Join(header.FileTags, " "); got != "work draft go" {
+		t.Errorf("FileTags = %q, want %q", got, "work draft go")
+	}
+
+	entry := &FileEntry{Name: "notes.org", Path: path}
+	if got := entry.Title(); got != "Field Notes" {
+		t.Errorf("Title() = %q, want %q", got, "Field Notes")
+	}
+	if entry.OrgFile != nil {
+		t.Error("Title() parsed the file")
+	}
+}
+
+func TestParseTree(t *testing.T) {
+	dir := t.TempDir()
+	for _, name := range []string{"a.org", "b.org", "sub/c.org", "sub/d.org"} {
+		path := filepath.Join(dir, name)
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte("#+TITLE: "+name+"\n"), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	tree, err := BuildFileTree(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var calls []int
+	parsed := ParseTree(tree, 3, func(done, total int) {
+		if total != 4 {
+			t.Errorf("total = %d, want 4", total)
+		}
+		calls = append(calls, done)
+	})
+	if !reflect.DeepEqual(calls, []int{1, 2, 3, 4}) {
+		t.Errorf("progress = %v, want 1 to 4", calls)
+	}
+	if len(parsed) != 4 {
+		t.Errorf("parsed %d files, want 4", len(parsed))
+	}
+	for _, e := range FlattenTree(tree) {
+		if !e.IsDir && (e.OrgFile == nil || parsed[e.Path] != e.OrgFile) {
+			t.Errorf("%s not parsed into the tree", e.RelPath)
+		}
+	}
+
+	if again := ParseTree(tree, 3, nil); len(again) != 0 {
+		t.Errorf("parsed files again: %v", again)
+	}
+}
+
+func TestLibrary(t *testing.T) {
+	dir := t.TempDir()
+	write := func(name string) {
+		path := filepath.Join(dir, name)
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte("* "+name+"\n"), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	write("a.org")
+	write("sub/b.org")
+
+	library, err := NewLibrary(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tree, version := library.Tree()
+	if len(FlattenTree(tree)) != 2 {
+		t.Fatalf("listed %d root entries, want 2", len(FlattenTree(tree)))
+	}
+	tree[0].Expanded = true
+	if other, _ := library.Tree(); other[0] == tree[0] || other[0].Expanded || other[0].Children[0].Parent != other[0] {
+		t.Error("copies of the listing are shared")
+	}
+
+	if changes, err := library.Refresh(); len(changes) != 0 || err != nil {
+		t.Errorf("Refresh() of an unchanged directory = %v, %v", changes, err)
+	}
+	write("c.org")
+	changes, err := library.Refresh()
+	if err != nil || !slices.Contains(changes, Change{"c.org", false, ChangeAdded}) {
+		t.Errorf("Refresh() after adding a file = %v, %v", changes, err)
+	}
+	tree, next := library.Tree()
+	if next == version || len(tree) != 3 {
+		t.Errorf("listing after adding a file: version %d (was %d), %d root entries", next, version, len(tree))
+	}
+
+	if err := os.Remove(filepath.Join(dir, "a.org")); err != nil {
+		t.Fatal(err)
+	}
+	later := time.Now().Add(time.Minute)
+	if err := os.Chtimes(filepath.Join(dir, "sub", "b.org"), later, later); err != nil {
+		t.Fatal(err)
+	}
+	changes, err = library.Refresh()
+	if err != nil || !slices.Contains(changes, Change{"a.org", false, ChangeRemoved}) ||
+		!slices.Contains(changes, Change{filepath.Join("sub", "b.org"), false, ChangeModified}) {
+		t.Errorf("Refresh() after removing and modifying files = %v, %v", changes, err)
+	}
+}
+
+func TestProperty(t *testing.T) {
+	input := `#+PROPERTY: header-args :results output
+#+PROPERTY: chroma-style dracula
+#+PROPERTY: Chroma-Style  nord
+
+Text`
+	f := &OrgFile{Document: goorg.New().Parse(strings.NewReader(input), "test.org")}
+
+	if got := f.Property("chroma-style"); got != "nord" {
+		t.Errorf("Property(chroma-style) = %q, want %q", got, "nord")
+	}
+	if got := f.Property("header-args"); got != ":results output" {
+		t.Errorf("Property(header-args) = %q, want %q", got, ":results output")
+	}
+	if got := f.Property("missing"); got != "" {
+		t.Errorf("Property(missing) = %q, want empty", got)
+	}
+}
+
+func TestOption(t *testing.T) {
+	input := `#+OPTIONS: toc:nil -:t
+#+OPTIONS: -:nil ^:{}
+
+Text`
+	f := &OrgFile{Document: goorg.New().Parse(strings.NewReader(input), "test.org")}
+
+	if got := f.Option("-"); got != "nil" {
+		t.Errorf("Option(-) = %q, want %q", got, "nil")
+	}
+	if got := f.Option("toc"); got != "nil" {
+		t.Errorf("Option(toc) = %q, want %q", got, "nil")
+	}
+	if got := f.Option("'"); got != "" {
+		t.Errorf("Option(') = %q, want empty", got)
+	}
+}
+
+func TestParseTimestamp(t *testing.T) {
+	date := func(s string) time.Time {
+		t, _ := time.Parse("2006-01-02 15:04", s)
+		return t
+	}
+
+	tests := []struct {
+		in   string
+		want Timestamp
+		ok   bool
+	}{
+		{"<2024-01-15 Mon>", Timestamp{Active: true, Start: date("2024-01-15 00:00")}, true},
+		{"[2024-01-15 Mon 9:05]", Timestamp{Start: date("2024-01-15 09:05"), HasTime: true}, true},
+		{"<2024-01-15 Mon 10:00-11:30>", Timestamp{Active: true, Start: date("2024-01-15 10:00"), End: date("2024-01-15 11:30"), HasTime: true, EndTime: true}, true},
+		{"<2024-01-15 Mon>--<2024-01-17 Wed>", Timestamp{Active: true, Start: date("2024-01-15 00:00"), End: date("2024-01-17 00:00")}, true},
+		{"<2024-02-01 Thu +1w -2d>", Timestamp{Active: true, Start: date("2024-02-01 00:00"), Repeater: "+1w", Warning: "-2d"}, true},
+		{"<2024-02-01 Thu .+1d/3d>", Timestamp{Active: true, Start: date("2024-02-01 00:00"), Repeater: ".+1d/3d"}, true},
+		{"<2024-02-30 Fri>", Timestamp{}, false},
+		{"[Smith 2020]", Timestamp{}, false},
+	}
+
+	for _, tt := range tests {
+		got, ok := ParseTimestamp(tt.in)
+		if ok != tt.ok || got != tt.want {
+			t.Errorf("ParseTimestamp(%q) = %+v, %t, want %+v, %t", tt.in, got, ok, tt.want, tt.ok)
+		}
+	}
+}
+
+func TestParsePlanning(t *testing.T) {
+	p, ok := ParsePlanning("CLOSED: [2024-03-02 Sat 10:00] SCHEDULED: <2024-03-01 Fri +1w>")
+	if !ok || p.Closed == nil || p.Scheduled == nil || p.Deadline != nil {
+		t.Fatalf("ParsePlanning = %+v, %t, want closed and scheduled", p, ok)
+	}
+	if p.Scheduled.Repeater != "+1w" || p.Closed.Active {
+		t.Errorf("ParsePlanning timestamps = %+v, %+v", *p.Scheduled, *p.Closed)
+	}
+
+	for _, line := range []string{"", "SCHEDULED: soon", "Text before DEADLINE: <2024-03-05 Tue>"} {
+		if _, ok := ParsePlanning(line); ok {
+			t.Errorf("ParsePlanning(%q) succeeded, want failure", line)
+		}
+	}
+}
+
+func TestParseSortOrder(t *testing.T) {
+	tests := []struct {
+		in      string
+		want    SortOrder
+		wantErr bool
+	}{
+		{"name", SortOrder{Field: SortByName}, false},
+		{"title:asc", SortOrder{Field: SortByTitle}, false},
+		{"date:desc", SortOrder{Field: SortByDate, Descending: true}, false},
+		{"Modified:DESC", SortOrder{Field: SortByModTime, Descending: true}, false},
+		{"size", SortOrder{}, true},
+		{"name:up", SortOrder{}, true},
+	}
+
+	for _, tt := range tests {
+		got, err := ParseSortOrder(tt.in)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("ParseSortOrder(%q) error = %v, wantErr %v", tt.in, err, tt.wantErr)
+			continue
+		}
+		if !tt.wantErr && got != tt.want {
+			t.Errorf("ParseSortOrder(%q) = %+v, want %+v", tt.in, got, tt.want)
+		}
+	}
+}
+
+func TestSortTree(t *testing.T) {
+	file := func(name, raw string, mod time.Time) *FileEntry {
+		return &FileEntry{
+			Name:    name,
+			ModTime: mod,
+			OrgFile: &OrgFile{
+				Name:       name,
+				RawContent: raw,
+				Document:   goorg.New().Parse(strings.NewReader(raw), name),
+			},
+		}
+	}
+	base := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	dir := &FileEntry{Name: "z-dir", IsDir: true}
+	entries := []*FileEntry{
+		dir,
+		file("a.org", "#+TITLE: Zebra\n#+DATE: <2024-03-01 Fri>\n", base.Add(2*time.Hour)),
+		file("b.org", "#+TITLE: Apple\n", base),
+		file("c.org", "#+TITLE: Mango\n#+DATE: 2023-12-24\n", base.Add(time.Hour)),
+	}
+
+	tests := []struct {
+		order SortOrder
+		want  []string
+	}{
+		{SortOrder{Field: SortByName, Descending: true}, []string{"z-dir", "c.org", "b.org", "a.org"}},
+		{SortOrder{Field: SortByTitle}, []string{"z-dir", "b.org", "c.org", "a.org"}},
+		{SortOrder{Field: SortByDate, Descending: true}, []string{"z-dir", "a.org", "c.org", "b.org"}},
+		{SortOrder{Field: SortByDate}, []string{"z-dir", "c.org", "a.org", "b.org"}},
+		{SortOrder{Field: SortByModTime, Descending: true}, []string{"z-dir", "a.org", "c.org", "b.org"}},
+	}
+
+	for _, tt := range tests {
+		SortTree(entries, tt.order)
+		var got []string
+		for _, e := range entries {
+			got = append(got, e.Name)
+		}
+		if strings.Join(got, " ") != strings.Join(tt.want, " ") {
+			t.Errorf("SortTree(%s) = %v, want %v", tt.order, got, tt.want)
+		}
+	}
+}
+
+func TestStats(t *testing.T) {
+	raw := `#+TITLE: Stats
+#+FILETAGS: :notes:go:
+
+* Heading one :go:
+:PROPERTIES:
+:ID: 1234
+:END:
+Some body text here.
+#+BEGIN_SRC go
+fmt.Println("not prose")
+
+return
+#+END_SRC
+** Nested :go:draft:
+More words.
+`
+	f := &OrgFile{
+		RawContent: raw,
+		Document:   goorg.New().Parse(strings.NewReader(raw), "stats.org"),
+	}
+
+	stats := f.Stats()
+	// "Heading one" (2) + "Some body text here." (4) + "Nested" (1) + "More words." (2)
+	if stats.Words != 9 {
+		t.Errorf("Words = %d, want 9", stats.Words)
+	}
+	if stats.CodeLines != 2 {
+		t.Errorf("CodeLines = %d, want 2", stats.CodeLines)
+	}
+	if stats.ReadingMinutes() != 1 {
+		t.Errorf("ReadingMinutes = %d, want 1", stats.ReadingMinutes())
+	}
+	if got := strings.Join(stats.Tags, " "); got != "go draft notes" {
+		t.Errorf("Tags = %q, want %q", got, "go draft notes")
+	}
+}
+
+func TestParseLogbook(t *testing.T) {
+	input := `- State "DONE"       from "TODO"       [2024-03-05 Tue 10:12]
+- Note taken on [2024-03-04 Mon 09:00] \\
+  Called the vendor.
+- State "TODO"       from              [2024-03-01 Fri 08:00]
+- Refiled on [2024-02-28 Wed 12:00]
+CLOCK: [2024-03-04 Mon 09:00]--[2024-03-04 Mon 10:30] =>  1:30
+CLOCK: [2024-03-05 Tue 09:00]
+`
+	entries := ParseLogbook(input)
+	if len(entries) != 6 {
+		t.Fatalf("got %d entries, want 6: %+v", len(entries), entries)
+	}
+
+	if e := entries[0]; e.Kind != LogState || e.From != "TODO" || e.To != "DONE" || e.Time.Start.Hour() != 10 {
+		t.Errorf("state change = %+v", e)
+	}
+	if e := entries[1]; e.Kind != LogNote || e.Text != "Called the vendor." {
+		t.Errorf("note = %+v", e)
+	}
+	if e := entries[2]; e.Kind != LogState || e.From != "" || e.To != "TODO" {
+		t.Errorf("initial state = %+v", e)
+	}
+	if e := entries[3]; e.Kind != LogOther || e.Text != "Refiled on [2024-02-28 Wed 12:00]" {
+		t.Errorf("other entry = %+v", e)
+	}
+	if e := entries[4]; e.Kind != LogClock || e.Duration != 90*time.Minute {
+		t.Errorf("closed clock = %+v", e)
+	}
+	if e := entries[5]; e.Kind != LogClock || e.Time.IsRange() || e.Duration != 0 {
+		t.Errorf("running clock = %+v", e)
+	}
+}
+
+func TestPlanned(t *testing.T) {
+	raw := `#+TODO: TODO NEXT(n) | DONE(d) CANCELED
+
+* TODO Report :work:
+DEADLINE: <2024-03-08 Fri> SCHEDULED: <2024-03-04 Mon 09:00>
+:PROPERTIES:
+:ID: report
+:END:
+** NEXT Review
+SCHEDULED: <2024-03-05 Tue +1w>
+* CANCELED Trip
+SCHEDULED: <2024-04-01 Mon>
+* Closed
+CLOSED: [2024-03-01 Fri 10:00] SCHEDULED: <2024-03-01 Fri>
+* Unplanned
+Some text.
+* TODO Secret :noexport:
+SCHEDULED: <2024-03-04 Mon>
+** TODO Nested secret
+DEADLINE: <2024-03-05 Tue>
+* COMMENT TODO Draft
+DEADLINE: <2024-03-06 Wed>
+`
+	f := &OrgFile{Document: goorg.New().Parse(strings.NewReader(raw), "planned.org")}
+
+	entries := f.Planned()
+	if len(entries) != 4 {
+		t.Fatalf("got %d entries, want 4: %+v", len(entries), entries)
+	}
+	report := entries[0]
+	if report.Title != "Report" || report.Status != "TODO" || report.Done || report.ID != "report" ||
+		!reflect.DeepEqual(report.Tags, []string{"work"}) {
+		t.Errorf("report = %+v", report)
+	}
+	if report.Planning.Deadline == nil || report.Planning.Scheduled == nil || report.Planning.Scheduled.Start.Hour() != 9 {
+		t.Errorf("report planning = %+v", report.Planning)
+	}
+	if review := entries[1]; review.Title != "Review" || review.Done || review.Planning.Scheduled.Repeater != "+1w" {
+		t.Errorf("nested = %+v", review)
+	}
+	if trip := entries[2]; !trip.Done {
+		t.Errorf("done keyword not done: %+v", trip)
+	}
+	if closed := entries[3]; !closed.Done {
+		t.Errorf("closed entry not done: %+v", closed)
+	}
+}
+
+func TestAgenda(t *testing.T) {
+	dir := t.TempDir()
+	write := func(name, content string) {
+		path := filepath.Join(dir, name)
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	write("work.org", `* TODO Report
+DEADLINE: <2024-03-06 Wed> SCHEDULED: <2024-03-04 Mon 14:00>
+* TODO Standup
+SCHEDULED: <2024-02-26 Mon 09:00 +1w>
+* DONE Shipped
+SCHEDULED: <2024-03-05 Tue>
+`)
+	write("sub/home.org", `* TODO Rent
+DEADLINE: <2024-03-01 Fri>
+* Later
+SCHEDULED: <2024-03-20 Wed>
+`)
+
+	library, err := NewLibrary(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tree, _ := library.Tree()
+	items, err := Agenda(tree, time.Date(2024, 3, 4, 18, 30, 0, 0, time.Local), 3)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var got []string
+	for _, item := range items {
+		line := fmt.Sprintf("%s %s %s %s", item.Day.Format("01-02"), item.Kind, item.Entry.Title, item.File)
+		if item.Overdue() {
+			line += " overdue since " + item.Date.Format("01-02")
+		}
+		got = append(got, line)
+	}
+	want := []string{
+		// The repetition of last week is past due, and repeats today
+		"03-04 S Standup work.org overdue since 02-26",
+		"03-04 S Standup work.org",
+		"03-04 S Report work.org",
+		"03-04 DL Rent sub/home.org overdue since 03-01",
+		"03-06 DL Report work.org",
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("agenda =\n%s\nwant\n%s", strings.Join(got, "\n"), strings.Join(want, "\n"))
+	}
+}
@@ -0,0 +1,273 @@
+package org
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"path"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+)
+
+// RoamNode is a node of an org-roam database: a file or a headline with an
+// ID
+type RoamNode struct {
+	ID      string
+	File    string // Slash separated, relative to the org directory
+	Level   int    // 0 for the node of a whole file
+	Title   string
+	Aliases []string
+}
+
+// Backlink is a node linking to a node of a file
+type Backlink struct {
+	Source RoamNode // Node the link is in
+	Target RoamNode // Node linked to
+}
+
+// Roam reads the nodes of the org files and the ID links between them
+// from an org-roam database, instead of scanning the files for them, and
+// follows the database as org-roam updates it
+type Roam struct {
+	path    string
+	library *Library
+
+	mu      sync.RWMutex
+	nodes   map[string]RoamNode   // By ID
+	titles  map[string]string     // IDs by lowercase title and alias
+	links   map[string][]Backlink // Links to the nodes of a file, by file
+	modTime time.Time             // Modification time of the database when read
+	version uint64                // Version of the library the files were matched with
+}
+
+// OpenRoam reads the org-roam database at path, with the sqlite3 command,
+// matching its files with the org files of library
+func OpenRoam(ctx context.Context, path string, library *Library) (*Roam, error) {
+	if _, err := exec.LookPath("sqlite3"); err != nil {
+		return nil, errors.New("reading an org-roam database needs the sqlite3 command")
+	}
+	r := &Roam{path: path, library: library}
+	if _, err := r.Refresh(ctx); err != nil {
+		return nil, err
+	}
+	return r, nil
+}
+
+// Refresh reads the database again if it or the listing of the library
+// changed since it was read, and reports whether it did
+func (r *Roam) Refresh(ctx context.Context) (bool, error) {
+	info, err := os.Stat(r.path)
+	if err != nil {
+		return false, err
+	}
+	tree, version := r.library.Tree()
+	r.mu.RLock()
+	current := info.ModTime().Equal(r.modTime) && version == r.version
+	r.mu.RUnlock()
+	if current {
+		return false, nil
+	}
+
+	nodes, titles, links, err := r.read(ctx, tree)
+	if err != nil {
+		return false, err
+	}
+	r.mu.Lock()
+	r.nodes, r.titles, r.links = nodes, titles, links
+	r.modTime, r.version = info.ModTime(), version
+	r.mu.Unlock()
+	return true, nil
+}
+
+// read reads the nodes, aliases and ID links of the database, keeping
+// the nodes of the files of tree
+func (r *Roam) read(ctx context.Context, tree []*FileEntry) (map[string]RoamNode, map[string]string, map[string][]Backlink, error) {
+	var nodeRows []struct {
+		ID    string `json:"id"`
+		File  string `json:"file"`
+		Level int    `json:"level"`
+		Title string `json:"title"`
+	}
+	if err := r.query(ctx, "SELECT id, file, level, title FROM nodes", &nodeRows); err != nil {
+		return nil, nil, nil, err
+	}
+	var aliasRows []struct {
+		Node  string `json:"node_id"`
+		Alias string `json:"alias"`
+	}
+	if err := r.query(ctx, "SELECT node_id, alias FROM aliases", &aliasRows); err != nil {
+		return nil, nil, nil, err
+	}
+	var linkRows []struct {
+		Source string `json:"source"`
+		Dest   string `json:"dest"`
+	}
+	if err := r.query(ctx, `SELECT DISTINCT source, dest FROM links WHERE type = '"id"'`, &linkRows); err != nil {
+		return nil, nil, nil, err
+	}
+
+	files := roamFiles(tree)
+	nodes := map[string]RoamNode{}
+	for _, row := range nodeRows {
+		file, ok := files.match(lispString(row.File))
+		if !ok {
+			// Not served
+			continue
+		}
+		id := lispString(row.ID)
+		nodes[id] = RoamNode{ID: id, File: file, Level: row.Level, Title: lispString(row.Title)}
+	}
+	for _, row := range aliasRows {
+		id := lispString(row.Node)
+		if node, ok := nodes[id]; ok {
+			node.Aliases = append(node.Aliases, lispString(row.Alias))
+			nodes[id] = node
+		}
+	}
+
+	// Names shared by several nodes go to titles before aliases, then to
+	// files before headlines
+	titles := map[string]string{}
+	rank := func(node RoamNode, key string) string {
+		alias := 1
+		if strings.ToLower(node.Title) == key {
+			alias = 0
+		}
+		return fmt.Sprintf("%d %09d %s", alias, node.Level, node.ID)
+	}
+	for id, node := range nodes {
+		for _, name := range append([]string{node.Title}, node.Aliases...) {
+			key := strings.ToLower(name)
+			if other, ok := titles[key]; !ok || rank(node, key) < rank(nodes[other], key) {
+				titles[key] = id
+			}
+		}
+	}
+
+	links := map[string][]Backlink{}
+	for _, row := range linkRows {
+		source, ok := nodes[lispString(row.Source)]
+		target, ok2 := nodes[lispString(row.Dest)]
+		if !ok || !ok2 || source.File == target.File {
+			continue
+		}
+		links[target.File] = append(links[target.File], Backlink{Source: source, Target: target})
+	}
+	return nodes, titles, links, nil
+}
+
+// query runs a query on the database, read-only, and decodes its rows
+func (r *Roam) query(ctx context.Context, query string, rows any) error {
+	cmd := exec.CommandContext(ctx, "sqlite3", "-readonly", "-json", r.path, query)
+	var stdout, stderr bytes.Buffer
+	cmd.Stdout, cmd.Stderr = &stdout, &stderr
+	if err := cmd.Run(); err != nil {
+		if msg := strings.TrimSpace(stderr.String()); msg != "" {
+			return fmt.Errorf("sqlite3: %s", msg)
+		}
+		return err
+	}
+	if stdout.Len() == 0 {
+		// No rows
+		return nil
+	}
+	return json.Unmarshal(stdout.Bytes(), rows)
+}
+
+// Node returns the node with the given ID
+func (r *Roam) Node(id string) (RoamNode, bool) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	node, ok := r.nodes[id]
+	return node, ok
+}
+
+// Lookup returns the node with the given title or alias, ignoring case,
+// as roam: links name them
+func (r *Roam) Lookup(name string) (RoamNode, bool) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	id, ok := r.titles[strings.ToLower(name)]
+	if !ok {
+		return RoamNode{}, false
+	}
+	return r.nodes[id], true
+}
+
+// Backlinks returns the links from nodes of other files to the nodes of
+// the file at rel, a slash separated path relative to the org directory
+func (r *Roam) Backlinks(rel string) []Backlink {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	return r.links[rel]
+}
+
+// BacklinkCount returns the number of nodes of other files linking to
+// the file at rel, a slash separated path relative to the org directory
+func (r *Roam) BacklinkCount(rel string) int {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	sources := map[string]bool{}
+	for _, link := range r.links[rel] {
+		sources[link.Source.ID] = true
+	}
+	return len(sources)
+}
+
+// roamFileIndex lists the org files of the library by base name, as slash
+// separated paths relative to the org directory, to find the files of a
+// database among
+type roamFileIndex map[string][]string
+
+// roamFiles indexes the org files of tree
+func roamFiles(tree []*FileEntry) roamFileIndex {
+	index := roamFileIndex{}
+	for _, f := range listFiles(tree, nil) {
+		if !f.isDir {
+			rel := filepath.ToSlash(f.relPath)
+			index[path.Base(rel)] = append(index[path.Base(rel)], rel)
+		}
+	}
+	return index
+}
+
+// match returns the org file a database has at file. The database has
+// the absolute paths of the machine org-roam ran on, which the org
+// directory may be a copy of anywhere: the org file whose path relative to
+// the directory is the longest ending of file is the one.
+func (index roamFileIndex) match(file string) (string, bool) {
+	file = filepath.ToSlash(file)
+	best := ""
+	for _, rel := range index[path.Base(file)] {
+		if (file == rel || strings.HasSuffix(file, "/"+rel)) && len(rel) > len(best) {
+			best = rel
+		}
+	}
+	return best, best != ""
+}
+
+// lispString returns the string an emacsql value stands for: org-roam
+// stores strings as Emacs Lisp reads them, in double quotes
+func lispString(s string) string {
+	if len(s) < 2 || s[0] != '"' || s[len(s)-1] != '"' {
+		return s
+	}
+	s = s[1 : len(s)-1]
+	if !strings.Contains(s, `\`) {
+		return s
+	}
+	var b strings.Builder
+	for i := 0; i < len(s); i++ {
+		if s[i] == '\\' && i+1 < len(s) {
+			i++
+		}
+		b.WriteByte(s[i])
+	}
+	return b.String()
+}
@@ -0,0 +1,99 @@
+package org
+
+import (
+	"context"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestRoam(t *testing.T) {
+	if _, err := exec.LookPath("sqlite3"); err != nil {
+		t.Skip("sqlite3 not installed")
+	}
+	dir := t.TempDir()
+	for _, name := range []string{"emacs.org", "lisp.org", filepath.Join("daily", "2024-03-01.org")} {
+		path := filepath.Join(dir, name)
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte("* "+name+"\n"), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	library, err := NewLibrary(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// The database of org-roam on another machine, with its strings as
+	// emacsql stores them
+	db := filepath.Join(t.TempDir(), "org-roam.db")
+	schema := `
+CREATE TABLE nodes (id NOT NULL PRIMARY KEY, file NOT NULL, level NOT NULL, pos NOT NULL, todo, priority, scheduled, deadline, title, properties, olp);
+CREATE TABLE aliases (node_id NOT NULL, alias);
+CREATE TABLE links (pos NOT NULL, source NOT NULL, dest NOT NULL, type NOT NULL, properties NOT NULL);
+INSERT INTO nodes (id, file, level, pos, title) VALUES
+	('"e1"', '"/home/ada/org/emacs.org"', 0, 1, '"Emacs"'),
+	('"e2"', '"/home/ada/org/emacs.org"', 1, 40, '"Org \"mode\""'),
+	('"l1"', '"/home/ada/org/lisp.org"', 0, 1, '"Lisp"'),
+	('"d1"', '"/home/ada/org/daily/2024-03-01.org"', 0, 1, '"2024-03-01"'),
+	('"x1"', '"/home/ada/elsewhere.org"', 0, 1, '"Elsewhere"');
+INSERT INTO aliases (node_id, alias) VALUES ('"e1"', '"GNU Emacs"'), ('"x1"', '"Nowhere"');
+INSERT INTO links (pos, source, dest, type, properties) VALUES
+	(10, '"l1"', '"e1"', '"id"', '()'),
+	(20, '"l1"', '"e2"', '"id"', '()'),
+	(30, '"d1"', '"e2"', '"id"', '()'),
+	(50, '"e2"', '"e1"', '"id"', '()'),
+	(60, '"d1"', '"x1"', '"id"', '()'),
+	(70, '"d1"', '"https://gnu.org"', '"https"', '()');
+`
+	cmd := exec.Command("sqlite3", db)
+	cmd.Stdin = strings.NewReader(schema)
+	if out, err := cmd.CombinedOutput(); err != nil {
+		t.Fatalf("sqlite3: %v: %s", err, out)
+	}
+
+	ctx := context.Background()
+	roam, err := OpenRoam(ctx, db, library)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if node, ok := roam.Node("e2"); !ok || node.File != "emacs.org" || node.Level != 1 || node.Title != `Org "mode"` {
+		t.Errorf(`Node("e2") = %+v, %v`, node, ok)
+	}
+	if node, ok := roam.Node("d1"); !ok || node.File != "daily/2024-03-01.org" {
+		t.Errorf(`Node("d1") = %+v, %v`, node, ok)
+	}
+	if _, ok := roam.Node("x1"); ok {
+		t.Error("found a node of a file outside of the org directory")
+	}
+	if node, ok := roam.Lookup("gnu emacs"); !ok || node.ID != "e1" {
+		t.Errorf(`Lookup("gnu emacs") = %+v, %v`, node, ok)
+	}
+
+	backlinks := roam.Backlinks("emacs.org")
+	if len(backlinks) != 3 {
+		t.Errorf("Backlinks(emacs.org) = %+v, want the 3 links from other files", backlinks)
+	}
+	if got := roam.BacklinkCount("emacs.org"); got != 2 {
+		t.Errorf("BacklinkCount(emacs.org) = %d, want 2", got)
+	}
+	if got := roam.BacklinkCount("lisp.org"); got != 0 {
+		t.Errorf("BacklinkCount(lisp.org) = %d, want 0", got)
+	}
+
+	if changed, err := roam.Refresh(ctx); err != nil || changed {
+		t.Errorf("Refresh() of an unchanged database = %v, %v", changed, err)
+	}
+	later := time.Now().Add(time.Minute)
+	if err := os.Chtimes(db, later, later); err != nil {
+		t.Fatal(err)
+	}
+	if changed, err := roam.Refresh(ctx); err != nil || !changed {
+		t.Errorf("Refresh() of a changed database = %v, %v", changed, err)
+	}
+}
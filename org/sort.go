@@ -0,0 +1,140 @@
+package org
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// SortField selects the key files in the tree are ordered by
+type SortField int
+
+const (
+	SortByName    SortField = iota // File name (alphabetical)
+	SortByTitle                    // #+TITLE, falling back to the file name
+	SortByDate                     // #+DATE
+	SortByModTime                  // File modification time
+)
+
+// sortFieldNames maps sort fields to the names used in flags and the UI
+var sortFieldNames = []string{"name", "title", "date", "modified"}
+
+// String returns the name of the sort field
+func (f SortField) String() string {
+	if f < 0 || int(f) >= len(sortFieldNames) {
+		return "unknown"
+	}
+	return sortFieldNames[f]
+}
+
+// Next returns the sort field that follows f when cycling through fields
+func (f SortField) Next() SortField {
+	return (f + 1) % SortField(len(sortFieldNames))
+}
+
+// SortOrder describes how files in the tree are ordered
+type SortOrder struct {
+	Field      SortField
+	Descending bool
+}
+
+// String returns the order in the form accepted by ParseSortOrder
+func (o SortOrder) String() string {
+	if o.Descending {
+		return o.Field.String() + ":desc"
+	}
+	return o.Field.String()
+}
+
+// ParseSortOrder parses a sort order such as "title" or "date:desc"
+func ParseSortOrder(s string) (SortOrder, error) {
+	name, dir, _ := strings.Cut(strings.ToLower(strings.TrimSpace(s)), ":")
+
+	var order SortOrder
+	found := false
+	for i, n := range sortFieldNames {
+		if n == name {
+			order.Field = SortField(i)
+			found = true
+			break
+		}
+	}
+	if !found {
+		return order, fmt.Errorf("unknown sort field %q (want one of %s)", name, strings.Join(sortFieldNames, ", "))
+	}
+
+	switch dir {
+	case "", "asc":
+	case "desc":
+		order.Descending = true
+	default:
+		return order, fmt.Errorf("unknown sort direction %q (want asc or desc)", dir)
+	}
+	return order, nil
+}
+
+// dateRegexp extracts the ISO date from #+DATE values like <2024-01-15 Mon>
+var dateRegexp = regexp.MustCompile(`\d{4}-\d{2}-\d{2}`)
+
+// SortTree orders the files of every directory in the tree. Directories
+// stay first and alphabetical; files without a value for the sort field
+// (e.g. no #+DATE) go last regardless of direction. Title and date
+// ordering read the headers of files not parsed yet rather than parsing
+// them.
+func SortTree(entries []*FileEntry, order SortOrder) {
+	var dirs, files []*FileEntry
+	for _, e := range entries {
+		if e.IsDir {
+			SortTree(e.Children, order)
+			dirs = append(dirs, e)
+		} else {
+			files = append(files, e)
+		}
+	}
+
+	keys := make(map[*FileEntry]string, len(files))
+	for _, f := range files {
+		keys[f] = sortKey(f, order.Field)
+	}
+
+	sort.SliceStable(files, func(i, j int) bool {
+		a, b := files[i], files[j]
+		ka, kb := keys[a], keys[b]
+		if ka != kb {
+			// Missing values always sort last
+			if ka == "" || kb == "" {
+				return kb == ""
+			}
+			if order.Descending {
+				return ka > kb
+			}
+			return ka < kb
+		}
+		return strings.ToLower(a.Name) < strings.ToLower(b.Name)
+	})
+
+	copy(entries, dirs)
+	copy(entries[len(dirs):], files)
+}
+
+// sortKey returns the comparable key of a file for the given field
+func sortKey(e *FileEntry, field SortField) string {
+	switch field {
+	case SortByTitle:
+		return strings.ToLower(e.Title())
+	case SortByDate:
+		date := e.Header().Date
+		if e.OrgFile != nil {
+			date = e.OrgFile.Date()
+		}
+		return dateRegexp.FindString(date)
+	case SortByModTime:
+		if e.ModTime.IsZero() {
+			return ""
+		}
+		return e.ModTime.UTC().Format("20060102150405.000000000")
+	default:
+		return strings.ToLower(e.Name)
+	}
+}
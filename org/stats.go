@@ -0,0 +1,141 @@
+package org
+
+import (
+	"sort"
+	"strings"
+
+	goorg "github.com/niklasfasching/go-org/org"
+)
+
+// wordsPerMinute is the reading speed used for reading time estimates
+const wordsPerMinute = 200
+
+// wordsPerCodeLine is the reading-time weight of a line of code, in words.
+// Code is read much slower than prose.
+const wordsPerCodeLine = 10
+
+// FileStats holds summary information about an org file
+type FileStats struct {
+	Words     int      // Words of prose, excluding keywords, drawers and code
+	CodeLines int      // Non-blank lines in source, example and LaTeX blocks
+	Tags      []string // File and headline tags, most used first
+}
+
+// ReadingMinutes returns the estimated reading time in whole minutes
+// (at least one for non-empty files), with code weighted by wordsPerCodeLine
+func (s FileStats) ReadingMinutes() int {
+	words := s.Words + s.CodeLines*wordsPerCodeLine
+	if words == 0 {
+		return 0
+	}
+	return (words + wordsPerMinute - 1) / wordsPerMinute
+}
+
+// Stats returns summary information about the file. It is computed from
+// the parsed document on first use and cached.
+func (f *OrgFile) Stats() FileStats {
+	if f.stats == nil {
+		denote, _ := ParseDenoteName(f.Name)
+		stats := FileStats{Tags: collectTags(f.Document, denote.Keywords)}
+		stats.count(f.Document.Nodes, false)
+		f.stats = &stats
+	}
+	return *f.stats
+}
+
+// count adds the words and code lines of nodes to s. Text inside code
+// blocks counts as code lines rather than words.
+func (s *FileStats) count(nodes []goorg.Node, code bool) {
+	for _, n := range nodes {
+		switch n := n.(type) {
+		case goorg.Text:
+			if code {
+				for _, line := range strings.Split(n.Content, "\n") {
+					if strings.TrimSpace(line) != "" {
+						s.CodeLines++
+					}
+				}
+			} else {
+				s.Words += len(strings.Fields(n.Content))
+			}
+		case goorg.Headline:
+			s.count(n.Title, code)
+			s.count(n.Children, code)
+		case goorg.Block:
+			switch strings.ToUpper(n.Name) {
+			case "SRC", "EXAMPLE", "EXPORT":
+				s.count(n.Children, true)
+			default:
+				s.count(n.Children, code)
+			}
+		case goorg.Example:
+			s.count(n.Children, true)
+		case goorg.LatexBlock:
+			s.count(n.Content, true)
+		case goorg.Paragraph:
+			s.count(n.Children, code)
+		case goorg.Emphasis:
+			s.count(n.Content, code)
+		case goorg.RegularLink:
+			s.count(n.Description, code)
+		case goorg.List:
+			s.count(n.Items, code)
+		case goorg.ListItem:
+			s.count(n.Children, code)
+		case goorg.DescriptiveListItem:
+			s.count(n.Term, code)
+			s.count(n.Details, code)
+		case goorg.Table:
+			for _, row := range n.Rows {
+				for _, col := range row.Columns {
+					s.count(col.Children, code)
+				}
+			}
+		case goorg.FootnoteDefinition:
+			s.count(n.Children, code)
+		case goorg.NodeWithMeta:
+			s.count([]goorg.Node{n.Node}, code)
+		case goorg.NodeWithName:
+			s.count([]goorg.Node{n.Node}, code)
+		}
+		// Keywords, drawers, comments and timestamps aren't read
+	}
+}
+
+// collectTags gathers #+FILETAGS, or fileTags without them, and headline
+// tags, ordered by how often they are used
+func collectTags(doc *goorg.Document, fileTags []string) []string {
+	counts := map[string]int{}
+	if filetags := doc.Get("FILETAGS"); filetags != "" {
+		fileTags = strings.Split(filetags, ":")
+	}
+	for _, tag := range fileTags {
+		if tag = strings.TrimSpace(tag); tag != "" {
+			counts[tag]++
+		}
+	}
+	var walk func(nodes []goorg.Node)
+	walk = func(nodes []goorg.Node) {
+		for _, n := range nodes {
+			if h, ok := n.(goorg.Headline); ok {
+				for _, tag := range h.Tags {
+					counts[tag]++
+				}
+				walk(h.Children)
+			}
+		}
+	}
+	walk(doc.Nodes)
+
+	tags := make([]string, 0, len(counts))
+	for tag := range counts {
+		tags = append(tags, tag)
+	}
+	sort.Slice(tags, func(i, j int) bool {
+		if counts[tags[i]] != counts[tags[j]] {
+			return counts[tags[i]] > counts[tags[j]]
+		}
+		return tags[i] < tags[j]
+	})
+	return tags
+}
@@ -0,0 +1,131 @@
+package org
+
+import (
+	"regexp"
+	"strings"
+	"time"
+)
+
+// timestampBody matches the inside of an org timestamp: a date, an optional
+// day name, an optional time or time range, and repeater and warning cookies
+const timestampBody = `(\d{4}-\d{2}-\d{2})(?: +[^\s\d>\]+-]+)?(?: +(\d{1,2}:\d{2})(?:-(\d{1,2}:\d{2}))?)?((?: +(?:\+\+|\.\+|\+|--|-)\d+[hdwmy](?:/\d+[hdwmy])?)*) *`
+
+// TimestampRegexp matches active (<...>) and inactive ([...]) timestamps,
+// including date ranges joined by --
+var TimestampRegexp = regexp.MustCompile(
+	`<` + timestampBody + `>(?:--<` + timestampBody + `>)?` +
+		`|\[` + timestampBody + `\](?:--\[` + timestampBody + `\])?`,
+)
+
+// cookieRegexp matches a repeater or warning cookie of a timestamp
+var cookieRegexp = regexp.MustCompile(`(\+\+|\.\+|\+|--|-)\d+[hdwmy](?:/\d+[hdwmy])?`)
+
+// Timestamp is a parsed org timestamp
+type Timestamp struct {
+	Active   bool      // <...> rather than [...]
+	Start    time.Time // Start date, and time when HasTime is set
+	End      time.Time // End of a time or date range, zero otherwise
+	HasTime  bool      // Start includes a time of day
+	EndTime  bool      // End includes a time of day
+	Repeater string    // Repeater cookie such as +1w, .+1d or ++1m
+	Warning  string    // Warning period such as -2d
+}
+
+// IsRange reports whether the timestamp spans a time or date range
+func (t Timestamp) IsRange() bool {
+	return !t.End.IsZero()
+}
+
+// ParseTimestamp parses an org timestamp matched by TimestampRegexp
+func ParseTimestamp(s string) (Timestamp, bool) {
+	m := TimestampRegexp.FindStringSubmatch(s)
+	if m == nil || len(m[0]) != len(s) {
+		return Timestamp{}, false
+	}
+
+	// Submatches of the four timestamp bodies: active start and end,
+	// inactive start and end
+	const fields = 4
+	ts := Timestamp{Active: s[0] == '<'}
+	first := 1
+	if !ts.Active {
+		first = 1 + 2*fields
+	}
+	start := m[first : first+fields]
+	end := m[first+fields : first+2*fields]
+
+	var ok bool
+	if ts.Start, ts.HasTime, ok = parseDateTime(start[0], start[1]); !ok {
+		return Timestamp{}, false
+	}
+	switch {
+	case end[0] != "":
+		// Date range: <start>--<end>
+		if ts.End, ts.EndTime, ok = parseDateTime(end[0], end[1]); !ok {
+			return Timestamp{}, false
+		}
+	case start[2] != "":
+		// Time range within the day: <date 10:00-11:30>
+		if ts.End, ts.EndTime, ok = parseDateTime(start[0], start[2]); !ok {
+			return Timestamp{}, false
+		}
+	}
+
+	for _, cookie := range cookieRegexp.FindAllString(start[3], -1) {
+		if cookie[0] == '-' {
+			ts.Warning = cookie
+		} else {
+			ts.Repeater = cookie
+		}
+	}
+	return ts, true
+}
+
+// parseDateTime parses a date and an optional time of day
+func parseDateTime(date, clock string) (time.Time, bool, bool) {
+	if clock == "" {
+		t, err := time.Parse("2006-01-02", date)
+		return t, false, err == nil
+	}
+	t, err := time.Parse("2006-01-02 15:04", date+" "+clock)
+	return t, true, err == nil
+}
+
+// planningRegexp matches one SCHEDULED, DEADLINE or CLOSED entry of a
+// planning line
+var planningRegexp = regexp.MustCompile(`(SCHEDULED|DEADLINE|CLOSED):\s*(` + TimestampRegexp.String() + `)`)
+
+// Planning holds the timestamps of a headline's planning line
+type Planning struct {
+	Scheduled *Timestamp
+	Deadline  *Timestamp
+	Closed    *Timestamp
+}
+
+// ParsePlanning parses a planning line, the line right below a headline
+// made only of SCHEDULED, DEADLINE and CLOSED entries
+func ParsePlanning(line string) (Planning, bool) {
+	var p Planning
+	rest := line
+	found := false
+	for _, m := range planningRegexp.FindAllStringSubmatchIndex(line, -1) {
+		ts, ok := ParseTimestamp(line[m[4]:m[5]])
+		if !ok {
+			return Planning{}, false
+		}
+		switch line[m[2]:m[3]] {
+		case "SCHEDULED":
+			p.Scheduled = &ts
+		case "DEADLINE":
+			p.Deadline = &ts
+		case "CLOSED":
+			p.Closed = &ts
+		}
+		rest = strings.Replace(rest, line[m[0]:m[1]], "", 1)
+		found = true
+	}
+	if !found || strings.TrimSpace(rest) != "" {
+		return Planning{}, false
+	}
+	return p, true
+}
@@ -0,0 +1,49 @@
+package main
+
+import (
+	"context"
+	"time"
+
+	"org-charm/org"
+	"org-charm/remind"
+
+	"github.com/charmbracelet/log"
+)
+
+// matrixTokenEnv is the environment variable holding the access token of
+// the Matrix user reminders are posted as, kept out of the command line
+const matrixTokenEnv = "ORG_CHARM_MATRIX_TOKEN"
+
+// remindInterval is how often deadlines are checked for reminders to send
+const remindInterval = time.Minute
+
+// sendReminders sends reminders of the deadlines of the org files in
+// library to sinks as they come within their warning period, checking at
+// startup and every remindInterval, in the background. The reminders sent
+// are saved to path, unless empty, not to be sent again after a restart.
+func sendReminders(sinks []remind.Sink, warning int, path string, library *org.Library) {
+	notifier, err := remind.New(sinks, warning, path)
+	if err != nil {
+		log.Fatal("Failed to read the reminders sent", "path", path, "error", err)
+	}
+	check := func() {
+		ctx, cancel := context.WithTimeout(context.Background(), remindInterval)
+		defer cancel()
+		tree, _ := library.Tree()
+		sent, err := notifier.Check(ctx, tree, time.Now())
+		if err != nil {
+			log.Error("Failed to send deadline reminders", "error", err)
+		}
+		if sent > 0 {
+			log.Info("Sent deadline reminders", "reminders", sent)
+		}
+	}
+
+	log.Info("Sending deadline reminders", "sinks", sinks, "warning_days", warning, "path", path)
+	go func() {
+		check()
+		for range time.Tick(remindInterval) {
+			check()
+		}
+	}()
+}
@@ -0,0 +1,206 @@
+// Package remind sends reminders of the DEADLINE entries of the org files
+// as they come within their warning period, to chat rooms and webhooks.
+// Each deadline is sent to each sink once, when its warning period starts
+// or it's first seen within it; entries done and deadlines already past
+// are left out.
+package remind
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"time"
+
+	"org-charm/org"
+)
+
+// Deadline is a reminder of a deadline of an entry, as sinks post it
+type Deadline struct {
+	File    string    `json:"file"` // Slash separated, relative to the org directory
+	Title   string    `json:"title"`
+	Status  string    `json:"status,omitempty"` // TODO keyword, if any
+	Tags    []string  `json:"tags,omitempty"`
+	ID      string    `json:"id,omitempty"`
+	Date    time.Time `json:"deadline"`
+	HasTime bool      `json:"has_time"`
+	Days    int       `json:"days_left"` // Days until the deadline
+	Text    string    `json:"text"`      // The reminder as a sentence, for chat
+}
+
+// text returns the reminder of a deadline as a sentence
+func (d Deadline) text() string {
+	title := d.Title
+	if d.Status != "" {
+		title = d.Status + " " + title
+	}
+	date := d.Date.Format("Mon 2 Jan 2006")
+	if d.HasTime {
+		date += d.Date.Format(" 15:04")
+	}
+	var when string
+	switch d.Days {
+	case 0:
+		when = "is due today"
+	case 1:
+		when = "is due tomorrow"
+	default:
+		when = fmt.Sprintf("is due in %d days", d.Days)
+	}
+	return fmt.Sprintf("%s %s (%s) — %s", title, when, date, d.File)
+}
+
+// Sink posts reminders somewhere, such as a chat room
+type Sink interface {
+	Send(ctx context.Context, d Deadline) error
+	// String names the sink in logs, without secrets
+	String() string
+}
+
+// Notifier sends reminders of the deadlines of the org files to its sinks
+type Notifier struct {
+	sinks   []Sink
+	warning int // Days of warning of deadlines without a period of their own
+
+	// Reminders sent, by sink and deadline, so each is sent once, and the
+	// file they're saved to, or empty
+	sent map[string]bool
+	path string
+}
+
+// New creates a notifier sending reminders to sinks, warning of deadlines
+// without a warning period of their own warning days ahead. The reminders
+// sent are saved to path, and those a previous run saved there loaded, so
+// a restarted server doesn't send them again; with an empty path they are
+// kept in memory only.
+func New(sinks []Sink, warning int, path string) (*Notifier, error) {
+	n := &Notifier{sinks: sinks, warning: warning, sent: map[string]bool{}, path: path}
+	if path == "" {
+		return n, nil
+	}
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return n, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	var keys []string
+	if err := json.Unmarshal(data, &keys); err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	for _, key := range keys {
+		n.sent[key] = true
+	}
+	return n, nil
+}
+
+// Check sends the reminders of the deadlines of the org files in tree
+// within their warning period at now that weren't sent yet, and returns
+// how many it sent. Reminders that fail are sent again on the next Check,
+// unless the deadline has passed by then.
+func (n *Notifier) Check(ctx context.Context, tree []*org.FileEntry, now time.Time) (int, error) {
+	// Timestamps are parsed as UTC, so dates are compared as such
+	wall := time.Date(now.Year(), now.Month(), now.Day(), now.Hour(), now.Minute(), 0, 0, time.UTC)
+	today := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, time.UTC)
+
+	var due []Deadline
+	err := org.EachPlanned(tree, func(entry *org.FileEntry, planned org.PlannedEntry) {
+		ts := planned.Planning.Deadline
+		if planned.Done || ts == nil || wall.Before(ts.WarningStart(n.warning)) {
+			return
+		}
+		day := time.Date(ts.Start.Year(), ts.Start.Month(), ts.Start.Day(), 0, 0, 0, 0, time.UTC)
+		if day.Before(today) {
+			// Reminded of before it passed, or while the server was down
+			return
+		}
+		d := Deadline{
+			File:    entry.RelPath,
+			Title:   planned.Title,
+			Status:  planned.Status,
+			Tags:    planned.Tags,
+			ID:      planned.ID,
+			Date:    ts.Start,
+			HasTime: ts.HasTime,
+			Days:    int(day.Sub(today).Hours() / 24),
+		}
+		d.Text = d.text()
+		due = append(due, d)
+	})
+	if err != nil {
+		return 0, err
+	}
+
+	sent, changed := 0, false
+	seen := map[string]bool{}
+	var errs []error
+	for _, d := range due {
+		for i, sink := range n.sinks {
+			key := fmt.Sprintf("%d\x00%s", i, deadlineKey(d))
+			seen[key] = true
+			if n.sent[key] {
+				continue
+			}
+			if err := sink.Send(ctx, d); err != nil {
+				errs = append(errs, fmt.Errorf("%s: %w", sink, err))
+				continue
+			}
+			n.sent[key] = true
+			sent++
+			changed = true
+		}
+	}
+	// Forget the deadlines done, removed, moved or past, for a deadline
+	// moved back to be reminded of again
+	for key := range n.sent {
+		if !seen[key] {
+			delete(n.sent, key)
+			changed = true
+		}
+	}
+	if changed {
+		if err := n.save(); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return sent, errors.Join(errs...)
+}
+
+// save writes the reminders sent to the notifier's file, if it has one,
+// through a temporary file so a crash never leaves a torn one
+func (n *Notifier) save() error {
+	if n.path == "" {
+		return nil
+	}
+	keys := make([]string, 0, len(n.sent))
+	for key := range n.sent {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	data, err := json.Marshal(keys)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(n.path), 0o700); err != nil {
+		return err
+	}
+	tmp := n.path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0o600); err != nil {
+		return err
+	}
+	return os.Rename(tmp, n.path)
+}
+
+// deadlineKey identifies a deadline of an entry: its ID, or where it is
+// without one, and its date
+func deadlineKey(d Deadline) string {
+	entry := d.ID
+	if entry == "" {
+		entry = d.File + "\x00" + d.Title
+	}
+	return entry + "\x00" + d.Date.Format(time.DateTime)
+}
@@ -0,0 +1,139 @@
+package remind
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"org-charm/org"
+)
+
+func TestCheck(t *testing.T) {
+	dir := t.TempDir()
+	content := `* TODO Report :work:
+DEADLINE: <2024-03-06 Wed>
+* TODO Taxes
+DEADLINE: <2024-03-20 Wed -20d>
+* TODO Rent
+DEADLINE: <2024-03-01 Fri>
+* TODO Trip
+DEADLINE: <2024-04-01 Mon>
+* DONE Shipped
+DEADLINE: <2024-03-05 Tue>
+`
+	if err := os.WriteFile(filepath.Join(dir, "todo.org"), []byte(content), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	library, err := org.NewLibrary(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var mu sync.Mutex
+	received := map[string][]string{}
+	failing := true
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		defer mu.Unlock()
+		var body map[string]any
+		if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+			t.Errorf("%s: %v", r.URL.Path, err)
+		}
+		switch {
+		case r.URL.Path == "/slack":
+			received["slack"] = append(received["slack"], body["text"].(string))
+		case strings.HasPrefix(r.URL.Path, "/_matrix/client/v3/rooms/!room:example.com/send/m.room.message/"):
+			if r.Method != http.MethodPut || r.Header.Get("Authorization") != "Bearer secret" {
+				t.Errorf("matrix %s, authorization %q", r.Method, r.Header.Get("Authorization"))
+			}
+			received["matrix"] = append(received["matrix"], body["body"].(string))
+		case r.URL.Path == "/hook":
+			if failing {
+				http.Error(w, "down", http.StatusServiceUnavailable)
+				return
+			}
+			received["http"] = append(received["http"], body["title"].(string))
+		}
+	}))
+	defer srv.Close()
+
+	var sinks []Sink
+	for _, spec := range []string{"slack=" + srv.URL + "/slack", "matrix=" + srv.URL + "/!room:example.com", "http=" + srv.URL + "/hook"} {
+		sink, err := ParseSink(spec, "secret")
+		if err != nil {
+			t.Fatal(err)
+		}
+		sinks = append(sinks, sink)
+	}
+	path := filepath.Join(dir, "state", "reminders.json")
+	n, err := New(sinks, 3, path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx := context.Background()
+	check := func(now time.Time) (int, error) {
+		tree, _ := library.Tree()
+		return n.Check(ctx, tree, now)
+	}
+
+	// Report is 2 days away, within the 3 days of warning; Taxes warns 20
+	// days ahead; Rent is already past; Trip is too far off and Shipped
+	// done
+	now := time.Date(2024, 3, 4, 9, 0, 0, 0, time.Local)
+	sent, err := check(now)
+	if err == nil || sent != 4 {
+		t.Fatalf("first check sent %d, %v; want 4 and the http sink failing", sent, err)
+	}
+	want := []string{
+		"TODO Report is due in 2 days (Wed 6 Mar 2024) — todo.org",
+		"TODO Taxes is due in 16 days (Wed 20 Mar 2024) — todo.org",
+	}
+	for _, sink := range []string{"slack", "matrix"} {
+		if got := strings.Join(received[sink], "\n"); got != strings.Join(want, "\n") {
+			t.Errorf("%s received\n%s\nwant\n%s", sink, got, strings.Join(want, "\n"))
+		}
+	}
+
+	// Only the reminders that failed are sent again
+	mu.Lock()
+	failing = false
+	mu.Unlock()
+	if sent, err := check(now.Add(time.Hour)); err != nil || sent != 2 {
+		t.Errorf("second check sent %d, %v; want 2", sent, err)
+	}
+	if got := strings.Join(received["http"], ","); got != "Report,Taxes" {
+		t.Errorf("http received %s", got)
+	}
+	if sent, err := check(now.Add(2 * time.Hour)); err != nil || sent != 0 {
+		t.Errorf("third check sent %d, %v; want none", sent, err)
+	}
+
+	// A restarted notifier remembers what was sent
+	if n, err = New(sinks, 3, path); err != nil {
+		t.Fatal(err)
+	}
+	if sent, err := check(now.Add(3 * time.Hour)); err != nil || sent != 0 {
+		t.Errorf("check after a restart sent %d, %v; want none", sent, err)
+	}
+
+	// Trip comes within its warning period
+	if sent, err := check(time.Date(2024, 3, 29, 0, 0, 0, 0, time.Local)); err != nil || sent != 3 {
+		t.Errorf("check on Mar 29 sent %d, %v; want 3", sent, err)
+	}
+
+	for _, spec := range []string{"slack", "irc=https://example.com", "slack=ftp://example.com", "matrix=https://matrix.example.com/room"} {
+		if _, err := ParseSink(spec, "secret"); err == nil {
+			t.Errorf("ParseSink(%q) succeeded", spec)
+		}
+	}
+	if _, err := ParseSink("matrix=https://matrix.example.com/!room:example.com", ""); err == nil {
+		t.Error("matrix sink without a token succeeded")
+	}
+}
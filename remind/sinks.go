@@ -0,0 +1,154 @@
+package remind
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"time"
+)
+
+// postTimeout bounds how long posting a reminder may take
+const postTimeout = 10 * time.Second
+
+// ParseSink parses a sink given as kind=URL:
+//
+//   - slack=URL posts to a Slack incoming webhook
+//   - matrix=URL posts to a Matrix room, given as the homeserver and the
+//     room ID, as in https://matrix.example.com/!room:example.com, with
+//     matrixToken as the access token of the user posting
+//   - http=URL posts the reminder as JSON
+func ParseSink(spec, matrixToken string) (Sink, error) {
+	kind, rawURL, ok := strings.Cut(spec, "=")
+	if !ok {
+		return nil, fmt.Errorf("%q: want kind=URL, as in slack=https://hooks.slack.com/…", spec)
+	}
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return nil, err
+	}
+	if (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return nil, fmt.Errorf("%s: want an http or https URL", kind)
+	}
+	switch kind {
+	case "slack":
+		return NewSlack(rawURL), nil
+	case "matrix":
+		room := strings.TrimPrefix(u.Path, "/")
+		if !strings.HasPrefix(room, "!") || !strings.Contains(room, ":") {
+			return nil, fmt.Errorf("matrix: want the room ID as the path, as in https://matrix.example.com/!room:example.com")
+		}
+		if matrixToken == "" {
+			return nil, fmt.Errorf("matrix: no access token")
+		}
+		return NewMatrix(u.Scheme+"://"+u.Host, room, matrixToken), nil
+	case "http":
+		return NewHTTP(rawURL), nil
+	}
+	return nil, fmt.Errorf("%q: unknown sink, want slack, matrix or http", kind)
+}
+
+// client posts the reminders of all sinks
+var client = &http.Client{Timeout: postTimeout}
+
+// send sends a request with a JSON body, and drains the response
+func send(ctx context.Context, method, target string, body any, header map[string]string) error {
+	data, err := json.Marshal(body)
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequestWithContext(ctx, method, target, bytes.NewReader(data))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", "org-charm")
+	for k, v := range header {
+		req.Header.Set(k, v)
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	io.Copy(io.Discard, resp.Body)
+	if resp.StatusCode >= 300 {
+		return fmt.Errorf("responded %s", resp.Status)
+	}
+	return nil
+}
+
+// Slack posts reminders to a Slack incoming webhook
+type Slack struct {
+	url string
+}
+
+// NewSlack creates a sink posting to the Slack incoming webhook at url
+func NewSlack(url string) *Slack {
+	return &Slack{url}
+}
+
+func (s *Slack) Send(ctx context.Context, d Deadline) error {
+	return send(ctx, http.MethodPost, s.url, map[string]string{"text": d.Text}, nil)
+}
+
+func (s *Slack) String() string {
+	// The path of a webhook is its secret
+	return "slack"
+}
+
+// Matrix posts reminders to a Matrix room as notices, as bots do
+type Matrix struct {
+	homeserver string
+	room       string
+	token      string
+	txn        atomic.Uint64 // Transactions sent, for their IDs
+}
+
+// NewMatrix creates a sink posting to the room with the given ID, such as
+// !room:example.com, of homeserver, as the user of the access token
+func NewMatrix(homeserver, room, token string) *Matrix {
+	return &Matrix{homeserver: strings.TrimSuffix(homeserver, "/"), room: room, token: token}
+}
+
+func (m *Matrix) Send(ctx context.Context, d Deadline) error {
+	// Transaction IDs make retries idempotent, so they're unique to a
+	// reminder and a run
+	txn := strconv.FormatInt(time.Now().UnixNano(), 36) + "-" + strconv.FormatUint(m.txn.Add(1), 10)
+	target := m.homeserver + "/_matrix/client/v3/rooms/" + url.PathEscape(m.room) +
+		"/send/m.room.message/" + txn
+	body := map[string]string{"msgtype": "m.notice", "body": d.Text}
+	return send(ctx, http.MethodPut, target, body, map[string]string{"Authorization": "Bearer " + m.token})
+}
+
+func (m *Matrix) String() string {
+	return "matrix " + m.room
+}
+
+// HTTP posts reminders as JSON, the Deadline, to a URL
+type HTTP struct {
+	url string
+}
+
+// NewHTTP creates a sink posting reminders as JSON to url
+func NewHTTP(url string) *HTTP {
+	return &HTTP{url}
+}
+
+func (h *HTTP) Send(ctx context.Context, d Deadline) error {
+	return send(ctx, http.MethodPost, h.url, d, nil)
+}
+
+func (h *HTTP) String() string {
+	u, err := url.Parse(h.url)
+	if err != nil {
+		return "http"
+	}
+	return "http " + u.Redacted()
+}
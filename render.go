@@ -0,0 +1,134 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"net/http"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"org-charm/org"
+	"org-charm/ui"
+
+	"github.com/charmbracelet/log"
+	"github.com/muesli/termenv"
+)
+
+const (
+	// renderDefaultWidth is the width documents are rendered at for
+	// requests and commands that don't give one
+	renderDefaultWidth = 80
+	// renderMaxWidth bounds the width documents are rendered at
+	renderMaxWidth = 1000
+)
+
+// colorProfiles maps the colors requests and commands may ask for to
+// termenv's profiles
+var colorProfiles = map[string]termenv.Profile{
+	"truecolor": termenv.TrueColor,
+	"256":       termenv.ANSI256,
+	"16":        termenv.ANSI,
+	"none":      termenv.Ascii,
+}
+
+// renderOptions returns the settings of the server, render, at the width
+// and in the colors asked for, either of which may be empty for the
+// defaults: 80 columns, or the width of render when set, and true color.
+// Widths under ui.MinRenderWidth are refused, and a narrower render width,
+// that of a small terminal, is widened to it.
+func renderOptions(render ui.RenderOptions, width, color string) (ui.RenderOptions, error) {
+	if render.Width == 0 {
+		render.Width = renderDefaultWidth
+	}
+	render.Width = max(render.Width, ui.MinRenderWidth)
+	if width != "" {
+		w, err := strconv.Atoi(width)
+		if err != nil || w < ui.MinRenderWidth || w > renderMaxWidth {
+			return render, fmt.Errorf("width must be between %d and %d", ui.MinRenderWidth, renderMaxWidth)
+		}
+		render.Width = w
+	}
+	render.Profile = termenv.TrueColor
+	if color != "" {
+		profile, ok := colorProfiles[color]
+		if !ok {
+			return render, fmt.Errorf("unknown color %q, want truecolor, 256, 16 or none", color)
+		}
+		render.Profile = profile
+	}
+	return render, nil
+}
+
+// serveRender serves the org files of library rendered to ANSI text as
+// sessions show them on addr, for curl host/notes/todo.org to print them
+// without the TUI
+func serveRender(addr string, library *org.Library, render ui.RenderOptions) {
+	handler := renderHandler(library, render)
+	log.Info("Serving rendered documents", "addr", addr)
+	go func() {
+		if err := http.ListenAndServe(addr, handler); err != nil {
+			log.Error("Render server error", "error", err)
+		}
+	}()
+}
+
+// renderHandler serves the org files of library rendered to ANSI text.
+// The width and colors are set with the width and color query parameters,
+// as in ?width=120&color=256, and / lists the files.
+func renderHandler(library *org.Library, render ui.RenderOptions) http.Handler {
+	render.Root = library.Root()
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /{path...}", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+		rel := r.PathValue("path")
+		if rel == "" {
+			tree, _ := library.Tree()
+			writeFileList(w, tree)
+			return
+		}
+		options, err := renderOptions(render, r.URL.Query().Get("width"), r.URL.Query().Get("color"))
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		doc, err := library.Open(rel)
+		switch {
+		case errors.Is(err, org.ErrNotOrgFile):
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		case errors.Is(err, fs.ErrNotExist):
+			http.Error(w, rel+": no such org file", http.StatusNotFound)
+			return
+		case err != nil:
+			log.Error("Failed to open document", "path", rel, "error", err)
+			http.Error(w, "failed to open document", http.StatusInternalServerError)
+			return
+		}
+		ansi, err := ui.RenderANSI(r.Context(), doc, options)
+		if err != nil {
+			// The client went away
+			return
+		}
+		io.WriteString(w, ansi)
+	})
+	return mux
+}
+
+// writeFileList writes the paths of the org files of tree, a line each
+func writeFileList(w io.Writer, tree []*org.FileEntry) {
+	var b strings.Builder
+	var list func(entries []*org.FileEntry)
+	list = func(entries []*org.FileEntry) {
+		for _, e := range entries {
+			if !e.IsDir {
+				b.WriteString(filepath.ToSlash(e.RelPath) + "\n")
+			}
+			list(e.Children)
+		}
+	}
+	list(tree)
+	io.WriteString(w, b.String())
+}
@@ -0,0 +1,116 @@
+package main
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"org-charm/org"
+	"org-charm/ui"
+
+	"github.com/charmbracelet/x/ansi"
+	"github.com/muesli/termenv"
+)
+
+// testLibrary returns a library of org files written to a temporary
+// directory, by path relative to it
+func testLibrary(t *testing.T, files map[string]string) *org.Library {
+	t.Helper()
+	dir := t.TempDir()
+	for name, content := range files {
+		path := filepath.Join(dir, filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	library, err := org.NewLibrary(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return library
+}
+
+func TestRenderOptions(t *testing.T) {
+	tests := []struct {
+		base         int
+		width, color string
+		want         int
+		profile      termenv.Profile
+		err          bool
+	}{
+		{0, "", "", renderDefaultWidth, termenv.TrueColor, false},
+		{120, "", "256", 120, termenv.ANSI256, false},
+		{5, "", "none", ui.MinRenderWidth, termenv.Ascii, false}, // A tiny terminal
+		{0, "100", "16", 100, termenv.ANSI, false},
+		{0, "1", "", 0, 0, true},
+		{0, "29", "", 0, 0, true},
+		{0, "1001", "", 0, 0, true},
+		{0, "wide", "", 0, 0, true},
+		{0, "", "sepia", 0, 0, true},
+	}
+	for _, tt := range tests {
+		got, err := renderOptions(ui.RenderOptions{Width: tt.base}, tt.width, tt.color)
+		if tt.err {
+			if err == nil {
+				t.Errorf("renderOptions(%d, %q, %q) succeeded", tt.base, tt.width, tt.color)
+			}
+			continue
+		}
+		if err != nil || got.Width != tt.want || got.Profile != tt.profile {
+			t.Errorf("renderOptions(%d, %q, %q) = width %d, profile %v, %v; want %d, %v",
+				tt.base, tt.width, tt.color, got.Width, got.Profile, err, tt.want, tt.profile)
+		}
+	}
+}
+
+func TestServeRender(t *testing.T) {
+	library := testLibrary(t, map[string]string{
+		"notes.org":     "#+TITLE: Notes\n* Heading\nSome text.\n-----\n",
+		"sub/later.org": "* Later\n",
+	})
+	srv := httptest.NewServer(renderHandler(library, ui.RenderOptions{}))
+	defer srv.Close()
+
+	get := func(path string) (int, string) {
+		t.Helper()
+		resp, err := http.Get(srv.URL + path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer resp.Body.Close()
+		body, _ := io.ReadAll(resp.Body)
+		return resp.StatusCode, string(body)
+	}
+
+	if code, body := get("/"); code != http.StatusOK || body != "sub/later.org\nnotes.org\n" {
+		t.Errorf("listing = %d %q", code, body)
+	}
+	code, body := get("/notes.org?width=40&color=none")
+	if code != http.StatusOK || !strings.Contains(body, "Heading") || strings.Contains(body, "\x1b[") {
+		t.Errorf("rendered = %d %q", code, body)
+	}
+	for _, line := range strings.Split(body, "\n") {
+		if w := ansi.StringWidth(line); w > 40 {
+			t.Errorf("line wider than 40 columns (%d): %q", w, line)
+		}
+	}
+	if code, _ := get("/notes.org"); code != http.StatusOK {
+		t.Errorf("default render = %d", code)
+	}
+	if code, _ := get("/notes.org?width=1"); code != http.StatusBadRequest {
+		t.Errorf("width 1 = %d, want %d", code, http.StatusBadRequest)
+	}
+	if code, _ := get("/missing.org"); code != http.StatusNotFound {
+		t.Errorf("missing file = %d, want %d", code, http.StatusNotFound)
+	}
+	if code, _ := get("/notes.txt"); code != http.StatusBadRequest {
+		t.Errorf("not an org file = %d, want %d", code, http.StatusBadRequest)
+	}
+}
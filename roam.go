@@ -0,0 +1,41 @@
+package main
+
+import (
+	"context"
+	"time"
+
+	"org-charm/org"
+
+	"github.com/charmbracelet/log"
+)
+
+// roamTimeout bounds how long reading the org-roam database may take
+const roamTimeout = time.Minute
+
+// openRoam reads the org-roam database at path for sessions to resolve
+// links and count backlinks with
+func openRoam(path string, library *org.Library) *org.Roam {
+	ctx, cancel := context.WithTimeout(context.Background(), roamTimeout)
+	defer cancel()
+	start := time.Now()
+	roam, err := org.OpenRoam(ctx, path, library)
+	if err != nil {
+		log.Fatal("Invalid -roam-db flag", "error", err)
+	}
+	log.Info("Read org-roam database", "path", path, "took", time.Since(start))
+	return roam
+}
+
+// refreshRoam reads the org-roam database again if org-roam changed it or
+// the org files were listed anew. Sessions go on with what was read last
+// if it fails.
+func refreshRoam(roam *org.Roam) {
+	ctx, cancel := context.WithTimeout(context.Background(), roamTimeout)
+	defer cancel()
+	start := time.Now()
+	if changed, err := roam.Refresh(ctx); err != nil {
+		log.Error("Failed to read org-roam database", "error", err)
+	} else if changed {
+		log.Debug("Read org-roam database again", "took", time.Since(start))
+	}
+}
@@ -0,0 +1,209 @@
+// Code generated by protoc-gen-go-grpc. DO NOT EDIT.
+// versions:
+// - protoc-gen-go-grpc v1.5.1
+// - protoc             (unknown)
+// source: orgcharm.proto
+
+package rpc
+
+import (
+	context "context"
+	grpc "google.golang.org/grpc"
+	codes "google.golang.org/grpc/codes"
+	status "google.golang.org/grpc/status"
+)
+
+// This is a compile-time assertion to ensure that this generated file
+// is compatible with the grpc package it is being compiled against.
+// Requires gRPC-Go v1.64.0 or later.
+const _ = grpc.SupportPackageIsVersion9
+
+const (
+	OrgCharm_ListFiles_FullMethodName      = "/orgcharm.v1.OrgCharm/ListFiles"
+	OrgCharm_GetDocument_FullMethodName    = "/orgcharm.v1.OrgCharm/GetDocument"
+	OrgCharm_RenderDocument_FullMethodName = "/orgcharm.v1.OrgCharm/RenderDocument"
+)
+
+// OrgCharmClient is the client API for OrgCharm service.
+//
+// For semantics around ctx use and closing/ending streaming RPCs, please refer to https://pkg.go.dev/google.golang.org/grpc/?tab=doc#ClientConn.NewStream.
+//
+// OrgCharm gives other services the org files org-charm serves, their
+// parsed structure and their renderings for terminals.
+type OrgCharmClient interface {
+	// ListFiles lists the org files and directories of the org directory.
+	ListFiles(ctx context.Context, in *ListFilesRequest, opts ...grpc.CallOption) (*ListFilesResponse, error)
+	// GetDocument returns the structure of a parsed org file.
+	GetDocument(ctx context.Context, in *GetDocumentRequest, opts ...grpc.CallOption) (*Document, error)
+	// RenderDocument renders an org file to ANSI text as sessions show it.
+	RenderDocument(ctx context.Context, in *RenderDocumentRequest, opts ...grpc.CallOption) (*RenderDocumentResponse, error)
+}
+
+type orgCharmClient struct {
+	cc grpc.ClientConnInterface
+}
+
+func NewOrgCharmClient(cc grpc.ClientConnInterface) OrgCharmClient {
+	return &orgCharmClient{cc}
+}
+
+func (c *orgCharmClient) ListFiles(ctx context.Context, in *ListFilesRequest, opts ...grpc.CallOption) (*ListFilesResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(ListFilesResponse)
+	err := c.cc.Invoke(ctx, OrgCharm_ListFiles_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *orgCharmClient) GetDocument(ctx context.Context, in *GetDocumentRequest, opts ...grpc.CallOption) (*Document, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(Document)
+	err := c.cc.Invoke(ctx, OrgCharm_GetDocument_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *orgCharmClient) RenderDocument(ctx context.Context, in *RenderDocumentRequest, opts ...grpc.CallOption) (*RenderDocumentResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(RenderDocumentResponse)
+	err := c.cc.Invoke(ctx, OrgCharm_RenderDocument_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// OrgCharmServer is the server API for OrgCharm service.
+// All implementations must embed UnimplementedOrgCharmServer
+// for forward compatibility.
+//
+// OrgCharm gives other services the org files org-charm serves, their
+// parsed structure and their renderings for terminals.
+type OrgCharmServer interface {
+	// ListFiles lists the org files and directories of the org directory.
+	ListFiles(context.Context, *ListFilesRequest) (*ListFilesResponse, error)
+	// GetDocument returns the structure of a parsed org file.
+	GetDocument(context.Context, *GetDocumentRequest) (*Document, error)
+	// RenderDocument renders an org file to ANSI text as sessions show it.
+	RenderDocument(context.Context, *RenderDocumentRequest) (*RenderDocumentResponse, error)
+	mustEmbedUnimplementedOrgCharmServer()
+}
+
+// UnimplementedOrgCharmServer must be embedded to have
+// forward compatible implementations.
+//
+// NOTE: this should be embedded by value instead of pointer to avoid a nil
+// pointer dereference when methods are called.
+type UnimplementedOrgCharmServer struct{}
+
+func (UnimplementedOrgCharmServer) ListFiles(context.Context, *ListFilesRequest) (*ListFilesResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method ListFiles not implemented")
+}
+func (UnimplementedOrgCharmServer) GetDocument(context.Context, *GetDocumentRequest) (*Document, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method GetDocument not implemented")
+}
+func (UnimplementedOrgCharmServer) RenderDocument(context.Context, *RenderDocumentRequest) (*RenderDocumentResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method RenderDocument not implemented")
+}
+func (UnimplementedOrgCharmServer) mustEmbedUnimplementedOrgCharmServer() {}
+func (UnimplementedOrgCharmServer) testEmbeddedByValue()                  {}
+
+// UnsafeOrgCharmServer may be embedded to opt out of forward compatibility for this service.
+// Use of this interface is not recommended, as added methods to OrgCharmServer will
+// result in compilation errors.
+type UnsafeOrgCharmServer interface {
+	mustEmbedUnimplementedOrgCharmServer()
+}
+
+func RegisterOrgCharmServer(s grpc.ServiceRegistrar, srv OrgCharmServer) {
+	// If the following call pancis, it indicates UnimplementedOrgCharmServer was
+	// embedded by pointer and is nil.  This will cause panics if an
+	// unimplemented method is ever invoked, so we test this at initialization
+	// time to prevent it from happening at runtime later due to I/O.
+	if t, ok := srv.(interface{ testEmbeddedByValue() }); ok {
+		t.testEmbeddedByValue()
+	}
+	s.RegisterService(&OrgCharm_ServiceDesc, srv)
+}
+
+func _OrgCharm_ListFiles_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ListFilesRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(OrgCharmServer).ListFiles(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: OrgCharm_ListFiles_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(OrgCharmServer).ListFiles(ctx, req.(*ListFilesRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _OrgCharm_GetDocument_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(GetDocumentRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(OrgCharmServer).GetDocument(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: OrgCharm_GetDocument_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(OrgCharmServer).GetDocument(ctx, req.(*GetDocumentRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _OrgCharm_RenderDocument_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(RenderDocumentRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(OrgCharmServer).RenderDocument(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: OrgCharm_RenderDocument_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(OrgCharmServer).RenderDocument(ctx, req.(*RenderDocumentRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+// OrgCharm_ServiceDesc is the grpc.ServiceDesc for OrgCharm service.
+// It's only intended for direct use with grpc.RegisterService,
+// and not to be introspected or modified (even as a copy)
+var OrgCharm_ServiceDesc = grpc.ServiceDesc{
+	ServiceName: "orgcharm.v1.OrgCharm",
+	HandlerType: (*OrgCharmServer)(nil),
+	Methods: []grpc.MethodDesc{
+		{
+			MethodName: "ListFiles",
+			Handler:    _OrgCharm_ListFiles_Handler,
+		},
+		{
+			MethodName: "GetDocument",
+			Handler:    _OrgCharm_GetDocument_Handler,
+		},
+		{
+			MethodName: "RenderDocument",
+			Handler:    _OrgCharm_RenderDocument_Handler,
+		},
+	},
+	Streams:  []grpc.StreamDesc{},
+	Metadata: "orgcharm.proto",
+}
@@ -0,0 +1,202 @@
+// Package rpc serves the org files over gRPC, for other services to list
+// them, read their structure and render them with org-charm's renderer
+package rpc
+
+//go:generate protoc --go_out=. --go_opt=paths=source_relative --go-grpc_out=. --go-grpc_opt=paths=source_relative orgcharm.proto
+
+import (
+	"context"
+	"errors"
+	"io/fs"
+	"path/filepath"
+
+	"org-charm/export"
+	"org-charm/org"
+	"org-charm/ui"
+
+	"github.com/muesli/termenv"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+// defaultWidth is the width documents are rendered at when a request
+// doesn't set one, and maxWidth the widest they're rendered at
+const (
+	defaultWidth = 80
+	maxWidth     = 1000
+)
+
+// profiles maps the color profiles of requests to termenv's
+var profiles = map[ColorProfile]termenv.Profile{
+	ColorProfile_COLOR_PROFILE_TRUE_COLOR: termenv.TrueColor,
+	ColorProfile_COLOR_PROFILE_ANSI256:    termenv.ANSI256,
+	ColorProfile_COLOR_PROFILE_ANSI:       termenv.ANSI,
+	ColorProfile_COLOR_PROFILE_ASCII:      termenv.Ascii,
+}
+
+// Server implements the OrgCharm service over the org files of a library
+type Server struct {
+	UnimplementedOrgCharmServer
+
+	library *org.Library
+	render  ui.RenderOptions // Rendering settings of the server, which requests override
+}
+
+// NewServer creates a server for the org files of library, rendering
+// them with the settings of render unless requests set them
+func NewServer(library *org.Library, render ui.RenderOptions) *Server {
+	render.Root = library.Root()
+	return &Server{library: library, render: render}
+}
+
+// ListFiles lists the org files and directories of the library
+func (s *Server) ListFiles(ctx context.Context, req *ListFilesRequest) (*ListFilesResponse, error) {
+	tree, _ := s.library.Tree()
+	resp := &ListFilesResponse{}
+	var list func(entries []*org.FileEntry)
+	list = func(entries []*org.FileEntry) {
+		for _, e := range entries {
+			file := &File{
+				Path:     filepath.ToSlash(e.RelPath),
+				IsDir:    e.IsDir,
+				Modified: timestamppb.New(e.ModTime),
+			}
+			if !e.IsDir {
+				header := e.Header()
+				file.Title = e.Title()
+				file.Date = header.Date
+				file.Tags = header.FileTags
+			}
+			resp.Files = append(resp.Files, file)
+			list(e.Children)
+		}
+	}
+	list(tree)
+	return resp, nil
+}
+
+// GetDocument returns the structure of an org file
+func (s *Server) GetDocument(ctx context.Context, req *GetDocumentRequest) (*Document, error) {
+	doc, err := s.open(req.GetPath())
+	if err != nil {
+		return nil, err
+	}
+	structure := export.Structure(doc)
+	return &Document{
+		File:       structure.File,
+		Title:      structure.Title,
+		Author:     structure.Author,
+		Date:       structure.Date,
+		Tags:       structure.Tags,
+		Body:       structure.Body,
+		Timestamps: timestamps(structure.Timestamps),
+		Links:      links(structure.Links),
+		Headings:   headings(structure.Headings),
+	}, nil
+}
+
+// RenderDocument renders an org file to ANSI text
+func (s *Server) RenderDocument(ctx context.Context, req *RenderDocumentRequest) (*RenderDocumentResponse, error) {
+	options := s.render
+	options.Width = int(req.GetWidth())
+	if options.Width == 0 {
+		options.Width = defaultWidth
+	}
+	if options.Width < ui.MinRenderWidth || options.Width > maxWidth {
+		return nil, status.Errorf(codes.InvalidArgument, "width must be between %d and %d", ui.MinRenderWidth, maxWidth)
+	}
+	profile, ok := profiles[req.GetProfile()]
+	if !ok {
+		return nil, status.Errorf(codes.InvalidArgument, "unknown color profile %v", req.GetProfile())
+	}
+	options.Profile = profile
+	if style := req.GetChromaStyle(); style != "" {
+		if err := ui.CheckChromaStyle(style); err != nil {
+			return nil, status.Error(codes.InvalidArgument, err.Error())
+		}
+		options.ChromaStyle = style
+	}
+	options.Hyperlinks = req.GetHyperlinks()
+
+	doc, err := s.open(req.GetPath())
+	if err != nil {
+		return nil, err
+	}
+	ansi, err := ui.RenderANSI(ctx, doc, options)
+	if err != nil {
+		return nil, status.FromContextError(err).Err()
+	}
+	return &RenderDocumentResponse{Ansi: ansi}, nil
+}
+
+// open loads the org file at path, with the status of the error for
+// paths that aren't org files or don't exist
+func (s *Server) open(path string) (*org.OrgFile, error) {
+	doc, err := s.library.Open(path)
+	switch {
+	case errors.Is(err, org.ErrNotOrgFile):
+		return nil, status.Error(codes.InvalidArgument, err.Error())
+	case errors.Is(err, fs.ErrNotExist):
+		return nil, status.Errorf(codes.NotFound, "%s: no such org file", path)
+	case err != nil:
+		return nil, status.Error(codes.Internal, err.Error())
+	}
+	return doc, nil
+}
+
+// headings converts the headings of a document structure
+func headings(hs []export.Heading) []*Heading {
+	var out []*Heading
+	for _, h := range hs {
+		out = append(out, &Heading{
+			Level:      int32(h.Level),
+			Title:      h.Title,
+			Status:     h.Status,
+			Priority:   h.Priority,
+			Tags:       h.Tags,
+			Properties: h.Properties,
+			Scheduled:  timestamp(h.Scheduled),
+			Deadline:   timestamp(h.Deadline),
+			Closed:     timestamp(h.Closed),
+			Body:       h.Body,
+			Timestamps: timestamps(h.Timestamps),
+			Links:      links(h.Links),
+			Children:   headings(h.Children),
+		})
+	}
+	return out
+}
+
+// timestamps converts the timestamps of a document structure
+func timestamps(ts []export.Timestamp) []*Timestamp {
+	var out []*Timestamp
+	for i := range ts {
+		out = append(out, timestamp(&ts[i]))
+	}
+	return out
+}
+
+// timestamp converts a timestamp of a document structure, if set
+func timestamp(ts *export.Timestamp) *Timestamp {
+	if ts == nil {
+		return nil
+	}
+	return &Timestamp{
+		Text:     ts.Text,
+		Active:   ts.Active,
+		Start:    ts.Start,
+		End:      ts.End,
+		Repeater: ts.Repeater,
+		Warning:  ts.Warning,
+	}
+}
+
+// links converts the links of a document structure
+func links(ls []export.Link) []*Link {
+	var out []*Link
+	for _, l := range ls {
+		out = append(out, &Link{Url: l.URL, Description: l.Description, Kind: l.Kind})
+	}
+	return out
+}
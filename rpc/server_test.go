@@ -0,0 +1,116 @@
+package rpc
+
+import (
+	"context"
+	"net"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"org-charm/org"
+	"org-charm/ui"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/status"
+	"google.golang.org/grpc/test/bufconn"
+)
+
+func TestServer(t *testing.T) {
+	dir := t.TempDir()
+	if err := os.MkdirAll(filepath.Join(dir, "sub"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	files := map[string]string{
+		"todo.org":    "#+TITLE: Todo\n#+FILETAGS: :home:\n\n* TODO Taxes :money:\nDEADLINE: <2024-04-15 Mon>\nSee [[https://example.com][the site]].\n",
+		"sub/b.org":   "* B\nText.\n",
+		".hidden.org": "* Secret\n",
+	}
+	for name, content := range files {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	library, err := org.NewLibrary(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	lis := bufconn.Listen(1 << 20)
+	srv := grpc.NewServer()
+	RegisterOrgCharmServer(srv, NewServer(library, ui.RenderOptions{}))
+	go srv.Serve(lis)
+	defer srv.Stop()
+	conn, err := grpc.NewClient("passthrough:///bufnet",
+		grpc.WithContextDialer(func(ctx context.Context, _ string) (net.Conn, error) { return lis.DialContext(ctx) }),
+		grpc.WithTransportCredentials(insecure.NewCredentials()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+	client := NewOrgCharmClient(conn)
+	ctx := context.Background()
+
+	list, err := client.ListFiles(ctx, &ListFilesRequest{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var paths []string
+	for _, f := range list.Files {
+		paths = append(paths, f.Path)
+		if f.Path == "todo.org" && (f.Title != "Todo" || strings.Join(f.Tags, ",") != "home") {
+			t.Errorf("todo.org = %v", f)
+		}
+	}
+	if got := strings.Join(paths, " "); got != "sub sub/b.org todo.org" {
+		t.Errorf("files = %s", got)
+	}
+
+	doc, err := client.GetDocument(ctx, &GetDocumentRequest{Path: "todo.org"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(doc.Headings) != 1 {
+		t.Fatalf("headings = %v", doc.Headings)
+	}
+	h := doc.Headings[0]
+	if h.Title != "Taxes" || h.Status != "TODO" || h.Deadline.GetStart() != "2024-04-15" || len(h.Links) != 1 || h.Links[0].Url != "https://example.com" {
+		t.Errorf("heading = %v", h)
+	}
+
+	rendered, err := client.RenderDocument(ctx, &RenderDocumentRequest{Path: "todo.org", Width: 40, Profile: ColorProfile_COLOR_PROFILE_ASCII})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(rendered.Ansi, "Taxes") || strings.Contains(rendered.Ansi, "\x1b[") {
+		t.Errorf("ASCII rendering = %q", rendered.Ansi)
+	}
+	colored, err := client.RenderDocument(ctx, &RenderDocumentRequest{Path: "todo.org"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(colored.Ansi, "\x1b[") {
+		t.Errorf("true color rendering has no colors: %q", colored.Ansi)
+	}
+
+	for path, code := range map[string]codes.Code{
+		"missing.org":   codes.NotFound,
+		"../escape.org": codes.InvalidArgument,
+		".hidden.org":   codes.InvalidArgument,
+	} {
+		_, err := client.GetDocument(ctx, &GetDocumentRequest{Path: path})
+		if status.Code(err) != code {
+			t.Errorf("GetDocument(%q) = %v, want %v", path, err, code)
+		}
+	}
+	if _, err := client.RenderDocument(ctx, &RenderDocumentRequest{Path: "todo.org", ChromaStyle: "nope"}); status.Code(err) != codes.InvalidArgument {
+		t.Errorf("unknown chroma style = %v", err)
+	}
+	for _, width := range []int32{1, 3, -1, 1001} {
+		if _, err := client.RenderDocument(ctx, &RenderDocumentRequest{Path: "todo.org", Width: width}); status.Code(err) != codes.InvalidArgument {
+			t.Errorf("width %d = %v, want %v", width, err, codes.InvalidArgument)
+		}
+	}
+}
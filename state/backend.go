@@ -0,0 +1,244 @@
+package state
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+)
+
+// Backend persists the state of each user, encoded, by user id. Stores
+// cache what they load, so a backend is only read once per user; it must
+// be safe for concurrent use if several stores share it.
+type Backend interface {
+	// Load returns the state saved for id, or nil if there is none
+	Load(id string) ([]byte, error)
+	// Save replaces the state saved for id
+	Save(id string, data []byte) error
+}
+
+// dirBackend keeps the state of each user as a JSON file of a directory
+type dirBackend struct {
+	dir string
+}
+
+// NewDirBackend returns a backend keeping the state of each user as a
+// JSON file in dir, named after the user id, which must be usable as a
+// file name
+func NewDirBackend(dir string) (Backend, error) {
+	if err := os.MkdirAll(dir, 0o700); err != nil {
+		return nil, err
+	}
+	return dirBackend{dir}, nil
+}
+
+func (b dirBackend) Load(id string) ([]byte, error) {
+	if !validID(id) {
+		return nil, nil
+	}
+	data, err := os.ReadFile(b.path(id))
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	return data, err
+}
+
+func (b dirBackend) Save(id string, data []byte) error {
+	if !validID(id) {
+		return ErrInvalidID
+	}
+	// Write to a temporary file first so a crash never leaves a torn file
+	tmp := b.path(id) + ".tmp"
+	if err := os.WriteFile(tmp, data, 0o600); err != nil {
+		return err
+	}
+	return os.Rename(tmp, b.path(id))
+}
+
+// path returns the state file of a user
+func (b dirBackend) path(id string) string {
+	return filepath.Join(b.dir, id+".json")
+}
+
+// validID reports whether id is safe to use as a file name
+func validID(id string) bool {
+	return id != "" && !strings.ContainsAny(id, `/\.`)
+}
+
+// DB is a backend keeping the state of all users in a single file, to back
+// up or sync as one. Saves are appended to the file, which is rewritten
+// with the latest state of each user once it has more superseded saves
+// than current ones, as it's opened or saved to. Only one process may have
+// a file open.
+//
+// The file is a log of JSON lines rather than a bolt or SQLite database as
+// servers are built without cgo, into a single static binary, and neither
+// a pure Go SQLite nor bolt is among the dependencies; the state of a user
+// is small and only read once per session, which a log serves well.
+type DB struct {
+	mu    sync.Mutex
+	path  string
+	file  *os.File
+	users map[string][]byte // Latest state by user id
+	stale int               // Superseded saves in the file
+	torn  bool              // The file has a save torn by a crash
+}
+
+// dbCompactMin is the number of superseded saves a DB file may have before
+// it's rewritten while open, so that databases of a few users aren't
+// rewritten on every other save
+const dbCompactMin = 100
+
+// dbRecord is a line of a DB file: the state of a user as of a save
+type dbRecord struct {
+	ID    string          `json:"id"`
+	State json.RawMessage `json:"state"`
+}
+
+// OpenDB opens the state database at path, creating it if needed
+func OpenDB(path string) (*DB, error) {
+	if err := os.MkdirAll(filepath.Dir(path), 0o700); err != nil {
+		return nil, err
+	}
+	db := &DB{path: path, users: map[string][]byte{}}
+	if err := db.read(); err != nil {
+		return nil, err
+	}
+	if db.stale > len(db.users) || db.torn {
+		if err := db.compact(); err != nil {
+			return nil, err
+		}
+	}
+	file, err := os.OpenFile(path, os.O_WRONLY|os.O_APPEND|os.O_CREATE, 0o600)
+	if err != nil {
+		return nil, err
+	}
+	db.file = file
+	return db, nil
+}
+
+// read loads the latest state of each user from the file
+func (db *DB) read() error {
+	file, err := os.Open(db.path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	scanner := bufio.NewScanner(file)
+	scanner.Buffer(nil, 64<<20)
+	for scanner.Scan() {
+		var record dbRecord
+		if err := json.Unmarshal(scanner.Bytes(), &record); err != nil || record.ID == "" {
+			// A save torn by a crash; the one before it stands
+			db.torn = true
+			continue
+		}
+		if _, ok := db.users[record.ID]; ok {
+			db.stale++
+		}
+		db.users[record.ID] = bytes.Clone(record.State)
+	}
+	return scanner.Err()
+}
+
+// compact rewrites the file with only the latest state of each user,
+// through a temporary file so a crash never loses saves
+func (db *DB) compact() error {
+	tmp := db.path + ".tmp"
+	file, err := os.OpenFile(tmp, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0o600)
+	if err != nil {
+		return err
+	}
+	w := bufio.NewWriter(file)
+	for id, data := range db.users {
+		line, err := json.Marshal(dbRecord{id, data})
+		if err != nil {
+			file.Close()
+			return err
+		}
+		w.Write(append(line, '\n'))
+	}
+	if err := w.Flush(); err != nil {
+		file.Close()
+		return err
+	}
+	if err := file.Sync(); err != nil {
+		file.Close()
+		return err
+	}
+	if err := file.Close(); err != nil {
+		return err
+	}
+	db.stale, db.torn = 0, false
+	return os.Rename(tmp, db.path)
+}
+
+func (db *DB) Load(id string) ([]byte, error) {
+	db.mu.Lock()
+	defer db.mu.Unlock()
+	return bytes.Clone(db.users[id]), nil
+}
+
+func (db *DB) Save(id string, data []byte) error {
+	if id == "" {
+		return ErrInvalidID
+	}
+	// One line per save: compact the indented encoding stores produce
+	var state bytes.Buffer
+	if err := json.Compact(&state, data); err != nil {
+		return err
+	}
+	line, err := json.Marshal(dbRecord{id, state.Bytes()})
+	if err != nil {
+		return err
+	}
+
+	db.mu.Lock()
+	defer db.mu.Unlock()
+	if _, err := db.file.Write(append(line, '\n')); err != nil {
+		return err
+	}
+	if err := db.file.Sync(); err != nil {
+		return err
+	}
+	if _, ok := db.users[id]; ok {
+		db.stale++
+	}
+	db.users[id] = state.Bytes()
+	if db.stale > max(len(db.users), dbCompactMin) {
+		// The save is in the file either way; a failed compaction is
+		// tried again on the next save
+		db.recompact()
+	}
+	return nil
+}
+
+// recompact compacts the file of the open database, and reopens it for the
+// saves to follow
+func (db *DB) recompact() error {
+	if err := db.compact(); err != nil {
+		return err
+	}
+	file, err := os.OpenFile(db.path, os.O_WRONLY|os.O_APPEND|os.O_CREATE, 0o600)
+	if err != nil {
+		return err
+	}
+	db.file.Close()
+	db.file = file
+	return nil
+}
+
+// Close closes the file of the database
+func (db *DB) Close() error {
+	db.mu.Lock()
+	defer db.mu.Unlock()
+	return db.file.Close()
+}
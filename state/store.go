@@ -0,0 +1,215 @@
+package state
+
+import (
+	"encoding/json"
+	"errors"
+	"maps"
+	"slices"
+	"sync"
+)
+
+// MaxRecent is how many recently viewed documents are remembered per user
+const MaxRecent = 10
+
+// ErrInvalidID is returned for user ids that can't be used as file names
+var ErrInvalidID = errors.New("invalid user id")
+
+// User holds the persisted state of one user
+type User struct {
+	Recent  []string           `json:"recent,omitempty"`  // Recently viewed documents, newest first (paths relative to the org directory)
+	Pins    []string           `json:"pins,omitempty"`    // Pinned documents, in the order they were pinned
+	Reading map[string]Reading `json:"reading,omitempty"` // Reading positions by document
+	Session *Session           `json:"session,omitempty"` // UI state of the last session
+
+	// Marks set in documents, by document and then mark name (a-z), as
+	// offsets of the rendered view
+	Marks map[string]map[string]int `json:"marks,omitempty"`
+
+	// Viewing preferences, once the user changed one
+	Preferences *Preferences `json:"preferences,omitempty"`
+}
+
+// Reading records how far a user got in a document
+type Reading struct {
+	Offset   int     `json:"offset"`   // Last scroll offset of the rendered view
+	Progress float64 `json:"progress"` // Furthest fraction of the document seen (0 to 1)
+}
+
+// Session is the UI state of a session, restored when the user reconnects.
+// The file list has no filters, so its sort order and folders are all of
+// the list there is to restore.
+type Session struct {
+	Document string   `json:"document,omitempty"` // Open document (relative path), empty in the file list
+	Raw      bool     `json:"raw,omitempty"`      // Document shown as raw source
+	Offset   int      `json:"offset,omitempty"`   // Scroll offset of the document view
+	Selected string   `json:"selected,omitempty"` // Selected file list entry (relative path)
+	Expanded []string `json:"expanded,omitempty"` // Expanded directories (relative paths)
+	Sort     string   `json:"sort,omitempty"`     // File list sort order, as accepted by org.ParseSortOrder
+}
+
+// Preferences are how a user likes documents shown, kept across sessions
+type Preferences struct {
+	Motion        string `json:"motion,omitempty"` // Animation setting, as accepted by ui.ParseMotion
+	ZebraTables   bool   `json:"zebra_tables,omitempty"`
+	ShowHeaders   bool   `json:"show_headers,omitempty"` // Babel header arguments of source blocks
+	LineNumbers   bool   `json:"line_numbers,omitempty"`
+	NoWrap        bool   `json:"no_wrap,omitempty"` // Scroll wide code and tables instead of wrapping them
+	ReadingColumn bool   `json:"reading_column,omitempty"`
+}
+
+// Equal reports whether s and o describe the same state
+func (s Session) Equal(o Session) bool {
+	return s.Document == o.Document && s.Raw == o.Raw && s.Offset == o.Offset &&
+		s.Selected == o.Selected && s.Sort == o.Sort && slices.Equal(s.Expanded, o.Expanded)
+}
+
+// Store keeps per-user state in memory and, when it has a backend,
+// persists each user's state in it so it survives restarts.
+// It is safe for concurrent use by all sessions.
+type Store struct {
+	mu      sync.Mutex
+	backend Backend // Nil keeps state in memory
+	users   map[string]*User
+}
+
+// NewStore creates a store persisting each user's state as a JSON file in
+// dir. An empty dir keeps state in memory only, for the lifetime of the
+// server.
+func NewStore(dir string) (*Store, error) {
+	if dir == "" {
+		return NewBackendStore(nil), nil
+	}
+	backend, err := NewDirBackend(dir)
+	if err != nil {
+		return nil, err
+	}
+	return NewBackendStore(backend), nil
+}
+
+// NewBackendStore creates a store persisting to backend, or keeping state
+// in memory only if it's nil
+func NewBackendStore(backend Backend) *Store {
+	return &Store{
+		backend: backend,
+		users:   make(map[string]*User),
+	}
+}
+
+// Get returns a copy of the state of the user with the given id
+func (s *Store) Get(id string) User {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.load(id).clone()
+}
+
+// Update applies fn to the state of the user with the given id and
+// persists the result
+func (s *Store) Update(id string, fn func(*User)) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	u := s.load(id)
+	fn(u)
+	return s.save(id, u)
+}
+
+// load returns the cached state of a user, reading it from the backend on
+// first access. Callers must hold s.mu.
+func (s *Store) load(id string) *User {
+	if u, ok := s.users[id]; ok {
+		return u
+	}
+	u := &User{}
+	if s.backend != nil {
+		// Missing or unreadable state starts the user with empty state
+		if data, err := s.backend.Load(id); err == nil && data != nil {
+			_ = json.Unmarshal(data, u)
+		}
+	}
+	s.users[id] = u
+	return u
+}
+
+// save writes a user's state to the backend. Callers must hold s.mu.
+func (s *Store) save(id string, u *User) error {
+	if s.backend == nil {
+		return nil
+	}
+	data, err := json.MarshalIndent(u, "", "  ")
+	if err != nil {
+		return err
+	}
+	return s.backend.Save(id, data)
+}
+
+// clone returns a deep copy of u
+func (u *User) clone() User {
+	c := User{
+		Recent:  slices.Clone(u.Recent),
+		Pins:    slices.Clone(u.Pins),
+		Reading: maps.Clone(u.Reading),
+	}
+	if u.Session != nil {
+		session := *u.Session
+		session.Expanded = slices.Clone(session.Expanded)
+		c.Session = &session
+	}
+	if u.Marks != nil {
+		c.Marks = make(map[string]map[string]int, len(u.Marks))
+		for path, marks := range u.Marks {
+			c.Marks[path] = maps.Clone(marks)
+		}
+	}
+	if u.Preferences != nil {
+		preferences := *u.Preferences
+		c.Preferences = &preferences
+	}
+	return c
+}
+
+// AddRecent moves path to the front of the recently viewed list
+func (u *User) AddRecent(path string) {
+	u.Recent = slices.DeleteFunc(u.Recent, func(p string) bool { return p == path })
+	u.Recent = slices.Insert(u.Recent, 0, path)
+	if len(u.Recent) > MaxRecent {
+		u.Recent = u.Recent[:MaxRecent]
+	}
+}
+
+// IsPinned reports whether path is pinned
+func (u User) IsPinned(path string) bool {
+	return slices.Contains(u.Pins, path)
+}
+
+// TogglePin pins path, or unpins it if it was pinned, and reports whether
+// it is pinned now
+func (u *User) TogglePin(path string) bool {
+	if u.IsPinned(path) {
+		u.Pins = slices.DeleteFunc(u.Pins, func(p string) bool { return p == path })
+		return false
+	}
+	u.Pins = append(u.Pins, path)
+	return true
+}
+
+// SetReading records the reading position in the document at path. The
+// progress only ever grows: scrolling back up doesn't unread a document.
+func (u *User) SetReading(path string, offset int, progress float64) {
+	if u.Reading == nil {
+		u.Reading = make(map[string]Reading)
+	}
+	r := u.Reading[path]
+	r.Offset = offset
+	r.Progress = max(r.Progress, min(progress, 1))
+	u.Reading[path] = r
+}
+
+// SetMark records a mark of the document at path
+func (u *User) SetMark(path, name string, offset int) {
+	if u.Marks == nil {
+		u.Marks = make(map[string]map[string]int)
+	}
+	if u.Marks[path] == nil {
+		u.Marks[path] = make(map[string]int)
+	}
+	u.Marks[path][name] = offset
+}
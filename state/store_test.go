@@ -0,0 +1,177 @@
+package state
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestAddRecent(t *testing.T) {
+	var u User
+	for i := 0; i < MaxRecent+2; i++ {
+		u.AddRecent(fmt.Sprintf("%d.org", i))
+	}
+	u.AddRecent("5.org")
+
+	if len(u.Recent) != MaxRecent {
+		t.Fatalf("len(Recent) = %d, want %d", len(u.Recent), MaxRecent)
+	}
+	if u.Recent[0] != "5.org" || u.Recent[1] != "11.org" {
+		t.Errorf("Recent = %v, want 5.org then 11.org first", u.Recent)
+	}
+	for _, p := range u.Recent[1:] {
+		if p == "5.org" {
+			t.Errorf("Recent contains a duplicate: %v", u.Recent)
+		}
+	}
+}
+
+func TestTogglePin(t *testing.T) {
+	var u User
+	if !u.TogglePin("a.org") || !u.TogglePin("b.org") {
+		t.Fatal("TogglePin on unpinned paths should report pinned")
+	}
+	if u.TogglePin("a.org") {
+		t.Error("TogglePin on a pinned path should report unpinned")
+	}
+	if u.IsPinned("a.org") || !u.IsPinned("b.org") {
+		t.Errorf("Pins = %v, want [b.org]", u.Pins)
+	}
+}
+
+func TestStorePersists(t *testing.T) {
+	dir := t.TempDir()
+
+	s, err := NewStore(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	session := Session{Document: "notes.org", Offset: 42, Expanded: []string{"projects"}}
+	err = s.Update("abc123", func(u *User) {
+		u.AddRecent("notes.org")
+		u.Session = &session
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// A new store (e.g. after a restart) reads the state back from disk
+	s2, err := NewStore(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := s2.Get("abc123").Recent; len(got) != 1 || got[0] != "notes.org" {
+		t.Errorf("Recent after reload = %v, want [notes.org]", got)
+	}
+	if got := s2.Get("abc123").Session; got == nil || !got.Equal(session) {
+		t.Errorf("Session after reload = %+v, want %+v", got, session)
+	}
+
+	if err := s.Update("../escape", func(u *User) {}); err != ErrInvalidID {
+		t.Errorf("Update with invalid id: err = %v, want ErrInvalidID", err)
+	}
+}
+
+func TestSetReading(t *testing.T) {
+	var u User
+	u.SetReading("a.org", 40, 0.5)
+	u.SetReading("a.org", 10, 0.2)
+
+	r := u.Reading["a.org"]
+	if r.Offset != 10 {
+		t.Errorf("Offset = %d, want the last offset 10", r.Offset)
+	}
+	if r.Progress != 0.5 {
+		t.Errorf("Progress = %v, want the furthest progress 0.5", r.Progress)
+	}
+
+	u.SetReading("a.org", 0, 1.5)
+	if u.Reading["a.org"].Progress != 1 {
+		t.Errorf("Progress = %v, want it capped at 1", u.Reading["a.org"].Progress)
+	}
+}
+
+func TestDB(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "state", "users.db")
+	db, err := OpenDB(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	s := NewBackendStore(db)
+	for i := range 5 {
+		err := s.Update("abc123", func(u *User) {
+			u.SetReading("notes.org", i*10, 0.1*float64(i))
+			u.SetMark("notes.org", "a", i)
+			u.Preferences = &Preferences{Motion: "off", ZebraTables: true}
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := s.Update("../any id", func(u *User) { u.AddRecent("a.org") }); err != nil {
+		t.Errorf("Update with an id that isn't a file name: %v", err)
+	}
+	if err := db.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	// A crash tears the last save
+	file, err := os.OpenFile(path, os.O_WRONLY|os.O_APPEND, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	file.WriteString(`{"id":"abc123","state":{"rec`)
+	file.Close()
+
+	db, err = OpenDB(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+	u := NewBackendStore(db).Get("abc123")
+	if r := u.Reading["notes.org"]; r.Offset != 40 {
+		t.Errorf("Reading after reopening = %+v, want the last saved", r)
+	}
+	if u.Marks["notes.org"]["a"] != 4 || u.Preferences == nil || !u.Preferences.ZebraTables {
+		t.Errorf("marks and preferences after reopening = %v, %+v", u.Marks, u.Preferences)
+	}
+	if got := NewBackendStore(db).Get("../any id").Recent; len(got) != 1 {
+		t.Errorf("Recent of the other user = %v", got)
+	}
+
+	// Reopening dropped the superseded and torn saves
+	content, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if lines := strings.Count(string(content), "\n"); lines != 2 {
+		t.Errorf("file has %d lines after compaction, want 2", lines)
+	}
+
+	// An open database compacts its file as saves pile up
+	for i := range 3 * dbCompactMin {
+		if err := db.Save("abc123", []byte(fmt.Sprintf(`{"recent":["%d.org"]}`, i))); err != nil {
+			t.Fatal(err)
+		}
+	}
+	content, err = os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if lines := strings.Count(string(content), "\n"); lines > dbCompactMin+2 {
+		t.Errorf("file has %d lines after %d saves, want it compacted", lines, 3*dbCompactMin)
+	}
+	if got := NewBackendStore(db).Get("abc123").Recent; len(got) != 1 || got[0] != fmt.Sprintf("%d.org", 3*dbCompactMin-1) {
+		t.Errorf("Recent after compacting = %v, want the last saved", got)
+	}
+	db.Close()
+	if db, err = OpenDB(path); err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+	if got := NewBackendStore(db).Get("abc123").Recent; len(got) != 1 || got[0] != fmt.Sprintf("%d.org", 3*dbCompactMin-1) {
+		t.Errorf("Recent after compacting and reopening = %v, want the last saved", got)
+	}
+}
@@ -0,0 +1,108 @@
+package ui
+
+import (
+	"cmp"
+	"slices"
+	"sync"
+	"time"
+
+	"org-charm/org"
+)
+
+// SessionStats is what a session has cost the server so far
+type SessionStats struct {
+	ID      uint64
+	User    string
+	Term    string
+	Started time.Time
+
+	Document   string        // Name of the open document, if any
+	HeldBytes  int64         // Size of the rendering of the open document the session holds
+	Renders    int           // Renders of documents, chunks of long ones included
+	RenderTime time.Duration // Time spent rendering documents
+
+	// Slowest render, and the document it was of
+	SlowestRender   time.Duration
+	SlowestDocument string
+}
+
+// sessionAccount keeps the stats of a session. Copies of the model share
+// it, renders in the background included.
+type sessionAccount struct {
+	mu    sync.Mutex
+	stats SessionStats
+}
+
+// sessions registers the accounts of the sessions running, by id
+var sessions = struct {
+	mu       sync.Mutex
+	next     uint64
+	accounts map[uint64]*sessionAccount
+}{accounts: map[uint64]*sessionAccount{}}
+
+// TrackSession registers the session of m, of user on a term terminal,
+// for its stats to show in Sessions, and returns the function ending it,
+// which returns its final stats
+func TrackSession(m Model, user, term string) (end func() SessionStats) {
+	sessions.mu.Lock()
+	sessions.next++
+	id := sessions.next
+	sessions.accounts[id] = m.account
+	sessions.mu.Unlock()
+
+	m.account.mu.Lock()
+	m.account.stats.ID, m.account.stats.User, m.account.stats.Term = id, user, term
+	m.account.stats.Started = time.Now()
+	m.account.mu.Unlock()
+
+	return func() SessionStats {
+		sessions.mu.Lock()
+		delete(sessions.accounts, id)
+		sessions.mu.Unlock()
+		return m.account.snapshot()
+	}
+}
+
+// Sessions returns the stats of the sessions running, those spending the
+// most time rendering first
+func Sessions() []SessionStats {
+	sessions.mu.Lock()
+	stats := make([]SessionStats, 0, len(sessions.accounts))
+	for _, account := range sessions.accounts {
+		stats = append(stats, account.snapshot())
+	}
+	sessions.mu.Unlock()
+	slices.SortFunc(stats, func(a, b SessionStats) int {
+		return cmp.Or(cmp.Compare(b.RenderTime, a.RenderTime), cmp.Compare(a.ID, b.ID))
+	})
+	return stats
+}
+
+// snapshot returns a copy of the stats
+func (a *sessionAccount) snapshot() SessionStats {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	return a.stats
+}
+
+// rendered counts a render of doc that took took
+func (a *sessionAccount) rendered(doc *org.OrgFile, took time.Duration) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	a.stats.Renders++
+	a.stats.RenderTime += took
+	if took > a.stats.SlowestRender {
+		a.stats.SlowestRender, a.stats.SlowestDocument = took, doc.Name
+	}
+}
+
+// hold records the document open and the bytes of its rendering held,
+// nil and 0 once it's closed
+func (a *sessionAccount) hold(doc *org.OrgFile, bytes int64) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	a.stats.Document, a.stats.HeldBytes = "", bytes
+	if doc != nil {
+		a.stats.Document = doc.Name
+	}
+}
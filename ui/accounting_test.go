@@ -0,0 +1,45 @@
+package ui
+
+import (
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+
+	"org-charm/org"
+)
+
+func TestSessionStats(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "notes.org")
+	if err := os.WriteFile(path, []byte("* Notes\nSome text.\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	doc, err := org.Load(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	m := NewModel(createTestRenderer(), dir, "", Options{})
+	m.width, m.height = 80, 24
+	m.resizeViewport()
+	end := TrackSession(m, "alice", "xterm")
+	m.openDocument(doc)
+
+	i := slices.IndexFunc(Sessions(), func(s SessionStats) bool { return s.User == "alice" })
+	if i < 0 {
+		t.Fatal("session not registered")
+	}
+	stats := Sessions()[i]
+	if stats.Document != doc.Name || stats.HeldBytes == 0 || stats.Renders != 1 || stats.SlowestDocument != doc.Name {
+		t.Errorf("stats after opening %s: %+v", doc.Name, stats)
+	}
+
+	m.closeDocument()
+	if final := end(); final.Document != "" || final.HeldBytes != 0 || final.Renders != 1 {
+		t.Errorf("final stats after closing the document: %+v", final)
+	}
+	if slices.ContainsFunc(Sessions(), func(s SessionStats) bool { return s.User == "alice" }) {
+		t.Error("session still registered after ending")
+	}
+}
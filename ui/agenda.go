@@ -0,0 +1,170 @@
+package ui
+
+import (
+	"path/filepath"
+	"strings"
+	"time"
+
+	"org-charm/org"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+	"github.com/charmbracelet/x/ansi"
+)
+
+const (
+	// agendaKey lists the scheduled entries and deadlines of the week
+	agendaKey = "a"
+
+	// agendaDays is the number of days the agenda shows, from today
+	agendaDays = 7
+
+	// agendaMaxShown is the number of agenda lines listed at once
+	agendaMaxShown = 14
+)
+
+// agenda is the state of the agenda overlay, listing the entries of all
+// the org files scheduled or due in the coming days
+type agenda struct {
+	open     bool
+	loading  bool
+	items    []org.AgendaItem
+	selected int
+}
+
+// agendaLoadedMsg reports the items of the agenda were gathered, unless it
+// failed with err
+type agendaLoadedMsg struct {
+	items []org.AgendaItem
+	err   error
+}
+
+// openAgenda shows the agenda overlay, gathering its items in the
+// background as the files may have to be parsed
+func (m *Model) openAgenda() tea.Cmd {
+	m.agenda = agenda{open: true, loading: true}
+	tree := m.fileTree
+	return func() tea.Msg {
+		items, err := org.Agenda(tree, time.Now(), agendaDays)
+		return agendaLoadedMsg{items, err}
+	}
+}
+
+// handleAgendaLoaded lists the items in the agenda overlay, or closes it
+// with the reason there are none to list
+func (m *Model) handleAgendaLoaded(msg agendaLoadedMsg) tea.Cmd {
+	if !m.agenda.open {
+		return nil
+	}
+	switch {
+	case msg.err != nil:
+		m.agenda = agenda{}
+		return m.setStatus("Could not gather the agenda: " + msg.err.Error())
+	case len(msg.items) == 0:
+		m.agenda = agenda{}
+		return m.setStatus("Nothing scheduled or due this week")
+	}
+	m.agenda.loading = false
+	m.agenda.items = msg.items
+	return nil
+}
+
+// handleAgendaKey moves the selection of the agenda overlay, or closes it,
+// opening the entry of the selected item with enter
+func (m *Model) handleAgendaKey(msg tea.KeyMsg) tea.Cmd {
+	a := &m.agenda
+	switch msg.String() {
+	case "up", "k", "ctrl+p":
+		if a.selected > 0 {
+			a.selected--
+		}
+	case "down", "j", "ctrl+n":
+		if a.selected < len(a.items)-1 {
+			a.selected++
+		}
+	case "home", "g":
+		a.selected = 0
+	case "end", "G":
+		a.selected = max(0, len(a.items)-1)
+	case "enter":
+		if a.loading {
+			return nil
+		}
+		a.open = false
+		return m.openAgendaItem(a.items[a.selected])
+	default:
+		a.open = false
+	}
+	return nil
+}
+
+// openAgendaItem opens the document of an agenda item at its headline
+func (m *Model) openAgendaItem(item org.AgendaItem) tea.Cmd {
+	entry := findEntryByPath(m.fileTree, filepath.Join(m.rootDir, filepath.FromSlash(item.File)))
+	if entry == nil {
+		return m.setStatus(item.File + " is not in the file list")
+	}
+	m.pendingAnchor = pendingAnchor{entry.Path, "*" + item.Entry.Title}
+	return m.openEntry(entry)
+}
+
+// agendaLine describes an agenda item, e.g. "09:00 DL: TODO Report"
+func agendaLine(item org.AgendaItem) string {
+	when := "     "
+	switch {
+	case item.Overdue():
+		when = item.Date.Format("01-02")
+	case item.HasTime:
+		when = item.Date.Format("15:04")
+	}
+	line := when + " " + item.Kind + ": "
+	if item.Entry.Status != "" {
+		line += item.Entry.Status + " "
+	}
+	return line + item.Entry.Title
+}
+
+// renderAgenda renders the agenda overlay over the screen
+func (m Model) renderAgenda() string {
+	a := m.agenda
+	width := min(m.width-8, 88)
+	var b strings.Builder
+
+	b.WriteString(m.styles.HelpKey.Render("Agenda"))
+	b.WriteString("\n\n")
+
+	if a.loading {
+		b.WriteString(m.styles.HelpText.Render("Gathering entries…"))
+	}
+
+	// Keep the selection in the window of shown items
+	start := max(0, a.selected-agendaMaxShown+1)
+	end := min(len(a.items), start+agendaMaxShown)
+	for i := start; i < end; i++ {
+		item := a.items[i]
+		if i == start || !item.Day.Equal(a.items[i-1].Day) {
+			if i > start {
+				b.WriteString("\n")
+			}
+			b.WriteString(m.styles.HelpText.Render(item.Day.Format("Monday 2 January")) + "\n")
+		}
+		meta := ansi.Truncate(item.File, width/3, "…")
+		line := ansi.Truncate(agendaLine(item), width-lipgloss.Width(meta)-4, "…")
+		gap := strings.Repeat(" ", max(1, width-2-lipgloss.Width(line)-lipgloss.Width(meta)))
+		if i == a.selected {
+			b.WriteString(m.styles.FileItemSelected.Render("▸ " + line))
+		} else {
+			b.WriteString(m.styles.FileItem.Render(line))
+		}
+		b.WriteString(gap + m.styles.HelpText.Render(meta))
+		if i < end-1 {
+			b.WriteString("\n")
+		}
+	}
+
+	b.WriteString("\n\n")
+	b.WriteString(m.styles.HelpText.Render("↑/↓ select • enter open • esc close"))
+
+	popup := m.styles.Sized(&m.styles.Popup, width+4).Render(b.String())
+	return lipgloss.Place(m.width, m.height, lipgloss.Center, lipgloss.Center, popup)
+}
@@ -0,0 +1,54 @@
+package ui
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+func TestAgendaOverlay(t *testing.T) {
+	dir := t.TempDir()
+	today := time.Now().Format("2006-01-02 Mon")
+	source := "* Notes\n" + strings.Repeat("Text.\n\n", 30) + "* TODO Call Ada\nSCHEDULED: <" + today + ">\n"
+	if err := os.WriteFile(filepath.Join(dir, "todo.org"), []byte(source), 0644); err != nil {
+		t.Fatal(err)
+	}
+	var model tea.Model = NewModel(createTestRenderer(), dir, "", Options{Motion: MotionOff})
+	model, _ = model.Update(tea.WindowSizeMsg{Width: 80, Height: 24})
+	m := model.(Model)
+
+	if !containsCommand(m.paletteCommands(), "Show agenda") {
+		t.Error("palette has no agenda command")
+	}
+	model, cmd := m.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune(agendaKey)})
+	model, _ = model.Update(cmdMsg[agendaLoadedMsg](t, cmd))
+	m = model.(Model)
+	if !m.agenda.open || len(m.agenda.items) != 1 {
+		t.Fatalf("agenda = %+v, want the scheduled entry", m.agenda)
+	}
+	if view := stripANSI(m.View()); !strings.Contains(view, "S: TODO Call Ada") {
+		t.Errorf("agenda not shown:\n%s", view)
+	}
+
+	// Enter opens the document, parsed for the agenda, at the entry
+	model, cmd = m.Update(tea.KeyMsg{Type: tea.KeyEnter})
+	m = model.(Model)
+	m.handleDocRendered(cmdMsg[docRenderedMsg](t, cmd))
+	if m.agenda.open || m.currentView != ViewDocument || m.viewport.YOffset == 0 {
+		t.Errorf("entry not opened at its headline: view %v, offset %d", m.currentView, m.viewport.YOffset)
+	}
+}
+
+// containsCommand reports whether cmds has a command titled title
+func containsCommand(cmds []paletteCommand, title string) bool {
+	for _, c := range cmds {
+		if c.title == title {
+			return true
+		}
+	}
+	return false
+}
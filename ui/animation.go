@@ -0,0 +1,142 @@
+package ui
+
+import (
+	"fmt"
+	"math/rand/v2"
+	"strings"
+	"time"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/harmonica"
+	"github.com/charmbracelet/x/ansi"
+)
+
+// Motion controls how much the UI animates
+type Motion int
+
+const (
+	MotionFull    Motion = iota // Full 60fps animations
+	MotionReduced               // Short, low frame rate animations
+	MotionOff                   // No animations
+)
+
+// motionNames maps motion settings to the names used in flags and the UI
+var motionNames = []string{"full", "reduced", "off"}
+
+// String returns the name of the motion setting
+func (m Motion) String() string {
+	if m < 0 || int(m) >= len(motionNames) {
+		return "unknown"
+	}
+	return motionNames[m]
+}
+
+// ParseMotion parses a motion setting name: full, reduced or off
+func ParseMotion(s string) (Motion, error) {
+	for i, name := range motionNames {
+		if strings.EqualFold(s, name) {
+			return Motion(i), nil
+		}
+	}
+	return MotionFull, fmt.Errorf("unknown motion setting %q (want one of %s)", s, strings.Join(motionNames, ", "))
+}
+
+// Frame rate and spring frequency of reduced-motion animations. The
+// stiffer spring settles in a fraction of the frames.
+const (
+	reducedAnimFPS       = 20
+	reducedAnimFrequency = 6.0
+)
+
+// animFrameRate returns the animation frame rate for the motion setting
+func (m Model) animFrameRate() int {
+	if m.motion == MotionReduced {
+		return reducedAnimFPS
+	}
+	return animFPS
+}
+
+// newAnimSpring returns the spring driving animations for the motion setting
+func (m Model) newAnimSpring() harmonica.Spring {
+	if m.motion == MotionReduced {
+		return harmonica.NewSpring(harmonica.FPS(reducedAnimFPS), reducedAnimFrequency, animDamping)
+	}
+	return harmonica.NewSpring(harmonica.FPS(animFPS), animFrequency, animDamping)
+}
+
+// animTick returns a command that sends the next animation frame
+func (m Model) animTick() tea.Cmd {
+	return tea.Tick(time.Second/time.Duration(m.animFrameRate()), func(t time.Time) tea.Msg {
+		return animTickMsg(t)
+	})
+}
+
+// startAnimation starts an animation of the given type from the beginning.
+// It returns nil, leaving animType at AnimNone, when animations are off.
+func (m *Model) startAnimation(anim AnimationType) tea.Cmd {
+	if m.motion == MotionOff {
+		m.stopAnimation()
+		return nil
+	}
+	m.animType = anim
+	m.animValue = 0.0
+	m.animVelocity = 0.0
+	m.animTarget = 1.0
+	m.animSeed = rand.Uint64()
+	return m.animTick()
+}
+
+// stopAnimation ends the running animation, if any
+func (m *Model) stopAnimation() {
+	m.animType = AnimNone
+	m.animValue = 1.0
+	m.animVelocity = 0.0
+	m.animFromContent = ""
+	m.animToContent = ""
+	m.frame.from, m.frame.to = "", ""
+	m.frame.fromCells, m.frame.toCells = nil, nil
+}
+
+// animFrame holds what animation frames are drawn with from one frame to
+// the next, so drawing a frame doesn't allocate much more than the frame.
+// Copies of the model share it; they draw one frame at a time.
+type animFrame struct {
+	buf []byte // Last frame drawn, reused for the next
+
+	// Content a poof goes from and to, split into terminal columns
+	from, to           string
+	fromCells, toCells [][]cell
+}
+
+// poofCells returns the columns of the content a poof goes from and to,
+// splitting it on the first frame of the poof
+func (f *animFrame) poofCells(from, to string) (fromCells, toCells [][]cell) {
+	if f.fromCells == nil || from != f.from || to != f.to {
+		f.from, f.fromCells = from, contentCells(from)
+		f.to, f.toCells = to, contentCells(to)
+	}
+	return f.fromCells, f.toCells
+}
+
+// contentCells splits each line of content into its terminal columns
+func contentCells(content string) [][]cell {
+	p := ansi.GetParser()
+	defer ansi.PutParser(p)
+	var pen sgrPen
+	lines := strings.Split(content, "\n")
+	cells := make([][]cell, len(lines))
+	for i, line := range lines {
+		cells[i] = lineCells(line, &pen, p)
+	}
+	return cells
+}
+
+// cycleMotion switches to the next motion setting for this session
+func (m *Model) cycleMotion() tea.Cmd {
+	m.motion = (m.motion + 1) % Motion(len(motionNames))
+	m.animSpring = m.newAnimSpring()
+	if m.motion == MotionOff {
+		m.stopAnimation()
+	}
+	return m.setStatus("Animations: " + m.motion.String())
+}
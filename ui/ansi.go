@@ -0,0 +1,64 @@
+package ui
+
+import (
+	"context"
+	"io"
+	"path/filepath"
+	"strings"
+
+	"org-charm/org"
+
+	"github.com/charmbracelet/lipgloss"
+	"github.com/muesli/termenv"
+)
+
+// MinRenderWidth is the narrowest width documents are laid out at, as in
+// sessions; RenderANSI callers should refuse narrower ones
+const MinRenderWidth = minContentWidth
+
+// RenderOptions holds the settings of a document rendered outside of a
+// session
+type RenderOptions struct {
+	Width         int             // Width to lay the document out at
+	Profile       termenv.Profile // Colors the output may use
+	ChromaStyle   string          // Syntax highlighting style; empty matches the theme
+	Hyperlinks    bool            // Emit OSC 8 hyperlinks
+	ShowExcluded  bool            // Show excluded subtrees dimmed instead of hiding them
+	GuessLanguage bool            // Guess the language of source blocks without one
+	LatexUnicode  bool            // Approximate LaTeX math with unicode symbols
+	Root          string          // Directory of the org files, which relative links resolve in
+}
+
+// RenderANSI renders doc with its metadata header as sessions show it,
+// to text with ANSI escape sequences, for other programs to show. Images
+// are shown as placeholders. Rendering stops early with the error of ctx
+// once it is done.
+func RenderANSI(ctx context.Context, doc *org.OrgFile, options RenderOptions) (string, error) {
+	lg := lipgloss.NewRenderer(io.Discard)
+	lg.SetColorProfile(options.Profile)
+	styles := NewStyles(lg)
+
+	renderer := NewRenderer(styles, options.Width)
+	renderer.SetContext(ctx)
+	renderer.SetHyperlinks(options.Hyperlinks)
+	renderer.SetShowExcluded(options.ShowExcluded)
+	renderer.SetGraphics(GraphicsNone)
+	renderer.SetGuessLanguage(options.GuessLanguage)
+	renderer.SetLatexUnicode(options.LatexUnicode)
+	renderer.SetChromaStyle(options.ChromaStyle)
+	if style := doc.Property("chroma-style"); style != "" {
+		renderer.SetChromaStyle(style)
+	}
+	renderer.SetSource(doc.RawContent, doc.Document.Nodes)
+	renderer.SetImageDir(options.Root, filepath.Dir(doc.Path))
+	renderer.SetExcludeTags(strings.Fields(doc.Document.Get("EXCLUDE_TAGS")))
+	renderer.SetSmartPunctuation(doc.Option("-") != "nil")
+
+	var b strings.Builder
+	writeDocHeader(&b, styles, doc, options.Width)
+	b.WriteString(renderer.RenderDocument(doc.Document.Nodes))
+	if err := ctx.Err(); err != nil {
+		return "", err
+	}
+	return b.String(), nil
+}
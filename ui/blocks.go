@@ -0,0 +1,82 @@
+package ui
+
+import (
+	"strings"
+
+	"github.com/charmbracelet/x/ansi"
+)
+
+// setDocContent fills the viewport with the rendered document lines,
+// cropping blocks wider than the viewport to their scroll offsets. Cropped
+// edges are marked with ‹ and › so it's clear there is more to see.
+func (m *Model) setDocContent() {
+	if len(m.blockOffsets) != len(m.docBlocks) {
+		m.blockOffsets = make([]int, len(m.docBlocks))
+	}
+
+	lines := append([]string(nil), m.docLines...)
+	for i, block := range m.docBlocks {
+		window := m.blockWindow(block)
+		if window <= 0 {
+			continue
+		}
+		offset := min(m.blockOffsets[i], block.Width-window)
+		m.blockOffsets[i] = max(0, offset)
+		start := block.Col + m.blockOffsets[i]
+
+		for n := block.Line; n < block.Line+block.Height && n < len(lines); n++ {
+			line := lines[n]
+			var b strings.Builder
+			b.WriteString(ansi.Cut(line, 0, block.Col))
+			if start > block.Col {
+				b.WriteString(m.styles.HelpKey.Render("‹"))
+				b.WriteString(ansi.Cut(line, start+1, start+window))
+			} else {
+				b.WriteString(ansi.Cut(line, start, start+window))
+			}
+			if start+window < block.Col+block.Width {
+				b.WriteString(m.styles.HelpKey.Render("›"))
+			}
+			lines[n] = b.String()
+		}
+	}
+	m.viewport.SetContent(strings.Join(lines, "\n"))
+}
+
+// blockWindow returns how many columns of block fit in the viewport,
+// keeping one column for the right edge marker, or 0 if it fits entirely
+func (m Model) blockWindow(block BlockRef) int {
+	if block.Height == 0 || block.Col+block.Width <= m.viewport.Width {
+		return 0
+	}
+	return max(0, m.viewport.Width-block.Col-1)
+}
+
+// focusedBlock returns the index of the first scrollable block visible in
+// the viewport, or -1 if there is none
+func (m Model) focusedBlock() int {
+	top, bottom := m.viewport.YOffset, m.viewport.YOffset+m.viewport.Height
+	for i, block := range m.docBlocks {
+		if m.blockWindow(block) > 0 && block.Line < bottom && block.Line+block.Height > top {
+			return i
+		}
+	}
+	return -1
+}
+
+// scrollBlock scrolls the focused block sideways by delta columns and
+// reports whether it moved
+func (m *Model) scrollBlock(delta int) bool {
+	i := m.focusedBlock()
+	if i < 0 {
+		return false
+	}
+	block := m.docBlocks[i]
+	offset := max(0, min(block.Width-m.blockWindow(block), m.blockOffsets[i]+delta))
+	if offset == m.blockOffsets[i] {
+		return false
+	}
+	m.blockOffsets[i] = offset
+	m.setDocContent()
+	return true
+}
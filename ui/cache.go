@@ -0,0 +1,109 @@
+package ui
+
+import (
+	"context"
+	"fmt"
+	"maps"
+	"slices"
+
+	"org-charm/cache"
+	"org-charm/org"
+)
+
+// renderKey identifies a rendering of a document: the parsed document, the
+// widths it was laid out for, the theme and the view options it depends
+// on. A file parsed again after changing is a different document.
+type renderKey struct {
+	doc       *org.OrgFile
+	width     int    // Body width
+	available int    // Width the body is centered in
+	theme     string // Color profile of the session
+	view      string // View options, and drawers and code blocks toggled open
+}
+
+// renderCache keeps renderings of documents and the openings shown in
+// previews, shared by all sessions, so resizing back to a width or
+// opening a document another session has read doesn't render it again
+var renderCache = struct {
+	documents *cache.LRU[renderKey, renderedDocument]
+	previews  *cache.LRU[renderKey, []string]
+}{
+	documents: cache.New[renderKey](0, func(doc renderedDocument) int64 {
+		return int64(len(doc.content))
+	}),
+	previews: cache.New[renderKey](0, func(lines []string) int64 {
+		var n int64
+		for _, line := range lines {
+			n += int64(len(line))
+		}
+		return n
+	}),
+}
+
+// previewBudgetShare is the part of the cache budget that goes to previews
+const previewBudgetShare = 10
+
+// SetCacheBudget limits the memory of the renderings kept for sessions to
+// share to about budget bytes, a tenth of it for previews, dropping the
+// least recently used renderings beyond it. 0 doesn't limit it.
+func SetCacheBudget(budget int64) {
+	renderCache.documents.SetBudget(budget - budget/previewBudgetShare)
+	renderCache.previews.SetBudget(budget / previewBudgetShare)
+}
+
+// CacheStats returns the use of the caches of document renderings and
+// previews
+func CacheStats() (documents, previews cache.Stats) {
+	return renderCache.documents.Stats(), renderCache.previews.Stats()
+}
+
+// viewOptions describes the options of the session renderings depend on
+func (m Model) viewOptions() string {
+	o := m.options
+	return fmt.Sprintf("%t %q %t %v %t %t %d nowrap=%t zebra=%t headers=%t",
+		o.Hyperlinks, o.ChromaStyle, o.ShowExcluded, o.Graphics, o.GuessLanguage, o.LatexUnicode, o.FoldCode,
+		m.noWrap, m.zebraTables, m.showHeaders)
+}
+
+// renderKey returns the key of the rendering of doc for the session as it
+// is now
+func (m Model) renderKey(doc *org.OrgFile) renderKey {
+	view := fmt.Sprintf("%s drawers=%t/%v code=%v", m.viewOptions(),
+		m.expandDrawers, slices.Sorted(maps.Keys(m.toggledDrawers)),
+		slices.Sorted(maps.Keys(m.unfoldedCode)))
+	return renderKey{
+		doc:       doc,
+		width:     m.contentWidth(),
+		available: m.availableWidth(),
+		theme:     fmt.Sprint(m.renderer.ColorProfile()),
+		view:      view,
+	}
+}
+
+// previewKey returns the key of the preview of doc at width
+func (m Model) previewKey(doc *org.OrgFile, width int) renderKey {
+	return renderKey{
+		doc:   doc,
+		width: width,
+		theme: fmt.Sprint(m.renderer.ColorProfile()),
+		view:  m.viewOptions(),
+	}
+}
+
+// cachedDocument returns the rendering of doc, rendering it only if it
+// hasn't been rendered the same way before, e.g. at the width the terminal
+// was resized back to. Renderings with a selected link, which change with
+// every tab, aren't cached.
+func (m Model) cachedDocument(doc *org.OrgFile) renderedDocument {
+	if m.linkIndex >= 0 {
+		rendered, _ := m.renderDocument(context.Background(), doc)
+		return rendered
+	}
+	key := m.renderKey(doc)
+	if rendered, ok := renderCache.documents.Get(key); ok {
+		return rendered
+	}
+	rendered, _ := m.renderDocument(context.Background(), doc)
+	renderCache.documents.Add(key, rendered)
+	return rendered
+}
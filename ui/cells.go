@@ -0,0 +1,93 @@
+package ui
+
+import "github.com/charmbracelet/x/ansi"
+
+// sgrReset is the SGR sequence resetting colors and attributes
+const sgrReset = "\x1b[0m"
+
+// sgrPen follows the colors and attributes SGR sequences set as content
+// is decoded, so they can be set again after something else is drawn over
+// part of the content
+type sgrPen struct {
+	seqs []byte // SGR sequences since colors and attributes were last reset
+}
+
+// update follows seq, just decoded by p, and reports whether it is an SGR
+// sequence
+func (pen *sgrPen) update(seq string, p *ansi.Parser) bool {
+	cmd := ansi.Cmd(p.Command())
+	if !ansi.HasCsiPrefix(seq) || cmd.Final() != 'm' || cmd.Prefix() != 0 || cmd.Intermediate() != 0 {
+		return false
+	}
+	if sgrResets(p.Params()) {
+		pen.seqs = pen.seqs[:0]
+		if seq == sgrReset || seq == "\x1b[m" {
+			return true
+		}
+	}
+	pen.seqs = append(pen.seqs, seq...)
+	return true
+}
+
+// sgrResets reports whether SGR parameters reset colors and attributes,
+// as no parameters or a 0 parameter do. Color parameters are skipped, so
+// the 0 of 38;5;0 (black) isn't taken for a reset.
+func sgrResets(params ansi.Params) bool {
+	if len(params) == 0 {
+		return true
+	}
+	for i := 0; i < len(params); i++ {
+		switch params[i].Param(0) {
+		case 0:
+			return true
+		case 38, 48, 58:
+			if params[i].HasMore() {
+				// 38:2::r:g:b, with the color in sub-parameters
+				for i < len(params) && params[i].HasMore() {
+					i++
+				}
+			} else if i+1 < len(params) {
+				switch params[i+1].Param(0) {
+				case 5:
+					i += 2
+				case 2:
+					i += 4
+				}
+			}
+		}
+	}
+	return false
+}
+
+// cell is a terminal column of content: the grapheme drawn there and the
+// SGR sequences setting its colors and attributes. The column covered by
+// the second half of a wide grapheme (CJK, emoji) has no grapheme.
+type cell struct {
+	grapheme string
+	pen      string
+}
+
+// lineCells splits line into its terminal columns. Colors and attributes
+// are followed with pen from the lines before; other escape sequences,
+// such as hyperlinks, are dropped.
+func lineCells(line string, pen *sgrPen, p *ansi.Parser) []cell {
+	cells := make([]cell, 0, len(line))
+	current := string(pen.seqs)
+	var state byte
+	for line != "" {
+		seq, width, n, newState := ansi.DecodeSequence(line, state, p)
+		state = newState
+		line = line[n:]
+		if width == 0 {
+			if pen.update(seq, p) {
+				current = string(pen.seqs)
+			}
+			continue
+		}
+		cells = append(cells, cell{seq, current})
+		for ; width > 1; width-- {
+			cells = append(cells, cell{"", current})
+		}
+	}
+	return cells
+}
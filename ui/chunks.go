@@ -0,0 +1,145 @@
+package ui
+
+import (
+	"bytes"
+	"math"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	goorg "github.com/niklasfasching/go-org/org"
+)
+
+const (
+	// chunkedMinLines is the length in source lines from which documents
+	// are rendered a chunk at a time, as the reader gets to them
+	chunkedMinLines = 2000
+
+	// chunkMargin is how many lines past the bottom of the viewport are
+	// rendered ahead of the reader
+	chunkMargin = 100
+)
+
+// docChunks renders a document a top-level node at a time. The renderer
+// keeps its state between chunks, so numbering, footnotes and the like
+// come out as when rendering the document at once. Cached renderings share
+// it, so sessions reading on render the rest for each other.
+type docChunks struct {
+	mu       sync.Mutex
+	renderer *Renderer
+	nodes    []goorg.Node // Nodes left to render
+	content  strings.Builder
+	lines    int // Lines of content
+	margin   int // Columns the body is indented by to center it
+	done     bool
+}
+
+// newDocChunks prepares the rendering of nodes after header, centered by
+// margin columns
+func newDocChunks(renderer *Renderer, header string, nodes []goorg.Node, margin int) *docChunks {
+	c := &docChunks{renderer: renderer, nodes: nodes, margin: margin}
+	c.content.WriteString(header)
+	c.lines = strings.Count(header, "\n")
+	renderer.beginDocument(nodes)
+	return c
+}
+
+// renderUntil renders chunks until there are at least lines lines, or the
+// whole document for math.MaxInt, and returns the document so far. A
+// render interrupted through the context of the renderer returns its
+// error, leaving the chunks incomplete.
+func (c *docChunks) renderUntil(lines int) (renderedDocument, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for len(c.nodes) > 0 && c.lines < lines {
+		n := chunkEnd(c.nodes)
+		if err := c.renderer.WriteNodes(c, c.nodes[:n]); err != nil {
+			return renderedDocument{}, err
+		}
+		c.nodes = c.nodes[n:]
+	}
+	if len(c.nodes) == 0 && !c.done {
+		c.content.WriteString(c.renderer.renderFootnotes())
+		c.done = true
+	}
+	return c.result(), nil
+}
+
+// Write appends rendered output to the content, counting its lines, for
+// the renderer to stream chunks into. renderUntil holds the lock.
+func (c *docChunks) Write(p []byte) (int, error) {
+	c.lines += bytes.Count(p, []byte("\n"))
+	return c.content.Write(p)
+}
+
+// chunkEnd returns the number of nodes in the chunk at the start of nodes:
+// a node along with the keywords before it that belong to it
+func chunkEnd(nodes []goorg.Node) int {
+	for i, node := range nodes {
+		kw, ok := node.(goorg.Keyword)
+		if !ok || (kw.Key != "CAPTION" && kw.Key != "HEADER" && kw.Key != "HEADERS") {
+			return i + 1
+		}
+	}
+	return len(nodes)
+}
+
+// result returns the document rendered so far, with the positions of the
+// things in it located. The positions are copies, for renderings of the
+// document so far to keep as they are while rendering goes on.
+func (c *docChunks) result() renderedDocument {
+	content := centerLines(c.content.String(), c.margin)
+	links := slices.Clone(c.renderer.Links())
+	LocateLinks(content, links)
+	headings := slices.Clone(c.renderer.Headings())
+	LocateHeadings(content, headings)
+	blocks := slices.Clone(c.renderer.WideBlocks())
+	LocateBlocks(content, blocks)
+	code := slices.Clone(c.renderer.CodeBlocks())
+	LocateCodeBlocks(content, code)
+	tables := slices.Clone(c.renderer.Tables())
+	LocateTables(content, tables)
+	drawers := slices.Clone(c.renderer.Drawers())
+	LocateDrawers(content, drawers)
+	anchors := slices.Clone(c.renderer.Anchors())
+	LocateAnchors(content, anchors)
+
+	var more *docChunks
+	if !c.done {
+		more = c
+	}
+	return renderedDocument{content, links, headings, blocks, code, tables, drawers, anchors, more}
+}
+
+// renderAhead renders the open document far enough for the viewport to
+// show lines, and past it by the margin
+func (m *Model) renderAhead(lines int) {
+	if m.docMore == nil || m.rawView || len(m.docLines) >= lines+chunkMargin {
+		return
+	}
+	m.renderOn(lines + chunkMargin)
+}
+
+// renderRest renders the rest of the open document, for jumps to places
+// that may not be rendered yet
+func (m *Model) renderRest() {
+	if m.docMore == nil || m.rawView {
+		return
+	}
+	m.renderOn(math.MaxInt)
+}
+
+// renderOn renders the open document until it has lines lines and shows
+// it. The cached rendering is replaced to count the size it has grown to.
+func (m *Model) renderOn(lines int) {
+	// Only the first render of a document is interrupted, so rendering on
+	// always gets to the end
+	start := time.Now()
+	doc, _ := m.docMore.renderUntil(lines)
+	m.account.rendered(m.currentDoc, time.Since(start))
+	if m.linkIndex < 0 {
+		renderCache.documents.Add(m.renderKey(m.currentDoc), doc)
+	}
+	m.showRendered(doc)
+}
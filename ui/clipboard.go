@@ -0,0 +1,115 @@
+package ui
+
+import (
+	"fmt"
+	"time"
+
+	"org-charm/export"
+
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// markdownKey copies the document converted to Markdown
+const markdownKey = "M"
+
+// statusTimeout is how long a status notice stays in the footer
+const statusTimeout = 3 * time.Second
+
+// statusMsg sets a transient notice in the footer
+type statusMsg string
+
+// clearStatusMsg clears the notice with the given id, unless a newer one replaced it
+type clearStatusMsg int
+
+// setStatus shows a transient notice in the footer and returns the
+// command that clears it again
+func (m *Model) setStatus(text string) tea.Cmd {
+	m.statusID++
+	m.status = text
+	id := m.statusID
+	return tea.Tick(statusTimeout, func(time.Time) tea.Msg {
+		return clearStatusMsg(id)
+	})
+}
+
+// copyToClipboard returns a command that copies text to the client's
+// clipboard using an OSC 52 escape sequence on the session output
+func (m Model) copyToClipboard(text, what string) tea.Cmd {
+	if text == "" {
+		return func() tea.Msg {
+			return statusMsg("Nothing to copy")
+		}
+	}
+	output := m.renderer.Output()
+	return func() tea.Msg {
+		output.Copy(text)
+		return statusMsg(fmt.Sprintf("Copied %s to clipboard", what))
+	}
+}
+
+// yank copies the most specific thing in focus: the selected link URL,
+// otherwise the raw source of the current document (or the file selected
+// in the list)
+func (m Model) yank() tea.Cmd {
+	switch m.currentView {
+	case ViewDocument:
+		if m.linkIndex >= 0 && m.linkIndex < len(m.docLinks) {
+			return m.copyToClipboard(m.docLinks[m.linkIndex].URL, "link")
+		}
+		return m.copyToClipboard(m.currentDoc.RawContent, "document source")
+	case ViewFileList:
+		if len(m.flatList) == 0 {
+			return nil
+		}
+		entry := m.flatList[m.selectedIndex]
+		if entry.IsDir {
+			return nil
+		}
+		if orgFile, err := entry.GetOrgFile(); err == nil {
+			return m.copyToClipboard(orgFile.RawContent, "document source")
+		}
+	}
+	return nil
+}
+
+// yankMarkdown copies the current document (or the file selected in the
+// list) converted to Markdown, for sharing with people who don't use org
+func (m Model) yankMarkdown() tea.Cmd {
+	doc := m.currentDoc
+	if m.currentView == ViewFileList {
+		if len(m.flatList) == 0 || m.flatList[m.selectedIndex].IsDir {
+			return nil
+		}
+		orgFile, err := m.flatList[m.selectedIndex].GetOrgFile()
+		if err != nil {
+			return nil
+		}
+		doc = orgFile
+	}
+	if doc == nil {
+		return nil
+	}
+	markdown, err := export.Markdown(doc)
+	if err != nil {
+		return func() tea.Msg {
+			return statusMsg("Could not convert to Markdown: " + err.Error())
+		}
+	}
+	return m.copyToClipboard(markdown, "document as Markdown")
+}
+
+// yankSubtree copies the raw source of the heading the top of the
+// viewport falls under, including its nested headings
+func (m Model) yankSubtree() tea.Cmd {
+	if m.currentView != ViewDocument || m.rawView {
+		return nil
+	}
+	idx := HeadingAt(m.docHeadings, m.viewport.YOffset)
+	if idx < 0 {
+		return func() tea.Msg {
+			return statusMsg("No heading at this position")
+		}
+	}
+	heading := m.docHeadings[idx]
+	return m.copyToClipboard(m.currentDoc.Subtree(heading.Index), "“"+heading.Title+"”")
+}
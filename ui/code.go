@@ -0,0 +1,96 @@
+package ui
+
+import (
+	"slices"
+
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+const (
+	// copyCodeKey copies the source block in view
+	copyCodeKey = "C"
+
+	// headersKey shows or hides the babel header arguments of source blocks
+	headersKey = "B"
+)
+
+// codeBlockInView returns the index of the first source block visible in
+// the viewport, only counting those long enough to fold if foldable is
+// set, or -1 if there is none
+func (m Model) codeBlockInView(foldable bool) int {
+	top, bottom := m.viewport.YOffset, m.viewport.YOffset+m.viewport.Height
+	for i, block := range m.docCode {
+		if foldable && !block.Fold {
+			continue
+		}
+		if block.Line < bottom && block.Line+block.Height > top {
+			return i
+		}
+	}
+	return -1
+}
+
+// toggleCodeFold expands or folds the long source block in view, keeping
+// its header where it is
+func (m *Model) toggleCodeFold() {
+	if m.currentView != ViewDocument || m.rawView {
+		return
+	}
+	i := m.codeBlockInView(true)
+	if i < 0 {
+		return
+	}
+
+	if m.unfoldedCode == nil {
+		m.unfoldedCode = make(map[int]bool)
+	}
+	m.unfoldedCode[i] = !m.unfoldedCode[i]
+	row := m.docCode[i].Line - m.viewport.YOffset
+	m.refreshDocument()
+	if i < len(m.docCode) {
+		m.viewport.SetYOffset(max(0, m.docCode[i].Line-max(0, row)))
+	}
+}
+
+// yankCodeBlock copies the code of the source block in view
+func (m Model) yankCodeBlock() tea.Cmd {
+	if m.currentView != ViewDocument || m.rawView {
+		return nil
+	}
+	i := m.codeBlockInView(false)
+	if i < 0 {
+		return func() tea.Msg {
+			return statusMsg("No code block in view")
+		}
+	}
+	block := m.docCode[i]
+	what := "code block"
+	switch {
+	case block.Name != "":
+		what = "“" + block.Name + "”"
+	case block.Lang != "":
+		what = block.Lang + " block"
+	}
+	return m.copyToClipboard(block.Code, what)
+}
+
+// syntaxThemes are the syntax highlighting styles sessions switch between
+// from the palette, the first matching the theme of the UI
+var syntaxThemes = []string{"", "monokai", "dracula", "nord", "github-dark", "solarized-dark", "gruvbox"}
+
+// cycleSyntaxTheme switches the session to the next of syntaxThemes for
+// highlighting source blocks. Documents setting their own style with
+// #+PROPERTY: chroma-style keep it.
+func (m *Model) cycleSyntaxTheme() tea.Cmd {
+	// A style of the server not in the list is followed by the first
+	i := slices.Index(syntaxThemes, m.options.ChromaStyle)
+	m.options.ChromaStyle = syntaxThemes[(i+1)%len(syntaxThemes)]
+	if m.currentView == ViewDocument && !m.rawView {
+		m.refreshDocument()
+	}
+	name := m.options.ChromaStyle
+	if name == "" {
+		name = "matching the theme"
+	}
+	return m.setStatus("Syntax highlighting: " + name)
+}
@@ -0,0 +1,28 @@
+package ui
+
+import "testing"
+
+func TestCycleSyntaxTheme(t *testing.T) {
+	for _, name := range syntaxThemes[1:] {
+		if err := CheckChromaStyle(name); err != nil {
+			t.Error(err)
+		}
+	}
+
+	m := NewModel(createTestRenderer(), t.TempDir(), "", Options{ChromaStyle: "vim"})
+	if !containsCommand(m.paletteCommands(), "Switch syntax highlighting theme") {
+		t.Error("palette has no theme command")
+	}
+	var seen []string
+	for range syntaxThemes {
+		m.cycleSyntaxTheme()
+		seen = append(seen, m.options.ChromaStyle)
+	}
+	if seen[0] != "" || seen[1] != syntaxThemes[1] || seen[len(seen)-1] != syntaxThemes[len(syntaxThemes)-1] {
+		t.Errorf("styles cycled through = %q", seen)
+	}
+	m.cycleSyntaxTheme()
+	if m.options.ChromaStyle != "" {
+		t.Errorf("style after a full cycle = %q, want the theme's", m.options.ChromaStyle)
+	}
+}
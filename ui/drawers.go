@@ -0,0 +1,51 @@
+package ui
+
+import (
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+const (
+	// drawerKey expands or collapses the drawer in view
+	drawerKey = "d"
+
+	// allDrawersKey expands or collapses all drawers
+	allDrawersKey = "D"
+)
+
+// drawerInView returns the index of the first drawer visible in the
+// viewport, or -1 if there is none
+func (m Model) drawerInView() int {
+	top, bottom := m.viewport.YOffset, m.viewport.YOffset+m.viewport.Height
+	for i, drawer := range m.docDrawers {
+		if drawer.Line < bottom && drawer.Line+drawer.Height > top {
+			return i
+		}
+	}
+	return -1
+}
+
+// toggleDrawer expands or collapses the drawer in view, keeping its first
+// line where it is
+func (m *Model) toggleDrawer() tea.Cmd {
+	if m.currentView != ViewDocument || m.rawView {
+		return nil
+	}
+	i := m.drawerInView()
+	if i < 0 {
+		return func() tea.Msg {
+			return statusMsg("No drawer in view")
+		}
+	}
+
+	if m.toggledDrawers == nil {
+		m.toggledDrawers = make(map[int]bool)
+	}
+	m.toggledDrawers[i] = !m.toggledDrawers[i]
+	// Keep the drawer's header on the same screen row
+	row := m.docDrawers[i].Line - m.viewport.YOffset
+	m.refreshDocument()
+	if i < len(m.docDrawers) {
+		m.viewport.SetYOffset(max(0, m.docDrawers[i].Line-max(0, row)))
+	}
+	return nil
+}
@@ -0,0 +1,36 @@
+package ui
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+func TestToggleDrawerKeepsScroll(t *testing.T) {
+	dir := t.TempDir()
+	source := "* Notes\nIntro.\n\n:LOGBOOK:\n- Note taken\n- Another note\n:END:\n\n" + strings.Repeat("More text.\n\n", 40)
+	if err := os.WriteFile(filepath.Join(dir, "notes.org"), []byte(source), 0644); err != nil {
+		t.Fatal(err)
+	}
+	var model tea.Model = NewModel(createTestRenderer(), dir, "", Options{})
+	model, _ = model.Update(tea.WindowSizeMsg{Width: 80, Height: 24})
+	m := model.(Model)
+	cmd := m.openEntry(m.flatList[0])
+	m.handleDocRendered(cmdMsg[docRenderedMsg](t, m.handleDocParsed(cmdMsg[docParsedMsg](t, cmd))))
+	if m.currentView != ViewDocument || len(m.docDrawers) != 1 {
+		t.Fatalf("view %v with drawers %+v, want the document with its drawer", m.currentView, m.docDrawers)
+	}
+
+	// d opens the drawer in view, without paging down as the viewport would
+	model, _ = m.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune(drawerKey)})
+	m = model.(Model)
+	if !m.toggledDrawers[0] {
+		t.Error("drawer not toggled")
+	}
+	if m.viewport.YOffset != 0 {
+		t.Errorf("offset = %d after toggling the drawer, want 0", m.viewport.YOffset)
+	}
+}
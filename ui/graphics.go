@@ -0,0 +1,350 @@
+package ui
+
+import (
+	"fmt"
+	"hash/fnv"
+	"image"
+	"image/color"
+	_ "image/gif" // Decoders for linked images
+	_ "image/jpeg"
+	_ "image/png"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/charmbracelet/x/ansi/kitty"
+)
+
+// GraphicsProtocol is a way of drawing images a terminal supports
+type GraphicsProtocol int
+
+const (
+	// GraphicsAuto detects the protocol of each session's terminal
+	GraphicsAuto GraphicsProtocol = iota
+	// GraphicsNone shows placeholders instead of images
+	GraphicsNone
+	// GraphicsKitty is the Kitty graphics protocol (kitty, Ghostty)
+	GraphicsKitty
+	// GraphicsBlocks means the terminal has no graphics protocol; images
+	// are drawn with half-block characters
+	GraphicsBlocks
+)
+
+// graphicsNames are the names of the protocols, as used by the -graphics flag
+var graphicsNames = map[GraphicsProtocol]string{
+	GraphicsAuto:   "auto",
+	GraphicsNone:   "none",
+	GraphicsKitty:  "kitty",
+	GraphicsBlocks: "blocks",
+}
+
+// String returns the name of the protocol
+func (p GraphicsProtocol) String() string {
+	return graphicsNames[p]
+}
+
+// ParseGraphics parses a graphics protocol name: auto, none, kitty or
+// blocks
+func ParseGraphics(s string) (GraphicsProtocol, error) {
+	for p, name := range graphicsNames {
+		if strings.EqualFold(s, name) {
+			return p, nil
+		}
+	}
+	return GraphicsNone, fmt.Errorf("unknown graphics protocol %q (want auto, none, kitty or blocks)", s)
+}
+
+// DetectGraphics returns the best graphics protocol of a client terminal,
+// judging by its terminal type and the environment the client sent. Only
+// the Kitty protocol is used: iTerm2 and sixel images are painted over the
+// cells, so the line-by-line repaints of the screen erase them, while
+// Kitty's Unicode placeholders scroll and repaint like text.
+func DetectGraphics(term string, environ []string) GraphicsProtocol {
+	env := make(map[string]string, len(environ))
+	for _, kv := range environ {
+		if k, v, ok := strings.Cut(kv, "="); ok {
+			env[k] = v
+		}
+	}
+
+	switch {
+	case term == "xterm-kitty" || env["KITTY_WINDOW_ID"] != "",
+		term == "xterm-ghostty" || env["TERM_PROGRAM"] == "ghostty":
+		return GraphicsKitty
+	}
+	return GraphicsBlocks
+}
+
+// imageBackend draws images into a block of terminal cells
+type imageBackend interface {
+	// render returns rows lines of cols cells showing img, identified by id
+	render(img image.Image, id uint32, cols, rows int) string
+}
+
+// imageBackendFor returns the backend drawing images with protocol p, or
+// nil if images should be placeholders
+func imageBackendFor(p GraphicsProtocol) imageBackend {
+	switch p {
+	case GraphicsKitty:
+		return kittyBackend{}
+	case GraphicsBlocks:
+		return halfBlockBackend{}
+	}
+	return nil
+}
+
+// kittyBackend draws images with the Kitty graphics protocol, placing them
+// with Unicode placeholder cells that scroll and repaint like text
+type kittyBackend struct{}
+
+func (kittyBackend) render(img image.Image, id uint32, cols, rows int) string {
+	var b strings.Builder
+	err := kitty.EncodeGraphics(&b, img, &kitty.Options{
+		Action:           kitty.TransmitAndPut,
+		Quite:            2,
+		ID:               int(id),
+		Format:           kitty.PNG,
+		Transmission:     kitty.Direct,
+		Chunk:            true,
+		VirtualPlacement: true,
+		Columns:          cols,
+		Rows:             rows,
+	})
+	if err != nil {
+		return ""
+	}
+
+	// The placeholder cells' foreground color is the image id; each row
+	// starts with diacritics for its row and column, the rest follow on
+	color := fmt.Sprintf("\x1b[38;2;%d;%d;%dm", id>>16&0xff, id>>8&0xff, id&0xff)
+	for row := range rows {
+		if row > 0 {
+			b.WriteString("\n")
+		}
+		b.WriteString(color)
+		b.WriteRune(kitty.Placeholder)
+		b.WriteRune(kitty.Diacritic(row))
+		b.WriteRune(kitty.Diacritic(0))
+		b.WriteString(strings.Repeat(string(kitty.Placeholder), cols-1))
+		b.WriteString("\x1b[39m")
+	}
+	return b.String()
+}
+
+// halfBlockBackend draws images as character art: each cell shows two
+// pixels, the upper half block in the color of the top one over the
+// color of the bottom one
+type halfBlockBackend struct{}
+
+func (halfBlockBackend) render(img image.Image, _ uint32, cols, rows int) string {
+	var b strings.Builder
+	for row := range rows {
+		if row > 0 {
+			b.WriteString("\n")
+		}
+		for col := range cols {
+			top := averageColor(img, col, 2*row, cols, 2*rows)
+			bottom := averageColor(img, col, 2*row+1, cols, 2*rows)
+			switch {
+			case top.A < 0x80 && bottom.A < 0x80:
+				b.WriteString("\x1b[0m ")
+			case top.A < 0x80:
+				fmt.Fprintf(&b, "\x1b[0;38;2;%d;%d;%dm▄", bottom.R, bottom.G, bottom.B)
+			case bottom.A < 0x80:
+				fmt.Fprintf(&b, "\x1b[0;38;2;%d;%d;%dm▀", top.R, top.G, top.B)
+			default:
+				fmt.Fprintf(&b, "\x1b[38;2;%d;%d;%d;48;2;%d;%d;%dm▀", top.R, top.G, top.B, bottom.R, bottom.G, bottom.B)
+			}
+		}
+		b.WriteString("\x1b[0m")
+	}
+	return b.String()
+}
+
+// averageColor returns the average color of the pixels of img covered by
+// cell (x, y) of a w by h grid laid over it
+func averageColor(img image.Image, x, y, w, h int) color.NRGBA {
+	bounds := img.Bounds()
+	x0 := bounds.Min.X + x*bounds.Dx()/w
+	x1 := max(x0+1, bounds.Min.X+(x+1)*bounds.Dx()/w)
+	y0 := bounds.Min.Y + y*bounds.Dy()/h
+	y1 := max(y0+1, bounds.Min.Y+(y+1)*bounds.Dy()/h)
+
+	var r, g, b, a, n uint64
+	for py := y0; py < y1; py++ {
+		for px := x0; px < x1; px++ {
+			pr, pg, pb, pa := img.At(px, py).RGBA()
+			r, g, b, a = r+uint64(pr), g+uint64(pg), b+uint64(pb), a+uint64(pa)
+			n++
+		}
+	}
+	if a == 0 {
+		return color.NRGBA{}
+	}
+	// Colors are alpha-premultiplied; divide by coverage to un-premultiply
+	return color.NRGBA{
+		R: uint8(r * 0xff / a),
+		G: uint8(g * 0xff / a),
+		B: uint8(b * 0xff / a),
+		A: uint8(a / n >> 8),
+	}
+}
+
+const (
+	// maxImageRows caps the height of an image, in terminal rows
+	maxImageRows = 24
+
+	// Assumed size of a terminal cell in pixels, to size images in cells
+	cellWidth  = 8
+	cellHeight = 16
+)
+
+// imageCells returns the size in cells of an image of size bounds drawn at
+// most maxCols wide, keeping its aspect ratio
+func imageCells(bounds image.Rectangle, maxCols int) (int, int) {
+	w, h := bounds.Dx(), bounds.Dy()
+	if w <= 0 || h <= 0 {
+		return 0, 0
+	}
+	cols := max(1, min(maxCols, (w+cellWidth-1)/cellWidth))
+	rows := (cols*cellWidth*h/w + cellHeight/2) / cellHeight
+	if rows > maxImageRows {
+		rows = maxImageRows
+		cols = max(1, rows*cellHeight*w/h/cellWidth)
+	}
+	return cols, max(1, rows)
+}
+
+// imagePath resolves the target of an image link relative to dir,
+// refusing files outside root: sessions must only see the org directory
+func imagePath(url, root, dir string) (string, bool) {
+	if root == "" || strings.Contains(url, "://") {
+		return "", false
+	}
+	target := strings.TrimPrefix(url, "file:")
+	if !filepath.IsAbs(target) {
+		target = filepath.Join(dir, target)
+	}
+	absRoot, err := resolvePath(root)
+	if err != nil {
+		return "", false
+	}
+	absTarget, err := resolvePath(target)
+	if err != nil {
+		return "", false
+	}
+	rel, err := filepath.Rel(absRoot, absTarget)
+	if err != nil || rel == ".." || strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+		return "", false
+	}
+	return absTarget, true
+}
+
+// resolvePath returns the absolute path of path with symlinks resolved
+func resolvePath(path string) (string, error) {
+	abs, err := filepath.Abs(path)
+	if err != nil {
+		return "", err
+	}
+	return filepath.EvalSymlinks(abs)
+}
+
+// imageID returns the id an image is transmitted with: a hash of its path,
+// 24 bits wide so it fits a truecolor foreground, and never 0
+func imageID(path string) uint32 {
+	h := fnv.New32a()
+	h.Write([]byte(path))
+	return max(1, h.Sum32()&0xffffff)
+}
+
+// maxCachedImages caps how many drawn images are kept in imageCache
+const maxCachedImages = 64
+
+// imageKey identifies a drawn image: the file, its version and how it was
+// drawn
+type imageKey struct {
+	path    string
+	modTime time.Time
+	maxCols int
+	backend imageBackend
+}
+
+// imageCache keeps drawn images, shared by all sessions, so documents
+// don't decode their images on every render. The oldest entries are
+// dropped once it's full.
+var imageCache = struct {
+	sync.Mutex
+	drawn map[imageKey]string
+	order []imageKey
+}{drawn: map[imageKey]string{}}
+
+// drawImage draws the image file at path at most maxCols wide with
+// backend, reusing the cached drawing while the file doesn't change
+func drawImage(backend imageBackend, path string, maxCols int) (string, error) {
+	info, err := os.Stat(path)
+	if err != nil {
+		return "", err
+	}
+	key := imageKey{path, info.ModTime(), maxCols, backend}
+
+	imageCache.Lock()
+	drawn, ok := imageCache.drawn[key]
+	imageCache.Unlock()
+	if ok {
+		return drawn, nil
+	}
+
+	img, err := loadImage(path)
+	if err != nil {
+		return "", err
+	}
+	cols, rows := imageCells(img.Bounds(), maxCols)
+	if cols == 0 {
+		return "", fmt.Errorf("empty image %s", path)
+	}
+	drawn = backend.render(img, imageID(path), cols, rows)
+	if drawn == "" {
+		return "", fmt.Errorf("could not draw %s", path)
+	}
+
+	imageCache.Lock()
+	defer imageCache.Unlock()
+	if _, ok := imageCache.drawn[key]; !ok {
+		if len(imageCache.order) >= maxCachedImages {
+			delete(imageCache.drawn, imageCache.order[0])
+			imageCache.order = imageCache.order[1:]
+		}
+		imageCache.order = append(imageCache.order, key)
+	}
+	imageCache.drawn[key] = drawn
+	return drawn, nil
+}
+
+// maxImagePixels caps the size of the images decoded, so a small file
+// declaring a huge image can't exhaust the server's memory
+const maxImagePixels = 4096 * 4096
+
+// loadImage decodes the image file at path, refusing images of more than
+// maxImagePixels pixels before decoding them
+func loadImage(path string) (image.Image, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	config, _, err := image.DecodeConfig(f)
+	if err != nil {
+		return nil, err
+	}
+	if config.Width <= 0 || config.Height <= 0 || config.Width > maxImagePixels/max(1, config.Height) {
+		return nil, fmt.Errorf("%s: image of %dx%d pixels too large", path, config.Width, config.Height)
+	}
+	if _, err := f.Seek(0, io.SeekStart); err != nil {
+		return nil, err
+	}
+	img, _, err := image.Decode(f)
+	return img, err
+}
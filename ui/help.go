@@ -0,0 +1,171 @@
+package ui
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/charmbracelet/bubbles/viewport"
+	"github.com/charmbracelet/lipgloss"
+)
+
+// openHelp shows the help overlay scrolled to the top
+func (m *Model) openHelp() {
+	m.showHelp = true
+	m.helpViewport = viewport.New(0, 0)
+	m.helpViewport.MouseWheelEnabled = false
+	m.helpViewport.SetContent(m.renderHelpContent())
+	m.resizeHelp()
+}
+
+// resizeHelp fits the help viewport between the overlay title and footer
+func (m *Model) resizeHelp() {
+	// App padding, blank lines after the title and before the footer, and
+	// the footer line
+	chrome := 2 + 1 + 2 + lipgloss.Height(m.renderHelpTitle())
+	m.helpViewport.Width = m.width - 4
+	m.helpViewport.Height = max(1, m.height-chrome)
+	m.helpViewport.SetYOffset(m.helpViewport.YOffset)
+}
+
+// handleHelpKey scrolls the help overlay, or closes it for any key that
+// isn't a scroll key
+func (m *Model) handleHelpKey(key string) {
+	vp := &m.helpViewport
+	switch key {
+	case "up", "k":
+		vp.ScrollUp(1)
+	case "down", "j":
+		vp.ScrollDown(1)
+	case "ctrl+u", "u":
+		vp.HalfPageUp()
+	case "ctrl+d", "d":
+		vp.HalfPageDown()
+	case "pgup", "b":
+		vp.PageUp()
+	case "pgdown", " ", "f":
+		vp.PageDown()
+	case "home", "g":
+		vp.GotoTop()
+	case "end", "G":
+		vp.GotoBottom()
+	default:
+		m.showHelp = false
+	}
+}
+
+// renderHelpTitle renders the title line of the help overlay
+func (m Model) renderHelpTitle() string {
+	return m.styles.Sized(&m.styles.DocTitle, m.width-8).Render("  ⌨️  Keyboard Shortcuts")
+}
+
+// renderHelpContent renders the scrollable list of keyboard shortcuts
+func (m Model) renderHelpContent() string {
+	var b strings.Builder
+
+	sections := []struct {
+		name  string
+		items []helpItem
+	}{
+		{
+			name: "Navigation",
+			items: []helpItem{
+				{"↑ / k", "Move up"},
+				{"↓ / j", "Move down"},
+				{"← / h", "Go back"},
+				{"→ / l / Enter", "Open / Select"},
+				{"gg / Home", "Go to top"},
+				{"G / End", "Go to bottom"},
+				{"Ctrl+d / Ctrl+u", "Half page down/up"},
+				{"{ / }", "Previous/next paragraph or folder"},
+				{"count + motion", "Repeat, e.g. 10j; 5G goes to line 5"},
+			},
+		},
+		{
+			name: "File List",
+			items: []helpItem{
+				{"s", "Cycle sort: name, title, date, modified"},
+				{"S", "Reverse sort direction"},
+				{"*", "Pin/unpin file (also in documents)"},
+			},
+		},
+		{
+			name: "Document View",
+			items: []helpItem{
+				{"Page Up / Ctrl+u", "Scroll up"},
+				{"Page Down / Ctrl+d", "Scroll down"},
+				{"n", "Next document"},
+				{"p", "Previous document"},
+				{"Tab / Shift+Tab", "Select next/previous link"},
+				{"Enter", "Follow selected link"},
+				{"r", "Toggle raw/rendered view"},
+				{"#", "Toggle line numbers (raw or split view)"},
+				{"|", "Show the source beside the rendered document"},
+				{"+ / -", "Widen/narrow text"},
+				{"w", "Toggle reading column"},
+				{"W", "Toggle wrapping of wide code and tables"},
+				{"h / l", "Scroll a wide table or code block"},
+				{"t", "Move a row cursor through the table in view"},
+				{"Z", "Toggle striped table rows"},
+				{"d / D", "Expand/collapse the drawer in view / all drawers"},
+				{"m{a-z} / '{a-z}", "Set mark / jump to mark ('' jumps back)"},
+				{"v", "Select lines; y copies them as plain text"},
+				{"y", "Copy source (or selected link)"},
+				{"Y", "Copy current heading subtree"},
+				{"M", "Copy the document as Markdown (also in the file list)"},
+				{"P", "Export the document to PDF (when the server sets it up)"},
+				{"H", "Show the git history; Enter views an older version, Esc returns"},
+				{"R", "Show the linked references (with an org-roam database)"},
+				{"C", "Copy the code block in view"},
+				{"B", "Show/hide babel header arguments of code blocks"},
+				{"Enter", "Expand/fold the long code block in view (no link selected)"},
+				{"Esc", "Return to file list"},
+			},
+		},
+		{
+			name: "General",
+			items: []helpItem{
+				{"Mouse", "Click to select or follow links, double-click to open, wheel to scroll"},
+				{"c", "Show credits & changelog"},
+				{"A", "Cycle animations: full, reduced, off"},
+				{"a", "Show the agenda of the week; Enter opens an entry"},
+				{":", "Command palette"},
+				{"?", "Toggle this help"},
+				{"q / Ctrl+c", "Quit"},
+			},
+		},
+	}
+
+	for _, section := range sections {
+		sectionTitle := m.styles.Heading3.Render("  " + section.name)
+		b.WriteString(sectionTitle)
+		b.WriteString("\n")
+
+		for _, item := range section.items {
+			line := "    " + m.styles.HelpKey.Render(fmt.Sprintf("%-20s", item.key)) +
+				m.styles.HelpText.Render(item.desc)
+			b.WriteString(line)
+			b.WriteString("\n")
+		}
+		b.WriteString("\n")
+	}
+
+	return strings.TrimRight(b.String(), "\n")
+}
+
+// renderHelp renders the help overlay
+func (m Model) renderHelp() string {
+	var b strings.Builder
+
+	b.WriteString(m.renderHelpTitle())
+	b.WriteString("\n\n")
+	b.WriteString(m.helpViewport.View())
+	b.WriteString("\n\n")
+
+	footer := "  Press any key to close this help"
+	if !m.helpViewport.AtTop() || !m.helpViewport.AtBottom() {
+		footer = fmt.Sprintf("  %3.f%% • ↑/↓ PgUp/PgDn to scroll, any other key to close", m.helpViewport.ScrollPercent()*100)
+	}
+	b.WriteString(m.styles.HelpText.Render(footer))
+
+	return m.styles.App.Render(b.String())
+}
@@ -0,0 +1,309 @@
+package ui
+
+import (
+	"regexp"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/charmbracelet/lipgloss"
+
+	"org-charm/org"
+)
+
+// Patterns used by the raw org source highlighter
+var (
+	rawHeadlineRegexp = regexp.MustCompile(`^(\*+)(\s+)(.*)$`)
+	rawTagsRegexp     = regexp.MustCompile(`(\s+)(:[\p{L}0-9_@#%:]+:)(\s*)$`)
+	rawPriorityRegexp = regexp.MustCompile(`^\[#[A-Z0-9]\]`)
+	rawBlockRegexp    = regexp.MustCompile(`(?i)^(\s*)(#\+(begin|end)_\w+)(.*)$`)
+	rawKeywordRegexp  = regexp.MustCompile(`^(\s*)(#\+[^:\s]+:)(.*)$`)
+	rawCommentRegexp  = regexp.MustCompile(`^\s*#(\s.*)?$`)
+	rawDrawerRegexp   = regexp.MustCompile(`^(\s*)(:[\w-]+\+?:)(.*)$`)
+	rawListRegexp     = regexp.MustCompile(`^(\s*)([-+]|\d+[.)]|[a-zA-Z][.)])(\s+)(\[[ Xx-]\])?(.*)$`)
+	rawTableRegexp    = regexp.MustCompile(`^\s*\|`)
+	rawPlanningRegexp = regexp.MustCompile(`\b(SCHEDULED|DEADLINE|CLOSED):`)
+
+	// Inline constructs, tried in order at each position
+	rawInlineRegexp = regexp.MustCompile(
+		`\[\[[^\]]*\](?:\[[^\]]*\])?\]` + // links
+			`|<\d{4}-\d{2}-\d{2}[^>\n]*>` + // active timestamps
+			`|\[\d{4}-\d{2}-\d{2}[^\]\n]*\]` + // inactive timestamps
+			`|\[fn:[^\]\n]*\]` + // footnote references
+			`|\[\d*/\d*\]|\[\d*%\]` + // statistic cookies
+			`|` + emphasisPattern(`*`) + `|` + emphasisPattern(`/`) + `|` + emphasisPattern(`_`) +
+			`|` + emphasisPattern(`=`) + `|` + emphasisPattern(`~`) + `|` + emphasisPattern(`+`),
+	)
+)
+
+// isTimestamp reports whether span is a whole org timestamp
+func isTimestamp(span string) bool {
+	_, ok := org.ParseTimestamp(span)
+	return ok
+}
+
+// emphasisPattern returns a pattern matching text enclosed in marker,
+// without whitespace just inside the markers
+func emphasisPattern(marker string) string {
+	m := regexp.QuoteMeta(marker)
+	return m + `[^\s` + m + `](?:[^` + m + `\n]*?[^\s` + m + `])?` + m
+}
+
+// rawHighlighter styles raw org source line by line. The output contains
+// exactly the input bytes plus ANSI escape sequences.
+type rawHighlighter struct {
+	styles       *Styles
+	todoKeywords map[string]bool
+	doneKeywords map[string]bool
+	inBlock      bool
+}
+
+// newRawHighlighter creates a highlighter for a document whose TODO
+// keyword setting is todoSetting (e.g. "TODO NEXT | DONE")
+func newRawHighlighter(styles *Styles, todoSetting string) *rawHighlighter {
+	h := &rawHighlighter{
+		styles:       styles,
+		todoKeywords: map[string]bool{},
+		doneKeywords: map[string]bool{},
+	}
+	done := false
+	for _, kw := range strings.Fields(todoSetting) {
+		if kw == "|" {
+			done = true
+			continue
+		}
+		// Strip fast-access keys like TODO(t)
+		if idx := strings.Index(kw, "("); idx > 0 {
+			kw = kw[:idx]
+		}
+		h.todoKeywords[kw] = true
+		if done {
+			h.doneKeywords[kw] = true
+		}
+	}
+	// Without a separator the last keyword is the done state
+	if !done {
+		fields := strings.Fields(todoSetting)
+		if len(fields) > 0 {
+			h.doneKeywords[fields[len(fields)-1]] = true
+		}
+	}
+	return h
+}
+
+// HighlightOrgSource returns content with org syntax highlighting applied
+func HighlightOrgSource(styles *Styles, todoSetting, content string) string {
+	h := newRawHighlighter(styles, todoSetting)
+	lines := strings.Split(content, "\n")
+	for i, line := range lines {
+		lines[i] = h.line(line)
+	}
+	return strings.Join(lines, "\n")
+}
+
+// paint renders s with style without altering its bytes
+func paint(style lipgloss.Style, s string) string {
+	if s == "" {
+		return ""
+	}
+	return style.Inline(true).TabWidth(lipgloss.NoTabConversion).Render(s)
+}
+
+// line highlights a single line of org source
+func (h *rawHighlighter) line(line string) string {
+	s := h.styles
+
+	// Block delimiters and their contents
+	if m := rawBlockRegexp.FindStringSubmatch(line); m != nil {
+		h.inBlock = strings.EqualFold(m[3], "begin")
+		return m[1] + paint(s.BlockHeader, m[2]) + paint(s.KeywordValue, m[4])
+	}
+	if h.inBlock {
+		return paint(s.Verbatim, line)
+	}
+
+	if m := rawHeadlineRegexp.FindStringSubmatch(line); m != nil {
+		return h.headline(len(m[1]), m[1], m[2], m[3])
+	}
+	if m := rawKeywordRegexp.FindStringSubmatch(line); m != nil {
+		return m[1] + paint(s.Keyword, m[2]) + paint(s.KeywordValue, m[3])
+	}
+	if rawCommentRegexp.MatchString(line) {
+		return paint(s.HelpText, line)
+	}
+	if m := rawDrawerRegexp.FindStringSubmatch(line); m != nil {
+		return m[1] + paint(s.DrawerHeader, m[2]) + paint(s.Property, m[3])
+	}
+	if rawTableRegexp.MatchString(line) {
+		return h.table(line)
+	}
+	if m := rawListRegexp.FindStringSubmatch(line); m != nil {
+		checkbox := ""
+		if m[4] != "" {
+			checkbox = paint(s.CheckboxEmpty, m[4])
+			if strings.ContainsAny(m[4], "Xx") {
+				checkbox = paint(s.CheckboxDone, m[4])
+			} else if strings.Contains(m[4], "-") {
+				checkbox = paint(s.CheckboxPartial, m[4])
+			}
+		}
+		return m[1] + paint(s.ListBullet, m[2]) + m[3] + checkbox + h.inline(m[5])
+	}
+
+	return h.inline(line)
+}
+
+// headline highlights a headline: stars, TODO keyword, priority, title and tags
+func (h *rawHighlighter) headline(level int, stars, space, rest string) string {
+	s := h.styles
+
+	var style lipgloss.Style
+	switch level {
+	case 1:
+		style = s.Heading1
+	case 2:
+		style = s.Heading2
+	case 3:
+		style = s.Heading3
+	default:
+		style = s.Heading4
+	}
+
+	var b strings.Builder
+	b.WriteString(paint(style, stars))
+	b.WriteString(space)
+
+	// TODO keyword
+	if word, after, found := strings.Cut(rest, " "); found && h.todoKeywords[word] {
+		if h.doneKeywords[word] {
+			b.WriteString(paint(s.Done.UnsetPadding(), word))
+		} else {
+			b.WriteString(paint(s.Todo.UnsetPadding(), word))
+		}
+		b.WriteString(" ")
+		rest = after
+	}
+
+	// Priority cookie
+	if p := rawPriorityRegexp.FindString(rest); p != "" {
+		b.WriteString(paint(s.Priority, p))
+		rest = rest[len(p):]
+	}
+
+	// Tags
+	tags := ""
+	if m := rawTagsRegexp.FindStringSubmatchIndex(rest); m != nil {
+		tags = rest[m[2]:m[3]] + paint(s.Tag, rest[m[4]:m[5]]) + rest[m[6]:m[7]]
+		rest = rest[:m[0]]
+	}
+
+	b.WriteString(paint(style, rest))
+	b.WriteString(tags)
+	return b.String()
+}
+
+// table highlights table rows, styling the borders
+func (h *rawHighlighter) table(line string) string {
+	var b strings.Builder
+	trimmed := strings.TrimSpace(line)
+	if strings.HasPrefix(trimmed, "|-") || strings.HasPrefix(trimmed, "|+") {
+		return paint(h.styles.TableBorder, line)
+	}
+	cells := strings.Split(line, "|")
+	for i, cell := range cells {
+		if i > 0 {
+			b.WriteString(paint(h.styles.TableBorder, "|"))
+		}
+		if i == 0 {
+			b.WriteString(cell)
+		} else {
+			b.WriteString(h.inline(cell))
+		}
+	}
+	return b.String()
+}
+
+// inline highlights inline markup, links, timestamps and planning keywords
+func (h *rawHighlighter) inline(text string) string {
+	s := h.styles
+	var b strings.Builder
+
+	pos := 0
+	for _, loc := range rawInlineRegexp.FindAllStringIndex(text, -1) {
+		start, end := loc[0], loc[1]
+		span := text[start:end]
+
+		var style lipgloss.Style
+		switch {
+		case strings.HasPrefix(span, "[["):
+			style = s.Link
+		case strings.HasPrefix(span, "<"):
+			style = s.Timestamp.UnsetPadding()
+		case strings.HasPrefix(span, "[fn:"):
+			style = s.FootnoteRef
+		case strings.HasPrefix(span, "[") && isTimestamp(span):
+			style = s.TimestampInactive
+		case strings.HasPrefix(span, "["):
+			style = s.Statistics
+		default:
+			var ok bool
+			if style, ok = h.emphasisStyle(text, start, end); !ok {
+				continue
+			}
+		}
+
+		b.WriteString(h.planning(text[pos:start]))
+		b.WriteString(paint(style, span))
+		pos = end
+	}
+	b.WriteString(h.planning(text[pos:]))
+	return b.String()
+}
+
+// emphasisStyle validates an emphasis candidate at text[start:end] against
+// org's border rules and returns the style for its marker
+func (h *rawHighlighter) emphasisStyle(text string, start, end int) (lipgloss.Style, bool) {
+	s := h.styles
+	marker := text[start]
+	if start > 0 {
+		prev, _ := utf8.DecodeLastRuneInString(text[:start])
+		if !unicode.IsSpace(prev) && !strings.ContainsRune(`-({'"`, prev) {
+			return lipgloss.Style{}, false
+		}
+	}
+	if end < len(text) {
+		next, _ := utf8.DecodeRuneInString(text[end:])
+		if !unicode.IsSpace(next) && !strings.ContainsRune(`-.,:!?;'")}[`, next) {
+			return lipgloss.Style{}, false
+		}
+	}
+
+	switch marker {
+	case '*':
+		return s.Bold, true
+	case '/':
+		return s.Italic, true
+	case '_':
+		return s.Underline, true
+	case '=':
+		return s.Verbatim, true
+	case '~':
+		return s.InlineCode, true
+	default:
+		return s.Strikethrough, true
+	}
+}
+
+// planning highlights SCHEDULED/DEADLINE/CLOSED keywords in plain text
+func (h *rawHighlighter) planning(text string) string {
+	return rawPlanningRegexp.ReplaceAllStringFunc(text, func(kw string) string {
+		switch kw {
+		case "SCHEDULED:":
+			return paint(h.styles.Scheduled, kw)
+		case "DEADLINE:":
+			return paint(h.styles.Deadline, kw)
+		default:
+			return paint(h.styles.Closed, kw)
+		}
+	})
+}
@@ -0,0 +1,210 @@
+package ui
+
+import (
+	"context"
+	"errors"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"org-charm/org"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+	"github.com/charmbracelet/x/ansi"
+)
+
+const (
+	// historyKey lists the commits of the open document
+	historyKey = "H"
+
+	// historyLimit is how many of the newest commits the history lists
+	historyLimit = 200
+
+	// historyMaxShown is the number of commits listed at once
+	historyMaxShown = 12
+
+	// historyTimeout bounds how long git may take to list the commits or
+	// read an older version
+	historyTimeout = 10 * time.Second
+)
+
+// history is the state of the git history overlay of the open document
+type history struct {
+	open     bool
+	loading  bool
+	path     string       // Path of the document the history is of
+	commits  []org.Commit // Newest first
+	selected int
+}
+
+// historyLoadedMsg reports the commits of the document at path were
+// listed, unless it failed with err
+type historyLoadedMsg struct {
+	path    string
+	commits []org.Commit
+	err     error
+}
+
+// revisionLoadedMsg reports the document at path was read as of commit,
+// unless it failed with err
+type revisionLoadedMsg struct {
+	path   string
+	commit org.Commit
+	file   *org.OrgFile
+	err    error
+}
+
+// openHistory shows the history overlay of the open document, listing
+// its commits in the background
+func (m *Model) openHistory() tea.Cmd {
+	if m.currentView != ViewDocument || m.currentDoc == nil {
+		return nil
+	}
+	path := m.currentDoc.Path
+	m.history = history{open: true, loading: true, path: path}
+	return func() tea.Msg {
+		ctx, cancel := context.WithTimeout(context.Background(), historyTimeout)
+		defer cancel()
+		commits, err := org.FileHistory(ctx, path, historyLimit)
+		return historyLoadedMsg{path, commits, err}
+	}
+}
+
+// handleHistoryLoaded lists the commits in the history overlay, or closes
+// it with the reason there are none to list
+func (m *Model) handleHistoryLoaded(msg historyLoadedMsg) tea.Cmd {
+	if !m.history.open || msg.path != m.history.path {
+		return nil
+	}
+	switch {
+	case errors.Is(msg.err, org.ErrNoHistory):
+		m.history = history{}
+		return m.setStatus("Not in a git repository")
+	case msg.err != nil:
+		m.history = history{}
+		return m.setStatus("Could not list the history: " + msg.err.Error())
+	case len(msg.commits) == 0:
+		m.history = history{}
+		return m.setStatus("No commits of " + filepath.Base(msg.path) + " yet")
+	}
+	m.history.loading = false
+	m.history.commits = msg.commits
+	if m.revision != nil {
+		// Start at the version shown
+		for i, c := range msg.commits {
+			if c.Hash == m.revision.Hash {
+				m.history.selected = i
+			}
+		}
+	}
+	return nil
+}
+
+// handleHistoryKey moves the selection of the history overlay, or closes
+// it, showing the selected version with enter
+func (m *Model) handleHistoryKey(msg tea.KeyMsg) tea.Cmd {
+	h := &m.history
+	switch msg.String() {
+	case "up", "k", "ctrl+p":
+		if h.selected > 0 {
+			h.selected--
+		}
+	case "down", "j", "ctrl+n":
+		if h.selected < len(h.commits)-1 {
+			h.selected++
+		}
+	case "home", "g":
+		h.selected = 0
+	case "end", "G":
+		h.selected = max(0, len(h.commits)-1)
+	case "enter":
+		if h.loading {
+			return nil
+		}
+		h.open = false
+		path, commit := h.path, h.commits[h.selected]
+		return func() tea.Msg {
+			ctx, cancel := context.WithTimeout(context.Background(), historyTimeout)
+			defer cancel()
+			file, err := org.FileAt(ctx, path, commit)
+			return revisionLoadedMsg{path, commit, file, err}
+		}
+	default:
+		h.open = false
+	}
+	return nil
+}
+
+// handleRevisionLoaded shows the older version of the open document in
+// its place, until esc goes back to the current one
+func (m *Model) handleRevisionLoaded(msg revisionLoadedMsg) tea.Cmd {
+	if m.currentView != ViewDocument || m.currentDoc == nil || m.currentDoc.Path != msg.path {
+		return nil
+	}
+	if msg.err != nil {
+		return m.setStatus("Could not read " + msg.commit.ShortHash() + ": " + msg.err.Error())
+	}
+	m.saveReading()
+	commit := msg.commit
+	m.revision = &commit
+	m.resetDocument(msg.file)
+	m.refreshDocument()
+	return m.setStatus("Showing " + msg.file.Name + " as of " + commit.ShortHash() + " • esc returns to the current version")
+}
+
+// leaveRevision goes back from an older version of the open document to
+// the current one, or to the file list if the file is gone
+func (m *Model) leaveRevision() tea.Cmd {
+	entry := findEntryByPath(m.fileTree, filepath.Clean(m.currentDoc.Path))
+	if entry == nil {
+		m.closeDocument()
+		return nil
+	}
+	return m.openEntry(entry)
+}
+
+// revisionLabel describes the older version shown, e.g. "abc1234 (2024-05-01)"
+func (m Model) revisionLabel() string {
+	return m.revision.ShortHash() + " (" + m.revision.Date.Local().Format("2006-01-02") + ")"
+}
+
+// renderHistory renders the history overlay over the screen
+func (m Model) renderHistory() string {
+	h := m.history
+	width := min(m.width-8, 88)
+	var b strings.Builder
+
+	b.WriteString(m.styles.HelpKey.Render("History of " + filepath.Base(h.path)))
+	b.WriteString("\n\n")
+
+	if h.loading {
+		b.WriteString(m.styles.HelpText.Render("Listing commits…"))
+	}
+
+	// Keep the selection in the window of shown commits
+	start := max(0, h.selected-historyMaxShown+1)
+	end := min(len(h.commits), start+historyMaxShown)
+	for i := start; i < end; i++ {
+		c := h.commits[i]
+		meta := c.Date.Local().Format("2006-01-02 15:04") + "  " + c.Author
+		subject := ansi.Truncate(c.Subject, width-lipgloss.Width(meta)-13, "…")
+		gap := strings.Repeat(" ", max(1, width-11-lipgloss.Width(subject)-lipgloss.Width(meta)))
+		line := c.ShortHash() + "  " + subject
+		if i == h.selected {
+			b.WriteString(m.styles.FileItemSelected.Render("▸ " + line))
+		} else {
+			b.WriteString(m.styles.FileItem.Render(line))
+		}
+		b.WriteString(gap + m.styles.HelpText.Render(meta))
+		if i < end-1 {
+			b.WriteString("\n")
+		}
+	}
+
+	b.WriteString("\n\n")
+	b.WriteString(m.styles.HelpText.Render("↑/↓ select • enter view this version • esc close"))
+
+	popup := m.styles.Sized(&m.styles.Popup, width+4).Render(b.String())
+	return lipgloss.Place(m.width, m.height, lipgloss.Center, lipgloss.Center, popup)
+}
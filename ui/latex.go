@@ -0,0 +1,144 @@
+package ui
+
+import (
+	"regexp"
+	"strings"
+
+	"github.com/charmbracelet/lipgloss"
+	"github.com/charmbracelet/x/ansi"
+	goorg "github.com/niklasfasching/go-org/org"
+)
+
+var (
+	latexEnvRegexp     = regexp.MustCompile(`^\s*\\(?:begin|end)\{([^}]+)\}\s*$`)
+	latexCommandRegexp = regexp.MustCompile(`\\([a-zA-Z]+)`)
+	latexFracRegexp    = regexp.MustCompile(`\\[dt]?frac\{([^{}]*)\}\{([^{}]*)\}`)
+	latexScriptRegexp  = regexp.MustCompile(`([\^_])(?:\{([^{}]*)\}|([a-zA-Z0-9+\-=()]))`)
+)
+
+// latexSymbols are the unicode characters LaTeX commands are approximated
+// with
+var latexSymbols = map[string]string{
+	"alpha": "α", "beta": "β", "gamma": "γ", "delta": "δ", "epsilon": "ε",
+	"varepsilon": "ε", "zeta": "ζ", "eta": "η", "theta": "θ", "iota": "ι",
+	"kappa": "κ", "lambda": "λ", "mu": "μ", "nu": "ν", "xi": "ξ", "pi": "π",
+	"rho": "ρ", "sigma": "σ", "tau": "τ", "upsilon": "υ", "phi": "φ",
+	"varphi": "φ", "chi": "χ", "psi": "ψ", "omega": "ω",
+	"Gamma": "Γ", "Delta": "Δ", "Theta": "Θ", "Lambda": "Λ", "Xi": "Ξ",
+	"Pi": "Π", "Sigma": "Σ", "Phi": "Φ", "Psi": "Ψ", "Omega": "Ω",
+	"sum": "∑", "prod": "∏", "int": "∫", "oint": "∮", "partial": "∂",
+	"nabla": "∇", "infty": "∞", "sqrt": "√", "pm": "±", "mp": "∓",
+	"times": "×", "div": "÷", "cdot": "·", "ldots": "…", "cdots": "⋯",
+	"leq": "≤", "le": "≤", "geq": "≥", "ge": "≥", "neq": "≠", "ne": "≠",
+	"approx": "≈", "equiv": "≡", "sim": "∼", "propto": "∝",
+	"in": "∈", "notin": "∉", "subset": "⊂", "subseteq": "⊆", "supset": "⊃",
+	"cup": "∪", "cap": "∩", "emptyset": "∅", "forall": "∀", "exists": "∃",
+	"neg": "¬", "wedge": "∧", "land": "∧", "vee": "∨", "lor": "∨",
+	"to": "→", "rightarrow": "→", "leftarrow": "←", "Rightarrow": "⇒",
+	"Leftarrow": "⇐", "leftrightarrow": "↔", "Leftrightarrow": "⇔",
+	"mapsto": "↦", "implies": "⟹", "iff": "⟺",
+	"left": "", "right": "", "quad": "  ", "qquad": "    ",
+}
+
+// Superscript and subscript forms of the characters that have them
+var (
+	superscripts = strings.NewReplacer(
+		"0", "⁰", "1", "¹", "2", "²", "3", "³", "4", "⁴", "5", "⁵", "6", "⁶",
+		"7", "⁷", "8", "⁸", "9", "⁹", "+", "⁺", "-", "⁻", "=", "⁼", "(", "⁽",
+		")", "⁾", "n", "ⁿ", "i", "ⁱ")
+	subscripts = strings.NewReplacer(
+		"0", "₀", "1", "₁", "2", "₂", "3", "₃", "4", "₄", "5", "₅", "6", "₆",
+		"7", "₇", "8", "₈", "9", "₉", "+", "₊", "-", "₋", "=", "₌", "(", "₍",
+		")", "₎", "a", "ₐ", "e", "ₑ", "i", "ᵢ", "j", "ⱼ", "k", "ₖ", "n", "ₙ",
+		"x", "ₓ")
+)
+
+// latexToUnicode approximates LaTeX math with unicode: symbols for known
+// commands, raised and lowered characters for scripts that have them, and
+// a slash for fractions. Anything else is kept as written.
+func latexToUnicode(s string) string {
+	s = latexFracRegexp.ReplaceAllStringFunc(s, func(m string) string {
+		parts := latexFracRegexp.FindStringSubmatch(m)
+		return fractionPart(parts[1]) + "/" + fractionPart(parts[2])
+	})
+	s = latexCommandRegexp.ReplaceAllStringFunc(s, func(m string) string {
+		if symbol, ok := latexSymbols[m[1:]]; ok {
+			return symbol
+		}
+		return m
+	})
+	return latexScriptRegexp.ReplaceAllStringFunc(s, func(m string) string {
+		parts := latexScriptRegexp.FindStringSubmatch(m)
+		replacer := superscripts
+		if parts[1] == "_" {
+			replacer = subscripts
+		}
+		// Keep scripts with characters that have no raised or lowered form
+		var b strings.Builder
+		for _, c := range parts[2] + parts[3] {
+			converted := replacer.Replace(string(c))
+			if converted == string(c) {
+				return m
+			}
+			b.WriteString(converted)
+		}
+		return b.String()
+	})
+}
+
+// fractionPart returns a numerator or denominator for an inline fraction,
+// parenthesized unless it is a single term
+func fractionPart(s string) string {
+	if strings.ContainsAny(s, " +-") {
+		return "(" + s + ")"
+	}
+	return s
+}
+
+// renderLatexBlock renders a \begin{...} ... \end{...} environment as a
+// framed block labeled with the environment's name
+func (r *Renderer) renderLatexBlock(block goorg.LatexBlock) string {
+	lines := strings.Split(exampleText(block.Content, ""), "\n")
+	name := "latex"
+	if m := latexEnvRegexp.FindStringSubmatch(lines[0]); m != nil {
+		name = m[1]
+		lines = lines[1:]
+	}
+	if n := len(lines); n > 0 && latexEnvRegexp.MatchString(lines[n-1]) {
+		lines = lines[:n-1]
+	}
+
+	body := strings.Join(lines, "\n")
+	if r.latexUnicode {
+		body = latexToUnicode(body)
+	}
+	return r.renderLatexFrame(name, body)
+}
+
+// renderExportBlock renders a #+BEGIN_EXPORT block, which only export to
+// its backend uses, as a framed block labeled with the backend
+func (r *Renderer) renderExportBlock(block goorg.Block) string {
+	backend := "export"
+	if len(block.Parameters) > 0 {
+		backend = strings.ToLower(block.Parameters[0]) + " export"
+	}
+	return r.renderLatexFrame(backend, exampleText(block.Children, ""))
+}
+
+// renderLatexFrame renders body dimmed in a rounded frame with label set
+// into its top border
+func (r *Renderer) renderLatexFrame(label, body string) string {
+	style := r.styles.LatexBlock
+	width := r.blockWidth(style, body) - style.GetHorizontalBorderSize()
+	lines := strings.Split(style.Width(width).Render(body), "\n")
+
+	border := lipgloss.RoundedBorder()
+	top := border.TopLeft + border.Top + " " + label + " "
+	if fill := lipgloss.Width(lines[0]) - ansi.StringWidth(top) - 1; fill > 0 {
+		lines[0] = r.styles.LatexLabel.Render(top + strings.Repeat(border.Top, fill) + border.TopRight)
+	}
+
+	rendered := strings.Join(lines, "\n")
+	r.recordWide(rendered)
+	return rendered
+}
@@ -0,0 +1,149 @@
+package ui
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/charmbracelet/lipgloss"
+)
+
+// Content width settings for the document view
+const (
+	readingColumnWidth = 80 // Body width in reading column mode
+	minContentWidth    = 30 // Narrowest width the content can be set to
+	widthStep          = 4  // Columns added/removed per width key press
+	horizontalStep     = 8  // Columns panned per left/right key press
+)
+
+// availableWidth returns the widest the document body can be rendered,
+// beside the source pane in the split view
+func (m Model) availableWidth() int {
+	return m.width - 8 - m.splitWidth()
+}
+
+// contentWidth returns the width the document body is rendered at, taking
+// the manual wrap width and reading column mode into account
+func (m Model) contentWidth() int {
+	available := m.availableWidth()
+	width := available
+	if m.wrapWidth > 0 {
+		width = m.wrapWidth
+	} else if m.readingColumn && readingColumnWidth < width {
+		width = readingColumnWidth
+	}
+	if width > available {
+		width = available
+	}
+	if width < minContentWidth {
+		width = minContentWidth
+	}
+	return width
+}
+
+// adjustWrapWidth widens (delta > 0) or narrows (delta < 0) the document
+// body. Widening back to the full width returns to automatic sizing.
+func (m *Model) adjustWrapWidth(delta int) {
+	width := m.contentWidth() + delta
+	switch {
+	case width >= m.availableWidth():
+		m.wrapWidth = 0
+		if m.readingColumn && delta > 0 {
+			m.readingColumn = false
+		}
+	case width < minContentWidth:
+		m.wrapWidth = minContentWidth
+	default:
+		m.wrapWidth = width
+	}
+}
+
+// centerContent indents every line of content so that a body of the
+// given width is centered in the available width
+func (m Model) centerContent(content string, width int) string {
+	return centerLines(content, (m.availableWidth()-width)/2)
+}
+
+// centerLines indents every non-empty line of content by margin columns
+func centerLines(content string, margin int) string {
+	if margin <= 0 {
+		return content
+	}
+	pad := strings.Repeat(" ", margin)
+	lines := strings.Split(content, "\n")
+	for i, line := range lines {
+		if line != "" {
+			lines[i] = pad + line
+		}
+	}
+	return strings.Join(lines, "\n")
+}
+
+// renderScrollbar renders a one-column scrollbar for a viewport showing
+// height lines of total lines, scrolled down by offset lines
+func (m Model) renderScrollbar(height, total, offset int) string {
+	if height <= 0 {
+		return ""
+	}
+	lines := make([]string, height)
+	if total <= height {
+		for i := range lines {
+			lines[i] = " "
+		}
+		return strings.Join(lines, "\n")
+	}
+
+	// Thumb size reflects the visible fraction, position the scroll offset
+	thumb := height * height / total
+	if thumb < 1 {
+		thumb = 1
+	}
+	maxOffset := total - height
+	if offset > maxOffset {
+		offset = maxOffset
+	}
+	start := 0
+	if maxOffset > 0 {
+		start = (offset*(height-thumb) + maxOffset/2) / maxOffset
+	}
+
+	for i := range lines {
+		if i >= start && i < start+thumb {
+			lines[i] = m.styles.ScrollThumb.Render("┃")
+		} else {
+			lines[i] = m.styles.ScrollTrack.Render("│")
+		}
+	}
+	return strings.Join(lines, "\n")
+}
+
+// withScrollbar places a scrollbar for the viewport to the right of content
+func (m Model) withScrollbar(content string) string {
+	bar := m.renderScrollbar(m.viewport.Height, m.viewport.TotalLineCount(), m.viewport.YOffset)
+	return lipgloss.JoinHorizontal(lipgloss.Top, content, bar)
+}
+
+// Smallest terminal the UI lays out in; smaller ones get a notice asking
+// for a larger window instead of a wrapped, garbled layout
+const (
+	minTermWidth  = 60
+	minTermHeight = 20
+)
+
+// tooSmall reports whether the terminal is smaller than the UI needs
+func (m Model) tooSmall() bool {
+	return m.width < minTermWidth || m.height < minTermHeight
+}
+
+// renderTooSmall renders the notice shown while the terminal is too small,
+// centered in what room there is. The UI goes on as usual once the
+// terminal is resized.
+func (m Model) renderTooSmall() string {
+	notice := lipgloss.JoinVertical(lipgloss.Center,
+		m.styles.Notice.Render("Terminal too small"),
+		m.styles.HelpText.Render(fmt.Sprintf("Please resize to at least %d×%d", minTermWidth, minTermHeight)),
+		m.styles.HelpText.Render(fmt.Sprintf("(now %d×%d)", m.width, m.height)),
+	)
+	placed := lipgloss.Place(m.width, m.height, lipgloss.Center, lipgloss.Center, notice)
+	// Cut what doesn't fit in tiny terminals rather than have it wrap
+	return lipgloss.NewStyle().MaxWidth(m.width).MaxHeight(m.height).Render(placed)
+}
@@ -0,0 +1,61 @@
+package ui
+
+import (
+	"org-charm/org"
+)
+
+// followLibrary switches the file list to the latest listing of the
+// library once it's refreshed, so files added, removed or modified on disk
+// show up in every session. Expanded directories, parsed files that
+// haven't changed and the selection carry over.
+func (m *Model) followLibrary() {
+	if m.library.Version() == m.libraryVersion {
+		return
+	}
+	tree, version := m.library.Tree()
+
+	previous := map[string]*org.FileEntry{}
+	var collect func(entries []*org.FileEntry)
+	collect = func(entries []*org.FileEntry) {
+		for _, e := range entries {
+			previous[e.RelPath] = e
+			collect(e.Children)
+		}
+	}
+	collect(m.fileTree)
+	var carry func(entries []*org.FileEntry)
+	carry = func(entries []*org.FileEntry) {
+		for _, e := range entries {
+			prev := previous[e.RelPath]
+			switch {
+			case prev == nil || prev.IsDir != e.IsDir:
+			case e.IsDir:
+				e.Expanded = prev.Expanded
+			case prev.OrgFile != nil && !prev.OrgFile.Changed():
+				e.OrgFile = prev.OrgFile
+			}
+			carry(e.Children)
+		}
+	}
+	carry(tree)
+
+	var selected string
+	inTree := m.selectedIndex >= m.shortcutCount() && m.selectedIndex < len(m.flatList)
+	if inTree {
+		selected = m.flatList[m.selectedIndex].RelPath
+	}
+
+	m.fileTree, m.libraryVersion = tree, version
+	org.SortTree(m.fileTree, m.sortOrder)
+	m.refreshFlatList()
+	if inTree {
+		for i := m.shortcutCount(); i < len(m.flatList); i++ {
+			if m.flatList[i].RelPath == selected {
+				m.selectedIndex = i
+				break
+			}
+		}
+	}
+	m.collectOrgFiles()
+	m.ensureSelectedVisible()
+}
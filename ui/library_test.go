@@ -0,0 +1,39 @@
+package ui
+
+import (
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+
+	"org-charm/org"
+)
+
+func TestFollowLibrary(t *testing.T) {
+	dir := t.TempDir()
+	for _, name := range []string{"b.org", "c.org"} {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte("* "+name+"\n"), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	library, err := org.NewLibrary(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	m := NewModel(createTestRenderer(), dir, "", Options{Library: library})
+	m.selectedIndex = slices.IndexFunc(m.flatList, func(e *org.FileEntry) bool { return e.Name == "c.org" })
+
+	if err := os.WriteFile(filepath.Join(dir, "a.org"), []byte("* a\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := library.Refresh(); err != nil {
+		t.Fatal(err)
+	}
+	m.followLibrary()
+	if len(m.orgFiles) != 3 {
+		t.Errorf("session lists %d files after one was added, want 3", len(m.orgFiles))
+	}
+	if name := m.flatList[m.selectedIndex].Name; name != "c.org" {
+		t.Errorf("selection moved to %s", name)
+	}
+}
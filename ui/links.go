@@ -0,0 +1,153 @@
+package ui
+
+import (
+	"path/filepath"
+	"strings"
+
+	"org-charm/org"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+)
+
+// cycleLink moves the link selection by delta (wrapping around) and
+// scrolls the viewport so the selected link is visible
+func (m *Model) cycleLink(delta int) {
+	if len(m.docLinks) == 0 {
+		return
+	}
+
+	if m.linkIndex < 0 {
+		// Start from the first link on screen (or the last one when going back)
+		m.linkIndex = m.firstVisibleLink(delta)
+	} else {
+		m.linkIndex = (m.linkIndex + delta + len(m.docLinks)) % len(m.docLinks)
+	}
+
+	m.refreshDocument()
+	m.scrollToLine(m.docLinks[m.linkIndex].Line)
+}
+
+// firstVisibleLink returns the first link in the viewport when moving
+// forward, or the last one when moving backward. Falls back to the first
+// or last link of the document when none is visible.
+func (m Model) firstVisibleLink(delta int) int {
+	top := m.viewport.YOffset
+	bottom := top + m.viewport.Height
+
+	if delta > 0 {
+		for i, link := range m.docLinks {
+			if link.Line >= top {
+				return i
+			}
+		}
+		return 0
+	}
+
+	for i := len(m.docLinks) - 1; i >= 0; i-- {
+		if m.docLinks[i].Line < bottom {
+			return i
+		}
+	}
+	return len(m.docLinks) - 1
+}
+
+// scrollToLine scrolls the viewport so line is visible, keeping some
+// context above it when a scroll is needed
+func (m *Model) scrollToLine(line int) {
+	top := m.viewport.YOffset
+	bottom := top + m.viewport.Height
+	if line >= top && line < bottom {
+		return
+	}
+	m.viewport.SetYOffset(line - m.viewport.Height/3)
+}
+
+// followLink activates a link: internal links to places in the document
+// jump to them, org files inside the served tree and the org-roam nodes in
+// them are opened in the viewer, and everything else is shown in a popup so it can be copied
+func (m *Model) followLink(link LinkRef) tea.Cmd {
+	if _, ok := m.anchorLine(link.URL); !ok {
+		// The target may be further on than rendered yet
+		m.renderRest()
+	}
+	if line, ok := m.anchorLine(link.URL); ok {
+		m.markSet()[lastJumpMark] = m.viewport.YOffset
+		m.viewport.SetYOffset(line)
+		return nil
+	}
+	if node, ok := m.roamLink(link.URL); ok {
+		return m.openRoamNode(node)
+	}
+	if entry := m.resolveOrgLink(link.URL); entry != nil {
+		return m.openEntry(entry)
+	}
+
+	m.linkPopup = link.URL
+	return nil
+}
+
+// anchorLine returns the rendered line an internal link points to. Links
+// without a prefix name a <<target>> or named element, or else a headline.
+func (m Model) anchorLine(url string) (int, bool) {
+	if line, ok := m.docAnchors[url]; ok {
+		return line, true
+	}
+	line, ok := m.docAnchors["*"+url]
+	return line, ok
+}
+
+// resolveOrgLink maps an org file link to an entry of the file tree.
+// Only files that are part of the served tree can be opened.
+func (m Model) resolveOrgLink(url string) *org.FileEntry {
+	target := strings.TrimPrefix(url, "file:")
+
+	// Drop search options such as file:notes.org::*Heading
+	if idx := strings.Index(target, "::"); idx >= 0 {
+		target = target[:idx]
+	}
+	if !strings.HasSuffix(strings.ToLower(target), ".org") {
+		return nil
+	}
+	if strings.Contains(url, "://") {
+		return nil
+	}
+
+	base := m.rootDir
+	if m.currentDoc != nil {
+		base = filepath.Dir(m.currentDoc.Path)
+	}
+	if !filepath.IsAbs(target) {
+		target = filepath.Join(base, target)
+	}
+
+	return findEntryByPath(m.fileTree, filepath.Clean(target))
+}
+
+// findEntryByPath searches the file tree for the file with the given path
+func findEntryByPath(entries []*org.FileEntry, path string) *org.FileEntry {
+	for _, e := range entries {
+		if e.IsDir {
+			if found := findEntryByPath(e.Children, path); found != nil {
+				return found
+			}
+		} else if filepath.Clean(e.Path) == path {
+			return e
+		}
+	}
+	return nil
+}
+
+// renderLinkPopup renders the popup showing an external link target
+func (m Model) renderLinkPopup() string {
+	var b strings.Builder
+
+	b.WriteString(m.styles.Heading3.Render(linkIcon(m.linkPopup) + " Link"))
+	b.WriteString("\n\n")
+	b.WriteString(m.styles.Link.Render(m.linkPopup))
+	b.WriteString("\n\n")
+	b.WriteString(m.styles.HelpKey.Render("y") + m.styles.HelpText.Render(" copy to clipboard • any key to close"))
+
+	popup := m.styles.Popup.MaxWidth(m.width - 4).Render(b.String())
+	return lipgloss.Place(m.width, m.height, lipgloss.Center, lipgloss.Center, popup)
+}
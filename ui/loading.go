@@ -0,0 +1,132 @@
+package ui
+
+import (
+	"context"
+
+	"org-charm/org"
+
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// docParsedMsg reports a document parsed in the background
+type docParsedMsg struct {
+	entry *org.FileEntry
+	file  *org.OrgFile
+	err   error
+}
+
+// docRenderedMsg reports a document rendered in the background, with the
+// key its rendering is cached under
+type docRenderedMsg struct {
+	entry    *org.FileEntry
+	key      renderKey
+	rendered renderedDocument
+}
+
+// openEntry opens the document of a file entry. Documents are parsed on
+// first open and kept on the entry until the file changes; parsing and
+// rendering happen in the background while a spinner shows in the footer.
+func (m *Model) openEntry(entry *org.FileEntry) tea.Cmd {
+	if entry.IsDir {
+		return nil
+	}
+	m.stopLoading()
+	if entry.OrgFile != nil && !entry.OrgFile.Changed() {
+		return m.openFile(entry, entry.OrgFile)
+	}
+
+	m.loading, m.loadingStep = entry, "Opening"
+	path := entry.Path
+	parse := func() tea.Msg {
+		file, err := org.Load(path)
+		return docParsedMsg{entry, file, err}
+	}
+	return tea.Batch(m.spinner.Tick, parse)
+}
+
+// openFile opens the parsed document of entry right away if the session
+// has rendered it as it would be shown now, and renders it in the
+// background otherwise
+func (m *Model) openFile(entry *org.FileEntry, doc *org.OrgFile) tea.Cmd {
+	view := m.documentView(doc)
+	key := view.renderKey(doc)
+	if _, ok := renderCache.documents.Get(key); ok {
+		m.stopLoading()
+		m.openDocument(doc)
+		return nil
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	m.loading, m.loadingStep, m.stopRender = entry, "Rendering", cancel
+	render := func() tea.Msg {
+		rendered, err := view.renderDocument(ctx, doc)
+		if err != nil {
+			// Interrupted by the reader going elsewhere
+			return nil
+		}
+		return docRenderedMsg{entry, key, rendered}
+	}
+	return tea.Batch(m.spinner.Tick, render)
+}
+
+// stopLoading gives up on the document being opened, interrupting its
+// render
+func (m *Model) stopLoading() {
+	if m.stopRender != nil {
+		m.stopRender()
+		m.stopRender = nil
+	}
+	m.loading = nil
+}
+
+// documentView returns a copy of the model as it would be right after
+// opening doc, for rendering the document ahead of opening it
+func (m Model) documentView(doc *org.OrgFile) Model {
+	m.resetDocument(doc)
+	return m
+}
+
+// handleDocParsed keeps a document parsed in the background on its entry
+// and goes on to open it, unless another document was opened meanwhile
+func (m *Model) handleDocParsed(msg docParsedMsg) tea.Cmd {
+	current := msg.entry == m.loading
+	if msg.err != nil {
+		if !current {
+			return nil
+		}
+		m.stopLoading()
+		return m.setStatus("Could not open " + msg.entry.Name + ": " + msg.err.Error())
+	}
+
+	if msg.entry.OrgFile == nil || msg.entry.OrgFile.Changed() {
+		msg.entry.OrgFile = msg.file
+	}
+	if !current {
+		return nil
+	}
+	return m.openFile(msg.entry, msg.entry.OrgFile)
+}
+
+// handleDocRendered caches a document rendered in the background and
+// opens it, unless another document was opened meanwhile
+func (m *Model) handleDocRendered(msg docRenderedMsg) {
+	renderCache.documents.Add(msg.key, msg.rendered)
+	if msg.entry != m.loading {
+		return
+	}
+	m.stopLoading()
+	m.openDocument(msg.key.doc)
+}
+
+// loadingNotice returns the footer notice for the document being opened,
+// or exported to PDF, or "" when none is
+func (m Model) loadingNotice() string {
+	if m.loading == nil {
+		if m.exporting != "" {
+			return m.styles.Notice.Render(m.spinner.View() + " Exporting " + m.exporting + " to PDF…")
+		}
+		return ""
+	}
+	return m.styles.Notice.Render(m.spinner.View()+" "+m.loadingStep+" "+m.loading.Name+"…") +
+		" " + m.styles.HelpText.Render("(esc stops)")
+}
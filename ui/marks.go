@@ -0,0 +1,104 @@
+package ui
+
+import (
+	"fmt"
+
+	"org-charm/state"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/log"
+)
+
+// lastJumpMark is the mark holding the position before the last jump, so
+// jumping to mark ' goes back and forth as in vim
+const lastJumpMark = '\''
+
+// handleMarkKey handles vim-style marks in the document view: m{a-z} sets
+// a mark at the current scroll position and '{a-z} jumps to it. It reports
+// whether the key was consumed.
+func (m *Model) handleMarkKey(key string) (tea.Cmd, bool) {
+	if m.currentView != ViewDocument {
+		return nil, false
+	}
+
+	if op := m.keys.pendingMark; op != 0 {
+		m.keys = keySequence{}
+		if len(key) != 1 {
+			// Esc and other special keys cancel the sequence
+			return nil, true
+		}
+		if op == 'm' {
+			return m.setMark(rune(key[0])), true
+		}
+		return m.jumpToMark(rune(key[0])), true
+	}
+
+	if key == "m" || key == "'" {
+		m.keys = keySequence{pendingMark: key[0]}
+		return nil, true
+	}
+	return nil, false
+}
+
+// markSet returns the marks of the open document. Rendered and raw views
+// have separate marks as their lines differ; those of the rendered view
+// start out as the user saved them.
+func (m Model) markSet() map[rune]int {
+	key := m.currentDoc.Path
+	if m.rawView {
+		key += " (raw)"
+	}
+	set, ok := m.marks[key]
+	if !ok {
+		set = make(map[rune]int)
+		if rel, ok := m.relPath(m.currentDoc.Path); ok && !m.rawView {
+			for name, offset := range m.store.Get(m.userID).Marks[rel] {
+				if len(name) == 1 {
+					set[rune(name[0])] = offset
+				}
+			}
+		}
+		m.marks[key] = set
+	}
+	return set
+}
+
+// setMark records the current scroll position under name
+func (m *Model) setMark(name rune) tea.Cmd {
+	if name < 'a' || name > 'z' {
+		return m.setStatus("Marks are named a-z")
+	}
+	m.markSet()[name] = m.viewport.YOffset
+	m.saveMark(name)
+	return m.setStatus(fmt.Sprintf("Mark %c set", name))
+}
+
+// jumpToMark scrolls to the position recorded under name, remembering the
+// current position as mark '
+func (m *Model) jumpToMark(name rune) tea.Cmd {
+	marks := m.markSet()
+	line, ok := marks[name]
+	if !ok {
+		return m.setStatus(fmt.Sprintf("Mark %c not set", name))
+	}
+	marks[lastJumpMark] = m.viewport.YOffset
+	m.renderAhead(line + m.viewport.Height)
+	m.viewport.SetYOffset(line)
+	return nil
+}
+
+// saveMark persists the mark of the rendered view of the open document
+// under name, for the user's next sessions
+func (m *Model) saveMark(name rune) {
+	rel, ok := m.relPath(m.currentDoc.Path)
+	if !ok || m.rawView || m.revision != nil {
+		return
+	}
+	offset := m.viewport.YOffset
+	err := m.store.Update(m.userID, func(u *state.User) {
+		u.SetMark(rel, string(name), offset)
+	})
+	if err != nil {
+		log.Error("Failed to save mark", "error", err)
+	}
+}
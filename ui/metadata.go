@@ -0,0 +1,155 @@
+package ui
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"org-charm/org"
+
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// maxListTags is how many tags the file list shows for a file
+const maxListTags = 3
+
+// modificationTimeout bounds how long git may take to tell who last
+// modified a file
+const modificationTimeout = 10 * time.Second
+
+// modificationKey identifies a version of a file whose last modification
+// was looked up
+type modificationKey struct {
+	path    string
+	modTime time.Time
+}
+
+// modificationMsg reports the last modification of a file was looked up
+type modificationMsg struct{}
+
+// fileMetadata returns the metadata line shown under the selected file:
+// author, date, length, reading time, last modification, backlinks and top
+// tags
+func fileMetadata(orgFile *org.OrgFile, modified org.Modification, backlinks int) string {
+	var parts []string
+	if author := orgFile.Author(); author != "" {
+		parts = append(parts, author)
+	}
+	if date := orgFile.Date(); date != "" {
+		parts = append(parts, date)
+	}
+
+	stats := orgFile.Stats()
+	if stats.Words > 0 {
+		parts = append(parts, formatCount(stats.Words)+" words")
+		parts = append(parts, fmt.Sprintf("%d min read", stats.ReadingMinutes()))
+	}
+	if !modified.Time.IsZero() {
+		parts = append(parts, describeModification(modified, time.Now()))
+	}
+	if backlinks > 0 {
+		parts = append(parts, formatBacklinks(backlinks))
+	}
+	if len(stats.Tags) > 0 {
+		tags := stats.Tags
+		more := ""
+		if len(tags) > maxListTags {
+			more = fmt.Sprintf(" +%d", len(tags)-maxListTags)
+			tags = tags[:maxListTags]
+		}
+		parts = append(parts, "#"+strings.Join(tags, " #")+more)
+	}
+
+	return strings.Join(parts, " • ")
+}
+
+// lastModification returns who last modified the file at path, modified
+// at modTime, and when, as last looked up, or just its modification time
+// until it's looked up
+func lastModification(path string, modTime time.Time) org.Modification {
+	if mod, ok := org.CachedModification(path, modTime); ok {
+		return mod
+	}
+	return org.Modification{Time: modTime}
+}
+
+// lookUpModification returns the command looking up in the background who
+// last modified the open document or the selected file, unless it was
+// looked up already
+func (m *Model) lookUpModification() tea.Cmd {
+	var key modificationKey
+	switch {
+	case m.currentView == ViewDocument && m.currentDoc != nil && m.revision == nil:
+		key = modificationKey{m.currentDoc.Path, m.currentDoc.ModTime}
+	case m.currentView == ViewFileList && m.selectedIndex < len(m.flatList) && !m.flatList[m.selectedIndex].IsDir:
+		entry := m.flatList[m.selectedIndex]
+		key = modificationKey{entry.Path, entry.ModTime}
+	default:
+		return nil
+	}
+	if key == m.modificationKey {
+		return nil
+	}
+	// Looked up once per version, even if it fails
+	m.modificationKey = key
+	if _, ok := org.CachedModification(key.path, key.modTime); ok {
+		return nil
+	}
+	return func() tea.Msg {
+		ctx, cancel := context.WithTimeout(context.Background(), modificationTimeout)
+		defer cancel()
+		org.LastModification(ctx, key.path)
+		return modificationMsg{}
+	}
+}
+
+// describeModification describes who last modified a file and when, e.g.
+// "modified 2h ago by Ada"
+func describeModification(mod org.Modification, now time.Time) string {
+	s := "modified " + formatModTime(mod.Time, now)
+	switch {
+	case mod.Author != "":
+		s += " by " + mod.Author
+	case mod.Uncommitted:
+		s += ", not committed"
+	}
+	return s
+}
+
+// formatBacklinks describes how many nodes link to a file, e.g. "3 backlinks"
+func formatBacklinks(n int) string {
+	if n == 1 {
+		return "1 backlink"
+	}
+	return formatCount(n) + " backlinks"
+}
+
+// formatCount formats n with thousands separators, e.g. 12,345
+func formatCount(n int) string {
+	s := fmt.Sprint(n)
+	for i := len(s) - 3; i > 0; i -= 3 {
+		s = s[:i] + "," + s[i:]
+	}
+	return s
+}
+
+// formatModTime formats a modification time relative to now for recent
+// changes and as a date otherwise
+func formatModTime(t, now time.Time) string {
+	d := now.Sub(t)
+	switch {
+	case d < time.Minute:
+		return "just now"
+	case d < time.Hour:
+		return fmt.Sprintf("%dm ago", int(d.Minutes()))
+	case d < 24*time.Hour:
+		return fmt.Sprintf("%dh ago", int(d.Hours()))
+	case d < 7*24*time.Hour:
+		return fmt.Sprintf("%dd ago", int(d.Hours()/24))
+	case t.Year() == now.Year():
+		return t.Format("Jan 2")
+	default:
+		return t.Format("Jan 2, 2006")
+	}
+}
@@ -1,19 +1,25 @@
 package ui
 
 import (
-	cryptorand "crypto/rand"
+	"context"
 	"fmt"
+	"maps"
 	"math"
-	"math/big"
+	"math/rand/v2"
+	"path/filepath"
 	"strings"
 	"time"
 
+	"org-charm/analytics"
 	"org-charm/org"
+	"org-charm/state"
 
+	"github.com/charmbracelet/bubbles/spinner"
 	"github.com/charmbracelet/bubbles/viewport"
 	tea "github.com/charmbracelet/bubbletea"
 	"github.com/charmbracelet/harmonica"
 	"github.com/charmbracelet/lipgloss"
+	"github.com/charmbracelet/x/ansi"
 )
 
 // View represents which view is currently active
@@ -42,47 +48,112 @@ const (
 )
 
 // Particle characters for poof effect
-var poofChars = []rune{'·', '∘', '°', '⋅', '✦', '✧', '∗', '⁕', '※', ' '}
+var poofParticles = []string{"·", "∘", "°", "⋅", "✦", "✧", "∗", "⁕", "※", " "}
 
 // animTickMsg is sent on each animation frame
 type animTickMsg time.Time
 
-// secureRandInt returns a random int in [0, max) using crypto/rand
-func secureRandInt(max int) int {
-	if max <= 0 {
-		return 0
-	}
-	n, err := cryptorand.Int(cryptorand.Reader, big.NewInt(int64(max)))
-	if err != nil {
-		return 0
-	}
-	return int(n.Int64())
+// randomParticle returns a random particle of the poof effect
+func randomParticle(rng *rand.Rand) string {
+	return poofParticles[rng.IntN(len(poofParticles))]
 }
 
-// secureRandRune returns a random rune from the given slice
-func secureRandRune(runes []rune) rune {
-	return runes[secureRandInt(len(runes))]
+// Options holds server-wide settings for each session's UI
+type Options struct {
+	// Hyperlinks emits OSC 8 escape sequences so terminals that support
+	// them make web and mail links clickable
+	Hyperlinks bool
+
+	// StatusTemplate is the document status bar template, using tokens such
+	// as {file}, {scroll}, {words}, {heading} and {time}
+	StatusTemplate string
+
+	// SortOrder is the initial ordering of files in the file list
+	SortOrder org.SortOrder
+
+	// Motion is the initial animation setting; sessions can change it
+	Motion Motion
+
+	// ChromaStyle is the syntax highlighting style of source blocks, used
+	// unless a document sets its own with #+PROPERTY: chroma-style. Empty
+	// uses the style matching the theme.
+	ChromaStyle string
+
+	// ShowExcluded renders COMMENT headlines and subtrees tagged with an
+	// export exclusion tag (:noexport: by default) dimmed instead of
+	// hiding them
+	ShowExcluded bool
+
+	// Graphics is the protocol images are drawn with; GraphicsAuto is
+	// resolved per session by the SSH handler
+	Graphics GraphicsProtocol
+
+	// ZebraTables stripes alternate table rows; sessions can toggle it
+	ZebraTables bool
+
+	// GuessLanguage highlights source blocks without a language in the
+	// language their code looks like
+	GuessLanguage bool
+
+	// LatexUnicode approximates the math of LaTeX environments with
+	// unicode symbols
+	LatexUnicode bool
+
+	// FoldCode folds source blocks longer than this many lines to a
+	// preview that expands with enter; 0 never folds them
+	FoldCode int
+
+	// PDFCommand converts a document to PDF, with {input} and {output}
+	// standing for the paths of the org file and the PDF; PDFs are
+	// written under ExportsDir. Without it, PDF export is disabled.
+	PDFCommand []string
+	ExportsDir string
+
+	// Library is the listing of the org files shared by all sessions,
+	// which they follow as it's refreshed. Without it, the session lists
+	// the root directory on its own, once.
+	Library *org.Library
+
+	// Roam is the org-roam database of the org files, resolving id: and
+	// roam: links and listing the links to each file. Without it, links
+	// resolve within the open document only.
+	Roam *org.Roam
+
+	// Views counts the views of the documents, listing the most viewed in
+	// a Popular section of the file list. Without it, nothing is counted.
+	Views *analytics.Views
+
+	// Store holds per-user state such as recently viewed documents, and
+	// UserID identifies the session's user in it. Without either, state
+	// only lasts for the session.
+	Store  *state.Store
+	UserID string
 }
 
 // Model is the bubbletea model for the org file viewer
 type Model struct {
 	styles   *Styles
 	renderer *lipgloss.Renderer
+	options  Options
 
 	// Window dimensions
 	width  int
 	height int
 
 	// File tree state (ranger-style)
-	rootDir       string           // Root directory for org files
-	fileTree      []*org.FileEntry // Root level entries
-	flatList      []*org.FileEntry // Flattened visible entries
-	selectedIndex int              // Currently selected index in flatList
-	listOffset    int              // Scroll offset for file list
+	rootDir        string           // Root directory for org files
+	library        *org.Library     // Listing of the files shared by all sessions
+	libraryVersion uint64           // Version of the listing fileTree is a copy of
+	fileTree       []*org.FileEntry // Root level entries
+	flatList       []*org.FileEntry // Flattened visible entries
+	selectedIndex  int              // Currently selected index in flatList
+	listOffset     int              // Scroll offset for file list
+	sortOrder      org.SortOrder    // Ordering of files within each directory
+	sections       []listSection    // Pinned, recent and popular shortcut sections at the top of flatList
 
 	// Legacy compatibility
 	files    []string
-	orgFiles []*org.OrgFile
+	orgFiles []*org.FileEntry // Documents (non-index files) in file list order, for n/p
 
 	// Index file for main page (optional)
 	indexFile *org.OrgFile
@@ -97,16 +168,146 @@ type Model struct {
 	// Current document being viewed
 	currentDoc *org.OrgFile
 
-	// Show help overlay
-	showHelp bool
+	// Document being parsed or rendered in the background to be opened,
+	// the step it is at ("Opening" or "Rendering"), the spinner shown
+	// meanwhile and the function interrupting its render
+	loading     *org.FileEntry
+	loadingStep string
+	spinner     spinner.Model
+	stopRender  context.CancelFunc
+
+	// Name of the document being exported to PDF, the spinner showing
+	// meanwhile too
+	exporting string
+
+	// Show help overlay, scrolled in its own viewport
+	showHelp     bool
+	helpViewport viewport.Model
+
+	// Command palette opened with :
+	palette palette
+
+	// Git history of the open document listed with H, and the commit of
+	// the older version of the document shown in its place, if any
+	history  history
+	revision *org.Commit
+
+	// Linked references to the open document listed with R, and the
+	// headline to jump to in the document being opened for one
+	references    references
+	pendingAnchor pendingAnchor
+
+	// Entries scheduled or due in the coming days, listed with a
+	agenda agenda
+
+	// File whose last modification was looked up last
+	modificationKey modificationKey
 
 	// Show raw org content instead of rendered
 	rawView bool
 
+	// Show line numbers in the raw view
+	lineNumbers bool
+
+	// Document body width: manual wrap width (0 for automatic) and
+	// reading column mode, which caps and centers the body on wide terminals
+	wrapWidth     int
+	readingColumn bool
+
+	// Truncate wide code and tables (scrolled with left/right) instead of
+	// soft-wrapping them
+	noWrap bool
+
+	// Link selection state for the current document
+	docLinks  []LinkRef // Links in the rendered document
+	linkIndex int       // Selected link (-1 for none)
+	linkPopup string    // URL shown in the link popup ("" when hidden)
+
+	// Headlines of the rendered document, for outline-aware features
+	docHeadings []HeadingRef
+
+	// Lines internal links jump to, by the name links use (see AnchorRef)
+	docAnchors map[string]int
+
+	// Rest of the document when it is rendered in chunks as the reader
+	// gets to them (see docChunks)
+	docMore *docChunks
+
+	// Lines of the viewport content, and the lines starting a block of
+	// text in it, for { and }
+	docLines      []string
+	docParagraphs []int
+
+	// Lines selected in visual mode
+	visual visualSelection
+
+	// Reading progress of the open document when it was last saved
+	savedProgress float64
+
+	// Blocks wider than the viewport and how far each is scrolled sideways
+	docBlocks    []BlockRef
+	blockOffsets []int
+
+	// Source blocks of the document, for copying their code, and those
+	// expanded after being folded for their length
+	docCode      []CodeRef
+	unfoldedCode map[int]bool
+	showHeaders  bool // Show babel header arguments in source block labels
+
+	// Drawers of the document, those expanded (or collapsed, when all are
+	// expanded) one by one, and whether all drawers are expanded
+	docDrawers     []DrawerRef
+	toggledDrawers map[int]bool
+	expandDrawers  bool
+
+	// Tables of the document, the row cursor moving through one of them,
+	// and whether alternate rows are striped
+	docTables   []TableRef
+	tableRow    tableCursor
+	zebraTables bool
+
+	// Whether the org source is shown beside the rendered document, the
+	// source's lines and the anchors keeping the two scrolled together
+	splitView    bool
+	splitRaw     []string
+	splitAnchors []splitAnchor
+
+	// Partially typed key sequence (count prefix, pending g or mark), and
+	// whether its completions panel is shown
+	keys     keySequence
+	keysID   int
+	whichKey bool
+
+	// Marks set with m{a-z}, per document for the session (shared by copies
+	// of the model)
+	marks map[string]map[rune]int
+
+	// Last click in the file list, for double-click detection
+	lastClickIndex int
+	lastClickTime  time.Time
+
+	// Transient notice shown in the footer
+	status   string
+	statusID int
+
+	// Per-user state
+	store  *state.Store
+	userID string
+
+	// Resource use of the session, shared by copies of the model
+	account *sessionAccount
+
+	// UI state and viewing preferences as last saved for resuming the
+	// session, and the id of the latest scheduled save
+	savedSession     state.Session
+	savedPreferences state.Preferences
+	sessionSaveID    int
+
 	// Changelog content for credits view
 	changelog string
 
 	// Animation state
+	motion          Motion // Animation setting of the session
 	animType        AnimationType
 	animSpring      harmonica.Spring
 	animValue       float64 // Current animation progress (0.0 to 1.0)
@@ -115,40 +316,61 @@ type Model struct {
 	animFromContent string  // Content before transition (for poof)
 	animToContent   string  // Content after transition (for poof)
 	animContent     string  // Original content to reveal (for wave)
+	animSeed        uint64  // Seed of the randomness of the animation (for poof)
+	frame           *animFrame
 }
 
 // NewModel creates a new Model with the given renderer and org files directory
-func NewModel(renderer *lipgloss.Renderer, rootDir string, changelog string) Model {
+func NewModel(renderer *lipgloss.Renderer, rootDir string, changelog string, options Options) Model {
 	m := Model{
 		renderer:      renderer,
 		styles:        NewStyles(renderer),
+		options:       options,
 		changelog:     changelog,
 		rootDir:       rootDir,
-		orgFiles:      make([]*org.OrgFile, 0),
+		orgFiles:      make([]*org.FileEntry, 0),
+		spinner:       spinner.New(spinner.WithSpinner(spinner.Dot)),
+		frame:         &animFrame{},
+		account:       &sessionAccount{},
 		selectedIndex: 0,
 		listOffset:    0,
 		currentView:   ViewFileList,
 		showHelp:      false,
-		// Initialize animation - start with wave ripple
-		animType:     AnimWaveRipple,
-		animSpring:   harmonica.NewSpring(harmonica.FPS(animFPS), animFrequency, animDamping),
-		animValue:    0.0,
-		animVelocity: 0.0,
-		animTarget:   1.0,
+		linkIndex:     -1,
+		marks:         make(map[string]map[rune]int),
+		sortOrder:     options.SortOrder,
+		store:         options.Store,
+		library:       options.Library,
+		userID:        options.UserID,
+		motion:        options.Motion,
+		zebraTables:   options.ZebraTables,
 	}
 
-	// Build file tree
-	tree, err := org.BuildFileTree(rootDir)
-	if err == nil {
-		m.fileTree = tree
-		// Expand root level by default
-		for _, e := range m.fileTree {
-			if e.IsDir {
-				e.Expanded = true
-			}
+	// Initialize animation - start with wave ripple
+	m.animSpring = m.newAnimSpring()
+	m.startAnimation(AnimWaveRipple)
+
+	// Anonymous sessions keep their state to themselves
+	if m.store == nil || m.userID == "" {
+		m.store, _ = state.NewStore("")
+		m.userID = "session"
+	}
+	// Preferences are saved once they differ from the server's defaults
+	m.savedPreferences = m.preferences()
+
+	// Take a copy of the file tree shared with other sessions
+	if m.library == nil {
+		m.library, _ = org.NewLibrary(rootDir)
+	}
+	m.fileTree, m.libraryVersion = m.library.Tree()
+	// Expand root level by default
+	for _, e := range m.fileTree {
+		if e.IsDir {
+			e.Expanded = true
 		}
-		m.flatList = org.FlattenTree(m.fileTree)
 	}
+	org.SortTree(m.fileTree, m.sortOrder)
+	m.refreshFlatList()
 
 	// Check for index.org at root level
 	for _, entry := range m.fileTree {
@@ -160,21 +382,94 @@ func NewModel(renderer *lipgloss.Renderer, rootDir string, changelog string) Mod
 		}
 	}
 
-	// Build legacy orgFiles list (non-index files)
-	for _, entry := range m.flatList {
+	m.collectOrgFiles()
+
+	return m
+}
+
+// collectOrgFiles builds the legacy orgFiles list (non-index files) in
+// file list order. Files are parsed when first opened, not here.
+func (m *Model) collectOrgFiles() {
+	m.orgFiles = m.orgFiles[:0]
+	for _, entry := range m.flatList[m.shortcutCount():] {
 		if !entry.IsDir && strings.ToLower(entry.Name) != "index.org" {
-			if orgFile, err := entry.GetOrgFile(); err == nil {
-				m.orgFiles = append(m.orgFiles, orgFile)
-			}
+			m.orgFiles = append(m.orgFiles, entry)
 		}
 	}
+}
 
-	return m
+// setSortOrder re-sorts the file tree, keeping the selected entry selected
+func (m *Model) setSortOrder(order org.SortOrder) tea.Cmd {
+	m.sortOrder = order
+	org.SortTree(m.fileTree, order)
+	m.refreshFlatList()
+	m.collectOrgFiles()
+	m.ensureSelectedVisible()
+
+	return m.setStatus("Sorted by " + sortOrderLabel(order))
 }
 
-// refreshFlatList rebuilds the flat list based on current expansion state
+// sortOrderLabel describes a sort order for display, e.g. "date ↓"
+func sortOrderLabel(order org.SortOrder) string {
+	if order.Descending {
+		return order.Field.String() + " ↓"
+	}
+	return order.Field.String() + " ↑"
+}
+
+// openSelected opens the selected file, or expands/collapses the selected
+// directory
+func (m *Model) openSelected() tea.Cmd {
+	if len(m.flatList) == 0 {
+		return nil
+	}
+	entry := m.flatList[m.selectedIndex]
+	if entry.IsDir {
+		// Toggle directory expansion
+		entry.Expanded = !entry.Expanded
+		m.refreshFlatList()
+		return nil
+	}
+	return m.openEntry(entry)
+}
+
+// refreshFlatList rebuilds the flat list based on current expansion state,
+// with the pinned, recently viewed and popular documents on top
 func (m *Model) refreshFlatList() {
-	m.flatList = org.FlattenTree(m.fileTree)
+	var selected *org.FileEntry
+	section, _ := m.sectionAt(m.selectedIndex)
+	if m.selectedIndex < len(m.flatList) {
+		selected = m.flatList[m.selectedIndex]
+	}
+
+	pinned := m.pinnedEntries()
+	recent := m.recentEntries(pinned)
+	popular := m.popularEntries(append(pinned[:len(pinned):len(pinned)], recent...))
+	m.sections = []listSection{
+		{title: "Pinned", icon: "📌", count: len(pinned)},
+		{title: "Recent", icon: "🕘", count: len(recent)},
+		{title: "Popular", icon: "🔥", count: len(popular)},
+	}
+	m.flatList = append(append(append(pinned, recent...), popular...), org.FlattenTree(m.fileTree)...)
+
+	// Keep the selection on the same entry, within the same section
+	if selected != nil {
+		start, end := m.shortcutCount(), len(m.flatList)
+		if section >= 0 {
+			start = 0
+			for _, s := range m.sections[:section] {
+				start += s.count
+			}
+			end = start + m.sections[section].count
+		}
+		for i := start; i < end; i++ {
+			if m.flatList[i] == selected {
+				m.selectedIndex = i
+				break
+			}
+		}
+	}
+
 	// Ensure selected index is valid
 	if m.selectedIndex >= len(m.flatList) {
 		m.selectedIndex = len(m.flatList) - 1
@@ -188,6 +483,7 @@ func (m *Model) refreshFlatList() {
 func (m *Model) ensureSelectedVisible() {
 	// Calculate visible area for file list
 	visibleHeight := m.height - 10 // Account for header/footer
+	visibleHeight -= m.sectionLabelLines()
 	if visibleHeight < 1 {
 		visibleHeight = 1
 	}
@@ -203,21 +499,41 @@ func (m *Model) ensureSelectedVisible() {
 	}
 }
 
-// animTick returns a command that sends animation tick messages
-func animTick() tea.Cmd {
-	return tea.Tick(time.Second/animFPS, func(t time.Time) tea.Msg {
-		return animTickMsg(t)
-	})
-}
-
 // Init implements tea.Model
 func (m Model) Init() tea.Cmd {
 	// Start entrance animation
-	return animTick()
+	if m.animType == AnimNone {
+		return tea.Batch(m.watchDocument(), m.clockTick())
+	}
+	return tea.Batch(m.animTick(), m.watchDocument(), m.clockTick())
 }
 
 // Update implements tea.Model
 func (m Model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
+	model, cmd := m.update(msg)
+	if next, ok := model.(Model); ok && next.currentView == ViewDocument {
+		next.renderAhead(next.viewport.YOffset + next.viewport.Height)
+		model = next
+	}
+	if next, ok := model.(Model); ok {
+		if lookUp := next.lookUpModification(); lookUp != nil {
+			cmd = tea.Batch(cmd, lookUp)
+		}
+		model = next
+	}
+	switch msg.(type) {
+	case tea.KeyMsg, tea.MouseMsg:
+		// Remember where the user is in case the connection drops
+		if next, ok := model.(Model); ok {
+			save := next.scheduleSessionSave()
+			return next, tea.Batch(cmd, save)
+		}
+	}
+	return model, cmd
+}
+
+// update handles a message for Update
+func (m Model) update(msg tea.Msg) (tea.Model, tea.Cmd) {
 	var cmd tea.Cmd
 	var cmds []tea.Cmd
 
@@ -229,18 +545,15 @@ func (m Model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 
 			// Check if animation is complete (must be very close to target with low velocity)
 			if m.animValue > 0.99 && abs(m.animVelocity) < 0.005 {
-				m.animValue = 1.0
-				m.animVelocity = 0.0
-				m.animType = AnimNone
-				m.animFromContent = ""
-				m.animToContent = ""
+				m.stopAnimation()
 			} else {
 				// Continue animation
-				cmds = append(cmds, animTick())
+				cmds = append(cmds, m.animTick())
 			}
 		}
 
 	case tea.WindowSizeMsg:
+		firstSize := !m.ready
 		m.width = msg.Width
 		m.height = msg.Height
 
@@ -248,108 +561,230 @@ func (m Model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 		footerHeight := 3
 		verticalMargins := headerHeight + footerHeight
 
+		// One column is reserved for the scrollbar
 		if !m.ready {
-			m.viewport = viewport.New(msg.Width-4, msg.Height-verticalMargins)
+			m.viewport = viewport.New(msg.Width-5, msg.Height-verticalMargins)
 			m.viewport.YPosition = headerHeight
 			m.viewport.HighPerformanceRendering = false
+			// Wheel events are handled by the model (see handleMouse)
+			m.viewport.MouseWheelEnabled = false
+			// Keep the pager's letter keys free for the document commands,
+			// such as d for drawers; paging stays on pgup, pgdown and space
+			m.viewport.KeyMap.PageDown.SetKeys("pgdown", " ")
+			m.viewport.KeyMap.PageUp.SetKeys("pgup")
+			m.viewport.KeyMap.HalfPageDown.SetKeys("ctrl+d")
+			m.viewport.KeyMap.HalfPageUp.SetKeys("ctrl+u")
 			m.ready = true
 		} else {
-			m.viewport.Width = msg.Width - 4
+			m.resizeViewport()
 			m.viewport.Height = msg.Height - verticalMargins
 		}
 
 		if m.currentDoc != nil {
-			m.viewport.SetContent(m.renderDocument(m.currentDoc))
+			m.refreshDocument()
+		}
+		if m.showHelp {
+			m.resizeHelp()
 		}
+		if firstSize {
+			cmds = append(cmds, m.restoreSession())
+		}
+
+	case statusMsg:
+		cmds = append(cmds, m.setStatus(string(msg)))
+
+	case clearStatusMsg:
+		if int(msg) == m.statusID {
+			m.status = ""
+		}
+
+	case docParsedMsg:
+		cmds = append(cmds, m.handleDocParsed(msg))
+
+	case docRenderedMsg:
+		m.handleDocRendered(msg)
+
+	case watchMsg:
+		m.followLibrary()
+		cmds = append(cmds, m.watchDocument())
+
+	case clockMsg:
+		cmds = append(cmds, m.clockTick())
+
+	case docChangedMsg:
+		m.followLibrary()
+		cmds = append(cmds, m.handleDocChanged(msg), m.watchDocument())
+
+	case pdfExportedMsg:
+		cmds = append(cmds, m.handlePDFExported(msg))
+
+	case modificationMsg:
+		// Shown from the lookups kept as the view is drawn again
+
+	case historyLoadedMsg:
+		cmds = append(cmds, m.handleHistoryLoaded(msg))
+
+	case revisionLoadedMsg:
+		cmds = append(cmds, m.handleRevisionLoaded(msg))
+
+	case agendaLoadedMsg:
+		cmds = append(cmds, m.handleAgendaLoaded(msg))
+
+	case spinner.TickMsg:
+		if m.loading != nil || m.exporting != "" {
+			m.spinner, cmd = m.spinner.Update(msg)
+			cmds = append(cmds, cmd)
+		}
+
+	case whichKeyMsg:
+		m.whichKey = int(msg) == m.keysID
+
+	case saveSessionMsg:
+		if int(msg) == m.sessionSaveID {
+			m.saveSession()
+		}
+
+	case tea.MouseMsg:
+		cmds = append(cmds, m.handleMouse(msg))
+		m.saveReadingIfAdvanced()
+		return m, tea.Batch(cmds...)
 
 	case tea.KeyMsg:
-		// Handle help toggle first
-		if msg.String() == "?" {
-			m.showHelp = !m.showHelp
+		// If the link popup is shown, y copies the URL and any key closes it
+		if m.linkPopup != "" {
+			url := m.linkPopup
+			m.linkPopup = ""
+			if msg.String() == "y" {
+				return m, m.copyToClipboard(url, "link")
+			}
 			return m, nil
 		}
 
-		// If help is shown, any key closes it
+		if m.palette.open {
+			return m.handlePaletteKey(msg)
+		}
+		if m.history.open {
+			return m, m.handleHistoryKey(msg)
+		}
+		if m.references.open {
+			return m, m.handleReferencesKey(msg)
+		}
+		if m.agenda.open {
+			return m, m.handleAgendaKey(msg)
+		}
+
+		// While help is shown, scroll keys scroll it and any other key closes it
 		if m.showHelp {
-			m.showHelp = false
+			m.handleHelpKey(msg.String())
+			return m, nil
+		}
+		if msg.String() == "?" {
+			m.openHelp()
+			return m, nil
+		}
+
+		// Table row focus, visual selection, marks, movement keys, counts
+		// and multi-key sequences
+		if cmd, ok := m.handleTableKey(msg.String()); ok {
+			return m, cmd
+		}
+		if cmd, ok := m.handleVisualKey(msg.String()); ok {
+			return m, cmd
+		}
+		if cmd, ok := m.handleMarkKey(msg.String()); ok {
+			return m, tea.Batch(cmd, m.scheduleWhichKey())
+		}
+		if m.handleMotion(msg.String()) {
+			m.saveReadingIfAdvanced()
+			return m, m.scheduleWhichKey()
+		}
+		if m.visual.active && msg.String() != "q" && msg.String() != "ctrl+c" {
+			// Other commands would change the lines under the selection
 			return m, nil
 		}
 
 		switch msg.String() {
 		case "q", "ctrl+c":
+			m.saveReading()
+			m.saveSession()
 			return m, tea.Quit
 
 		case "esc":
-			if m.currentView == ViewDocument {
-				m.currentView = ViewFileList
-				m.currentDoc = nil
-				m.rawView = false
+			if m.loading != nil {
+				// Stop opening a document before leaving anything
+				m.stopLoading()
+			} else if m.currentView == ViewDocument && m.linkIndex >= 0 {
+				// Leave link selection before leaving the document
+				m.linkIndex = -1
+				m.refreshDocument()
+			} else if m.currentView == ViewDocument && m.revision != nil {
+				// Back to the current version of an older one
+				cmds = append(cmds, m.leaveRevision())
+			} else if m.currentView == ViewDocument {
+				m.closeDocument()
 			} else if m.currentView == ViewCredits {
 				m.currentView = ViewFileList
 			}
 
-		case "c":
+		case "s":
 			if m.currentView == ViewFileList {
-				m.currentView = ViewCredits
-				m.viewport.SetContent(m.renderCreditsContent())
-				m.viewport.GotoTop()
+				next := m.sortOrder
+				next.Field = next.Field.Next()
+				cmds = append(cmds, m.setSortOrder(next))
 			}
 
-		case "up", "k":
+		case "S":
 			if m.currentView == ViewFileList {
-				if m.selectedIndex > 0 {
-					m.selectedIndex--
-					m.ensureSelectedVisible()
-				}
+				next := m.sortOrder
+				next.Descending = !next.Descending
+				cmds = append(cmds, m.setSortOrder(next))
 			}
 
-		case "down", "j":
-			if m.currentView == ViewFileList {
-				if m.selectedIndex < len(m.flatList)-1 {
-					m.selectedIndex++
-					m.ensureSelectedVisible()
-				}
+		case "A":
+			cmds = append(cmds, m.cycleMotion())
+
+		case ":":
+			m.openPalette()
+
+		case "*":
+			// Pin or unpin the selected file or the open document
+			if m.currentView == ViewFileList && len(m.flatList) > 0 {
+				cmds = append(cmds, m.togglePin(m.flatList[m.selectedIndex]))
+			} else if m.currentView == ViewDocument {
+				cmds = append(cmds, m.togglePin(findEntryByPath(m.fileTree, filepath.Clean(m.currentDoc.Path))))
 			}
 
-		case "home", "g":
+		case "c":
 			if m.currentView == ViewFileList {
-				m.selectedIndex = 0
-				m.listOffset = 0
-			} else {
+				m.currentView = ViewCredits
+				m.viewport.SetContent(m.renderCreditsContent())
 				m.viewport.GotoTop()
 			}
 
-		case "end", "G":
-			if m.currentView == ViewFileList {
-				m.selectedIndex = len(m.flatList) - 1
-				m.ensureSelectedVisible()
-			} else {
-				m.viewport.GotoBottom()
-			}
+		case "home":
+			m.gotoLine(1)
+
+		case "end":
+			m.gotoBottom()
 
 		case "enter", "l", "right":
-			if m.currentView == ViewFileList && len(m.flatList) > 0 {
-				entry := m.flatList[m.selectedIndex]
-				if entry.IsDir {
-					// Toggle directory expansion
-					entry.Expanded = !entry.Expanded
-					m.refreshFlatList()
-				} else {
-					// Open org file
-					if orgFile, err := entry.GetOrgFile(); err == nil {
-						m.currentDoc = orgFile
-						m.currentView = ViewDocument
-						m.viewport.SetContent(m.renderDocument(m.currentDoc))
-						m.viewport.GotoTop()
-					}
-				}
+			if m.currentView == ViewDocument && msg.String() != "enter" && m.scrollBlock(horizontalStep) {
+				// Scrolled the wide block in view
+			} else if m.currentView == ViewDocument && msg.String() == "enter" && m.linkIndex >= 0 {
+				cmds = append(cmds, m.followLink(m.docLinks[m.linkIndex]))
+			} else if m.currentView == ViewDocument && msg.String() == "enter" {
+				m.toggleCodeFold()
+			} else if m.currentView == ViewFileList {
+				cmds = append(cmds, m.openSelected())
 			}
 
 		case "h", "left":
-			if m.currentView == ViewDocument {
-				m.currentView = ViewFileList
-				m.currentDoc = nil
-				m.rawView = false
+			if m.currentView == ViewDocument && m.focusedBlock() >= 0 {
+				// Scroll the wide block in view back, never closing the
+				// document when it's scrolled to its left edge
+				m.scrollBlock(-horizontalStep)
+			} else if m.currentView == ViewDocument {
+				m.closeDocument()
 			} else if m.currentView == ViewFileList && len(m.flatList) > 0 {
 				entry := m.flatList[m.selectedIndex]
 				if entry.IsDir && entry.Expanded {
@@ -383,47 +818,137 @@ func (m Model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 				// Capture current content for poof animation
 				m.animFromContent = m.viewport.View()
 
-				// Toggle view mode
+				// Toggle view mode, leaving the split view
 				m.rawView = !m.rawView
-				if m.rawView {
-					m.viewport.SetContent(m.currentDoc.RawContent)
-				} else {
-					m.viewport.SetContent(m.renderDocument(m.currentDoc))
-				}
+				m.splitView = false
+				m.resizeViewport()
+				m.linkIndex = -1
+				m.refreshDocument()
 				m.viewport.GotoTop()
 
 				// Capture new content
 				m.animToContent = m.viewport.View()
 
 				// Start poof animation
-				m.animType = AnimPoof
-				m.animValue = 0.0
-				m.animVelocity = 0.0
-				m.animTarget = 1.0
-				cmds = append(cmds, animTick())
+				cmds = append(cmds, m.startAnimation(AnimPoof))
 			}
 
-		case "n", "tab":
+		case "n":
 			// Next document
 			if m.currentView == ViewDocument && len(m.orgFiles) > 1 {
 				m.selectedIndex = (m.selectedIndex + 1) % len(m.orgFiles)
-				m.currentDoc = m.orgFiles[m.selectedIndex]
-				m.rawView = false
-				m.viewport.SetContent(m.renderDocument(m.currentDoc))
-				m.viewport.GotoTop()
+				cmds = append(cmds, m.openEntry(m.orgFiles[m.selectedIndex]))
 			}
 
-		case "p", "shift+tab":
+		case "p":
 			// Previous document
 			if m.currentView == ViewDocument && len(m.orgFiles) > 1 {
 				m.selectedIndex--
 				if m.selectedIndex < 0 {
 					m.selectedIndex = len(m.orgFiles) - 1
 				}
-				m.currentDoc = m.orgFiles[m.selectedIndex]
-				m.rawView = false
-				m.viewport.SetContent(m.renderDocument(m.currentDoc))
-				m.viewport.GotoTop()
+				cmds = append(cmds, m.openEntry(m.orgFiles[m.selectedIndex]))
+			}
+
+		case "#":
+			// Toggle line numbers in the raw view and the split view
+			if m.currentView == ViewDocument && (m.rawView || m.splitView) {
+				m.lineNumbers = !m.lineNumbers
+				m.refreshDocument()
+			}
+
+		case "+", "=":
+			// Widen the document body
+			if m.currentView == ViewDocument && !m.rawView {
+				m.adjustWrapWidth(widthStep)
+				m.refreshDocument()
+			}
+
+		case "-":
+			// Narrow the document body
+			if m.currentView == ViewDocument && !m.rawView {
+				m.adjustWrapWidth(-widthStep)
+				m.refreshDocument()
+			}
+
+		case "Z":
+			// Toggle striped table rows
+			if m.currentView == ViewDocument && !m.rawView {
+				m.zebraTables = !m.zebraTables
+				m.refreshDocument()
+			}
+
+		case "W":
+			// Toggle soft-wrapping of wide code and tables
+			if m.currentView == ViewDocument && !m.rawView {
+				m.noWrap = !m.noWrap
+				m.blockOffsets = nil
+				m.refreshDocument()
+			}
+
+		case "w":
+			// Toggle reading column mode
+			if m.currentView == ViewDocument && !m.rawView {
+				m.readingColumn = !m.readingColumn
+				m.wrapWidth = 0
+				m.refreshDocument()
+			}
+
+		case "y":
+			cmds = append(cmds, m.yank())
+
+		case "Y":
+			cmds = append(cmds, m.yankSubtree())
+
+		case markdownKey:
+			cmds = append(cmds, m.yankMarkdown())
+
+		case pdfKey:
+			cmds = append(cmds, m.exportPDF())
+
+		case historyKey:
+			cmds = append(cmds, m.openHistory())
+
+		case referencesKey:
+			cmds = append(cmds, m.openReferences())
+
+		case agendaKey:
+			cmds = append(cmds, m.openAgenda())
+
+		case copyCodeKey:
+			cmds = append(cmds, m.yankCodeBlock())
+
+		case headersKey:
+			// Show or hide babel header arguments of source blocks
+			if m.currentView == ViewDocument && !m.rawView {
+				m.showHeaders = !m.showHeaders
+				m.refreshDocument()
+			}
+
+		case drawerKey:
+			cmds = append(cmds, m.toggleDrawer())
+
+		case splitKey:
+			m.toggleSplit()
+
+		case allDrawersKey:
+			// Expand or collapse all drawers
+			if m.currentView == ViewDocument && !m.rawView {
+				m.expandDrawers = !m.expandDrawers
+				m.toggledDrawers = nil
+				m.refreshDocument()
+			}
+
+		case "tab":
+			// Select next link
+			if m.currentView == ViewDocument && !m.rawView {
+				m.cycleLink(1)
+			}
+
+		case "shift+tab":
+			// Select previous link
+			if m.currentView == ViewDocument && !m.rawView {
+				m.cycleLink(-1)
 			}
 		}
 	}
@@ -432,6 +957,7 @@ func (m Model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 	if (m.currentView == ViewDocument || m.currentView == ViewCredits) && !m.showHelp {
 		m.viewport, cmd = m.viewport.Update(msg)
 		cmds = append(cmds, cmd)
+		m.saveReadingIfAdvanced()
 	}
 
 	return m, tea.Batch(cmds...)
@@ -442,6 +968,9 @@ func (m Model) View() string {
 	if !m.ready {
 		return m.styles.App.Render("Loading...")
 	}
+	if m.tooSmall() {
+		return m.renderTooSmall()
+	}
 
 	var content string
 	switch m.currentView {
@@ -458,6 +987,32 @@ func (m Model) View() string {
 		content = m.renderHelp()
 	}
 
+	// Overlay link popup if shown
+	if m.linkPopup != "" {
+		content = m.renderLinkPopup()
+	}
+
+	if m.palette.open {
+		content = m.renderPalette()
+	}
+
+	if m.history.open {
+		content = m.renderHistory()
+	}
+
+	if m.references.open {
+		content = m.renderReferences()
+	}
+
+	if m.agenda.open {
+		content = m.renderAgenda()
+	}
+
+	// Completions of a pending key sequence
+	if m.whichKey && m.keys != (keySequence{}) {
+		content = overlayBottom(content, m.renderWhichKey())
+	}
+
 	// Apply wave animation (entrance only)
 	if m.animType == AnimWaveRipple {
 		content = m.applyWaveRipple(content)
@@ -469,22 +1024,7 @@ func (m Model) View() string {
 
 // stripANSI removes ANSI escape sequences from a string
 func stripANSI(s string) string {
-	var result strings.Builder
-	inEscape := false
-	for _, r := range s {
-		if r == '\033' {
-			inEscape = true
-			continue
-		}
-		if inEscape {
-			if (r >= 'a' && r <= 'z') || (r >= 'A' && r <= 'Z') {
-				inEscape = false
-			}
-			continue
-		}
-		result.WriteRune(r)
-	}
-	return result.String()
+	return ansi.Strip(s)
 }
 
 // applyWaveRipple creates a radial wave effect that reveals content from the center
@@ -494,7 +1034,6 @@ func (m Model) applyWaveRipple(content string) string {
 		return content
 	}
 
-	lines := strings.Split(content, "\n")
 	centerY := m.height / 2
 	centerX := m.width / 2
 
@@ -505,99 +1044,121 @@ func (m Model) applyWaveRipple(content string) string {
 	waveRadius := m.animValue * maxDist * 1.15
 	waveWidth := maxDist * 0.12
 
-	// Blue color codes for the wave
-	blueLight := "\033[38;2;122;162;247m"
-	blueMed := "\033[38;2;86;95;137m"
-	blueDark := "\033[38;2;59;66;97m"
-	reset := "\033[0m"
-
-	var result strings.Builder
+	// The frame is drawn into the buffer of the last one. Colors and
+	// attributes of the content are followed, to be set again where the
+	// content shows after wave characters or blanks.
+	p := ansi.GetParser()
+	defer ansi.PutParser(p)
+	var pen sgrPen
+	inked := true // Whether the terminal draws with the pen
+	unink := func(buf []byte) []byte {
+		if inked && len(pen.seqs) > 0 {
+			buf = append(buf, sgrReset...)
+		}
+		inked = false
+		return buf
+	}
 
-	for y, line := range lines {
+	buf := m.frame.buf[:0]
+	y := 0
+	for line := range strings.SplitSeq(content, "\n") {
 		if y > 0 {
-			result.WriteString("\n")
+			buf = append(buf, '\n')
 		}
+		dy := float64(y - centerY)
 
-		// Process the line, tracking visual column position
+		// Process the line grapheme by grapheme, tracking the visual column
 		visualCol := 0
-		inEscape := false
-		escapeSeq := strings.Builder{}
-
-		for _, r := range line {
-			// Handle ANSI escape sequences
-			if r == '\033' {
-				inEscape = true
-				escapeSeq.Reset()
-				escapeSeq.WriteRune(r)
-				continue
-			}
-			if inEscape {
-				escapeSeq.WriteRune(r)
-				if (r >= 'a' && r <= 'z') || (r >= 'A' && r <= 'Z') {
-					inEscape = false
-					// Calculate distance for this visual position
-					dx := float64(visualCol - centerX)
-					dy := float64(y - centerY)
-					dist := math.Sqrt(dx*dx + dy*dy)
-					// Only output escape sequence if inside wave (content visible)
-					if dist < waveRadius-waveWidth {
-						result.WriteString(escapeSeq.String())
+		var state byte
+		for line != "" {
+			seq, width, n, newState := ansi.DecodeSequence(line, state, p)
+			state = newState
+			line = line[n:]
+
+			dx := float64(visualCol - centerX)
+			dist := math.Sqrt(dx*dx + dy*dy)
+			revealed := dist < waveRadius-waveWidth
+
+			if width == 0 {
+				switch {
+				case pen.update(seq, p):
+					// Colors wait for content to show where the pen is off
+					if inked {
+						buf = append(buf, seq...)
 					}
+				case ansi.HasOscPrefix(seq) || revealed:
+					// Hyperlinks open and close wherever they are; other
+					// sequences, such as images, only apply where content
+					// is revealed
+					buf = append(buf, seq...)
 				}
 				continue
 			}
 
-			// Regular character - calculate distance
-			dx := float64(visualCol - centerX)
-			dy := float64(y - centerY)
-			dist := math.Sqrt(dx*dx + dy*dy)
-
-			if dist < waveRadius-waveWidth {
+			if revealed {
 				// Inside the wave - show content (revealed)
-				result.WriteRune(r)
+				if !inked {
+					buf = append(buf, pen.seqs...)
+					inked = true
+				}
+				buf = append(buf, seq...)
 			} else if dist < waveRadius {
-				// On the wave crest - show blue wave character
-				wavePos := (waveRadius - dist) / waveWidth
-				if wavePos > 0.7 {
-					result.WriteString(blueLight + "░" + reset)
-				} else if wavePos > 0.4 {
-					result.WriteString(blueMed + "▒" + reset)
-				} else {
-					result.WriteString(blueDark + "▓" + reset)
+				// On the wave crest - show blue wave characters over every
+				// column the grapheme covers
+				buf = unink(buf)
+				crest := waveCrest((waveRadius - dist) / waveWidth)
+				for range width {
+					buf = append(buf, crest...)
 				}
 			} else {
 				// Outside the wave - dark/hidden
-				result.WriteRune(' ')
+				buf = unink(buf)
+				for range width {
+					buf = append(buf, ' ')
+				}
 			}
 
-			visualCol++
+			visualCol += width
 		}
 
 		// Pad to full width with wave effect
-		for visualCol < m.width {
+		if visualCol < m.width {
+			buf = unink(buf)
+		}
+		for ; visualCol < m.width; visualCol++ {
 			dx := float64(visualCol - centerX)
-			dy := float64(y - centerY)
 			dist := math.Sqrt(dx*dx + dy*dy)
 
-			if dist < waveRadius-waveWidth {
-				result.WriteRune(' ')
-			} else if dist < waveRadius {
-				wavePos := (waveRadius - dist) / waveWidth
-				if wavePos > 0.7 {
-					result.WriteString(blueLight + "░" + reset)
-				} else if wavePos > 0.4 {
-					result.WriteString(blueMed + "▒" + reset)
-				} else {
-					result.WriteString(blueDark + "▓" + reset)
-				}
+			if dist < waveRadius-waveWidth || dist >= waveRadius {
+				buf = append(buf, ' ')
 			} else {
-				result.WriteRune(' ')
+				buf = append(buf, waveCrest((waveRadius-dist)/waveWidth)...)
 			}
-			visualCol++
 		}
+		y++
 	}
 
-	return result.String()
+	m.frame.buf = buf
+	return string(buf)
+}
+
+// Blue wave crest characters, from the trailing edge of the wave to the
+// leading one
+const (
+	waveCrestLight = "\033[38;2;122;162;247m░\033[0m"
+	waveCrestMed   = "\033[38;2;86;95;137m▒\033[0m"
+	waveCrestDark  = "\033[38;2;59;66;97m▓\033[0m"
+)
+
+// waveCrest returns the crest character at pos across the crest, from 0 at
+// the leading edge to 1 at the trailing one
+func waveCrest(pos float64) string {
+	if pos > 0.7 {
+		return waveCrestLight
+	} else if pos > 0.4 {
+		return waveCrestMed
+	}
+	return waveCrestDark
 }
 
 // applyPoofToViewport applies poof animation to viewport content, transitioning from old to new
@@ -607,24 +1168,15 @@ func (m Model) applyPoofToViewport(fromContent, toContent string) string {
 		return toContent
 	}
 
-	fromLines := strings.Split(fromContent, "\n")
-	toLines := strings.Split(toContent, "\n")
+	// Terminal columns for visual calculations, split once per animation
+	fromCells, toCells := m.frame.poofCells(fromContent, toContent)
 
-	// Strip ANSI for visual calculations
-	fromClean := make([]string, len(fromLines))
-	toClean := make([]string, len(toLines))
-	for i, l := range fromLines {
-		fromClean[i] = stripANSI(l)
-	}
-	for i, l := range toLines {
-		toClean[i] = stripANSI(l)
-	}
+	// Particles scatter as the seed of the animation and its progress have
+	// them, so a frame drawn again comes out the same
+	rng := rand.New(rand.NewPCG(m.animSeed, math.Float64bits(m.animValue)))
 
 	// Ensure both have the same number of lines
-	maxLines := len(fromLines)
-	if len(toLines) > maxLines {
-		maxLines = len(toLines)
-	}
+	maxLines := max(len(fromCells), len(toCells))
 
 	centerX := m.width / 2
 	centerY := maxLines / 2
@@ -633,44 +1185,27 @@ func (m Model) applyPoofToViewport(fromContent, toContent string) string {
 		maxDist = 1
 	}
 
-	var result strings.Builder
+	buf := m.frame.buf[:0]
+	pen := "" // SGR sequences the frame is drawn with at this point
 
 	for y := 0; y < maxLines; y++ {
 		if y > 0 {
-			result.WriteString("\n")
+			buf = append(buf, '\n')
 		}
 
 		// Get the source lines (or empty if beyond range)
-		var fromLineClean, toLineClean string
-		if y < len(fromClean) {
-			fromLineClean = fromClean[y]
+		var fromRow, toRow []cell
+		if y < len(fromCells) {
+			fromRow = fromCells[y]
 		}
-		if y < len(toClean) {
-			toLineClean = toClean[y]
+		if y < len(toCells) {
+			toRow = toCells[y]
 		}
 
 		// Determine max visual width
-		maxCols := len([]rune(fromLineClean))
-		if len([]rune(toLineClean)) > maxCols {
-			maxCols = len([]rune(toLineClean))
-		}
-		if maxCols < m.width {
-			maxCols = m.width
-		}
-
-		fromRunes := []rune(fromLineClean)
-		toRunes := []rune(toLineClean)
+		maxCols := max(len(fromRow), len(toRow), m.viewport.Width)
 
 		for x := 0; x < maxCols; x++ {
-			// Get characters at this position
-			var fromR, toR rune = ' ', ' '
-			if x < len(fromRunes) {
-				fromR = fromRunes[x]
-			}
-			if x < len(toRunes) {
-				toR = toRunes[x]
-			}
-
 			// Position-based phase offset for organic ripple effect
 			dx := float64(x - centerX)
 			dy := float64(y - centerY)
@@ -684,35 +1219,61 @@ func (m Model) applyPoofToViewport(fromContent, toContent string) string {
 				localAnim = 1
 			}
 
-			// Three phases: show old -> scatter -> show new
+			// Three phases: show old -> scatter -> show new. Content cells
+			// may be wide and colored; particles aren't.
+			out, outPen, row := " ", "", []cell(nil)
 			if localAnim < 0.3 {
 				// Phase 1: Show old content, starting to scatter
 				scatterChance := localAnim / 0.3
-				if secureRandInt(100) < int(scatterChance*70) {
-					result.WriteRune(secureRandRune(poofChars))
+				if rng.IntN(100) < int(scatterChance*70) {
+					out = randomParticle(rng)
 				} else {
-					result.WriteRune(fromR)
+					row = fromRow
 				}
 			} else if localAnim < 0.7 {
 				// Phase 2: Maximum scatter - particles
-				if secureRandInt(100) < 75 {
-					result.WriteRune(secureRandRune(poofChars))
-				} else {
-					result.WriteRune(' ')
+				if rng.IntN(100) < 75 {
+					out = randomParticle(rng)
 				}
 			} else {
 				// Phase 3: Reform into new content
 				reformProgress := (localAnim - 0.7) / 0.3
-				if secureRandInt(100) < int(reformProgress*100) {
-					result.WriteRune(toR)
+				if rng.IntN(100) < int(reformProgress*100) {
+					row = toRow
 				} else {
-					result.WriteRune(secureRandRune(poofChars))
+					out = randomParticle(rng)
+				}
+			}
+
+			// A wide grapheme covers the next column too; a column left
+			// over from one that wasn't drawn is blank
+			if x < len(row) {
+				wide := x+1 < len(row) && row[x+1].grapheme == ""
+				switch {
+				case row[x].grapheme == "":
+				case wide && x+1 < maxCols:
+					out, outPen = row[x].grapheme, row[x].pen
+					x++
+				case !wide:
+					out, outPen = row[x].grapheme, row[x].pen
+				}
+			}
+			if outPen != pen {
+				if pen != "" {
+					buf = append(buf, sgrReset...)
 				}
+				buf = append(buf, outPen...)
+				pen = outPen
 			}
+			buf = append(buf, out...)
 		}
 	}
+	if pen != "" {
+		buf = append(buf, sgrReset...)
+	}
 
-	return result.String()
+	m.frame.buf = buf
+	return string(buf)
 }
 
 // abs returns the absolute value of a float64
@@ -723,21 +1284,24 @@ func abs(x float64) float64 {
 	return x
 }
 
-func (m Model) renderFileList() string {
+// renderFileListHeader renders what precedes the file tree: the index
+// page when there is one, or the default header
+func (m Model) renderFileListHeader() string {
 	var b strings.Builder
 
 	// If we have an index.org, render it as the main page header
 	if m.indexFile != nil {
-		renderer := NewRenderer(m.styles, m.width-8)
+		renderer := m.newRenderer(m.width - 8)
+		renderer.SetSource(m.indexFile.RawContent, m.indexFile.Document.Nodes)
 
 		// Render index title if present
 		if title := m.indexFile.Title(); title != "" {
-			b.WriteString(m.styles.DocTitle.Width(m.width - 8).Render(title))
+			b.WriteString(m.styles.Sized(&m.styles.DocTitle, m.width-8).Render(title))
 			b.WriteString("\n\n")
 		}
 
 		// Render index content
-		b.WriteString(renderer.RenderNodes(m.indexFile.Document.Nodes))
+		b.WriteString(renderer.RenderDocument(m.indexFile.Document.Nodes))
 		b.WriteString("\n")
 
 		// Section header for file list
@@ -746,124 +1310,176 @@ func (m Model) renderFileList() string {
 	} else {
 		// Default header
 		headerText := "  📚 Org Files"
-		header := m.styles.Header.Width(m.width - 4).Render(headerText)
+		header := m.styles.Sized(&m.styles.Header, m.width-4).Render(headerText)
 		b.WriteString(header)
 		b.WriteString("\n\n")
 	}
 
-	// File tree
-	if len(m.flatList) == 0 {
-		emptyMsg := m.styles.Paragraph.Render("No .org files found in the directory.")
-		b.WriteString(emptyMsg)
-	} else {
-		// Calculate visible area
-		visibleHeight := m.height - 12 // Account for header/footer
-		if visibleHeight < 1 {
-			visibleHeight = 10
-		}
+	return b.String()
+}
 
-		// Calculate visible range
-		startIdx := m.listOffset
-		endIdx := m.listOffset + visibleHeight
-		if endIdx > len(m.flatList) {
-			endIdx = len(m.flatList)
-		}
+// fileListHeight returns how many rows of the file list fit on screen
+func (m Model) fileListHeight() int {
+	visibleHeight := m.height - 12 // Account for header/footer
+	visibleHeight -= m.sectionLabelLines()
+	if visibleHeight < 1 {
+		visibleHeight = 10
+	}
+	return visibleHeight
+}
 
-		listWidth := m.width - 8
+// renderFileRows renders the visible part of the file tree. rows maps each
+// rendered line to its index in flatList, or -1 for labels and hints.
+func (m Model) renderFileRows() (string, []int) {
+	visibleHeight := m.fileListHeight()
 
-		for i := startIdx; i < endIdx; i++ {
-			entry := m.flatList[i]
-			depth := entry.GetDepth()
+	// Calculate visible range
+	startIdx := m.listOffset
+	endIdx := m.listOffset + visibleHeight
+	if endIdx > len(m.flatList) {
+		endIdx = len(m.flatList)
+	}
 
-			// Build tree prefix (ranger-style)
-			indent := strings.Repeat("  ", depth)
+	listWidth := m.listColumnWidth()
+	var list strings.Builder
+	var rows []int
+	user := m.store.Get(m.userID)
+
+	for i := startIdx; i < endIdx; i++ {
+		entry := m.flatList[i]
+		depth := entry.GetDepth()
+		section, sectionStart := m.sectionAt(i)
+
+		// Section labels above the pinned, recent and all files groups
+		if m.shortcutCount() > 0 && (i == sectionStart || i == startIdx) {
+			if i > startIdx {
+				list.WriteString("\n")
+				rows = append(rows, -1)
+			}
+			label := "All files"
+			if section >= 0 {
+				label = m.sections[section].title
+			}
+			list.WriteString(m.styles.HelpText.Render("  " + label))
+			list.WriteString("\n")
+			rows = append(rows, -1)
+		}
 
-			// Icon based on type
-			var icon string
-			if entry.IsDir {
-				if entry.Expanded {
-					icon = "📂"
-				} else {
-					icon = "📁"
-				}
+		// Build tree prefix (ranger-style); shortcut entries are not nested
+		if section >= 0 {
+			depth = 0
+		}
+		indent := strings.Repeat("  ", depth)
+
+		// Icon based on type
+		var icon string
+		if section >= 0 {
+			icon = m.sections[section].icon
+		} else if entry.IsDir {
+			if entry.Expanded {
+				icon = "📂"
 			} else {
-				icon = "📄"
+				icon = "📁"
 			}
+		} else {
+			icon = "📄"
+		}
 
-			// Get display name (title for org files, name for dirs)
-			displayName := entry.Name
-			if !entry.IsDir {
-				if orgFile, err := entry.GetOrgFile(); err == nil {
-					if title := orgFile.Title(); title != "" && title != strings.TrimSuffix(entry.Name, ".org") {
-						displayName = title
-					}
-				}
+		// Get display name (title for org files, name for dirs)
+		displayName := entry.Name
+		if !entry.IsDir {
+			// Titles come from the file headers, so listing files doesn't
+			// parse them all
+			if title := entry.Title(); title != "" && title != strings.TrimSuffix(entry.Name, ".org") {
+				displayName = title
 			}
+		}
 
-			// Build the line
-			isSelected := i == m.selectedIndex
-			var line string
+		// Reading progress badge for files
+		var badge string
+		if !entry.IsDir {
+			if rel, ok := m.relPath(entry.Path); ok {
+				r, seen := user.Reading[rel]
+				badge = " " + m.readingBadge(r, seen)
+			}
+		}
 
-			if isSelected {
-				// Selected item with arrow indicator
-				prefix := indent + "▸ " + icon + " "
-				remaining := listWidth - len([]rune(stripANSI(prefix)))
-				if remaining < 10 {
-					remaining = 10
-				}
-				// Truncate if needed
-				displayRunes := []rune(displayName)
-				if len(displayRunes) > remaining {
-					displayName = string(displayRunes[:remaining-1]) + "…"
-				}
-				line = m.styles.FileItemActive.Render(prefix + displayName)
-
-				// Show metadata for selected file
-				if !entry.IsDir {
-					if orgFile, err := entry.GetOrgFile(); err == nil {
-						var meta strings.Builder
-						if author := orgFile.Author(); author != "" {
-							meta.WriteString(author)
-						}
-						if date := orgFile.Date(); date != "" {
-							if meta.Len() > 0 {
-								meta.WriteString(" • ")
-							}
-							meta.WriteString(date)
-						}
-						if meta.Len() > 0 {
-							line += "\n" + indent + "    " + m.styles.FileMeta.Render(meta.String())
-						}
+		// Build the line
+		isSelected := i == m.selectedIndex
+		var line string
+
+		if isSelected {
+			// Selected item with arrow indicator
+			prefix := indent + "▸ " + icon + " "
+			remaining := listWidth - ansi.StringWidth(prefix) - lipgloss.Width(badge)
+			if remaining < 10 {
+				remaining = 10
+			}
+			// Truncate if needed
+			displayName = ansi.Truncate(displayName, remaining, "…")
+			line = m.styles.FileItemActive.Render(prefix+displayName) + badge
+
+			// Show metadata for selected file (the preview shows it otherwise)
+			if !entry.IsDir && !m.showPreview() {
+				if orgFile, err := entry.GetOrgFile(); err == nil {
+					if meta := fileMetadata(orgFile, lastModification(entry.Path, entry.ModTime), m.backlinkCount(entry.Path)); meta != "" {
+						metaIndent := indent + "    "
+						meta = ansi.Truncate(meta, listWidth-len(metaIndent), "…")
+						line += "\n" + metaIndent + m.styles.FileMeta.Render(meta)
 					}
 				}
+			}
+		} else {
+			prefix := indent + "  " + icon + " "
+			remaining := listWidth - ansi.StringWidth(prefix) - lipgloss.Width(badge)
+			if remaining < 10 {
+				remaining = 10
+			}
+			// Truncate if needed
+			displayName = ansi.Truncate(displayName, remaining, "…")
+			if entry.IsDir {
+				line = m.styles.FileDir.Render(prefix + displayName)
 			} else {
-				prefix := indent + "  " + icon + " "
-				remaining := listWidth - len([]rune(stripANSI(prefix)))
-				if remaining < 10 {
-					remaining = 10
-				}
-				// Truncate if needed
-				displayRunes := []rune(displayName)
-				if len(displayRunes) > remaining {
-					displayName = string(displayRunes[:remaining-1]) + "…"
-				}
-				if entry.IsDir {
-					line = m.styles.FileDir.Render(prefix + displayName)
-				} else {
-					line = m.styles.FileItem.Render(prefix + displayName)
-				}
+				line = m.styles.FileItem.Render(prefix+displayName) + badge
 			}
-
-			b.WriteString(line)
-			b.WriteString("\n")
 		}
 
-		// Show scroll indicators if needed
-		if m.listOffset > 0 {
-			b.WriteString(m.styles.HelpText.Render("  ↑ more above\n"))
+		list.WriteString(line)
+		list.WriteString("\n")
+		for range strings.Split(line, "\n") {
+			rows = append(rows, i)
 		}
-		if endIdx < len(m.flatList) {
-			b.WriteString(m.styles.HelpText.Render("  ↓ more below\n"))
+	}
+
+	// Show scroll indicators if needed
+	if m.listOffset > 0 {
+		list.WriteString(m.styles.HelpText.Render("  ↑ more above\n"))
+	}
+	if endIdx < len(m.flatList) {
+		list.WriteString(m.styles.HelpText.Render("  ↓ more below\n"))
+	}
+
+	return list.String(), rows
+}
+
+func (m Model) renderFileList() string {
+	var b strings.Builder
+
+	b.WriteString(m.renderFileListHeader())
+
+	// File tree
+	if len(m.flatList) == 0 {
+		emptyMsg := m.styles.Paragraph.Render("No .org files found in the directory.")
+		b.WriteString(emptyMsg)
+	} else {
+		list, _ := m.renderFileRows()
+
+		// Preview of the selected entry next to the list
+		if m.showPreview() {
+			preview := m.renderPreview(m.flatList[m.selectedIndex], m.width-8-m.listColumnWidth(), m.fileListHeight())
+			b.WriteString(lipgloss.JoinHorizontal(lipgloss.Top, list, preview))
+		} else {
+			b.WriteString(list)
 		}
 	}
 
@@ -874,10 +1490,18 @@ func (m Model) renderFileList() string {
 		{"→/space", "expand"},
 		{"←", "collapse"},
 		{"enter", "open"},
+		{"*", "pin"},
+		{"s", "sort: " + sortOrderLabel(m.sortOrder)},
 		{"c", "credits"},
 		{"?", "help"},
 		{"q", "quit"},
 	})
+	if m.status != "" {
+		help = m.styles.Notice.Render(m.status)
+	}
+	if notice := m.loadingNotice(); notice != "" {
+		help = notice
+	}
 	b.WriteString(help)
 
 	return m.styles.App.Render(b.String())
@@ -887,12 +1511,12 @@ func (m Model) renderCreditsView() string {
 	var b strings.Builder
 
 	// Header
-	header := m.styles.Header.Width(m.width - 4).Render("  ✨ Credits & Changelog")
+	header := m.styles.Sized(&m.styles.Header, m.width-4).Render("  ✨ Credits & Changelog")
 	b.WriteString(header)
 	b.WriteString("\n")
 
 	// Viewport content
-	b.WriteString(m.viewport.View())
+	b.WriteString(m.withScrollbar(m.viewport.View()))
 	b.WriteString("\n")
 
 	// Footer
@@ -914,7 +1538,7 @@ func (m Model) renderCreditsContent() string {
 	var b strings.Builder
 
 	// Authors section
-	b.WriteString(m.styles.DocTitle.Width(m.width - 12).Render("Authors"))
+	b.WriteString(m.styles.Sized(&m.styles.DocTitle, m.width-12).Render("Authors"))
 	b.WriteString("\n\n")
 
 	b.WriteString(m.styles.Bold.Render("  • Austin Theriault"))
@@ -924,11 +1548,11 @@ func (m Model) renderCreditsContent() string {
 	b.WriteString("\n")
 
 	b.WriteString("\n")
-	b.WriteString(m.styles.HRule.Width(m.width - 12).Render(""))
+	b.WriteString(m.styles.Sized(&m.styles.HRule, m.width-12).Render(""))
 	b.WriteString("\n\n")
 
 	// Tools section
-	b.WriteString(m.styles.DocTitle.Width(m.width - 12).Render("Built With"))
+	b.WriteString(m.styles.Sized(&m.styles.DocTitle, m.width-12).Render("Built With"))
 	b.WriteString("\n\n")
 
 	tools := []struct {
@@ -951,11 +1575,11 @@ func (m Model) renderCreditsContent() string {
 	}
 
 	b.WriteString("\n")
-	b.WriteString(m.styles.HRule.Width(m.width - 12).Render(""))
+	b.WriteString(m.styles.Sized(&m.styles.HRule, m.width-12).Render(""))
 	b.WriteString("\n\n")
 
 	// Changelog section
-	b.WriteString(m.styles.DocTitle.Width(m.width - 12).Render("Changelog"))
+	b.WriteString(m.styles.Sized(&m.styles.DocTitle, m.width-12).Render("Changelog"))
 	b.WriteString("\n\n")
 
 	// Parse and render the changelog with simple formatting
@@ -994,37 +1618,64 @@ func (m Model) renderCreditsContent() string {
 	return b.String()
 }
 
-func (m Model) renderDocumentView() string {
-	var b strings.Builder
-
-	// Header with document info
+// renderDocumentHeader renders the header line of the document view: the
+// title with the breadcrumb, or author, date, length and last
+// modification at the top
+func (m Model) renderDocumentHeader() string {
 	title := m.currentDoc.Title()
 	author := m.currentDoc.Author()
 	date := m.currentDoc.Date()
 
 	headerContent := "  📄 " + title
-	if author != "" {
-		headerContent += " — " + author
+	if m.revision != nil {
+		// An older version, as of a commit
+		headerContent = "  🕓 " + title + " @ " + m.revisionLabel()
 	}
-	if date != "" {
-		headerContent += " (" + date + ")"
+	if crumbs := m.breadcrumb(); crumbs != "" {
+		// Show where the reader is once scrolled into a section
+		headerContent += " › " + crumbs
+	} else {
+		if author != "" {
+			headerContent += " — " + author
+		}
+		if date != "" {
+			headerContent += " (" + date + ")"
+		}
+		if stats := m.currentDoc.Stats(); stats.Words > 0 || stats.CodeLines > 0 {
+			headerContent += fmt.Sprintf(" · %s words · %d min read", formatCount(stats.Words), stats.ReadingMinutes())
+		}
+		if m.revision == nil {
+			headerContent += " · " + describeModification(lastModification(m.currentDoc.Path, m.currentDoc.ModTime), time.Now())
+			if n := m.backlinkCount(m.currentDoc.Path); n > 0 {
+				headerContent += " · " + formatBacklinks(n)
+			}
+		}
 	}
+	headerContent = ansi.Truncate(headerContent, m.width-8, "…")
 
-	header := m.styles.Header.Width(m.width - 4).Render(headerContent)
-	b.WriteString(header)
+	return m.styles.Sized(&m.styles.Header, m.width-4).Render(headerContent)
+}
+
+// renderDocumentView renders the open document with its header and footer
+func (m Model) renderDocumentView() string {
+	var b strings.Builder
+
+	b.WriteString(m.renderDocumentHeader())
 	b.WriteString("\n")
 
 	// Viewport content - apply poof animation if active
-	viewportContent := m.viewport.View()
+	viewportContent := m.highlightTableRow(m.highlightSelection(m.viewport.View()))
 	if m.animType == AnimPoof {
 		viewportContent = m.applyPoofToViewport(m.animFromContent, m.animToContent)
 	}
-	b.WriteString(viewportContent)
+	if m.splitActive() {
+		viewportContent = lipgloss.JoinHorizontal(lipgloss.Top, m.renderSplitPane(), viewportContent)
+	}
+	b.WriteString(m.withScrollbar(viewportContent))
 	b.WriteString("\n")
 
-	// Footer with scroll info and help
-	scrollPercent := fmt.Sprintf("%3.f%%", m.viewport.ScrollPercent()*100)
-	scrollInfo := m.styles.StatusBar.Render(" " + scrollPercent + " ")
+	// Footer with status info and help
+	scrollInfo := m.styles.StatusBar.Render(" " + m.renderStatusText() + " ")
 
 	var rawToggle string
 	if m.rawView {
@@ -1032,13 +1683,68 @@ func (m Model) renderDocumentView() string {
 	} else {
 		rawToggle = "raw"
 	}
-	help := m.renderHelpBar([]helpItem{
+	items := []helpItem{
 		{"↑/↓", "scroll"},
 		{"n/p", "next/prev"},
 		{"r", rawToggle},
+		{splitKey, "split"},
+		{"tab", "links"},
+		{"+/-", "width"},
 		{"esc", "back"},
 		{"q", "quit"},
-	})
+	}
+	if m.focusedBlock() >= 0 {
+		items = append([]helpItem{{"h/l", "scroll block"}}, items...)
+	}
+	if m.rawView {
+		items = []helpItem{
+			{"↑/↓", "scroll"},
+			{"n/p", "next/prev"},
+			{"r", rawToggle},
+			{"#", "line numbers"},
+			{"esc", "back"},
+			{"q", "quit"},
+		}
+	}
+	if m.revision != nil {
+		items = append([]helpItem{{historyKey, "history"}}, items...)
+		for i := range items {
+			if items[i].key == "esc" {
+				items[i].desc = "current version"
+			}
+		}
+	}
+	if m.visual.active {
+		first, last := m.visual.bounds()
+		items = []helpItem{
+			{"j/k", fmt.Sprintf("select (%d lines)", last-first+1)},
+			{"o", "other end"},
+			{"y", "copy"},
+			{"esc", "cancel"},
+		}
+	}
+	if m.tableRow.active {
+		items = []helpItem{
+			{"j/k", fmt.Sprintf("row %d/%d", m.tableRow.row+1, len(m.docTables[m.tableRow.table].Rows))},
+			{"y", "copy row"},
+			{"esc", "done"},
+		}
+	}
+	if m.linkIndex >= 0 {
+		items = []helpItem{
+			{"tab/shift+tab", "next/prev link"},
+			{"enter", "follow"},
+			{"y", "copy"},
+			{"esc", "done"},
+		}
+	}
+	help := m.renderHelpBar(items)
+	if m.status != "" {
+		help = m.styles.Notice.Render(m.status)
+	}
+	if notice := m.loadingNotice(); notice != "" {
+		help = notice
+	}
 
 	footer := lipgloss.JoinHorizontal(lipgloss.Center, scrollInfo, "  ", help)
 	b.WriteString(footer)
@@ -1046,40 +1752,246 @@ func (m Model) renderDocumentView() string {
 	return m.styles.App.Render(b.String())
 }
 
-func (m Model) renderDocument(doc *org.OrgFile) string {
+// breadcrumb returns the path of headings (H1 › H2 › H3) containing the
+// top line of the viewport, or "" above the first heading
+func (m Model) breadcrumb() string {
+	if m.rawView {
+		return ""
+	}
+	path := HeadingPath(m.docHeadings, HeadingAt(m.docHeadings, m.viewport.YOffset))
+	titles := make([]string, len(path))
+	for i, h := range path {
+		titles[i] = h.Title
+	}
+	return strings.Join(titles, " › ")
+}
+
+// newRenderer creates an org renderer configured with the session options
+func (m Model) newRenderer(width int) *Renderer {
+	renderer := NewRenderer(m.styles, width)
+	renderer.SetHyperlinks(m.options.Hyperlinks)
+	renderer.SetNoWrap(m.noWrap)
+	renderer.SetChromaStyle(m.options.ChromaStyle)
+	renderer.SetShowExcluded(m.options.ShowExcluded)
+	renderer.SetZebraTables(m.zebraTables)
+	renderer.SetGraphics(m.options.Graphics)
+	renderer.SetLatexUnicode(m.options.LatexUnicode)
+	renderer.SetGuessLanguage(m.options.GuessLanguage)
+	renderer.SetShowHeaders(m.showHeaders)
+	return renderer
+}
+
+// openDocument switches to the document view showing doc
+func (m *Model) openDocument(doc *org.OrgFile) {
+	m.saveReading()
+	m.revision = nil
+	m.resetDocument(doc)
+	m.refreshDocument()
+	m.jumpToPendingAnchor()
+	m.addRecent(doc)
+	m.recordView(doc)
+	m.saveReading()
+}
+
+// resetDocument sets up the document view of doc as it starts out
+func (m *Model) resetDocument(doc *org.OrgFile) {
+	m.currentDoc = doc
+	m.currentView = ViewDocument
+	m.rawView = false
+	m.linkIndex = -1
+	m.visual = visualSelection{}
+	m.tableRow = tableCursor{}
+	m.toggledDrawers = nil
+	m.unfoldedCode = nil
+	m.blockOffsets = nil
+	m.resizeViewport()
+	m.viewport.GotoTop()
+}
+
+// closeDocument returns from the document view to the file list
+func (m *Model) closeDocument() {
+	m.saveReading()
+	m.currentView = ViewFileList
+	m.currentDoc = nil
+	m.revision = nil
+	m.rawView = false
+	m.resizeViewport()
+	m.docLinks = nil
+	m.docHeadings = nil
+	m.docAnchors = nil
+	m.docMore = nil
+	m.linkIndex = -1
+	m.account.hold(nil, 0)
+	m.refreshFlatList()
+	m.ensureSelectedVisible()
+}
+
+// refreshDocument re-renders the current document into the viewport,
+// keeping the scroll position and the link positions up to date
+func (m *Model) refreshDocument() {
+	if m.currentDoc == nil {
+		return
+	}
+	if m.rawView {
+		content := m.renderRaw(m.currentDoc)
+		m.docLinks = nil
+		m.docHeadings = nil
+		m.docAnchors = nil
+		m.docBlocks = nil
+		m.docCode = nil
+		m.docTables = nil
+		m.docDrawers = nil
+		m.docMore = nil
+		m.tableRow = tableCursor{}
+		m.docLines = strings.Split(content, "\n")
+		m.docParagraphs = paragraphStarts(content)
+		m.viewport.SetContent(content)
+		return
+	}
+	doc := m.cachedDocument(m.currentDoc)
+	if doc.more != nil {
+		// Render on to where the reader is, which a cached rendering may
+		// not be at, or has got past
+		m.docMore = doc.more
+		m.renderOn(m.viewport.YOffset + m.viewport.Height + chunkMargin)
+		return
+	}
+	m.showRendered(doc)
+}
+
+// showRendered puts a rendering of the open document into the viewport,
+// keeping the scroll position
+func (m *Model) showRendered(doc renderedDocument) {
+	m.docMore = doc.more
+	m.docLinks = doc.links
+	m.docHeadings = doc.headings
+	m.docAnchors = make(map[string]int, len(doc.anchors))
+	for _, anchor := range doc.anchors {
+		// The first of the places with a name is the one links go to
+		if _, ok := m.docAnchors[anchor.Name]; !ok && anchor.Line >= 0 {
+			m.docAnchors[anchor.Name] = anchor.Line
+		}
+	}
+	m.docBlocks = doc.blocks
+	m.docCode = doc.code
+	m.docTables = doc.tables
+	m.docDrawers = doc.drawers
+	if m.tableRow.active && !m.tableRow.valid(m.docTables) {
+		m.tableRow = tableCursor{}
+	}
+	m.docLines = strings.Split(doc.content, "\n")
+	m.docParagraphs = paragraphStarts(doc.content)
+	m.account.hold(m.currentDoc, int64(len(doc.content)))
+	m.updateSplit()
+	m.setDocContent()
+}
+
+// renderRaw returns the syntax-highlighted org source of doc, with a line
+// number gutter when line numbers are enabled
+func (m Model) renderRaw(doc *org.OrgFile) string {
+	highlighted := HighlightOrgSource(m.styles, doc.Document.Get("TODO"), doc.RawContent)
+	if !m.lineNumbers {
+		return highlighted
+	}
+
+	lines := strings.Split(strings.TrimSuffix(highlighted, "\n"), "\n")
+	digits := len(fmt.Sprint(len(lines)))
+
+	var b strings.Builder
+	for i, line := range lines {
+		if i > 0 {
+			b.WriteString("\n")
+		}
+		b.WriteString(m.styles.LineNumber.Render(fmt.Sprintf("%*d │ ", digits, i+1)))
+		b.WriteString(line)
+	}
+	return b.String()
+}
+
+// renderedDocument is a rendered document along with the positions of the
+// things in it the model navigates to
+type renderedDocument struct {
+	content  string
+	links    []LinkRef
+	headings []HeadingRef
+	blocks   []BlockRef // Blocks wider than the body
+	code     []CodeRef
+	tables   []TableRef
+	drawers  []DrawerRef
+	anchors  []AnchorRef
+	more     *docChunks // Rest of a document rendered in chunks, nil once all is
+}
+
+// renderDocument renders doc with its metadata header and locates its
+// links, headlines and blocks in the result. Long documents are rendered
+// through the viewport and a margin past it; the rest comes with more.
+// Rendering stops early with the error of ctx once it is done.
+func (m Model) renderDocument(ctx context.Context, doc *org.OrgFile) (renderedDocument, error) {
+	start := time.Now()
 	var b strings.Builder
-	renderer := NewRenderer(m.styles, m.width-8)
+	width := m.contentWidth()
+	renderer := m.newRenderer(width)
+	renderer.SetContext(ctx)
+	renderer.SetActiveLink(m.linkIndex)
+	renderer.SetSource(doc.RawContent, doc.Document.Nodes)
+	renderer.SetImageDir(m.rootDir, filepath.Dir(doc.Path))
+	if style := doc.Property("chroma-style"); style != "" {
+		renderer.SetChromaStyle(style)
+	}
+	// The renderer may go on rendering after the session toggles more
+	renderer.SetDrawers(m.expandDrawers, maps.Clone(m.toggledDrawers))
+	renderer.SetFoldCode(m.options.FoldCode, maps.Clone(m.unfoldedCode))
+	renderer.SetExcludeTags(strings.Fields(doc.Document.Get("EXCLUDE_TAGS")))
+	renderer.SetSmartPunctuation(doc.Option("-") != "nil")
 
 	// Render document metadata header
+	writeDocHeader(&b, m.styles, doc, width)
+
+	// Render document content, long documents only as far as the reader
+	// has got
+	chunks := newDocChunks(renderer, b.String(), doc.Document.Nodes, (m.availableWidth()-width)/2)
+	lines := m.viewport.YOffset + m.viewport.Height + chunkMargin
+	if strings.Count(doc.RawContent, "\n") < chunkedMinLines {
+		lines = math.MaxInt
+	}
+	rendered, err := chunks.renderUntil(lines)
+	// Sessions reading on render the rest whatever happens to this one
+	renderer.SetContext(nil)
+	if err == nil {
+		m.account.rendered(doc, time.Since(start))
+	}
+	return rendered, err
+}
+
+// writeDocHeader writes the title, author and date of doc, if set, as
+// the header of its rendering at width
+func writeDocHeader(b *strings.Builder, styles *Styles, doc *org.OrgFile, width int) {
 	title := doc.Title()
 	author := doc.Author()
 	date := doc.Date()
+	if title == "" && author == "" && date == "" {
+		return
+	}
 
-	if title != "" || author != "" || date != "" {
-		// Title
-		if title != "" {
-			b.WriteString(m.styles.DocTitle.Width(m.width - 12).Render(title))
-			b.WriteString("\n")
-		}
-
-		// Author and date line
-		var meta []string
-		if author != "" {
-			meta = append(meta, m.styles.DocAuthor.Render("by "+author))
-		}
-		if date != "" {
-			meta = append(meta, m.styles.DocDate.Render(date))
-		}
-		if len(meta) > 0 {
-			b.WriteString(strings.Join(meta, m.styles.HelpText.Render(" • ")))
-			b.WriteString("\n")
-		}
+	// Title
+	if title != "" {
+		b.WriteString(styles.Sized(&styles.DocTitle, width-4).Render(title))
 		b.WriteString("\n")
 	}
 
-	// Render document content
-	b.WriteString(renderer.RenderNodes(doc.Document.Nodes))
-	return b.String()
+	// Author and date line
+	var meta []string
+	if author != "" {
+		meta = append(meta, styles.DocAuthor.Render("by "+author))
+	}
+	if date != "" {
+		meta = append(meta, styles.DocDate.Render(date))
+	}
+	if len(meta) > 0 {
+		b.WriteString(strings.Join(meta, styles.HelpText.Render(" • ")))
+		b.WriteString("\n")
+	}
+	b.WriteString("\n")
 }
 
 type helpItem struct {
@@ -1095,66 +2007,3 @@ func (m Model) renderHelpBar(items []helpItem) string {
 	}
 	return strings.Join(parts, m.styles.HelpText.Render(" • "))
 }
-
-func (m Model) renderHelp() string {
-	var b strings.Builder
-
-	title := m.styles.DocTitle.Width(m.width - 8).Render("  ⌨️  Keyboard Shortcuts")
-	b.WriteString(title)
-	b.WriteString("\n\n")
-
-	sections := []struct {
-		name  string
-		items []helpItem
-	}{
-		{
-			name: "Navigation",
-			items: []helpItem{
-				{"↑ / k", "Move up"},
-				{"↓ / j", "Move down"},
-				{"← / h", "Go back"},
-				{"→ / l / Enter", "Open / Select"},
-				{"g / Home", "Go to top"},
-				{"G / End", "Go to bottom"},
-			},
-		},
-		{
-			name: "Document View",
-			items: []helpItem{
-				{"Page Up / Ctrl+u", "Scroll up"},
-				{"Page Down / Ctrl+d", "Scroll down"},
-				{"n / Tab", "Next document"},
-				{"p / Shift+Tab", "Previous document"},
-				{"r", "Toggle raw/rendered view"},
-				{"Esc", "Return to file list"},
-			},
-		},
-		{
-			name: "General",
-			items: []helpItem{
-				{"c", "Show credits & changelog"},
-				{"?", "Toggle this help"},
-				{"q / Ctrl+c", "Quit"},
-			},
-		},
-	}
-
-	for _, section := range sections {
-		sectionTitle := m.styles.Heading3.Render("  " + section.name)
-		b.WriteString(sectionTitle)
-		b.WriteString("\n")
-
-		for _, item := range section.items {
-			line := "    " + m.styles.HelpKey.Render(fmt.Sprintf("%-20s", item.key)) +
-				m.styles.HelpText.Render(item.desc)
-			b.WriteString(line)
-			b.WriteString("\n")
-		}
-		b.WriteString("\n")
-	}
-
-	b.WriteString("\n")
-	b.WriteString(m.styles.HelpText.Render("  Press any key to close this help"))
-
-	return m.styles.App.Render(b.String())
-}
@@ -0,0 +1,223 @@
+package ui
+
+import "strings"
+
+// maxCount caps numeric count prefixes
+const maxCount = 9999
+
+// keySequence is a partially typed multi-key command: a numeric count
+// prefix (as in 10j), a pending g (as in gg) or a pending mark command
+// (as in ma or 'a)
+type keySequence struct {
+	count       int  // Count typed so far (0 for none)
+	pendingG    bool // A g was typed and waits for the second g
+	pendingMark byte // m or ' typed and waiting for the mark name (0 for none)
+}
+
+// handleMotion handles vim-style motions in the file list, document and
+// credits views, including counts and multi-key sequences. It reports
+// whether the key was consumed.
+func (m *Model) handleMotion(key string) bool {
+	if m.currentView != ViewFileList && m.currentView != ViewDocument && m.currentView != ViewCredits {
+		return false
+	}
+
+	// Count prefix; a leading 0 is not a count
+	if len(key) == 1 && key[0] >= '0' && key[0] <= '9' && (key != "0" || m.keys.count > 0) {
+		m.keys.count = min(m.keys.count*10+int(key[0]-'0'), maxCount)
+		return true
+	}
+
+	seq := m.keys
+	m.keys = keySequence{}
+	count := max(seq.count, 1)
+
+	// Esc cancels a pending sequence rather than leaving the view
+	if key == "esc" && seq != (keySequence{}) {
+		return true
+	}
+
+	if seq.pendingG {
+		if key == "g" {
+			m.gotoLine(count)
+			return true
+		}
+		// Any other key cancels the sequence and is handled on its own
+	}
+
+	switch key {
+	case "g":
+		m.keys = keySequence{count: seq.count, pendingG: true}
+	case "G":
+		if seq.count > 0 {
+			m.gotoLine(seq.count)
+		} else {
+			m.gotoBottom()
+		}
+	case "j", "down":
+		m.moveBy(count)
+	case "k", "up":
+		m.moveBy(-count)
+	case "ctrl+d":
+		m.moveBy(count * m.halfPage())
+	case "ctrl+u":
+		m.moveBy(-count * m.halfPage())
+	case "}":
+		m.paragraphJump(count)
+	case "{":
+		m.paragraphJump(-count)
+	default:
+		return false
+	}
+	return true
+}
+
+// moveBy moves the file list selection or scrolls the viewport by delta lines
+func (m *Model) moveBy(delta int) {
+	switch m.currentView {
+	case ViewFileList:
+		if len(m.flatList) == 0 {
+			return
+		}
+		m.selectedIndex = max(0, min(len(m.flatList)-1, m.selectedIndex+delta))
+		m.ensureSelectedVisible()
+	default:
+		if m.visual.active {
+			m.moveVisualCursor(m.visual.cursor + delta)
+			return
+		}
+		if delta < 0 {
+			m.viewport.ScrollUp(-delta)
+		} else {
+			m.viewport.ScrollDown(delta)
+		}
+	}
+}
+
+// halfPage returns half the height of the list or viewport
+func (m Model) halfPage() int {
+	if m.currentView == ViewFileList {
+		return max(m.fileListHeight()/2, 1)
+	}
+	return max(m.viewport.Height/2, 1)
+}
+
+// gotoLine selects the n-th entry of the file list or scrolls the n-th
+// line of the viewport to the top (1-based)
+func (m *Model) gotoLine(n int) {
+	if m.currentView == ViewFileList {
+		if len(m.flatList) == 0 {
+			return
+		}
+		m.selectedIndex = max(0, min(len(m.flatList)-1, n-1))
+		if m.selectedIndex == 0 {
+			m.listOffset = 0
+		}
+		m.ensureSelectedVisible()
+		return
+	}
+	if m.visual.active {
+		m.moveVisualCursor(n - 1)
+		return
+	}
+	m.renderAhead(n - 1 + m.viewport.Height)
+	m.viewport.SetYOffset(n - 1)
+}
+
+// gotoBottom selects the last entry or scrolls to the end
+func (m *Model) gotoBottom() {
+	if m.currentView == ViewFileList {
+		m.gotoLine(len(m.flatList))
+		return
+	}
+	m.renderRest()
+	if m.visual.active {
+		m.moveVisualCursor(len(m.docLines) - 1)
+		return
+	}
+	m.viewport.GotoBottom()
+}
+
+// paragraphJump moves count paragraphs forward (or backward for negative
+// count): to the next block of text in the viewport, or to the next
+// directory or section in the file list
+func (m *Model) paragraphJump(count int) {
+	var stops []int
+	current := m.viewport.YOffset
+	if m.currentView == ViewFileList {
+		stops = m.listParagraphs()
+		current = m.selectedIndex
+	} else if m.currentView == ViewDocument {
+		stops = m.docParagraphs
+		if m.visual.active {
+			current = m.visual.cursor
+		}
+	} else {
+		stops = paragraphStarts(m.renderCreditsContent())
+	}
+
+	target := current
+	for ; count > 0; count-- {
+		target = nextStop(stops, target)
+	}
+	for ; count < 0; count++ {
+		target = prevStop(stops, target)
+	}
+	m.gotoLine(target + 1)
+}
+
+// nextStop returns the first stop after pos, or pos if there is none
+func nextStop(stops []int, pos int) int {
+	for _, s := range stops {
+		if s > pos {
+			return s
+		}
+	}
+	return pos
+}
+
+// prevStop returns the last stop before pos, or 0 if there is none
+func prevStop(stops []int, pos int) int {
+	prev := 0
+	for _, s := range stops {
+		if s >= pos {
+			break
+		}
+		prev = s
+	}
+	return prev
+}
+
+// paragraphStarts returns the lines of content that start a block of text,
+// i.e. non-blank lines following a blank line
+func paragraphStarts(content string) []int {
+	var starts []int
+	blank := true
+	for i, line := range strings.Split(content, "\n") {
+		isBlank := strings.TrimSpace(stripANSI(line)) == ""
+		if blank && !isBlank {
+			starts = append(starts, i)
+		}
+		blank = isBlank
+	}
+	return starts
+}
+
+// listParagraphs returns the file list entries { and } stop at: the first
+// entry of each section and the directories of the tree
+func (m Model) listParagraphs() []int {
+	var stops []int
+	start := 0
+	for _, s := range m.sections {
+		if s.count > 0 {
+			stops = append(stops, start)
+		}
+		start += s.count
+	}
+	for i := start; i < len(m.flatList); i++ {
+		if i == start || m.flatList[i].IsDir {
+			stops = append(stops, i)
+		}
+	}
+	return stops
+}
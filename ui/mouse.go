@@ -0,0 +1,109 @@
+package ui
+
+import (
+	"strings"
+	"time"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+)
+
+const (
+	// doubleClickInterval is the longest gap between two clicks on the same
+	// file list entry that still counts as a double click
+	doubleClickInterval = 400 * time.Millisecond
+
+	// wheelStep is how many lines one mouse wheel notch scrolls
+	wheelStep = 3
+
+	// Offset of the content from the top left corner of the screen (the
+	// padding of the App style)
+	appTop  = 1
+	appLeft = 2
+)
+
+// handleMouse handles mouse events: the wheel scrolls the list or the
+// document (or the help overlay), clicks select and open files and follow links
+func (m *Model) handleMouse(msg tea.MouseMsg) tea.Cmd {
+	if m.linkPopup != "" || m.palette.open || m.history.open || m.references.open || m.agenda.open || m.animType != AnimNone {
+		return nil
+	}
+	if msg.Action != tea.MouseActionPress {
+		return nil
+	}
+	if m.showHelp {
+		switch msg.Button {
+		case tea.MouseButtonWheelUp:
+			m.helpViewport.ScrollUp(wheelStep)
+		case tea.MouseButtonWheelDown:
+			m.helpViewport.ScrollDown(wheelStep)
+		}
+		return nil
+	}
+
+	switch msg.Button {
+	case tea.MouseButtonWheelUp:
+		m.scrollWheel(-wheelStep)
+	case tea.MouseButtonWheelDown:
+		m.scrollWheel(wheelStep)
+	case tea.MouseButtonLeft:
+		switch m.currentView {
+		case ViewFileList:
+			return m.clickFileList(msg.X, msg.Y)
+		case ViewDocument:
+			return m.clickDocument(msg.X, msg.Y)
+		}
+	}
+	return nil
+}
+
+// scrollWheel scrolls by delta lines: the selection in the file list,
+// the viewport elsewhere
+func (m *Model) scrollWheel(delta int) {
+	if m.currentView == ViewFileList || m.currentView == ViewDocument || m.currentView == ViewCredits {
+		m.moveBy(delta)
+	}
+}
+
+// clickFileList selects the clicked entry, opening it on a double click
+func (m *Model) clickFileList(x, y int) tea.Cmd {
+	if m.showPreview() && x >= appLeft+m.listColumnWidth() {
+		return nil
+	}
+
+	top := appTop + strings.Count(m.renderFileListHeader(), "\n")
+	_, rows := m.renderFileRows()
+	row := y - top
+	if row < 0 || row >= len(rows) || rows[row] < 0 {
+		return nil
+	}
+	idx := rows[row]
+
+	doubleClick := idx == m.lastClickIndex && time.Since(m.lastClickTime) < doubleClickInterval
+	m.selectedIndex = idx
+	m.lastClickIndex = idx
+	m.lastClickTime = time.Now()
+
+	if doubleClick {
+		// A third click starts over rather than opening again
+		m.lastClickTime = time.Time{}
+		return m.openSelected()
+	}
+	return nil
+}
+
+// clickDocument follows the link under the click, if any
+func (m *Model) clickDocument(x, y int) tea.Cmd {
+	top := appTop + lipgloss.Height(m.renderDocumentHeader())
+	line := m.viewport.YOffset + y - top
+	col := x - appLeft - m.splitWidth()
+
+	for i, link := range m.docLinks {
+		if link.Line == line && col >= link.Col && col < link.Col+link.Width {
+			m.linkIndex = i
+			m.refreshDocument()
+			return m.followLink(link)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,7 @@
+//go:build !race
+
+package ui
+
+// raceEnabled reports whether the race detector is on, which allocates
+// where the code itself doesn't
+const raceEnabled = false
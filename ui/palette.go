@@ -0,0 +1,270 @@
+package ui
+
+import (
+	"sort"
+	"strings"
+	"unicode"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+	"github.com/charmbracelet/x/ansi"
+
+	"org-charm/org"
+)
+
+// paletteMaxShown is the number of matching commands listed at once
+const paletteMaxShown = 10
+
+// paletteCommand is an action offered by the command palette
+type paletteCommand struct {
+	title string
+	key   string                 // Key binding, replayed when run is nil
+	run   func(m *Model) tea.Cmd // Action for commands without a binding
+}
+
+// palette is the state of the : command palette
+type palette struct {
+	open     bool
+	query    string
+	commands []paletteCommand // Commands available in the current view
+	matches  []paletteCommand // Commands matching query, best first
+	selected int              // Selected entry of matches
+}
+
+// openPalette shows the command palette with the commands of the current view
+func (m *Model) openPalette() {
+	if m.currentView == ViewDocument {
+		// Every heading can be gone to
+		m.renderRest()
+	}
+	m.palette = palette{open: true, commands: m.paletteCommands()}
+	m.palette.filter()
+}
+
+// paletteCommands lists the commands available in the current view
+func (m Model) paletteCommands() []paletteCommand {
+	var cmds []paletteCommand
+
+	switch m.currentView {
+	case ViewDocument:
+		if m.rawView {
+			cmds = append(cmds,
+				paletteCommand{title: "Show rendered document", key: "r"},
+				paletteCommand{title: "Toggle line numbers", key: "#"},
+			)
+		} else {
+			cmds = append(cmds,
+				paletteCommand{title: "Show raw source", key: "r"},
+				paletteCommand{title: "Show source side by side", key: splitKey},
+				paletteCommand{title: "Widen text", key: "+"},
+				paletteCommand{title: "Narrow text", key: "-"},
+				paletteCommand{title: "Toggle reading column", key: "w"},
+				paletteCommand{title: "Toggle wrapping of wide code and tables", key: "W"},
+				paletteCommand{title: "Copy code block in view", key: copyCodeKey},
+				paletteCommand{title: "Show/hide babel header arguments", key: headersKey},
+				paletteCommand{title: "Expand/fold long code block in view", run: func(m *Model) tea.Cmd {
+					m.toggleCodeFold()
+					return nil
+				}},
+				paletteCommand{title: "Focus table rows", key: tableFocusKey},
+				paletteCommand{title: "Toggle striped table rows", key: "Z"},
+				paletteCommand{title: "Expand/collapse drawer in view", key: drawerKey},
+				paletteCommand{title: "Expand/collapse all drawers", key: allDrawersKey},
+			)
+		}
+		cmds = append(cmds,
+			paletteCommand{title: "Next document", key: "n"},
+			paletteCommand{title: "Previous document", key: "p"},
+			paletteCommand{title: "Pin/unpin document", key: "*"},
+			paletteCommand{title: "Copy source", key: "y"},
+			paletteCommand{title: "Copy current heading subtree", key: "Y"},
+			paletteCommand{title: "Copy as Markdown", key: markdownKey},
+			paletteCommand{title: "Export to PDF", key: pdfKey},
+			paletteCommand{title: "Show git history", key: historyKey},
+			paletteCommand{title: "Show linked references", key: referencesKey},
+			paletteCommand{title: "Back to file list", run: func(m *Model) tea.Cmd {
+				m.closeDocument()
+				return nil
+			}},
+		)
+		for _, h := range m.docHeadings {
+			cmds = append(cmds, paletteCommand{
+				title: "Go to heading: " + strings.Repeat("  ", h.Level-1) + h.Title,
+				run: func(m *Model) tea.Cmd {
+					m.markSet()[lastJumpMark] = m.viewport.YOffset
+					m.viewport.SetYOffset(h.Line)
+					return nil
+				},
+			})
+		}
+	case ViewFileList:
+		cmds = append(cmds,
+			paletteCommand{title: "Cycle sort field", key: "s"},
+			paletteCommand{title: "Reverse sort direction", key: "S"},
+			paletteCommand{title: "Pin/unpin selected file", key: "*"},
+			paletteCommand{title: "Copy selected file as Markdown", key: markdownKey},
+			paletteCommand{title: "Show credits & changelog", key: "c"},
+		)
+	}
+
+	cmds = append(cmds,
+		paletteCommand{title: "Cycle animations", key: "A"},
+		paletteCommand{title: "Switch syntax highlighting theme", run: func(m *Model) tea.Cmd {
+			return m.cycleSyntaxTheme()
+		}},
+		paletteCommand{title: "Show agenda", key: agendaKey},
+		paletteCommand{title: "Show keyboard shortcuts", key: "?"},
+		paletteCommand{title: "Quit", key: "q"},
+	)
+
+	// Every file can be opened from anywhere
+	var addFiles func(entries []*org.FileEntry)
+	addFiles = func(entries []*org.FileEntry) {
+		for _, e := range entries {
+			if e.IsDir {
+				addFiles(e.Children)
+				continue
+			}
+			cmds = append(cmds, paletteCommand{
+				title: "Open file: " + e.RelPath,
+				run: func(m *Model) tea.Cmd {
+					return m.openEntry(e)
+				},
+			})
+		}
+	}
+	addFiles(m.fileTree)
+
+	return cmds
+}
+
+// filter recomputes the commands matching the query
+func (p *palette) filter() {
+	type scored struct {
+		cmd   paletteCommand
+		score int
+	}
+	var found []scored
+	for _, cmd := range p.commands {
+		if score, ok := fuzzyMatch(p.query, cmd.title); ok {
+			found = append(found, scored{cmd, score})
+		}
+	}
+	sort.SliceStable(found, func(i, j int) bool { return found[i].score > found[j].score })
+
+	p.matches = p.matches[:0]
+	for _, f := range found {
+		p.matches = append(p.matches, f.cmd)
+	}
+	p.selected = 0
+}
+
+// fuzzyMatch reports whether the letters of query appear in order in s,
+// ignoring case and spaces. The score favors matches at word starts and
+// consecutive letters.
+func fuzzyMatch(query, s string) (int, bool) {
+	target := []rune(strings.ToLower(s))
+	score, pos, prev := 0, 0, -2
+	for _, q := range strings.ToLower(query) {
+		if unicode.IsSpace(q) {
+			continue
+		}
+		for pos < len(target) && target[pos] != q {
+			pos++
+		}
+		if pos == len(target) {
+			return 0, false
+		}
+		switch {
+		case pos == prev+1:
+			score += 3
+		case pos == 0 || !unicode.IsLetter(target[pos-1]) && !unicode.IsDigit(target[pos-1]):
+			score += 2
+		default:
+			score++
+		}
+		prev = pos
+		pos++
+	}
+	return score, true
+}
+
+// handlePaletteKey edits the query, moves the selection or runs the
+// selected command
+func (m Model) handlePaletteKey(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
+	p := &m.palette
+	switch msg.Type {
+	case tea.KeyEsc, tea.KeyCtrlC:
+		p.open = false
+	case tea.KeyEnter:
+		p.open = false
+		if len(p.matches) == 0 {
+			return m, nil
+		}
+		cmd := p.matches[p.selected]
+		if cmd.run != nil {
+			return m, cmd.run(&m)
+		}
+		return m.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune(cmd.key)})
+	case tea.KeyUp, tea.KeyCtrlP, tea.KeyCtrlK:
+		if p.selected > 0 {
+			p.selected--
+		}
+	case tea.KeyDown, tea.KeyCtrlN, tea.KeyCtrlJ, tea.KeyTab:
+		if p.selected < len(p.matches)-1 {
+			p.selected++
+		}
+	case tea.KeyBackspace:
+		if p.query == "" {
+			p.open = false
+		} else {
+			q := []rune(p.query)
+			p.query = string(q[:len(q)-1])
+			p.filter()
+		}
+	case tea.KeyCtrlU:
+		p.query = ""
+		p.filter()
+	case tea.KeyRunes, tea.KeySpace:
+		p.query += string(msg.Runes)
+		p.filter()
+	}
+	return m, nil
+}
+
+// renderPalette renders the command palette over the screen
+func (m Model) renderPalette() string {
+	p := m.palette
+	width := min(m.width-8, 64)
+	var b strings.Builder
+
+	b.WriteString(m.styles.HelpKey.Render(":") + " " + p.query + m.styles.HelpKey.Render("▏"))
+	b.WriteString("\n\n")
+
+	// Keep the selection in the window of shown matches
+	start := max(0, p.selected-paletteMaxShown+1)
+	end := min(len(p.matches), start+paletteMaxShown)
+	if len(p.matches) == 0 {
+		b.WriteString(m.styles.HelpText.Render("No matching commands"))
+	}
+	for i := start; i < end; i++ {
+		cmd := p.matches[i]
+		title := ansi.Truncate(cmd.title, width-lipgloss.Width(cmd.key)-4, "…")
+		gap := strings.Repeat(" ", max(1, width-2-lipgloss.Width(title)-lipgloss.Width(cmd.key)))
+		if i == p.selected {
+			b.WriteString(m.styles.FileItemSelected.Render("▸ " + title))
+		} else {
+			b.WriteString(m.styles.FileItem.Render(title))
+		}
+		b.WriteString(gap + m.styles.HelpKey.Render(cmd.key))
+		if i < end-1 {
+			b.WriteString("\n")
+		}
+	}
+
+	b.WriteString("\n\n")
+	b.WriteString(m.styles.HelpText.Render("↑/↓ select • enter run • esc close"))
+
+	popup := m.styles.Sized(&m.styles.Popup, width+4).Render(b.String())
+	return lipgloss.Place(m.width, m.height, lipgloss.Center, lipgloss.Center, popup)
+}
@@ -0,0 +1,70 @@
+package ui
+
+import (
+	"context"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"org-charm/export"
+
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+const (
+	// pdfKey exports the open document to PDF
+	pdfKey = "P"
+
+	// pdfTimeout bounds how long the PDF converter may run
+	pdfTimeout = 2 * time.Minute
+)
+
+// pdfExportedMsg reports the PDF export of the document name finished,
+// written to path unless it failed with err
+type pdfExportedMsg struct {
+	name string
+	path string
+	err  error
+}
+
+// exportPDF converts the open document to PDF in the background with the
+// converter the operator set up, into the exports directory at the path
+// of the document
+func (m *Model) exportPDF() tea.Cmd {
+	if m.currentView != ViewDocument || m.currentDoc == nil {
+		return nil
+	}
+	if len(m.options.PDFCommand) == 0 {
+		return m.setStatus("PDF export is not set up on this server")
+	}
+	if m.revision != nil {
+		return m.setStatus("Older versions can't be exported to PDF")
+	}
+	if m.exporting != "" {
+		return m.setStatus("Already exporting " + m.exporting)
+	}
+	rel, ok := m.relPath(m.currentDoc.Path)
+	if !ok {
+		return nil
+	}
+
+	doc, command := m.currentDoc, m.options.PDFCommand
+	output := filepath.Join(m.options.ExportsDir, filepath.FromSlash(strings.TrimSuffix(rel, filepath.Ext(rel))+".pdf"))
+	m.exporting = doc.Name
+	convert := func() tea.Msg {
+		ctx, cancel := context.WithTimeout(context.Background(), pdfTimeout)
+		defer cancel()
+		err := export.PDF(ctx, command, doc.Path, output)
+		return pdfExportedMsg{doc.Name, output, err}
+	}
+	return tea.Batch(m.spinner.Tick, convert)
+}
+
+// handlePDFExported reports how a PDF export went
+func (m *Model) handlePDFExported(msg pdfExportedMsg) tea.Cmd {
+	m.exporting = ""
+	if msg.err != nil {
+		return m.setStatus("PDF export of " + msg.name + " failed: " + msg.err.Error())
+	}
+	return m.setStatus("Exported " + msg.name + " to " + msg.path)
+}
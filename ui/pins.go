@@ -0,0 +1,41 @@
+package ui
+
+import (
+	"org-charm/org"
+	"org-charm/state"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/log"
+)
+
+// togglePin pins or unpins a document for the user
+func (m *Model) togglePin(entry *org.FileEntry) tea.Cmd {
+	if entry == nil || entry.IsDir {
+		return nil
+	}
+	rel, ok := m.relPath(entry.Path)
+	if !ok {
+		return nil
+	}
+
+	var pinned bool
+	err := m.store.Update(m.userID, func(u *state.User) {
+		pinned = u.TogglePin(rel)
+	})
+	if err != nil {
+		log.Error("Failed to save pins", "error", err)
+	}
+	m.refreshFlatList()
+	m.ensureSelectedVisible()
+
+	if pinned {
+		return m.setStatus("Pinned " + entry.Name)
+	}
+	return m.setStatus("Unpinned " + entry.Name)
+}
+
+// pinnedEntries returns the file entries of the user's pinned documents
+// that are still part of the tree, in the order they were pinned
+func (m Model) pinnedEntries() []*org.FileEntry {
+	return m.entriesForPaths(m.store.Get(m.userID).Pins, 0, nil)
+}
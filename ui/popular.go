@@ -0,0 +1,32 @@
+package ui
+
+import (
+	"time"
+
+	"org-charm/org"
+)
+
+// maxPopularShown is how many of the most viewed documents the file list
+// shows
+const maxPopularShown = 5
+
+// recordView counts a view of doc, if the server counts them
+func (m *Model) recordView(doc *org.OrgFile) {
+	if m.options.Views == nil {
+		return
+	}
+	if rel, ok := m.relPath(doc.Path); ok {
+		// The id of the key, as anonymous sessions have none
+		m.options.Views.Record(rel, m.options.UserID, time.Now())
+	}
+}
+
+// popularEntries returns the file entries of the documents viewed most
+// across all users, if the server counts views, that are still part of
+// the tree and not among exclude
+func (m Model) popularEntries(exclude []*org.FileEntry) []*org.FileEntry {
+	if m.options.Views == nil {
+		return nil
+	}
+	return m.entriesForPaths(m.options.Views.Popular(), maxPopularShown, exclude)
+}
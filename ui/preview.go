@@ -0,0 +1,99 @@
+package ui
+
+import (
+	"fmt"
+	"strings"
+
+	"org-charm/org"
+
+	"github.com/charmbracelet/x/ansi"
+)
+
+const (
+	// previewMinWidth is the terminal width from which the file list shows
+	// a preview of the selected document next to the list
+	previewMinWidth = 100
+
+	// previewMaxLines caps how much of a document is kept for previews
+	previewMaxLines = 200
+)
+
+// showPreview reports whether the file list has room for a preview pane
+func (m Model) showPreview() bool {
+	return m.width >= previewMinWidth
+}
+
+// listColumnWidth returns the width of the file list column
+func (m Model) listColumnWidth() int {
+	if m.showPreview() {
+		return (m.width - 8) * 2 / 5
+	}
+	return m.width - 8
+}
+
+// renderPreview renders the preview pane for entry: the title, metadata
+// line and the opening of the rendered document, cut to height lines
+func (m Model) renderPreview(entry *org.FileEntry, width, height int) string {
+	// Room for the margin, border and padding of the pane
+	inner := width - 4
+	if inner < minContentWidth || height < 1 {
+		return ""
+	}
+
+	var lines []string
+	if entry.IsDir {
+		lines = append(lines,
+			m.styles.FileDir.UnsetPadding().Render("📁 "+entry.Name),
+			"",
+			m.styles.FileMeta.Render(fmt.Sprintf("%d files", countFiles(entry.Children))),
+		)
+	} else if orgFile, err := entry.GetOrgFile(); err == nil {
+		lines = append(lines, m.styles.Heading2.Render(ansi.Truncate(orgFile.Title(), inner, "…")))
+		if meta := fileMetadata(orgFile, lastModification(entry.Path, entry.ModTime), m.backlinkCount(entry.Path)); meta != "" {
+			lines = append(lines, m.styles.FileMeta.Render(ansi.Truncate(meta, inner, "…")))
+		}
+		lines = append(lines, "")
+		lines = append(lines, m.previewBody(orgFile, inner)...)
+	}
+
+	if len(lines) > height {
+		lines = lines[:height]
+	}
+	return m.styles.Preview.Height(height).Render(strings.Join(lines, "\n"))
+}
+
+// previewBody returns the opening lines of the rendered document, rendered
+// on first use and cached per document, width and view options
+func (m Model) previewBody(orgFile *org.OrgFile, width int) []string {
+	key := m.previewKey(orgFile, width)
+	if lines, ok := renderCache.previews.Get(key); ok {
+		return lines
+	}
+
+	renderer := m.newRenderer(width)
+	renderer.SetSource(orgFile.RawContent, orgFile.Document.Nodes)
+	rendered := renderer.RenderNodes(orgFile.Document.Nodes)
+	lines := strings.Split(strings.TrimSpace(rendered), "\n")
+	if len(lines) > previewMaxLines {
+		lines = lines[:previewMaxLines]
+	}
+	for i, line := range lines {
+		lines[i] = ansi.Truncate(line, width, "")
+	}
+
+	renderCache.previews.Add(key, lines)
+	return lines
+}
+
+// countFiles returns the number of files in a directory subtree
+func countFiles(entries []*org.FileEntry) int {
+	n := 0
+	for _, e := range entries {
+		if e.IsDir {
+			n += countFiles(e.Children)
+		} else {
+			n++
+		}
+	}
+	return n
+}
@@ -0,0 +1,75 @@
+package ui
+
+import (
+	"fmt"
+
+	"org-charm/state"
+
+	"github.com/charmbracelet/log"
+)
+
+const (
+	// readDoneThreshold is the progress from which a document counts as read
+	readDoneThreshold = 0.98
+
+	// progressSaveStep is how much further a reader has to get before the
+	// progress is saved while reading (it is always saved on leaving)
+	progressSaveStep = 0.1
+)
+
+// readingProgress returns the fraction of the open document that has been
+// on screen, measured at the bottom of the viewport
+func (m Model) readingProgress() float64 {
+	total := m.viewport.TotalLineCount()
+	if total == 0 {
+		return 0
+	}
+	return min(float64(m.viewport.YOffset+m.viewport.Height)/float64(total), 1)
+}
+
+// saveReading records the reading position of the open document, unless
+// an older version of it is shown
+func (m *Model) saveReading() {
+	if m.currentDoc == nil || m.revision != nil {
+		return
+	}
+	rel, ok := m.relPath(m.currentDoc.Path)
+	if !ok {
+		return
+	}
+
+	progress := m.readingProgress()
+	offset := m.viewport.YOffset
+	err := m.store.Update(m.userID, func(u *state.User) {
+		if m.rawView {
+			// Raw lines don't match rendered ones; keep the last rendered offset
+			offset = u.Reading[rel].Offset
+		}
+		u.SetReading(rel, offset, progress)
+	})
+	if err != nil {
+		log.Error("Failed to save reading position", "error", err)
+	}
+	m.savedProgress = progress
+}
+
+// saveReadingIfAdvanced saves the reading position when the reader got
+// noticeably further since it was last saved
+func (m *Model) saveReadingIfAdvanced() {
+	if m.currentView == ViewDocument && m.readingProgress() >= m.savedProgress+progressSaveStep {
+		m.saveReading()
+	}
+}
+
+// readingBadge returns the file list indicator for a document's reading
+// state: unread, percentage read or done
+func (m Model) readingBadge(r state.Reading, seen bool) string {
+	switch {
+	case !seen:
+		return m.styles.FileMeta.Render("○")
+	case r.Progress >= readDoneThreshold:
+		return m.styles.Notice.UnsetItalic().Render("✓")
+	default:
+		return m.styles.FileMeta.Render(fmt.Sprintf("◐ %d%%", int(r.Progress*100)))
+	}
+}
@@ -0,0 +1,31 @@
+package ui
+
+import (
+	"org-charm/org"
+	"org-charm/state"
+
+	"github.com/charmbracelet/log"
+)
+
+// maxRecentShown is how many recently viewed documents the file list shows
+const maxRecentShown = 5
+
+// addRecent records doc as the user's most recently viewed document
+func (m *Model) addRecent(doc *org.OrgFile) {
+	rel, ok := m.relPath(doc.Path)
+	if !ok {
+		return
+	}
+	err := m.store.Update(m.userID, func(u *state.User) {
+		u.AddRecent(rel)
+	})
+	if err != nil {
+		log.Error("Failed to save recent documents", "error", err)
+	}
+}
+
+// recentEntries returns the file entries of the user's recently viewed
+// documents that are still part of the tree and not pinned, newest first
+func (m Model) recentEntries(pinned []*org.FileEntry) []*org.FileEntry {
+	return m.entriesForPaths(m.store.Get(m.userID).Recent, maxRecentShown, pinned)
+}
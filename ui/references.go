@@ -0,0 +1,183 @@
+package ui
+
+import (
+	"path/filepath"
+	"strings"
+
+	"org-charm/org"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+	"github.com/charmbracelet/x/ansi"
+)
+
+const (
+	// referencesKey lists the nodes of other files linking to the open
+	// document
+	referencesKey = "R"
+
+	// referencesMaxShown is the number of linked references listed at once
+	referencesMaxShown = 12
+)
+
+// references is the state of the linked references overlay of the open
+// document, listing the links to it the org-roam database knows of
+type references struct {
+	open     bool
+	path     string // Path of the document the references are to
+	links    []org.Backlink
+	selected int
+}
+
+// pendingAnchor is a place in a document being opened to jump to once it
+// shows
+type pendingAnchor struct {
+	path   string
+	anchor string // Name of the anchor, as internal links use it
+}
+
+// openReferences shows the linked references overlay of the open document
+func (m *Model) openReferences() tea.Cmd {
+	if m.currentView != ViewDocument || m.currentDoc == nil {
+		return nil
+	}
+	if m.options.Roam == nil {
+		return m.setStatus("Linked references need an org-roam database")
+	}
+	rel, ok := m.relPath(m.currentDoc.Path)
+	if !ok {
+		return nil
+	}
+	links := m.options.Roam.Backlinks(rel)
+	if len(links) == 0 {
+		return m.setStatus("No linked references to " + m.currentDoc.Name)
+	}
+	m.references = references{open: true, path: m.currentDoc.Path, links: links}
+	return nil
+}
+
+// handleReferencesKey moves the selection of the linked references
+// overlay, or closes it, opening the selected reference with enter
+func (m *Model) handleReferencesKey(msg tea.KeyMsg) tea.Cmd {
+	r := &m.references
+	switch msg.String() {
+	case "up", "k", "ctrl+p":
+		if r.selected > 0 {
+			r.selected--
+		}
+	case "down", "j", "ctrl+n":
+		if r.selected < len(r.links)-1 {
+			r.selected++
+		}
+	case "home", "g":
+		r.selected = 0
+	case "end", "G":
+		r.selected = max(0, len(r.links)-1)
+	case "enter":
+		r.open = false
+		return m.openRoamNode(r.links[r.selected].Source)
+	default:
+		r.open = false
+	}
+	return nil
+}
+
+// roamLink returns the node an id: or roam: link points to, if the
+// server reads an org-roam database and it knows of the node
+func (m Model) roamLink(url string) (org.RoamNode, bool) {
+	if m.options.Roam == nil {
+		return org.RoamNode{}, false
+	}
+	if id, ok := strings.CutPrefix(url, "id:"); ok {
+		// Drop search options such as id:1234::*Heading
+		id, _, _ = strings.Cut(id, "::")
+		return m.options.Roam.Node(id)
+	}
+	if name, ok := strings.CutPrefix(url, "roam:"); ok {
+		return m.options.Roam.Lookup(name)
+	}
+	return org.RoamNode{}, false
+}
+
+// openRoamNode opens the document of an org-roam node, at the headline of
+// nodes that aren't the whole file
+func (m *Model) openRoamNode(node org.RoamNode) tea.Cmd {
+	entry := findEntryByPath(m.fileTree, filepath.Join(m.rootDir, filepath.FromSlash(node.File)))
+	if entry == nil {
+		return m.setStatus(node.File + " is not in the file list")
+	}
+	m.pendingAnchor = pendingAnchor{}
+	if node.Level > 0 {
+		m.pendingAnchor = pendingAnchor{entry.Path, "id:" + node.ID}
+	}
+	return m.openEntry(entry)
+}
+
+// jumpToPendingAnchor scrolls the document just opened to the place it was
+// opened for, if any
+func (m *Model) jumpToPendingAnchor() {
+	anchor := m.pendingAnchor
+	m.pendingAnchor = pendingAnchor{}
+	if anchor.path != m.currentDoc.Path {
+		return
+	}
+	if _, ok := m.anchorLine(anchor.anchor); !ok {
+		// The headline may be further on than rendered yet
+		m.renderRest()
+	}
+	if line, ok := m.anchorLine(anchor.anchor); ok {
+		m.viewport.SetYOffset(line)
+	}
+}
+
+// backlinkCount returns how many nodes of other files link to the file at
+// path, as the org-roam database tells, or 0 without one
+func (m Model) backlinkCount(path string) int {
+	if m.options.Roam == nil {
+		return 0
+	}
+	rel, ok := m.relPath(path)
+	if !ok {
+		return 0
+	}
+	return m.options.Roam.BacklinkCount(rel)
+}
+
+// renderReferences renders the linked references overlay over the screen
+func (m Model) renderReferences() string {
+	r := m.references
+	width := min(m.width-8, 88)
+	var b strings.Builder
+
+	b.WriteString(m.styles.HelpKey.Render("Linked references to " + filepath.Base(r.path)))
+	b.WriteString("\n\n")
+
+	// Keep the selection in the window of shown references
+	start := max(0, r.selected-referencesMaxShown+1)
+	end := min(len(r.links), start+referencesMaxShown)
+	for i := start; i < end; i++ {
+		link := r.links[i]
+		line := link.Source.Title
+		if link.Target.Level > 0 {
+			line += " → " + link.Target.Title
+		}
+		meta := ansi.Truncate(link.Source.File, width/3, "…")
+		line = ansi.Truncate(line, width-lipgloss.Width(meta)-4, "…")
+		gap := strings.Repeat(" ", max(1, width-2-lipgloss.Width(line)-lipgloss.Width(meta)))
+		if i == r.selected {
+			b.WriteString(m.styles.FileItemSelected.Render("▸ " + line))
+		} else {
+			b.WriteString(m.styles.FileItem.Render(line))
+		}
+		b.WriteString(gap + m.styles.HelpText.Render(meta))
+		if i < end-1 {
+			b.WriteString("\n")
+		}
+	}
+
+	b.WriteString("\n\n")
+	b.WriteString(m.styles.HelpText.Render("↑/↓ select • enter open • esc close"))
+
+	popup := m.styles.Sized(&m.styles.Popup, width+4).Render(b.String())
+	return lipgloss.Place(m.width, m.height, lipgloss.Center, lipgloss.Center, popup)
+}
@@ -2,15 +2,28 @@ package ui
 
 import (
 	"bytes"
+	"cmp"
+	"context"
 	"fmt"
+	"io"
+	"regexp"
+	"runtime"
+	"slices"
+	"strconv"
 	"strings"
+	"time"
+	"unicode"
+	"unicode/utf8"
 
 	"github.com/alecthomas/chroma/v2"
 	"github.com/alecthomas/chroma/v2/formatters"
 	"github.com/alecthomas/chroma/v2/lexers"
 	"github.com/alecthomas/chroma/v2/styles"
 	"github.com/charmbracelet/lipgloss"
+	"github.com/charmbracelet/x/ansi"
 	goorg "github.com/niklasfasching/go-org/org"
+
+	"org-charm/org"
 )
 
 // Renderer handles rendering org document nodes to styled strings
@@ -18,61 +31,664 @@ type Renderer struct {
 	styles        *Styles
 	width         int
 	footnoteDepth int // Track nesting depth for nested footnotes
+
+	// Footnotes numbered in order of first reference, for the endnotes
+	footnotes     []footnote
+	footnoteIndex map[string]int // Footnote name to index in footnotes
+	footnoteIn    string         // Endnote being rendered, for back-references
+
+	// Link tracking for link selection
+	links      []LinkRef // Links in render order
+	activeLink int       // Index of the highlighted link (-1 for none)
+
+	hyperlinks bool // Emit OSC 8 hyperlinks for web and mail links
+	noWrap     bool // Keep wide code lines and table cells intact instead of soft-wrapping them
+	plainText  bool // Keep dashes, dots and quotes as typed (-:nil)
+	quotePrev  rune // Last rune of the inline text rendered, telling opening quotes from closing ones
+
+	// Headline tracking for outline-aware features
+	headings  []HeadingRef // Headlines in render order
+	headlines int          // Headlines of the source seen so far, shown or not
+
+	// Exported headlines of the document, for tables of contents
+	outline []goorg.Headline
+
+	anchors []AnchorRef // Places internal links jump to, in render order
+
+	// Blocks wider than the body, scrolled sideways one at a time
+	wideBlocks []BlockRef
+
+	tables []TableRef // Tables in render order
+	zebra  bool       // Stripe alternate table rows
+
+	captionCounts map[string]int // Captions numbered so far, by label
+
+	// Drawers in render order, collapsed to a summary line unless
+	// expandDrawers is set; toggledDrawers flips single drawers by index
+	drawers        []DrawerRef
+	expandDrawers  bool
+	toggledDrawers map[int]bool
+
+	// How images are drawn (nil for placeholders), and the directories
+	// image links resolve in: the document's, within the org root
+	images    imageBackend
+	imageRoot string
+	imageDir  string
+
+	chromaStyle string // Syntax highlighting style overriding the theme's
+
+	latexUnicode  bool // Approximate the math of LaTeX environments with unicode
+	guessLanguage bool // Highlight source blocks without a language as chroma guesses
+
+	lastLineNumber int // Last line number of a numbered block, for +n
+
+	// Subtrees left out of exports: COMMENT headlines and those tagged
+	// with one of excludeTags, hidden unless showExcluded dims them instead
+	excludeTags  []string
+	showExcluded bool
+
+	codeBlocks []CodeRef // Source blocks in render order
+
+	// Babel header arguments of the next source block from #+HEADER:
+	// lines, and whether header arguments are shown in block labels
+	headerArgs  []string
+	showHeaders bool
+
+	// Source blocks longer than foldCode lines (0 for none) show a preview,
+	// unless unfoldedCode has their index
+	foldCode     int
+	unfoldedCode map[int]bool
+
+	// Source lines of the verse blocks, which the parser reflows, keyed by
+	// the first child of their parsed block: the parser keeps no
+	// positions, and blocks are copied around but share their children
+	verses map[*goorg.Node][]string
+
+	// Context interrupting the render when done (nil for none), and the
+	// nodes rendered since the renderer last yielded the processor
+	ctx       context.Context
+	unyielded int
 }
 
-// Footnote symbol sets for different nesting levels
-var footnoteSymbols = [][]string{
-	{"1", "2", "3", "4", "5", "6", "7", "8", "9", "10", "11", "12", "13", "14", "15", "16", "17", "18", "19", "20"},
-	{"a", "b", "c", "d", "e", "f", "g", "h", "i", "j", "k", "l", "m", "n", "o", "p", "q", "r", "s", "t"},
-	{"i", "ii", "iii", "iv", "v", "vi", "vii", "viii", "ix", "x", "xi", "xii", "xiii", "xiv", "xv", "xvi", "xvii", "xviii", "xix", "xx"},
-	{"α", "β", "γ", "δ", "ε", "ζ", "η", "θ", "ι", "κ", "λ", "μ", "ν", "ξ", "ο", "π", "ρ", "σ", "τ", "υ"},
+// CodeRef describes a source block, so its code can be copied
+type CodeRef struct {
+	Name   string // Block name from #+NAME:, if any
+	Lang   string // Block language, if any
+	Code   string // Unhighlighted code
+	Line   int    // Rendered line of the block header (set by LocateCodeBlocks)
+	Height int    // Lines in the rendered block
+	Fold   bool   // Whether it is long enough to fold
+	Folded bool   // Whether only a preview of the code is shown
+	first  string // Header line without styling, for locating it
 }
 
-// getFootnoteSymbol returns the appropriate symbol for a footnote at the given depth
-func getFootnoteSymbol(name string, depth int) string {
-	// Clamp depth to available symbol sets
-	if depth >= len(footnoteSymbols) {
-		depth = len(footnoteSymbols) - 1
-	}
+// BlockRef describes a table or code block rendered wider than the body
+type BlockRef struct {
+	First  string // First line of the block, without styling or indentation
+	Line   int    // Rendered line the block starts on (set by LocateBlocks)
+	Col    int    // Column the block starts at (set by LocateBlocks)
+	Height int    // Lines in the block
+	Width  int    // Width of the block
+}
 
-	// Try to parse name as a number for numeric footnotes
-	var idx int
-	if _, err := fmt.Sscanf(name, "%d", &idx); err == nil && idx > 0 && idx <= len(footnoteSymbols[depth]) {
-		return footnoteSymbols[depth][idx-1]
-	}
+// DrawerRef describes a drawer, so it can be expanded or collapsed
+type DrawerRef struct {
+	Name   string // Drawer name, such as PROPERTIES or LOGBOOK
+	Open   bool   // Whether it is expanded
+	Line   int    // Rendered line of its first line (set by LocateDrawers)
+	Height int    // Lines in the rendered drawer
+	first  string // First line without styling, for locating it
+}
 
-	// For named footnotes, return the name with depth-appropriate formatting
-	switch depth {
-	case 0:
-		return name
-	case 1:
-		return strings.ToLower(name)
-	case 2:
-		return strings.ToLower(name)
-	default:
-		return name
-	}
+// TableRef describes a rendered table, so a row cursor can move through
+// its rows
+type TableRef struct {
+	First  string    // First line of the table, without styling or indentation
+	Line   int       // Rendered line the table starts on (set by LocateTables)
+	Height int       // Lines in the table
+	Rows   []RowSpan // Body rows, below the header
+}
+
+// RowSpan is the lines of a table row, which grows when cells wrap
+type RowSpan struct {
+	Offset int // First line of the row, relative to the table's first line
+	Height int // Lines in the row
+}
+
+// HeadingRef describes a headline encountered while rendering
+type HeadingRef struct {
+	Level int    // Headline level (number of stars)
+	Title string // Plain title text
+	Index int    // Position among the document's headlines (0-based)
+	Line  int    // Rendered line of the headline (set by LocateHeadings)
+}
+
+// AnchorRef describes a place internal links jump to
+type AnchorRef struct {
+	// Name internal links use for it: *Title for headlines, #id for their
+	// CUSTOM_ID, id:id for their ID, fn:name for endnotes, and the name of
+	// named elements and <<targets>> as is
+	Name  string
+	Line  int    // Rendered line it is on (set by LocateAnchors, -1 if not found)
+	first string // Text of its first line without styling, for locating it
+}
+
+// LinkRef describes a link encountered while rendering
+type LinkRef struct {
+	URL   string // Link target as written in the org source
+	Text  string // Display text (description or URL)
+	Line  int    // Rendered line the link starts on (set by LocateLinks)
+	Col   int    // Column the link starts at on that line (set by LocateLinks)
+	Width int    // Rendered width of the link, including its icon
+}
+
+// footnote is an entry of the endnotes section
+type footnote struct {
+	name       string
+	definition []goorg.Node // Definition contents, nil if undefined
+	heading    string       // Heading the first reference is under
 }
 
 // NewRenderer creates a new Renderer
 func NewRenderer(styles *Styles, width int) *Renderer {
 	return &Renderer{
-		styles: styles,
-		width:  width,
+		styles:        styles,
+		width:         width,
+		activeLink:    -1,
+		footnoteIndex: map[string]int{},
+		excludeTags:   []string{"noexport"},
+		captionCounts: map[string]int{},
+	}
+}
+
+// SetActiveLink highlights the link with the given render-order index (-1 for none)
+func (r *Renderer) SetActiveLink(idx int) {
+	r.activeLink = idx
+}
+
+// SetHyperlinks enables OSC 8 terminal hyperlinks for web and mail links
+func (r *Renderer) SetHyperlinks(enabled bool) {
+	r.hyperlinks = enabled
+}
+
+// SetSmartPunctuation turns dashes, ellipses and straight quotes in text
+// into their typographic forms, as org export does unless -:nil is set.
+// It is on by default.
+func (r *Renderer) SetSmartPunctuation(enabled bool) {
+	r.plainText = !enabled
+}
+
+// SetGraphics sets the graphics protocol of the terminal, which decides
+// whether images are drawn or shown as placeholders
+func (r *Renderer) SetGraphics(p GraphicsProtocol) {
+	r.images = imageBackendFor(p)
+}
+
+// SetImageDir sets the directory relative image links resolve in; images
+// outside root are never read
+func (r *Renderer) SetImageDir(root, dir string) {
+	r.imageRoot = root
+	r.imageDir = dir
+}
+
+// SetDrawers sets whether drawers are expanded, and the indices of the
+// drawers (in render order) that are the other way round
+func (r *Renderer) SetDrawers(expanded bool, toggled map[int]bool) {
+	r.expandDrawers = expanded
+	r.toggledDrawers = toggled
+}
+
+// SetFoldCode folds source blocks longer than lines (0 to never fold) to a
+// preview, except the blocks (by index in render order) in unfolded
+func (r *Renderer) SetFoldCode(lines int, unfolded map[int]bool) {
+	r.foldCode = lines
+	r.unfoldedCode = unfolded
+}
+
+// SetShowHeaders shows the babel header arguments of source blocks, such
+// as :results output, in their labels; they are hidden by default
+func (r *Renderer) SetShowHeaders(show bool) {
+	r.showHeaders = show
+}
+
+// SetGuessLanguage highlights source blocks without a language in the
+// language chroma's lexer analysis guesses from their code
+func (r *Renderer) SetGuessLanguage(enabled bool) {
+	r.guessLanguage = enabled
+}
+
+// SetLatexUnicode approximates the math of LaTeX environments with unicode
+// symbols instead of showing it as written
+func (r *Renderer) SetLatexUnicode(enabled bool) {
+	r.latexUnicode = enabled
+}
+
+// SetZebraTables stripes alternate body rows of tables
+func (r *Renderer) SetZebraTables(enabled bool) {
+	r.zebra = enabled
+}
+
+// SetChromaStyle sets the syntax highlighting style of source blocks,
+// overriding the one of the styles. Unknown names are ignored.
+func (r *Renderer) SetChromaStyle(name string) {
+	r.chromaStyle = name
+}
+
+// SetSource gives the org source of the rendered document and the nodes
+// parsed from it, so verse blocks can keep their original line breaks and
+// indentation
+func (r *Renderer) SetSource(source string, nodes []goorg.Node) {
+	r.verses = map[*goorg.Node][]string{}
+	sources := verseBlocks(source)
+	for i, block := range parsedVerses(nodes, nil) {
+		// The nth verse block parsed is the nth of the source, unless the
+		// parser saw the source differently
+		if i >= len(sources) || len(block.Children) == 0 ||
+			words(sources[i]) != words([]string{r.extractBlockText(block.Children)}) {
+			break
+		}
+		r.verses[&block.Children[0]] = sources[i]
+	}
+}
+
+// SetExcludeTags sets the tags marking subtrees left out of exports, as
+// set with #+EXCLUDE_TAGS: (noexport by default)
+func (r *Renderer) SetExcludeTags(tags []string) {
+	r.excludeTags = tags
+}
+
+// SetShowExcluded dims COMMENT and excluded subtrees instead of hiding them
+func (r *Renderer) SetShowExcluded(show bool) {
+	r.showExcluded = show
+}
+
+// isExcluded reports whether h is a COMMENT headline or has an excluded tag
+func (r *Renderer) isExcluded(h goorg.Headline) bool {
+	if h.IsComment {
+		return true
+	}
+	for _, tag := range h.Tags {
+		if slices.Contains(r.excludeTags, tag) {
+			return true
+		}
+	}
+	return false
+}
+
+// SetContext makes rendering stop early once ctx is done, e.g. when the
+// reader navigates away from a document still rendering. The output of an
+// interrupted render is incomplete and should be thrown away. nil renders
+// to the end.
+func (r *Renderer) SetContext(ctx context.Context) {
+	r.ctx = ctx
+}
+
+// SetNoWrap keeps long lines in source and example blocks and the cells
+// of tables intact (to be scrolled horizontally) instead of soft-wrapping
+// them
+func (r *Renderer) SetNoWrap(noWrap bool) {
+	r.noWrap = noWrap
+}
+
+// blockWidth returns the width of a block with the given style and
+// content: the content width normally, or wide enough for the longest
+// line when wrapping is disabled
+func (r *Renderer) blockWidth(style lipgloss.Style, content string) int {
+	width := r.width - 6
+	if r.noWrap {
+		if natural := lipgloss.Width(content) + style.GetHorizontalPadding(); natural > width {
+			width = natural
+		}
+	}
+	return width
+}
+
+// Links returns the links encountered so far, in render order
+func (r *Renderer) Links() []LinkRef {
+	return r.links
+}
+
+// Headings returns the headlines encountered so far, in render order
+func (r *Renderer) Headings() []HeadingRef {
+	return r.headings
+}
+
+// WideBlocks returns the blocks rendered wider than the body
+func (r *Renderer) WideBlocks() []BlockRef {
+	return r.wideBlocks
+}
+
+// recordWide records block if it is wider than the body, so the model can
+// scroll it sideways
+func (r *Renderer) recordWide(block string) {
+	width := lipgloss.Width(block)
+	if width <= r.width {
+		return
+	}
+	first, _, _ := strings.Cut(block, "\n")
+	r.wideBlocks = append(r.wideBlocks, BlockRef{
+		First:  strings.TrimSpace(stripANSI(first)),
+		Height: lipgloss.Height(block),
+		Width:  width,
+	})
+}
+
+// Tables returns the tables encountered so far, in render order
+func (r *Renderer) Tables() []TableRef {
+	return r.tables
+}
+
+// LocateTables sets the line of each table within the final rendered
+// output, matching their first lines in order
+func LocateTables(rendered string, tables []TableRef) {
+	lines := strings.Split(rendered, "\n")
+	line := 0
+	for i := range tables {
+		line = findLine(lines, line, tables[i].First)
+		if line >= len(lines) {
+			// Not found; leave the rest without rows to focus
+			tables[i].Rows = nil
+			continue
+		}
+		tables[i].Line = line
+		line += tables[i].Height
+	}
+}
+
+// Drawers returns the drawers encountered so far, in render order
+func (r *Renderer) Drawers() []DrawerRef {
+	return r.drawers
+}
+
+// LocateDrawers sets the line of each drawer within the final rendered
+// output, matching their first lines in order
+func LocateDrawers(rendered string, drawers []DrawerRef) {
+	lines := strings.Split(rendered, "\n")
+	line := 0
+	for i := range drawers {
+		line = findLine(lines, line, drawers[i].first)
+		if line >= len(lines) {
+			drawers[i].Line = len(lines) - 1
+			continue
+		}
+		drawers[i].Line = line
+		line += drawers[i].Height
+	}
+}
+
+// CodeBlocks returns the source blocks encountered so far, in render order
+func (r *Renderer) CodeBlocks() []CodeRef {
+	return r.codeBlocks
+}
+
+// LocateCodeBlocks sets the line of each source block within the final
+// rendered output, matching their headers in order
+func LocateCodeBlocks(rendered string, blocks []CodeRef) {
+	lines := strings.Split(rendered, "\n")
+	line := 0
+	for i := range blocks {
+		line = findLine(lines, line, blocks[i].first)
+		if line >= len(lines) {
+			blocks[i].Line = len(lines) - 1
+			continue
+		}
+		blocks[i].Line = line
+		line += blocks[i].Height
+	}
+}
+
+// Anchors returns the places internal links jump to encountered so far, in
+// render order
+func (r *Renderer) Anchors() []AnchorRef {
+	return r.anchors
+}
+
+// anchor records anchors for names at what is rendered next, keeping them
+// in render order. The returned function locates them at the first line
+// of the rendered output.
+func (r *Renderer) anchor(names ...string) func(rendered string) {
+	start := len(r.anchors)
+	for _, name := range names {
+		r.anchors = append(r.anchors, AnchorRef{Name: name})
+	}
+	return func(rendered string) {
+		first := firstLine(rendered)
+		for i := start; i < start+len(names); i++ {
+			r.anchors[i].first = first
+		}
+	}
+}
+
+// firstLine returns the first line of rendered with text, without styling
+// and surrounding spaces
+func firstLine(rendered string) string {
+	for _, line := range strings.Split(stripANSI(rendered), "\n") {
+		if text := strings.TrimSpace(line); text != "" {
+			return text
+		}
+	}
+	return ""
+}
+
+// LocateAnchors sets the line of each anchor within the final rendered
+// output, matching their first lines in order. Anchors nested in other
+// blocks are found on lines that contain their first line; those not
+// found are left at -1.
+func LocateAnchors(rendered string, anchors []AnchorRef) {
+	lines := strings.Split(rendered, "\n")
+	line := 0
+	for i := range anchors {
+		anchors[i].Line = -1
+		if anchors[i].first == "" {
+			continue
+		}
+		for n := line; n < len(lines); n++ {
+			if strings.Contains(stripANSI(lines[n]), anchors[i].first) {
+				anchors[i].Line, line = n, n
+				break
+			}
+		}
+	}
+}
+
+// targetRegexp matches a <<target>> of internal links
+var targetRegexp = regexp.MustCompile(`<<([^<>\n]+)>>`)
+
+// targets returns the names of the <<targets>> in the text of nodes
+func targets(nodes []goorg.Node) []string {
+	var names []string
+	for _, node := range nodes {
+		if text, ok := node.(goorg.Text); ok && !text.IsRaw {
+			for _, m := range targetRegexp.FindAllStringSubmatch(text.Content, -1) {
+				names = append(names, m[1])
+			}
+		}
+	}
+	return names
+}
+
+// findLine returns the index of the first line from start on whose text,
+// without styling and surrounding spaces, is text (len(lines) if none is)
+func findLine(lines []string, start int, text string) int {
+	for start < len(lines) && strings.TrimSpace(stripANSI(lines[start])) != text {
+		start++
+	}
+	return start
+}
+
+// LocateBlocks sets the line and column of each wide block within the
+// final rendered output, matching their first lines in order
+func LocateBlocks(rendered string, blocks []BlockRef) {
+	lines := strings.Split(rendered, "\n")
+	line := 0
+	for i := range blocks {
+		line = findLine(lines, line, blocks[i].First)
+		if line >= len(lines) {
+			// Not found; leave the rest unscrollable
+			blocks[i].Height = 0
+			continue
+		}
+		plain := stripANSI(lines[line])
+		blocks[i].Line = line
+		blocks[i].Col = ansi.StringWidth(plain) - ansi.StringWidth(strings.TrimLeft(plain, " "))
+		line += blocks[i].Height
+	}
+}
+
+// LocateHeadings fills in the rendered line of each headline by scanning
+// the rendered output for headline star prefixes in order
+func LocateHeadings(rendered string, headings []HeadingRef) {
+	lines := strings.Split(rendered, "\n")
+	line := 0
+	for i := range headings {
+		prefix := strings.Repeat("★", headings[i].Level) + " "
+		for line < len(lines) && !strings.HasPrefix(stripANSI(lines[line]), prefix) {
+			line++
+		}
+		if line >= len(lines) {
+			headings[i].Line = len(lines) - 1
+			continue
+		}
+		headings[i].Line = line
+		line++
+	}
+}
+
+// HeadingAt returns the index of the last headline starting at or above
+// line, or -1 if line is before the first headline
+func HeadingAt(headings []HeadingRef, line int) int {
+	idx := -1
+	for i, h := range headings {
+		if h.Line > line {
+			break
+		}
+		idx = i
+	}
+	return idx
+}
+
+// HeadingPath returns the headline at index idx together with its
+// ancestors, outermost first
+func HeadingPath(headings []HeadingRef, idx int) []HeadingRef {
+	if idx < 0 || idx >= len(headings) {
+		return nil
+	}
+	path := []HeadingRef{headings[idx]}
+	level := headings[idx].Level
+	for i := idx - 1; i >= 0 && level > 1; i-- {
+		if headings[i].Level < level {
+			path = append([]HeadingRef{headings[i]}, path...)
+			level = headings[i].Level
+		}
+	}
+	return path
+}
+
+// LocateLinks fills in the rendered position of each link by scanning the
+// rendered output for link icons in order. The output may contain content
+// before the rendered nodes (e.g. a document title header).
+func LocateLinks(rendered string, links []LinkRef) {
+	lines := strings.Split(rendered, "\n")
+	line, col := 0, 0
+	for i := range links {
+		icon := linkIcon(links[i].URL)
+		for line < len(lines) {
+			plain := stripANSI(lines[line])
+			if col < len(plain) {
+				if idx := strings.Index(plain[col:], icon); idx >= 0 {
+					links[i].Line = line
+					links[i].Col = lipgloss.Width(plain[:col+idx])
+					col += idx + len(icon)
+					break
+				}
+			}
+			line++
+			col = 0
+		}
+		if line >= len(lines) {
+			links[i].Line = len(lines) - 1
+		}
 	}
 }
 
 // RenderNodes renders a slice of org nodes
 func (r *Renderer) RenderNodes(nodes []goorg.Node) string {
 	var b strings.Builder
+	r.WriteNodes(&b, nodes)
+	return b.String()
+}
+
+// renderYieldEvery is how many nodes are rendered between yields of the
+// processor, so huge documents rendering don't hold it up for other
+// sessions
+const renderYieldEvery = 64
+
+// WriteNodes renders nodes to w as each is rendered. Nested nodes count
+// too, so rendering is interrupted within large subtrees once the context
+// set with SetContext is done, returning its error.
+func (r *Renderer) WriteNodes(w io.Writer, nodes []goorg.Node) error {
+	var caption []goorg.Node // #+CAPTION: the parser didn't attach
 	for _, node := range nodes {
+		if err := r.yield(); err != nil {
+			return err
+		}
+		// The parser leaves captions followed by other affiliated keywords
+		// such as #+NAME: on their own; they belong to the next node
+		if kw, ok := node.(goorg.Keyword); ok && kw.Key == "CAPTION" {
+			caption = parseInline(kw.Value)
+			continue
+		}
+		// Babel header arguments of #+HEADER: go with the next source block
+		if kw, ok := node.(goorg.Keyword); ok && (kw.Key == "HEADER" || kw.Key == "HEADERS") {
+			r.headerArgs = append(r.headerArgs, strings.Fields(kw.Value)...)
+			continue
+		}
+		if caption != nil {
+			node = goorg.NodeWithMeta{Node: node, Meta: goorg.Metadata{Caption: [][]goorg.Node{caption}}}
+			caption = nil
+		}
+
 		rendered := r.RenderNode(node)
+		r.headerArgs = nil
 		if rendered != "" {
-			b.WriteString(rendered)
-			b.WriteString("\n")
+			if _, err := io.WriteString(w, rendered+"\n"); err != nil {
+				return err
+			}
 		}
 	}
-	return b.String()
+	return r.interrupted()
+}
+
+// yield lets other goroutines run every renderYieldEvery nodes, and
+// returns the error of the render context once it is done
+func (r *Renderer) yield() error {
+	r.unyielded++
+	if r.unyielded >= renderYieldEvery {
+		r.unyielded = 0
+		runtime.Gosched()
+	}
+	return r.interrupted()
+}
+
+// interrupted returns the error of the render context, or nil while the
+// render goes on
+func (r *Renderer) interrupted() error {
+	if r.ctx == nil {
+		return nil
+	}
+	return r.ctx.Err()
+}
+
+// RenderDocument renders the nodes of a document followed by its endnotes
+func (r *Renderer) RenderDocument(nodes []goorg.Node) string {
+	r.beginDocument(nodes)
+	return r.RenderNodes(nodes) + r.renderFootnotes()
+}
+
+// beginDocument prepares for rendering the nodes of a document, which may
+// be rendered a few at a time
+func (r *Renderer) beginDocument(nodes []goorg.Node) {
+	r.outline = r.exportedHeadlines(nodes, nil)
 }
 
 // RenderNode renders a single org node
@@ -87,7 +703,7 @@ func (r *Renderer) RenderNode(node goorg.Node) string {
 	case goorg.List:
 		return r.renderList(n)
 	case goorg.ListItem:
-		return r.renderListItem(n, 0)
+		return r.renderListItem(n, 0, "•")
 	case goorg.DescriptiveListItem:
 		return r.renderDescriptiveListItem(n)
 	case goorg.Table:
@@ -102,8 +718,22 @@ func (r *Renderer) RenderNode(node goorg.Node) string {
 		return r.renderDrawer(n)
 	case goorg.Example:
 		return r.renderExample(n)
+	case goorg.LatexBlock:
+		return r.renderLatexBlock(n)
 	case goorg.FootnoteDefinition:
 		return r.renderFootnoteDefinition(n)
+	case goorg.NodeWithMeta:
+		return r.renderCaptioned(n)
+	case goorg.NodeWithName:
+		anchored := r.anchor(n.Name)
+		var rendered string
+		if block, ok := n.Node.(goorg.Block); ok && strings.EqualFold(block.Name, "SRC") {
+			rendered = r.renderSourceBlock(block, n.Name)
+		} else {
+			rendered = r.RenderNode(n.Node)
+		}
+		anchored(rendered)
+		return rendered
 	default:
 		return ""
 	}
@@ -112,18 +742,64 @@ func (r *Renderer) RenderNode(node goorg.Node) string {
 func (r *Renderer) renderHeadline(h goorg.Headline) string {
 	var b strings.Builder
 
+	// Subtrees excluded from export are hidden, or dimmed with what
+	// excluded them showing
+	if r.isExcluded(h) {
+		if !r.showExcluded {
+			r.headlines += countHeadlines(h)
+			return ""
+		}
+		if h.IsComment {
+			h.Title = append([]goorg.Node{goorg.Text{Content: "COMMENT "}}, h.Title...)
+			h.IsComment = false
+		}
+		excludeTags := r.excludeTags
+		r.excludeTags = nil
+		rendered := r.renderHeadline(h)
+		r.excludeTags = excludeTags
+
+		lines := strings.Split(rendered, "\n")
+		for i, line := range lines {
+			lines[i] = r.styles.Excluded.Render(stripANSI(line))
+		}
+		return strings.Join(lines, "\n")
+	}
+
+	// The footnote section only holds definitions, shown as endnotes
+	if isFootnoteSection(h) {
+		r.headlines++
+		for _, child := range h.Children {
+			r.RenderNode(child)
+		}
+		return ""
+	}
+
 	// Build the headline text
 	stars := strings.Repeat("★", h.Lvl)
 	title := r.renderInlineNodes(h.Title)
 
+	r.headings = append(r.headings, HeadingRef{
+		Level: h.Lvl,
+		Title: stripANSI(title),
+		Index: r.headlines,
+	})
+	r.headlines++
+
+	names := []string{"*" + headlineTarget(h)}
+	if h.Properties != nil {
+		if id, ok := h.Properties.Get("CUSTOM_ID"); ok {
+			names = append(names, "#"+id)
+		}
+		if id, ok := h.Properties.Get("ID"); ok {
+			names = append(names, "id:"+id)
+		}
+	}
+	anchored := r.anchor(names...)
+
 	// Add TODO/DONE status with styling
 	var status string
 	if h.Status != "" {
-		if h.Status == "DONE" {
-			status = r.styles.Done.Render(h.Status) + " "
-		} else {
-			status = r.styles.Todo.Render(h.Status) + " "
-		}
+		status = r.renderStatus(h.Status) + " "
 	}
 
 	// Add priority
@@ -132,14 +808,15 @@ func (r *Renderer) renderHeadline(h goorg.Headline) string {
 		priority = r.styles.Priority.Render("[#"+h.Priority+"]") + " "
 	}
 
-	// Add tags
-	var tags string
+	headline := fmt.Sprintf("%s %s%s%s", stars, status, priority, title)
+
+	// Add tags, aligned to the right margin when they fit
 	if len(h.Tags) > 0 {
-		tags = " " + r.styles.Tag.Render(":"+strings.Join(h.Tags, ":")+":")
+		tags := r.styles.Tag.Render(":" + strings.Join(h.Tags, ":") + ":")
+		gap := r.width - 4 - lipgloss.Width(headline) - lipgloss.Width(tags)
+		headline += strings.Repeat(" ", max(1, gap)) + tags
 	}
 
-	headline := fmt.Sprintf("%s %s%s%s%s", stars, status, priority, title, tags)
-
 	// Style based on level
 	var style lipgloss.Style
 	switch h.Lvl {
@@ -155,25 +832,92 @@ func (r *Renderer) renderHeadline(h goorg.Headline) string {
 
 	b.WriteString(style.Render(headline))
 	b.WriteString("\n")
-
-	// Render children
-	for _, child := range h.Children {
-		rendered := r.RenderNode(child)
-		if rendered != "" {
-			b.WriteString(rendered)
-			b.WriteString("\n")
+	anchored(headline)
+
+	// The planning line right below the headline is shown as a metadata row
+	children := h.Children
+	if len(children) > 0 {
+		if p, ok := children[0].(goorg.Paragraph); ok {
+			first, rest, _ := strings.Cut(strings.TrimSpace(goorg.String(p)), "\n")
+			if planning, ok := org.ParsePlanning(first); ok {
+				b.WriteString(r.renderPlanning(planning))
+				b.WriteString("\n")
+				// Text on the following lines joined the planning paragraph
+				children = children[1:]
+				if rest != "" {
+					children = append(goorg.New().Parse(strings.NewReader(rest), "").Nodes, children...)
+				}
+			}
 		}
 	}
 
+	if h.Properties != nil && len(h.Properties.Properties) > 0 {
+		b.WriteString(r.renderPropertyDrawer(*h.Properties))
+		b.WriteString("\n")
+	}
+
+	// Render children
+	b.WriteString(r.RenderNodes(children))
+
 	return b.String()
 }
 
+// renderCaptioned renders a node with its caption, numbered by kind as org
+// export does: tables and listings are captioned above, figures and the
+// rest below
+func (r *Renderer) renderCaptioned(n goorg.NodeWithMeta) string {
+	body := r.RenderNode(n.Node)
+	if len(n.Meta.Caption) == 0 || body == "" {
+		return body
+	}
+
+	kind, above := captionKind(n.Node)
+	var label string
+	if kind != "" {
+		r.captionCounts[kind]++
+		label = r.styles.CaptionLabel.Render(fmt.Sprintf("%s %d:", kind, r.captionCounts[kind])) + " "
+	}
+	texts := make([]string, len(n.Meta.Caption))
+	for i, caption := range n.Meta.Caption {
+		texts[i] = strings.TrimSpace(r.renderInlineNodes(caption))
+	}
+	caption := ansi.Wrap(label+r.styles.Caption.Render(strings.Join(texts, " ")), r.width-4, "")
+
+	if above {
+		return caption + "\n" + body
+	}
+	// Keep the blank line after images below their caption
+	trimmed := strings.TrimRight(body, "\n")
+	return trimmed + "\n" + caption + body[len(trimmed):]
+}
+
+// captionKind returns the label numbering captions of node (Table, Figure
+// or Listing, empty for other nodes) and whether the caption goes above it
+func captionKind(node goorg.Node) (string, bool) {
+	if named, ok := node.(goorg.NodeWithName); ok {
+		node = named.Node
+	}
+	switch n := node.(type) {
+	case goorg.Table:
+		return "Table", true
+	case goorg.Paragraph:
+		if _, ok := imageLink(n); ok {
+			return "Figure", false
+		}
+	case goorg.Block:
+		if strings.EqualFold(n.Name, "SRC") {
+			return "Listing", true
+		}
+	}
+	return "", false
+}
+
 func (r *Renderer) renderBlock(block goorg.Block) string {
 	name := strings.ToUpper(block.Name)
 
 	switch name {
 	case "SRC":
-		return r.renderSourceBlock(block)
+		return r.renderSourceBlock(block, "")
 	case "QUOTE":
 		return r.renderQuoteBlock(block)
 	case "EXAMPLE":
@@ -182,48 +926,168 @@ func (r *Renderer) renderBlock(block goorg.Block) string {
 		return r.renderVerseBlock(block)
 	case "CENTER":
 		return r.renderCenterBlock(block)
+	case "EXPORT":
+		return r.renderExportBlock(block)
+	case "NOTE", "TIP", "IMPORTANT", "WARNING", "CAUTION":
+		return r.renderAdmonition(block, name)
 	default:
 		// Generic block
 		content := r.extractBlockText(block.Children)
-		return r.styles.CodeBlock.Width(r.width - 6).Render(content)
+		return r.styles.Sized(&r.styles.CodeBlock, r.width-6).Render(content)
 	}
 }
 
-func (r *Renderer) renderSourceBlock(block goorg.Block) string {
+// renderSourceBlock renders a syntax highlighted source block in a frame
+// showing its name and language, with a hint for copying it
+func (r *Renderer) renderSourceBlock(block goorg.Block, name string) string {
 	content := r.extractBlockText(block.Children)
 	lang := ""
 
 	// Get language from parameters - first parameter is typically the language
-	if len(block.Parameters) > 0 {
+	if len(block.Parameters) > 0 && !strings.ContainsAny(block.Parameters[0], "-+:") {
 		lang = block.Parameters[0]
 	}
 
+	// Unlabeled blocks are highlighted in the language chroma guesses
+	guessed := ""
+	if lang == "" && r.guessLanguage {
+		if lexer := lexers.Analyse(content); lexer != nil {
+			guessed = lexer.Config().Name
+		}
+	}
+
 	// Try to syntax highlight with chroma
-	highlighted := r.highlightCode(content, lang)
+	highlighted := r.numberLines(r.highlightCode(content, cmp.Or(lang, guessed)), block.Parameters)
+
+	// Long blocks are folded to their first lines
+	lines := strings.Split(highlighted, "\n")
+	fold := r.foldCode > 0 && len(lines) > r.foldCode
+	folded := fold && !r.unfoldedCode[len(r.codeBlocks)]
+	if folded {
+		preview := min(codePreviewLines, r.foldCode)
+		more := len(lines) - preview
+		noun := "lines"
+		if more == 1 {
+			noun = "line"
+		}
+		hint := r.styles.CodeFold.Render(fmt.Sprintf("… %d more %s (enter to expand)", more, noun))
+		highlighted = strings.Join(append(lines[:preview:preview], hint), "\n")
+	}
+
+	// Babel header arguments, when shown, head the code
+	if args := append(slices.Clone(r.headerArgs), babelHeaderArgs(block.Parameters)...); r.showHeaders && len(args) > 0 {
+		wrapped := ansi.Wrap(strings.Join(args, " "), max(minColumnWidth, r.width-10), "")
+		highlighted = r.styles.LineNumber.Render(wrapped) + "\n" + highlighted
+	}
 
 	// Add language label
-	headerWidth := r.width - 8
+	blockWidth := r.blockWidth(r.styles.CodeBlock, highlighted)
+	headerWidth := blockWidth - 2
 	if headerWidth < 10 {
 		headerWidth = 10
 	}
 
-	var header string
+	var labels []string
+	if name != "" {
+		labels = append(labels, name)
+	}
 	if lang != "" {
-		langLabel := " " + lang + " "
-		lineLen := headerWidth - len(langLabel) - 2
-		if lineLen < 0 {
-			lineLen = 0
-		}
-		header = r.styles.BlockHeader.Render("┌─" + langLabel + strings.Repeat("─", lineLen) + "┐")
-	} else {
-		header = r.styles.BlockHeader.Render("┌" + strings.Repeat("─", headerWidth) + "┐")
+		labels = append(labels, lang)
+	}
+	if guessed != "" {
+		labels = append(labels, strings.ToLower(guessed)+" (guessed)")
+	}
+	label := ""
+	if len(labels) > 0 {
+		label = " " + ansi.Truncate(strings.Join(labels, " · "), max(1, headerWidth-6), "…") + " "
+	}
+	// Offer the copy key when there is room for it
+	hint := " " + copyCodeKey + " copy "
+	lineLen := headerWidth - ansi.StringWidth(label) - ansi.StringWidth(hint) - 2
+	if lineLen < 0 {
+		hint = ""
+		lineLen = max(0, headerWidth-ansi.StringWidth(label)-2)
+	}
+	header := r.styles.BlockHeader.Render("┌─" + label + strings.Repeat("─", lineLen) + hint + "─┐")
+	if label == "" {
+		header = r.styles.BlockHeader.Render("┌" + strings.Repeat("─", lineLen+1) + hint + "─┐")
 	}
 
 	footer := r.styles.BlockHeader.Render("└" + strings.Repeat("─", headerWidth) + "┘")
 
-	codeBlock := r.styles.CodeBlock.Width(r.width - 6).Render(highlighted)
+	codeBlock := r.styles.Sized(&r.styles.CodeBlock, blockWidth).Render(highlighted)
+
+	rendered := header + "\n" + codeBlock + "\n" + footer
+	r.recordWide(rendered)
+	r.codeBlocks = append(r.codeBlocks, CodeRef{
+		Name:   name,
+		Lang:   lang,
+		Code:   content,
+		Height: lipgloss.Height(rendered),
+		Fold:   fold,
+		Folded: folded,
+		first:  strings.TrimSpace(stripANSI(header)),
+	})
+	return rendered
+}
+
+// babelHeaderArgs returns the babel header arguments of a source block's
+// parameters: everything from the first :keyword on, after the language
+// and switches such as -n
+func babelHeaderArgs(params []string) []string {
+	for i, param := range params {
+		if strings.HasPrefix(param, ":") {
+			return params[i:]
+		}
+	}
+	return nil
+}
+
+// codePreviewLines is how many lines of a folded source block are shown
+const codePreviewLines = 10
 
-	return header + "\n" + codeBlock + "\n" + footer
+// CheckChromaStyle returns an error if name is not a known syntax
+// highlighting style
+func CheckChromaStyle(name string) error {
+	if _, ok := styles.Registry[name]; !ok {
+		return fmt.Errorf("unknown chroma style %q (see https://xyproto.github.io/splash/docs/)", name)
+	}
+	return nil
+}
+
+// numberLines prefixes the lines of a block with a line number gutter when
+// its parameters have a -n switch (numbering from 1, or the number after
+// it) or a +n switch (continuing from the previous numbered block, plus
+// the number after it)
+func (r *Renderer) numberLines(content string, params []string) string {
+	first := 0
+	for i, param := range params {
+		if param != "-n" && param != "+n" {
+			continue
+		}
+		offset := 0
+		if i+1 < len(params) {
+			offset, _ = strconv.Atoi(params[i+1])
+		}
+		if param == "-n" {
+			first = max(1, offset)
+		} else {
+			first = r.lastLineNumber + max(1, offset)
+		}
+		break
+	}
+	if first == 0 {
+		return content
+	}
+
+	lines := strings.Split(content, "\n")
+	last := first + len(lines) - 1
+	digits := len(strconv.Itoa(last))
+	for i, line := range lines {
+		lines[i] = r.styles.LineNumber.Render(fmt.Sprintf("%*d │ ", digits, first+i)) + line
+	}
+	r.lastLineNumber = last
+	return strings.Join(lines, "\n")
 }
 
 func (r *Renderer) highlightCode(code, lang string) string {
@@ -237,10 +1101,9 @@ func (r *Renderer) highlightCode(code, lang string) string {
 	}
 	lexer = chroma.Coalesce(lexer)
 
-	// Use a terminal-friendly style
-	style := styles.Get("monokai")
-	if style == nil {
-		style = styles.Fallback
+	style, ok := styles.Registry[r.chromaStyle]
+	if !ok {
+		style = styles.Get(r.styles.ChromaStyle)
 	}
 
 	formatter := formatters.Get("terminal256")
@@ -263,37 +1126,214 @@ func (r *Renderer) highlightCode(code, lang string) string {
 }
 
 func (r *Renderer) renderQuoteBlock(block goorg.Block) string {
-	content := r.renderInlineNodes(block.Children)
-	return r.styles.Quote.Width(r.width - 8).Render(content)
+	content := r.renderNested(block.Children, r.width-8-r.styles.Quote.GetHorizontalPadding())
+	return r.styles.Sized(&r.styles.Quote, r.width-8).Render(content)
+}
+
+// renderNested renders nodes nested in another block as blocks fitting in
+// width columns, keeping their structure
+func (r *Renderer) renderNested(nodes []goorg.Node, width int) string {
+	outer := r.width
+	r.width = max(minColumnWidth, width)
+	content := strings.TrimRight(r.RenderNodes(nodes), "\n")
+	r.width = outer
+	return content
+}
+
+// indentLines indents every line of s by n spaces
+func indentLines(s string, n int) string {
+	pad := strings.Repeat(" ", n)
+	return pad + strings.ReplaceAll(s, "\n", "\n"+pad)
 }
 
 func (r *Renderer) renderExampleBlock(block goorg.Block) string {
-	content := r.extractBlockText(block.Children)
-	return r.styles.Example.Width(r.width - 6).Render(content)
+	content := r.numberLines(exampleText(block.Children, ""), block.Parameters)
+	rendered := r.styles.Sized(&r.styles.Example, r.blockWidth(r.styles.Example, content)).Render(content)
+	r.recordWide(rendered)
+	return rendered
+}
+
+// admonitionIcons are shown before the title of admonition blocks
+var admonitionIcons = map[string]string{
+	"NOTE":      "📝",
+	"TIP":       "💡",
+	"IMPORTANT": "📌",
+	"WARNING":   "⚡",
+	"CAUTION":   "🛑",
 }
 
+// renderAdmonition renders a NOTE, TIP, IMPORTANT, WARNING or CAUTION
+// block as a callout box titled with its kind
+func (r *Renderer) renderAdmonition(block goorg.Block, kind string) string {
+	color := r.styles.AdmonitionColors[kind]
+	box := r.styles.Admonition.BorderForeground(color)
+	title := r.styles.AdmonitionTitle.Foreground(color).
+		Render(admonitionIcons[kind] + " " + kind[:1] + strings.ToLower(kind[1:]))
+
+	content := r.renderNested(block.Children, r.width-6-box.GetHorizontalPadding())
+	return box.Width(r.width - 6).Render(title + "\n" + content)
+}
+
+// renderVerseBlock renders a verse block line by line, keeping the line
+// breaks and indentation of the source and rendering inline markup
 func (r *Renderer) renderVerseBlock(block goorg.Block) string {
-	content := r.extractBlockText(block.Children)
-	return r.styles.Verse.Width(r.width - 6).Render(content)
+	lines := r.verseSource(block)
+	if lines == nil {
+		// Without the source, fall back to the reflowed text
+		content := r.extractBlockText(block.Children)
+		return r.styles.Sized(&r.styles.Verse, r.width-6).Render(content)
+	}
+
+	rendered := make([]string, len(lines))
+	for i, line := range lines {
+		text := strings.TrimLeft(line, " \t")
+		indent := line[:len(line)-len(text)]
+		rendered[i] = indent + r.renderInlineLine(text)
+	}
+	return r.styles.Sized(&r.styles.Verse, r.width-6).Render(strings.Join(rendered, "\n"))
+}
+
+// verseSource returns the source lines of block, or nil if they aren't
+// known
+func (r *Renderer) verseSource(block goorg.Block) []string {
+	if len(block.Children) == 0 {
+		return nil
+	}
+	return r.verses[&block.Children[0]]
+}
+
+// words returns the words of lines, a space apart
+func words(lines []string) string {
+	return strings.Join(strings.Fields(strings.Join(lines, " ")), " ")
+}
+
+// renderInlineLine renders the inline markup of a single line of text
+func (r *Renderer) renderInlineLine(text string) string {
+	if nodes := parseInline(text); nodes != nil {
+		return strings.TrimRight(r.renderInlineNodes(nodes), "\n")
+	}
+	return text
+}
+
+// parseInline parses the inline markup of a line of text, returning nil
+// if it doesn't parse as a single paragraph
+func parseInline(text string) []goorg.Node {
+	doc := goorg.New().Parse(strings.NewReader(text), "")
+	if len(doc.Nodes) == 1 {
+		if p, ok := doc.Nodes[0].(goorg.Paragraph); ok {
+			return p.Children
+		}
+	}
+	return nil
+}
+
+// verseBlocks returns the lines of each verse block in source, in order,
+// without their common indentation. Blocks of raw text, such as source
+// blocks, are skipped over as the parser does.
+func verseBlocks(source string) [][]string {
+	var blocks [][]string
+	var current []string
+	inVerse := false
+	raw := "" // Name of the raw text block the lines are in
+	for _, line := range strings.Split(source, "\n") {
+		trimmed := strings.ToLower(strings.TrimSpace(line))
+		switch {
+		case raw != "":
+			if strings.HasPrefix(trimmed, "#+end_"+raw) {
+				raw = ""
+			}
+		case !inVerse && rawTextBlockRegexp.MatchString(trimmed):
+			raw = rawTextBlockRegexp.FindStringSubmatch(trimmed)[1]
+		case !inVerse && strings.HasPrefix(trimmed, "#+begin_verse"):
+			inVerse, current = true, []string{}
+		case inVerse && strings.HasPrefix(trimmed, "#+end_verse"):
+			inVerse = false
+			blocks = append(blocks, dedent(current))
+		case inVerse:
+			current = append(current, strings.TrimRight(line, " \t"))
+		}
+	}
+	return blocks
+}
+
+// rawTextBlockRegexp matches the lowercased first line of the blocks the
+// parser keeps as raw text, capturing their name
+var rawTextBlockRegexp = regexp.MustCompile(`^#\+begin_(src|example|export)\b`)
+
+// parsedVerses appends the verse blocks in nodes and their subtrees to
+// verses, in order, shown or not
+func parsedVerses(nodes []goorg.Node, verses []goorg.Block) []goorg.Block {
+	for _, node := range nodes {
+		switch n := node.(type) {
+		case goorg.Block:
+			if strings.EqualFold(n.Name, "VERSE") {
+				verses = append(verses, n)
+			}
+			verses = parsedVerses(n.Children, verses)
+		case goorg.Headline:
+			verses = parsedVerses(n.Children, verses)
+		case goorg.List:
+			verses = parsedVerses(n.Items, verses)
+		case goorg.ListItem:
+			verses = parsedVerses(n.Children, verses)
+		case goorg.DescriptiveListItem:
+			verses = parsedVerses(n.Details, verses)
+		case goorg.Drawer:
+			verses = parsedVerses(n.Children, verses)
+		case goorg.FootnoteDefinition:
+			verses = parsedVerses(n.Children, verses)
+		case goorg.NodeWithMeta:
+			verses = parsedVerses([]goorg.Node{n.Node}, verses)
+		case goorg.NodeWithName:
+			verses = parsedVerses([]goorg.Node{n.Node}, verses)
+		}
+	}
+	return verses
+}
+
+// dedent removes the indentation common to the non-blank lines
+func dedent(lines []string) []string {
+	indent := -1
+	for _, line := range lines {
+		if text := strings.TrimLeft(line, " \t"); text != "" {
+			if n := len(line) - len(text); indent < 0 || n < indent {
+				indent = n
+			}
+		}
+	}
+	for i, line := range lines {
+		if len(line) >= indent && indent > 0 {
+			lines[i] = line[indent:]
+		}
+	}
+	return lines
 }
 
 func (r *Renderer) renderCenterBlock(block goorg.Block) string {
 	content := r.renderInlineNodes(block.Children)
-	return r.styles.Center.Width(r.width - 6).Render(content)
+	return r.styles.Sized(&r.styles.Center, r.width-6).Render(content)
 }
 
 func (r *Renderer) renderParagraph(p goorg.Paragraph) string {
+	if link, ok := imageLink(p); ok {
+		// Followed by a blank line, like the text of paragraphs
+		return r.renderImage(link) + "\n"
+	}
+	anchored := r.anchor(targets(p.Children)...)
 	content := r.renderInlineNodes(p.Children)
-	return r.styles.Paragraph.Width(r.width - 4).Render(content)
+	rendered := r.styles.Sized(&r.styles.Paragraph, r.width-4).Render(content)
+	anchored(rendered)
+	return rendered
 }
 
 func (r *Renderer) renderList(list goorg.List) string {
 	var b strings.Builder
 
-	for _, item := range list.Items {
+	bullets := listBullets(list)
+	for i, item := range list.Items {
 		switch n := item.(type) {
 		case goorg.ListItem:
-			b.WriteString(r.renderListItem(n, 0))
+			b.WriteString(r.renderListItem(n, 0, bullets[i]))
 			b.WriteString("\n")
 		case goorg.DescriptiveListItem:
 			b.WriteString(r.renderDescriptiveListItem(n))
@@ -304,16 +1344,60 @@ func (r *Renderer) renderList(list goorg.List) string {
 	return b.String()
 }
 
-func (r *Renderer) renderListItem(item goorg.ListItem, indent int) string {
-	var b strings.Builder
+// listBullets returns the bullet of each item of list: a dot for unordered
+// items, and for ordered ones the item's number, counting from the first
+// item's and restarting at [@n] counter cookies, in the style of its bullet
+func listBullets(list goorg.List) []string {
+	bullets := make([]string, len(list.Items))
+	counter := 0
+	for i, item := range list.Items {
+		bullets[i] = "•"
+		li, ok := item.(goorg.ListItem)
+		if !ok || list.Kind != "ordered" || li.Bullet == "" {
+			continue
+		}
+		counter++
+		if i == 0 {
+			counter = bulletNumber(li.Bullet)
+		}
+		if n, err := strconv.Atoi(li.Value); err == nil {
+			counter = n
+		}
 
-	indentStr := strings.Repeat("  ", indent)
+		delim := li.Bullet[len(li.Bullet)-1:]
+		switch first := li.Bullet[0]; {
+		case first >= 'a' && first <= 'z' && counter <= 26:
+			bullets[i] = string(rune('a'+counter-1)) + delim
+		case first >= 'A' && first <= 'Z' && counter <= 26:
+			bullets[i] = string(rune('A'+counter-1)) + delim
+		default:
+			bullets[i] = strconv.Itoa(counter) + delim
+		}
+	}
+	return bullets
+}
 
-	// Determine bullet style
-	bullet := "•"
-	if strings.HasPrefix(item.Bullet, "1") || strings.ContainsAny(item.Bullet, "0123456789") {
-		bullet = item.Bullet
+// bulletNumber returns the number of an ordered bullet like "3." or "c)"
+func bulletNumber(bullet string) int {
+	label := strings.TrimRight(bullet, ".)")
+	if n, err := strconv.Atoi(label); err == nil {
+		return n
+	}
+	if len(label) == 1 {
+		switch c := label[0]; {
+		case c >= 'a' && c <= 'z':
+			return int(c-'a') + 1
+		case c >= 'A' && c <= 'Z':
+			return int(c-'A') + 1
+		}
 	}
+	return 1
+}
+
+func (r *Renderer) renderListItem(item goorg.ListItem, indent int, bullet string) string {
+	var b strings.Builder
+
+	indentStr := strings.Repeat("  ", indent)
 
 	// Checkbox status
 	var checkbox string
@@ -326,9 +1410,17 @@ func (r *Renderer) renderListItem(item goorg.ListItem, indent int) string {
 		checkbox = r.styles.CheckboxEmpty.Render("[ ]") + " "
 	}
 
+	var names []string
+	for _, child := range item.Children {
+		if p, ok := child.(goorg.Paragraph); ok {
+			names = append(names, targets(p.Children)...)
+		}
+	}
+	anchored := r.anchor(names...)
+
 	// ListItem.Children contains block elements (usually Paragraph, but also nested List)
 	// We need to extract and render the inline content from Paragraphs,
-	// and recursively render nested Lists
+	// and recursively render nested Lists and other blocks below the item
 	var content string
 	var nestedContent string
 	for _, child := range item.Children {
@@ -339,39 +1431,36 @@ func (r *Renderer) renderListItem(item goorg.ListItem, indent int) string {
 			// Nested list - render with increased indent
 			nestedContent += "\n" + r.renderListWithIndent(c, indent+1)
 		default:
-			// For other block types, render them normally
-			content += r.RenderNode(child)
+			// Other blocks go below the item text, indented past the bullet
+			pad := 2 * (indent + 1)
+			nestedContent += "\n" + indentLines(r.renderNested([]goorg.Node{child}, r.width-pad), pad)
 		}
 	}
 
-	b.WriteString(indentStr)
-	b.WriteString(r.styles.ListBullet.Render(bullet))
-	b.WriteString(" ")
-	b.WriteString(checkbox)
-	b.WriteString(r.styles.ListItem.Render(content))
+	// Wrapped text hangs past the bullet and checkbox
+	prefix := indentStr + r.styles.ListBullet.Render(bullet) + " " + checkbox
+	pad := lipgloss.Width(prefix)
+	wrapped := ansi.Wrap(r.styles.ListItem.Render(content), max(minColumnWidth, r.width-4-pad), "")
+
+	b.WriteString(prefix)
+	b.WriteString(strings.TrimPrefix(indentLines(wrapped, pad), strings.Repeat(" ", pad)))
 	b.WriteString(nestedContent)
 
+	anchored(b.String())
 	return b.String()
 }
 
 func (r *Renderer) renderListWithIndent(list goorg.List, indent int) string {
 	var b strings.Builder
 
-	for _, item := range list.Items {
+	bullets := listBullets(list)
+	for i, item := range list.Items {
 		switch n := item.(type) {
 		case goorg.ListItem:
-			b.WriteString(r.renderListItem(n, indent))
+			b.WriteString(r.renderListItem(n, indent, bullets[i]))
 			b.WriteString("\n")
 		case goorg.DescriptiveListItem:
-			// Descriptive list items with indent
-			indentStr := strings.Repeat("  ", indent)
-			term := r.renderInlineNodes(n.Term)
-			details := r.renderInlineNodes(n.Details)
-			b.WriteString(indentStr)
-			b.WriteString(r.styles.ListBullet.Render("•") + " ")
-			b.WriteString(r.styles.DescTerm.Render(term) + " ")
-			b.WriteString(r.styles.DescSeparator.Render("::") + " ")
-			b.WriteString(r.styles.ListItem.Render(details))
+			b.WriteString(r.renderDescriptiveListItemWithIndent(n, indent))
 			b.WriteString("\n")
 		}
 	}
@@ -380,15 +1469,37 @@ func (r *Renderer) renderListWithIndent(list goorg.List, indent int) string {
 }
 
 func (r *Renderer) renderDescriptiveListItem(item goorg.DescriptiveListItem) string {
-	term := r.renderInlineNodes(item.Term)
-	details := r.renderInlineNodes(item.Details)
+	return r.renderDescriptiveListItemWithIndent(item, 0)
+}
+
+// renderDescriptiveListItemWithIndent renders a term :: details pair,
+// wrapping long details onto continuation lines aligned under the term.
+// Blocks other than paragraphs in the details go below, like nested lists.
+func (r *Renderer) renderDescriptiveListItemWithIndent(item goorg.DescriptiveListItem, indent int) string {
+	indentStr := strings.Repeat("  ", indent)
+	bullet := r.styles.ListBullet.Render("•") + " "
+	pad := len(indentStr) + lipgloss.Width(bullet)
+
+	var details []string
+	var nested string
+	for _, child := range item.Details {
+		switch c := child.(type) {
+		case goorg.Paragraph:
+			details = append(details, strings.TrimSpace(r.renderInlineNodes(c.Children)))
+		case goorg.List:
+			nested += "\n" + r.renderListWithIndent(c, indent+1)
+		default:
+			nested += "\n" + indentLines(r.renderNested([]goorg.Node{child}, r.width-pad), pad)
+		}
+	}
 
-	return r.styles.ListBullet.Render("•") + " " +
-		r.styles.DescTerm.Render(term) + " " +
+	text := r.styles.DescTerm.Render(r.renderInlineNodes(item.Term)) + " " +
 		r.styles.DescSeparator.Render("::") + " " +
-		r.styles.ListItem.Render(details)
-}
+		r.styles.ListItem.Render(strings.Join(details, " "))
+	wrapped := ansi.Wrap(text, max(minColumnWidth, r.width-4-pad), "")
 
+	return indentStr + bullet + strings.TrimPrefix(indentLines(wrapped, pad), strings.Repeat(" ", pad)) + nested
+}
 func (r *Renderer) renderTable(table goorg.Table) string {
 	var b strings.Builder
 
@@ -404,7 +1515,7 @@ func (r *Renderer) renderTable(table goorg.Table) string {
 		}
 		for i, col := range row.Columns {
 			content := r.renderInlineNodes(col.Children)
-			width := len(content)
+			width := ansi.StringWidth(content)
 			if width < 3 {
 				width = 3
 			}
@@ -420,6 +1531,24 @@ func (r *Renderer) renderTable(table goorg.Table) string {
 		return ""
 	}
 
+	// Width cookies (<10>, <r10>) cap their column; longer cells wrap,
+	// unless wrapping is off
+	for i, info := range table.ColumnInfos {
+		if i < len(colWidths) && info.DisplayLen > 0 && !r.noWrap {
+			colWidths[i] = min(colWidths[i], max(info.DisplayLen, 3))
+		}
+	}
+
+	// Fit the body width by wrapping cells, unless wide tables scroll
+	// sideways. Tables that don't fit even with narrow columns scroll too.
+	if !r.noWrap {
+		// Each column adds a border and two padding spaces, plus the final border
+		available := r.width - 4 - 3*len(colWidths) - 1
+		if fitted := fitColumns(colWidths, available); sum(fitted) <= available {
+			colWidths = fitted
+		}
+	}
+
 	// Helper to render a horizontal border
 	renderBorder := func(left, mid, right, fill string) string {
 		var sb strings.Builder
@@ -437,47 +1566,151 @@ func (r *Renderer) renderTable(table goorg.Table) string {
 	// Top border
 	b.WriteString(renderBorder("╭", "┬", "╮", "─"))
 	b.WriteString("\n")
+	lines := 1
+	var rows []RowSpan
+
+	// Rows above the first separator are the header
+	headerEnd := 0
+	for i, row := range table.Rows {
+		if isTableSeparator(row) {
+			headerEnd = i
+			break
+		}
+	}
 
 	// Render rows
 	for rowIdx, row := range table.Rows {
-		if row.IsSpecial {
-			// Separator row
+		if isTableSeparator(row) {
 			b.WriteString(renderBorder("├", "┼", "┤", "─"))
 			b.WriteString("\n")
+			lines++
+			continue
+		}
+		if row.IsSpecial {
+			// Alignment cookie rows (| <r> | <l10> |) aren't shown
 			continue
 		}
 
-		// Header row detection (first row before separator)
-		isHeader := rowIdx == 0 && len(table.Rows) > 1 && table.Rows[1].IsSpecial
+		isHeader := rowIdx < headerEnd
+		cellStyle := r.styles.TableCell
+		if !isHeader && r.zebra && len(rows)%2 == 1 {
+			cellStyle = r.styles.TableCellAlt
+		}
 
-		var rowStr strings.Builder
-		rowStr.WriteString(r.styles.TableBorder.Render("│"))
+		// Wrap each cell to its column; the row is as tall as its tallest cell
+		cells := make([][]string, len(row.Columns))
+		height := 1
 		for i, col := range row.Columns {
-			content := r.renderInlineNodes(col.Children)
 			width := 3
 			if i < len(colWidths) {
 				width = colWidths[i]
 			}
-			padded := fmt.Sprintf(" %-*s ", width, content)
-			if isHeader {
-				rowStr.WriteString(r.styles.TableHeader.Render(padded))
-			} else {
-				rowStr.WriteString(r.styles.TableCell.Render(padded))
-			}
+			cells[i] = strings.Split(ansi.Wrap(r.renderInlineNodes(col.Children), width, ""), "\n")
+			height = max(height, len(cells[i]))
+		}
+
+		for line := 0; line < height; line++ {
+			var rowStr strings.Builder
 			rowStr.WriteString(r.styles.TableBorder.Render("│"))
+			for i, cell := range cells {
+				width := 3
+				if i < len(colWidths) {
+					width = colWidths[i]
+				}
+				content := ""
+				if line < len(cell) {
+					content = cell[line]
+				}
+				align := ""
+				if info := row.Columns[i].ColumnInfo; info != nil {
+					align = info.Align
+				}
+				padded := " " + alignCell(content, width, align) + " "
+				if isHeader {
+					rowStr.WriteString(r.styles.TableHeader.Render(padded))
+				} else {
+					rowStr.WriteString(cellStyle.Render(padded))
+				}
+				rowStr.WriteString(r.styles.TableBorder.Render("│"))
+			}
+			b.WriteString(rowStr.String())
+			b.WriteString("\n")
 		}
-		b.WriteString(rowStr.String())
-		b.WriteString("\n")
+		if !isHeader {
+			rows = append(rows, RowSpan{Offset: lines, Height: height})
+		}
+		lines += height
 	}
 
 	// Bottom border
 	b.WriteString(renderBorder("╰", "┴", "╯", "─"))
 
+	first, _, _ := strings.Cut(b.String(), "\n")
+	r.tables = append(r.tables, TableRef{
+		First:  strings.TrimSpace(stripANSI(first)),
+		Height: lines + 1,
+		Rows:   rows,
+	})
+	r.recordWide(b.String())
 	return b.String()
 }
 
+// sum returns the sum of values
+func sum(values []int) int {
+	total := 0
+	for _, v := range values {
+		total += v
+	}
+	return total
+}
+
+// isTableSeparator reports whether row is a horizontal rule (|---+---|)
+func isTableSeparator(row goorg.Row) bool {
+	return row.IsSpecial && len(row.Columns) == 0
+}
+
+// alignCell pads content to width cells, aligned "left", "right" or
+// "center" as set by the column's alignment cookie or, for mostly
+// numeric columns, by the parser
+func alignCell(content string, width int, align string) string {
+	gap := max(width-ansi.StringWidth(content), 0)
+	switch align {
+	case "right":
+		return strings.Repeat(" ", gap) + content
+	case "center":
+		return strings.Repeat(" ", gap/2) + content + strings.Repeat(" ", gap-gap/2)
+	default:
+		return content + strings.Repeat(" ", gap)
+	}
+}
+
+// minColumnWidth is the narrowest a table column is shrunk to
+const minColumnWidth = 6
+
+// fitColumns shrinks the widest columns until the widths add up to at
+// most available, never below minColumnWidth (or a column's natural width
+// if that is smaller)
+func fitColumns(widths []int, available int) []int {
+	fitted := append([]int(nil), widths...)
+	total := sum(fitted)
+	for total > available {
+		widest := 0
+		for i, w := range fitted {
+			if w > fitted[widest] {
+				widest = i
+			}
+		}
+		if fitted[widest] <= minColumnWidth {
+			break
+		}
+		fitted[widest]--
+		total--
+	}
+	return fitted
+}
+
 func (r *Renderer) renderHorizontalRule() string {
-	return r.styles.HRule.Render(strings.Repeat("─", r.width-4))
+	return r.styles.HRule.Render(strings.Repeat("─", max(0, r.width-4)))
 }
 
 func (r *Renderer) renderKeyword(kw goorg.Keyword) string {
@@ -485,72 +1718,337 @@ func (r *Renderer) renderKeyword(kw goorg.Keyword) string {
 	switch strings.ToUpper(kw.Key) {
 	case "TITLE", "AUTHOR", "DATE", "OPTIONS":
 		return "" // These are metadata, don't render
+	case "TOC":
+		if toc, ok := r.renderTOC(kw.Value); ok {
+			return toc
+		}
+		return r.styles.Keyword.Render("#+"+kw.Key+": ") + r.styles.KeywordValue.Render(kw.Value)
 	default:
 		return r.styles.Keyword.Render("#+"+kw.Key+": ") + r.styles.KeywordValue.Render(kw.Value)
 	}
 }
 
 func (r *Renderer) renderPropertyDrawer(pd goorg.PropertyDrawer) string {
-	var b strings.Builder
-	b.WriteString(r.styles.DrawerHeader.Render(":PROPERTIES:"))
-	b.WriteString("\n")
-	for _, prop := range pd.Properties {
-		if len(prop) >= 2 {
-			b.WriteString(r.styles.Property.Render(fmt.Sprintf(":%s: %s", prop[0], prop[1])))
-			b.WriteString("\n")
+	return r.renderCollapsible("PROPERTIES", func() string {
+		var b strings.Builder
+		for _, prop := range pd.Properties {
+			if len(prop) >= 2 {
+				b.WriteString(r.styles.Property.Render(fmt.Sprintf(":%s: %s", prop[0], prop[1])))
+				b.WriteString("\n")
+			}
 		}
-	}
-	b.WriteString(r.styles.DrawerHeader.Render(":END:"))
-	return b.String()
+		return b.String()
+	})
 }
 
 func (r *Renderer) renderDrawer(d goorg.Drawer) string {
+	return r.renderCollapsible(d.Name, func() string {
+		if strings.EqualFold(d.Name, "LOGBOOK") {
+			if entries := org.ParseLogbook(goorg.String(d.Children...)); len(entries) > 0 {
+				return r.renderLogbook(entries)
+			}
+		}
+		return strings.TrimRight(r.RenderNodes(d.Children), "\n") + "\n"
+	})
+}
+
+// renderStatus renders a TODO keyword
+func (r *Renderer) renderStatus(status string) string {
+	if status == "DONE" {
+		return r.styles.Done.Render(status)
+	}
+	return r.styles.Todo.Render(status)
+}
+
+// renderLogbook renders LOGBOOK entries as a timeline, one line per entry
+// with notes below, and the total clocked time when there's more than one
+// clock line
+func (r *Renderer) renderLogbook(entries []org.LogEntry) string {
 	var b strings.Builder
-	b.WriteString(r.styles.DrawerHeader.Render(":" + d.Name + ":"))
-	b.WriteString("\n")
-	b.WriteString(r.renderInlineNodes(d.Children))
-	b.WriteString("\n")
-	b.WriteString(r.styles.DrawerHeader.Render(":END:"))
+	var clocked time.Duration
+	clocks := 0
+	marker := r.styles.LogMarker.Render("•") + " "
+	for _, entry := range entries {
+		when := r.styles.LogTime.Render(formatTimestampTime(entry.Time.Start, entry.Time.HasTime)) + "  "
+		note := entry.Text
+		switch entry.Kind {
+		case org.LogState:
+			b.WriteString(marker + when)
+			if entry.From != "" {
+				b.WriteString(r.renderStatus(entry.From) + " → ")
+			}
+			b.WriteString(r.renderStatus(entry.To))
+		case org.LogNote:
+			b.WriteString(marker + when + "✎")
+			// Short notes fit on the entry's line
+			if width := lipgloss.Width(marker + when + "✎ " + note); note != "" && width <= r.width-4 {
+				b.WriteString(" " + r.renderInlineLine(note))
+				note = ""
+			}
+		case org.LogClock:
+			b.WriteString(marker + when + "⏱ ")
+			if entry.Time.IsRange() {
+				clocked += entry.Duration
+				clocks++
+				b.WriteString(formatClocked(entry.Duration) + r.styles.LogTime.Render(" until "+entry.Time.End.Format("15:04")))
+			} else {
+				b.WriteString(r.styles.LogTime.Render("clocked in"))
+			}
+		default:
+			b.WriteString(marker + r.renderInlineLine(note))
+			note = ""
+		}
+		b.WriteString("\n")
+
+		if note != "" {
+			wrapped := ansi.Wrap(r.renderInlineLine(note), max(minColumnWidth, r.width-8), "")
+			b.WriteString(indentLines(wrapped, 4) + "\n")
+		}
+	}
+	if clocks > 1 {
+		b.WriteString(r.styles.LogTime.Render("  Total ") + formatClocked(clocked) + "\n")
+	}
 	return b.String()
 }
 
+// formatClocked formats clocked time as org does, hours:minutes
+func formatClocked(d time.Duration) string {
+	return fmt.Sprintf("%d:%02d", int(d.Hours()), int(d.Minutes())%60)
+}
+
+// renderCollapsible renders a drawer named name: its contents, rendered by
+// body, between :NAME: and :END: when it's expanded, or a summary line
+func (r *Renderer) renderCollapsible(name string, body func() string) string {
+	idx := len(r.drawers)
+	open := r.expandDrawers != r.toggledDrawers[idx]
+	header := r.styles.DrawerHeader.Render(":" + name + ":")
+
+	var rendered string
+	if open {
+		rendered = header + "\n" + body() + r.styles.DrawerHeader.Render(":END:")
+	} else {
+		rendered = header + " " + r.styles.DrawerSummary.Render("…")
+	}
+
+	first, _, _ := strings.Cut(rendered, "\n")
+	r.drawers = append(r.drawers, DrawerRef{
+		Name:   name,
+		Open:   open,
+		Height: lipgloss.Height(rendered),
+		first:  strings.TrimSpace(stripANSI(first)),
+	})
+	return rendered
+}
+
+// renderExample renders fixed-width ": " lines, one line per child
 func (r *Renderer) renderExample(ex goorg.Example) string {
-	content := r.extractBlockText(ex.Children)
-	return r.styles.Example.Width(r.width - 6).Render(content)
+	content := exampleText(ex.Children, "\n")
+	rendered := r.styles.Sized(&r.styles.Example, r.blockWidth(r.styles.Example, content)).Render(content)
+	r.recordWide(rendered)
+	return rendered
 }
 
+// renderFootnoteDefinition records the definition for the endnotes
+// section instead of rendering it in place
 func (r *Renderer) renderFootnoteDefinition(fn goorg.FootnoteDefinition) string {
-	return r.renderFootnoteDefinitionWithDepth(fn, r.footnoteDepth)
+	i := r.footnoteNumber(fn.Name) - 1
+	if r.footnotes[i].definition == nil {
+		r.footnotes[i].definition = fn.Children
+	}
+	return ""
 }
 
-// renderFootnoteDefinitionWithDepth renders a footnote with specific nesting depth
-func (r *Renderer) renderFootnoteDefinitionWithDepth(fn goorg.FootnoteDefinition, depth int) string {
-	// Save current depth and set new depth for nested content
-	oldDepth := r.footnoteDepth
-	r.footnoteDepth = depth + 1
-	content := r.renderInlineNodes(fn.Children)
-	r.footnoteDepth = oldDepth
+// footnoteNumber returns the number of the named footnote, numbering it
+// if it is new
+func (r *Renderer) footnoteNumber(name string) int {
+	if i, ok := r.footnoteIndex[name]; ok {
+		return i + 1
+	}
+	r.footnoteIndex[name] = len(r.footnotes)
+	r.footnotes = append(r.footnotes, footnote{name: name})
+	return len(r.footnotes)
+}
 
-	// Get appropriate symbol for this depth
-	symbol := getFootnoteSymbol(fn.Name, depth)
+// trimInlineDefinition drops the space after the colon of an inline
+// footnote definition, which the parser keeps in its paragraph
+func trimInlineDefinition(nodes []goorg.Node) []goorg.Node {
+	if len(nodes) != 1 {
+		return nodes
+	}
+	p, ok := nodes[0].(goorg.Paragraph)
+	if !ok || len(p.Children) == 0 {
+		return nodes
+	}
+	if text, ok := p.Children[0].(goorg.Text); ok {
+		children := append([]goorg.Node{goorg.Text{Content: strings.TrimLeft(text.Content, " "), IsRaw: text.IsRaw}}, p.Children[1:]...)
+		return []goorg.Node{goorg.Paragraph{Children: children}}
+	}
+	return nodes
+}
 
-	// Format label based on depth
-	var label string
-	switch depth {
-	case 0:
-		label = r.styles.FootnoteLabel.Render("[" + symbol + "]")
-	case 1:
-		label = r.styles.FootnoteNestedLabel1.Render(symbol + ".")
-	case 2:
-		label = r.styles.FootnoteNestedLabel2.Render(symbol + ")")
-	default:
-		label = r.styles.FootnoteNestedLabel3.Render("(" + symbol + ")")
+// exportedHeadlines appends the headlines of nodes that are exported, in
+// document order and including nested ones, to headlines
+func (r *Renderer) exportedHeadlines(nodes []goorg.Node, headlines []goorg.Headline) []goorg.Headline {
+	for _, node := range nodes {
+		h, ok := node.(goorg.Headline)
+		if !ok || r.isExcluded(h) || isFootnoteSection(h) {
+			continue
+		}
+		headlines = append(headlines, h)
+		headlines = r.exportedHeadlines(h.Children, headlines)
+	}
+	return headlines
+}
+
+// renderTOC renders the table of contents a #+TOC: keyword asks for, with
+// an entry linking to each headline down to the given depth, as export
+// does. Only headline tables of contents are supported.
+func (r *Renderer) renderTOC(value string) (string, bool) {
+	fields := strings.Fields(value)
+	if len(fields) == 0 || fields[0] != "headlines" {
+		return "", false
+	}
+	depth := 0
+	if len(fields) > 1 {
+		if n, err := strconv.Atoi(fields[1]); err == nil {
+			depth = n
+		}
+	}
+
+	var b strings.Builder
+	b.WriteString(r.styles.TOCTitle.Render("Contents"))
+	b.WriteString("\n")
+	for _, h := range r.outline {
+		if depth > 0 && h.Lvl > depth {
+			continue
+		}
+		// Entries are plain text: link styling doesn't nest, and footnotes
+		// and links of titles must not be recorded twice
+		var title []goorg.Node
+		for _, node := range h.Title {
+			switch n := node.(type) {
+			case goorg.FootnoteLink:
+			case goorg.RegularLink:
+				if len(n.Description) > 0 {
+					title = append(title, n.Description...)
+				} else {
+					title = append(title, goorg.Text{Content: n.URL, IsRaw: true})
+				}
+			default:
+				title = append(title, node)
+			}
+		}
+		text := stripANSI(r.renderInlineNodes(title))
+		link := goorg.RegularLink{
+			URL:         "*" + headlineTarget(h),
+			Description: []goorg.Node{goorg.Text{Content: text, IsRaw: true}},
+		}
+		b.WriteString(strings.Repeat("  ", h.Lvl-1) + r.renderLink(link))
+		b.WriteString("\n")
 	}
+	return b.String(), true
+}
+
+// headlineTarget returns the title of h as written in the source, which
+// internal links to it name
+func headlineTarget(h goorg.Headline) string {
+	return strings.TrimSpace(goorg.String(h.Title...))
+}
+
+// countHeadlines returns the number of headlines in the subtree of h
+func countHeadlines(h goorg.Headline) int {
+	n := 1
+	for _, child := range h.Children {
+		if c, ok := child.(goorg.Headline); ok {
+			n += countHeadlines(c)
+		}
+	}
+	return n
+}
+
+// isFootnoteSection reports whether h is a "Footnotes" heading holding
+// only footnote definitions, as org mode keeps them
+func isFootnoteSection(h goorg.Headline) bool {
+	if len(h.Title) != 1 || len(h.Children) == 0 {
+		return false
+	}
+	if title, ok := h.Title[0].(goorg.Text); !ok || strings.TrimSpace(title.Content) != "Footnotes" {
+		return false
+	}
+	for _, child := range h.Children {
+		switch c := child.(type) {
+		case goorg.FootnoteDefinition:
+		case goorg.Paragraph:
+			if strings.TrimSpace(goorg.String(c.Children...)) != "" {
+				return false
+			}
+		default:
+			return false
+		}
+	}
+	return true
+}
+
+// renderFootnotes renders the endnotes section: the defined footnotes in
+// order of number, each followed by a marker pointing back to the heading
+// it was first referenced under. Footnote definitions may reference other
+// footnotes, which are numbered as they are rendered.
+func (r *Renderer) renderFootnotes() string {
+	var entries []string
+	for i := 0; i < len(r.footnotes); i++ {
+		fn := r.footnotes[i]
+		if fn.definition == nil {
+			continue
+		}
+		label := r.styles.FootnoteLabel.Render(strconv.Itoa(i + 1))
+		pad := lipgloss.Width(label) + 1
+		var names []string
+		if !strings.HasPrefix(fn.name, ":") {
+			names = append(names, "fn:"+fn.name)
+		}
+		anchored := r.anchor(names...)
+
+		r.footnoteDepth++
+		r.footnoteIn = "footnote " + strconv.Itoa(i+1)
+		content := trimBlankLines(r.renderNested(fn.definition, r.width-pad))
+		r.footnoteIn = ""
+		r.footnoteDepth--
+
+		backRef := "↩"
+		if fn.heading != "" {
+			backRef += " " + fn.heading
+		}
+		backRef = r.styles.Footnote.Render(backRef)
+		lines := strings.Split(content, "\n")
+		if pad+lipgloss.Width(lines[len(lines)-1])+1+lipgloss.Width(backRef) > r.width {
+			content += "\n" + backRef
+		} else {
+			content += " " + backRef
+		}
 
-	// Add indentation based on depth
-	indent := strings.Repeat("   ", depth)
+		entry := label + " " + strings.TrimPrefix(indentLines(content, pad), strings.Repeat(" ", pad))
+		anchored(entry)
+		entries = append(entries, entry)
+	}
+	if len(entries) == 0 {
+		return ""
+	}
+	return r.renderHorizontalRule() + "\n" +
+		r.styles.Footnote.Bold(true).Render("Footnotes") + "\n\n" +
+		strings.Join(entries, "\n") + "\n"
+}
 
-	return indent + label + " " + r.styles.FootnoteContent.Render(content)
+// trimBlankLines removes the padding at the end of each line of s, and
+// the blank lines at its end
+func trimBlankLines(s string) string {
+	lines := strings.Split(s, "\n")
+	for i, line := range lines {
+		lines[i] = ansi.Truncate(line, lipgloss.Width(strings.TrimRight(stripANSI(line), " ")), "")
+	}
+	for len(lines) > 1 && strings.TrimSpace(stripANSI(lines[len(lines)-1])) == "" {
+		lines = lines[:len(lines)-1]
+	}
+	return strings.Join(lines, "\n")
 }
 
 // SetFootnoteDepth allows external callers to set the footnote nesting depth
@@ -565,17 +2063,112 @@ func (r *Renderer) GetFootnoteDepth() int {
 
 // renderInlineNodes renders inline content (text, emphasis, links, etc.)
 func (r *Renderer) renderInlineNodes(nodes []goorg.Node) string {
+	r.quotePrev = ' '
+	return r.renderInline(nodes)
+}
+
+// renderInline renders inline content following what was rendered before
+// it, as the content of emphasis and links follows the text around them:
+// quotes are curled the same across nodes as in a single text
+func (r *Renderer) renderInline(nodes []goorg.Node) string {
 	var b strings.Builder
-	for _, node := range nodes {
-		b.WriteString(r.renderInlineNode(node))
+	for i := 0; i < len(nodes); i++ {
+		// Runs of text and timestamps are rendered together, as the parser
+		// splits ranges and doesn't know the rest of the timestamp syntax
+		var run strings.Builder
+		for ; i < len(nodes); i++ {
+			switch n := nodes[i].(type) {
+			case goorg.Text:
+				if n.IsRaw {
+					break
+				}
+				run.WriteString(n.Content)
+				continue
+			case goorg.Timestamp:
+				run.WriteString(goorg.String(n))
+				continue
+			}
+			break
+		}
+		if run.Len() > 0 {
+			// <<targets>> are only anchors, invisible as in export
+			b.WriteString(r.renderTextWithTimestamps(targetRegexp.ReplaceAllString(run.String(), "")))
+		}
+		if i < len(nodes) {
+			b.WriteString(r.followedBy(r.renderInlineNode(nodes[i])))
+		}
+	}
+	return b.String()
+}
+
+// renderTextWithTimestamps renders text, rendering the timestamps in it
+func (r *Renderer) renderTextWithTimestamps(text string) string {
+	var b strings.Builder
+	last := 0
+	for _, loc := range org.TimestampRegexp.FindAllStringIndex(text, -1) {
+		ts, ok := org.ParseTimestamp(text[loc[0]:loc[1]])
+		if !ok {
+			continue
+		}
+		b.WriteString(r.smartPunctuation(text[last:loc[0]]))
+		b.WriteString(r.followedBy(r.renderOrgTimestamp(ts)))
+		last = loc[1]
+	}
+	b.WriteString(r.smartPunctuation(text[last:]))
+	return b.String()
+}
+
+// specialStrings are the special strings org export replaces in text
+var specialStrings = strings.NewReplacer("---", "—", "--", "–", "...", "…")
+
+// followedBy notes rendered, styled inline content, as what the text after
+// it follows, and returns it
+func (r *Renderer) followedBy(rendered string) string {
+	if last, _ := utf8.DecodeLastRuneInString(stripANSI(rendered)); last != utf8.RuneError {
+		r.quotePrev = last
+	}
+	return rendered
+}
+
+// smartPunctuation replaces special strings with dashes and ellipses, and
+// straight quotes with curly ones: opening after a space or an opening
+// bracket, closing (or an apostrophe) elsewhere, including right after
+// the inline content rendered before text
+func (r *Renderer) smartPunctuation(text string) string {
+	if r.plainText {
+		return text
+	}
+	text = specialStrings.Replace(text)
+	if !strings.ContainsAny(text, `"'`) {
+		return r.followedBy(text)
+	}
+
+	var b strings.Builder
+	prev := r.quotePrev
+	for _, c := range text {
+		opening := unicode.IsSpace(prev) || strings.ContainsRune("([{<–—", prev)
+		switch {
+		case c == '"' && opening:
+			b.WriteRune('“')
+		case c == '"':
+			b.WriteRune('”')
+		case c == '\'' && opening:
+			b.WriteRune('‘')
+		case c == '\'':
+			b.WriteRune('’')
+		default:
+			b.WriteRune(c)
+		}
+		prev = c
 	}
+	r.quotePrev = prev
 	return b.String()
 }
 
 func (r *Renderer) renderInlineNode(node goorg.Node) string {
 	switch n := node.(type) {
 	case goorg.Text:
-		return r.renderText(n.Content)
+		return n.Content
 	case goorg.Emphasis:
 		return r.renderEmphasis(n)
 	case goorg.RegularLink:
@@ -596,97 +2189,8 @@ func (r *Renderer) renderInlineNode(node goorg.Node) string {
 	}
 }
 
-// renderText handles plain text with planning keyword detection and inactive timestamps
-func (r *Renderer) renderText(content string) string {
-	// Check for planning keywords at start of text
-	planningKeywords := []struct {
-		keyword string
-		style   lipgloss.Style
-	}{
-		{"SCHEDULED:", r.styles.Scheduled},
-		{"DEADLINE:", r.styles.Deadline},
-		{"CLOSED:", r.styles.Closed},
-	}
-
-	for _, pk := range planningKeywords {
-		if strings.HasPrefix(content, pk.keyword) {
-			rest := content[len(pk.keyword):]
-			// Check for inactive timestamp in the rest (for CLOSED)
-			rest = r.renderInactiveTimestamps(rest)
-			return pk.style.Render(pk.keyword) + rest
-		}
-		// Also check for keyword with leading space (e.g., " DEADLINE:")
-		if strings.HasPrefix(content, " "+pk.keyword) {
-			rest := content[len(pk.keyword)+1:]
-			rest = r.renderInactiveTimestamps(rest)
-			return " " + pk.style.Render(pk.keyword) + rest
-		}
-	}
-
-	// Check for inactive timestamps anywhere in text
-	return r.renderInactiveTimestamps(content)
-}
-
-// renderInactiveTimestamps finds and styles inactive timestamps [YYYY-MM-DD ...]
-func (r *Renderer) renderInactiveTimestamps(content string) string {
-	var result strings.Builder
-	remaining := content
-
-	for {
-		// Find opening bracket for inactive timestamp
-		start := strings.Index(remaining, "[")
-		if start == -1 {
-			result.WriteString(remaining)
-			break
-		}
-
-		// Find closing bracket
-		end := strings.Index(remaining[start:], "]")
-		if end == -1 {
-			result.WriteString(remaining)
-			break
-		}
-		end += start // Adjust to absolute position
-
-		// Check if this looks like an inactive timestamp [YYYY-MM-DD ...]
-		timestampContent := remaining[start+1 : end]
-		if len(timestampContent) >= 10 && isInactiveTimestamp(timestampContent) {
-			result.WriteString(remaining[:start])
-			result.WriteString(r.styles.Timestamp.Render("[" + timestampContent + "]"))
-			remaining = remaining[end+1:]
-		} else {
-			// Not a timestamp, keep going
-			result.WriteString(remaining[:end+1])
-			remaining = remaining[end+1:]
-		}
-	}
-
-	return result.String()
-}
-
-// isInactiveTimestamp checks if content looks like a timestamp (YYYY-MM-DD ...)
-func isInactiveTimestamp(content string) bool {
-	// Basic check: starts with date pattern YYYY-MM-DD
-	if len(content) < 10 {
-		return false
-	}
-	// Check for digit patterns at expected positions
-	for i, c := range content[:10] {
-		if i == 4 || i == 7 {
-			if c != '-' {
-				return false
-			}
-		} else {
-			if c < '0' || c > '9' {
-				return false
-			}
-		}
-	}
-	return true
-}
-
 func (r *Renderer) renderEmphasis(e goorg.Emphasis) string {
-	content := r.renderInlineNodes(e.Content)
+	content := r.renderInline(e.Content)
 
 	// go-org uses the actual marker character as the Kind
 	switch e.Kind {
@@ -710,7 +2214,7 @@ func (r *Renderer) renderEmphasis(e goorg.Emphasis) string {
 func (r *Renderer) renderLink(link goorg.RegularLink) string {
 	var text string
 	if len(link.Description) > 0 {
-		text = r.renderInlineNodes(link.Description)
+		text = r.renderInline(link.Description)
 	} else {
 		text = link.URL
 	}
@@ -718,49 +2222,168 @@ func (r *Renderer) renderLink(link goorg.RegularLink) string {
 	// Truncate long URLs for display
 	displayText := text
 	maxLen := 40
-	if len(displayText) > maxLen {
-		displayText = displayText[:maxLen-3] + "..."
+	if ansi.StringWidth(displayText) > maxLen {
+		displayText = ansi.Truncate(displayText, maxLen, "...")
+	}
+
+	// Record the link so the model can offer link selection
+	idx := len(r.links)
+	label := linkIcon(link.URL) + " " + displayText
+	r.links = append(r.links, LinkRef{URL: link.URL, Text: text, Width: lipgloss.Width(label)})
+
+	style := r.styles.Link
+	if idx == r.activeLink {
+		style = r.styles.LinkActive
+	}
+
+	rendered := style.Render(label)
+	if r.hyperlinks && isHyperlinkable(link.URL) {
+		rendered = hyperlink(link.URL, rendered)
+	}
+	return rendered
+}
+
+// imageLink returns the link of a paragraph made of a single image link,
+// which org displays inline
+func imageLink(p goorg.Paragraph) (goorg.RegularLink, bool) {
+	var link goorg.RegularLink
+	found := false
+	for _, child := range p.Children {
+		switch n := child.(type) {
+		case goorg.RegularLink:
+			if found || n.Kind() != "image" {
+				return link, false
+			}
+			link, found = n, true
+		case goorg.Text:
+			if strings.TrimSpace(n.Content) != "" {
+				return link, false
+			}
+		case goorg.LineBreak:
+		default:
+			return link, false
+		}
+	}
+	return link, found
+}
+
+// renderImage draws the image a link points to, or a framed placeholder
+// with the link when the terminal can't draw it or the file can't be read
+func (r *Renderer) renderImage(link goorg.RegularLink) string {
+	if r.images != nil {
+		if path, ok := imagePath(link.URL, r.imageRoot, r.imageDir); ok {
+			if drawn, err := drawImage(r.images, path, r.width-4); err == nil {
+				return drawn
+			}
+		}
 	}
+	return r.styles.ImageFrame.Render("🖼  " + r.renderLink(link))
+}
+
+// isHyperlinkable reports whether a link should be emitted as an OSC 8 hyperlink
+func isHyperlinkable(url string) bool {
+	return strings.HasPrefix(url, "http://") ||
+		strings.HasPrefix(url, "https://") ||
+		strings.HasPrefix(url, "mailto:")
+}
+
+// hyperlink wraps text in an OSC 8 escape sequence pointing at url
+func hyperlink(url, text string) string {
+	return "\x1b]8;;" + url + "\x1b\\" + text + "\x1b]8;;\x1b\\"
+}
 
-	// Determine link type and icon
-	var icon string
+// linkIcon returns the icon shown in front of a link, based on its type
+func linkIcon(url string) string {
 	switch {
-	case strings.HasPrefix(link.URL, "http://") || strings.HasPrefix(link.URL, "https://"):
-		icon = "🔗"
-	case strings.HasPrefix(link.URL, "file:"):
-		icon = "📄"
-	case strings.HasPrefix(link.URL, "mailto:"):
-		icon = "📧"
-	case strings.HasSuffix(link.URL, ".org"):
-		icon = "📝"
+	case strings.HasPrefix(url, "http://") || strings.HasPrefix(url, "https://"):
+		return "🔗"
+	case strings.HasPrefix(url, "file:"):
+		return "📄"
+	case strings.HasPrefix(url, "mailto:"):
+		return "📧"
+	case strings.HasSuffix(url, ".org"):
+		return "📝"
 	default:
-		icon = "→"
+		return "→"
 	}
-
-	return r.styles.Link.Render(icon + " " + displayText)
 }
 
 func (r *Renderer) renderTimestamp(ts goorg.Timestamp) string {
-	// Format the timestamp nicely
-	var formatted string
-	if ts.IsDate {
-		formatted = ts.Time.Format("2006-01-02 Mon")
-	} else {
-		formatted = ts.Time.Format("2006-01-02 Mon 15:04")
+	return r.renderTextWithTimestamps(goorg.String(ts))
+}
+
+// renderPlanning renders the SCHEDULED, DEADLINE and CLOSED timestamps of a
+// headline on one row, or one per line if they don't fit
+func (r *Renderer) renderPlanning(p org.Planning) string {
+	var parts []string
+	add := func(label string, style lipgloss.Style, ts *org.Timestamp) {
+		if ts != nil {
+			parts = append(parts, style.Render(label)+" "+r.renderOrgTimestamp(*ts))
+		}
 	}
+	add("Scheduled", r.styles.Scheduled, p.Scheduled)
+	add("Deadline", r.styles.Deadline, p.Deadline)
+	add("Closed", r.styles.Closed, p.Closed)
+	if row := strings.Join(parts, "  "); lipgloss.Width(row) <= r.width-4 {
+		return row
+	}
+	return strings.Join(parts, "\n")
+}
 
-	// Add repeater/interval if present
-	if ts.Interval != "" {
-		formatted += " " + ts.Interval
+// renderOrgTimestamp renders a timestamp compactly: its date and time,
+// the end of a range, and its repeater and warning period. Inactive
+// timestamps keep their brackets and are dimmed.
+func (r *Renderer) renderOrgTimestamp(ts org.Timestamp) string {
+	formatted := formatTimestampTime(ts.Start, ts.HasTime)
+	if ts.IsRange() {
+		if ts.End.Format(time.DateOnly) == ts.Start.Format(time.DateOnly) && ts.EndTime {
+			formatted += "–" + ts.End.Format("15:04")
+		} else {
+			formatted += " → " + formatTimestampTime(ts.End, ts.EndTime)
+		}
+	}
+	if ts.Repeater != "" {
+		formatted += " ↻ " + ts.Repeater
+	}
+	if ts.Warning != "" {
+		formatted += " ⏰ " + ts.Warning
 	}
 
+	if !ts.Active {
+		return r.styles.TimestampInactive.Render("[" + formatted + "]")
+	}
 	// Use calendar emoji and styled timestamp
 	return r.styles.Timestamp.Render("📅 " + formatted)
 }
 
+// formatTimestampTime formats the date of a timestamp, with its time of
+// day if it has one
+func formatTimestampTime(t time.Time, withTime bool) string {
+	if withTime {
+		return t.Format("2006-01-02 Mon 15:04")
+	}
+	return t.Format("2006-01-02 Mon")
+}
 func (r *Renderer) renderFootnoteLink(fn goorg.FootnoteLink) string {
-	// Get appropriate symbol for current depth
-	symbol := getFootnoteSymbol(fn.Name, r.footnoteDepth)
+	name := fn.Name
+	if name == "" {
+		// Anonymous inline footnotes get a name no reference can have
+		name = ":" + strconv.Itoa(len(r.footnotes))
+	}
+	n := r.footnoteNumber(name)
+	if fn.Definition != nil && r.footnotes[n-1].definition == nil {
+		// Inline definition, [fn:: text] or [fn:name: text]
+		r.footnotes[n-1].definition = trimInlineDefinition(fn.Definition.Children)
+	}
+	if entry := &r.footnotes[n-1]; entry.heading == "" {
+		switch {
+		case r.footnoteIn != "":
+			entry.heading = r.footnoteIn
+		case len(r.headings) > 0:
+			entry.heading = r.headings[len(r.headings)-1].Title
+		}
+	}
+	symbol := strconv.Itoa(n)
 
 	// Format reference based on depth
 	switch r.footnoteDepth {
@@ -775,6 +2398,52 @@ func (r *Renderer) renderFootnoteLink(fn goorg.FootnoteLink) string {
 	}
 }
 
+// exampleText returns the verbatim text of an example block or fixed-width
+// lines, keeping leading whitespace and blank lines, with sep between the
+// text nodes (fixed-width lines don't have line breaks between them). Tabs
+// are expanded to 8-column tab stops, as Emacs shows them.
+func exampleText(nodes []goorg.Node, sep string) string {
+	var b strings.Builder
+	for i, node := range nodes {
+		switch n := node.(type) {
+		case goorg.Text:
+			if i > 0 {
+				b.WriteString(sep)
+			}
+			b.WriteString(n.Content)
+		case goorg.LineBreak:
+			b.WriteString(strings.Repeat("\n", n.Count))
+		default:
+			b.WriteString(goorg.String(n))
+		}
+	}
+	lines := strings.Split(strings.TrimSuffix(b.String(), "\n"), "\n")
+	for i, line := range lines {
+		lines[i] = expandTabs(line)
+	}
+	return strings.Join(lines, "\n")
+}
+
+// expandTabs replaces the tabs of line with spaces up to the next tab stop
+func expandTabs(line string) string {
+	if !strings.Contains(line, "\t") {
+		return line
+	}
+	var b strings.Builder
+	col := 0
+	for _, c := range line {
+		if c == '\t' {
+			n := 8 - col%8
+			b.WriteString(strings.Repeat(" ", n))
+			col += n
+			continue
+		}
+		b.WriteRune(c)
+		col += ansi.StringWidth(string(c))
+	}
+	return b.String()
+}
+
 // extractBlockText extracts plain text from block children
 func (r *Renderer) extractBlockText(nodes []goorg.Node) string {
 	var b strings.Builder
@@ -1,11 +1,32 @@
 package ui
 
 import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"hash/crc32"
+	"image"
+	"image/color"
+	"image/draw"
+	"image/png"
+	"math"
 	"os"
+	"os/exec"
+	"path/filepath"
+	"slices"
 	"strings"
 	"testing"
+	"time"
 
+	"org-charm/analytics"
+	"org-charm/org"
+	"org-charm/state"
+
+	tea "github.com/charmbracelet/bubbletea"
 	"github.com/charmbracelet/lipgloss"
+	"github.com/charmbracelet/x/ansi"
 	"github.com/muesli/termenv"
 	goorg "github.com/niklasfasching/go-org/org"
 )
@@ -195,3 +216,1839 @@ func TestRenderInlineNodeSwitch(t *testing.T) {
 		t.Errorf("renderInlineNode didn't produce bold ANSI code")
 	}
 }
+
+func TestLinkPositions(t *testing.T) {
+	r := createTestRenderer()
+	styles := NewStyles(r)
+	renderer := NewRenderer(styles, 80)
+
+	input := `First paragraph with [[https://charm.sh][Charm]].
+
+Second paragraph.
+
+See [[file:notes.org][the notes]] and [[mailto:me@example.com][mail me]].
+`
+	config := goorg.New()
+	doc := config.Parse(strings.NewReader(input), "test.org")
+
+	output := renderer.RenderNodes(doc.Nodes)
+	links := renderer.Links()
+	LocateLinks(output, links)
+
+	wantURLs := []string{"https://charm.sh", "file:notes.org", "mailto:me@example.com"}
+	if len(links) != len(wantURLs) {
+		t.Fatalf("expected %d links, got %d", len(wantURLs), len(links))
+	}
+
+	lines := strings.Split(output, "\n")
+	for i, link := range links {
+		if link.URL != wantURLs[i] {
+			t.Errorf("link %d: expected URL %q, got %q", i, wantURLs[i], link.URL)
+		}
+		if !strings.Contains(stripANSI(lines[link.Line]), link.Text) {
+			t.Errorf("link %d: line %d %q does not contain %q", i, link.Line, stripANSI(lines[link.Line]), link.Text)
+		}
+	}
+}
+
+func TestHyperlinks(t *testing.T) {
+	r := createTestRenderer()
+	styles := NewStyles(r)
+
+	input := `Visit [[https://charm.sh][Charm]] or read [[file:notes.org][the notes]].`
+	config := goorg.New()
+	doc := config.Parse(strings.NewReader(input), "test.org")
+
+	plain := NewRenderer(styles, 80).RenderNodes(doc.Nodes)
+	if strings.Contains(plain, "\x1b]8;;") {
+		t.Errorf("Hyperlinks emitted without being enabled: %q", plain)
+	}
+
+	renderer := NewRenderer(styles, 80)
+	renderer.SetHyperlinks(true)
+	output := renderer.RenderNodes(doc.Nodes)
+
+	if !strings.Contains(output, "\x1b]8;;https://charm.sh\x1b\\") {
+		t.Errorf("Expected OSC 8 hyperlink for web link, got %q", output)
+	}
+	if strings.Contains(output, "\x1b]8;;file:notes.org") {
+		t.Errorf("File links should not be emitted as hyperlinks: %q", output)
+	}
+	if got := stripANSI(output); strings.Contains(got, "charm.sh") {
+		t.Errorf("stripANSI leaked hyperlink target: %q", got)
+	}
+}
+
+func TestHighlightOrgSourceIsByteFaithful(t *testing.T) {
+	styles := NewStyles(createTestRenderer())
+
+	files, err := filepath.Glob("../orgfiles/*.org")
+	if err != nil || len(files) == 0 {
+		t.Fatalf("no sample org files found: %v", err)
+	}
+
+	for _, path := range files {
+		content, err := os.ReadFile(path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		highlighted := HighlightOrgSource(styles, "TODO | DONE", string(content))
+		if got := stripANSI(highlighted); got != string(content) {
+			t.Errorf("%s: highlighted source differs from input", path)
+		}
+		if highlighted == string(content) {
+			t.Errorf("%s: no highlighting applied", path)
+		}
+	}
+}
+
+func TestHighlightEmphasisBorders(t *testing.T) {
+	h := newRawHighlighter(NewStyles(createTestRenderer()), "")
+	// Borders are whole characters: the last byte of à is that of a
+	// no-break space, and an em space is a space
+	for text, emphasized := range map[string]bool{
+		"à*no* x":       false,
+		"x *yes*é":      false,
+		"x\u2003*yes*":  true,
+		"x *yes*\u2003": true,
+	} {
+		if got := h.inline(text) != text; got != emphasized {
+			t.Errorf("inline(%q) emphasized = %v, want %v", text, got, emphasized)
+		}
+	}
+}
+
+func TestHeadingPath(t *testing.T) {
+	r := createTestRenderer()
+	renderer := NewRenderer(NewStyles(r), 80)
+
+	input := `* Intro
+Text
+* Guide
+** Setup
+*** Keys
+More text
+** Usage
+`
+	config := goorg.New()
+	doc := config.Parse(strings.NewReader(input), "test.org")
+
+	output := renderer.RenderNodes(doc.Nodes)
+	headings := renderer.Headings()
+	LocateHeadings(output, headings)
+
+	keys := headings[3]
+	if keys.Title != "Keys" {
+		t.Fatalf("expected fourth heading to be Keys, got %q", keys.Title)
+	}
+
+	path := HeadingPath(headings, HeadingAt(headings, keys.Line+1))
+	var titles []string
+	for _, h := range path {
+		titles = append(titles, h.Title)
+	}
+	if got := strings.Join(titles, " › "); got != "Guide › Setup › Keys" {
+		t.Errorf("unexpected heading path %q", got)
+	}
+
+	if idx := HeadingAt(headings, -1); idx != -1 {
+		t.Errorf("expected no heading above the document, got %d", idx)
+	}
+}
+
+func TestParagraphJumps(t *testing.T) {
+	content := "Title\n\nFirst para\ncontinues\n\n\x1b[1mSecond\x1b[0m\n   \nThird"
+	stops := paragraphStarts(content)
+	want := []int{0, 2, 5, 7}
+	if fmt.Sprint(stops) != fmt.Sprint(want) {
+		t.Fatalf("paragraphStarts = %v, want %v", stops, want)
+	}
+
+	if got := nextStop(stops, 3); got != 5 {
+		t.Errorf("nextStop(3) = %d, want 5", got)
+	}
+	if got := nextStop(stops, 7); got != 7 {
+		t.Errorf("nextStop at the last paragraph = %d, want 7", got)
+	}
+	if got := prevStop(stops, 5); got != 2 {
+		t.Errorf("prevStop(5) = %d, want 2", got)
+	}
+	if got := prevStop(stops, 0); got != 0 {
+		t.Errorf("prevStop(0) = %d, want 0", got)
+	}
+}
+
+func TestFuzzyMatch(t *testing.T) {
+	if _, ok := fuzzyMatch("rws", "Show raw source"); !ok {
+		t.Error("expected letters in order to match")
+	}
+	if _, ok := fuzzyMatch("ecr", "Show raw source"); ok {
+		t.Error("expected letters out of order not to match")
+	}
+
+	// Word starts and runs of letters beat scattered letters
+	prefix, _ := fuzzyMatch("open", "Open file: notes.org")
+	scattered, _ := fuzzyMatch("open", "Copy current heading subtree")
+	if prefix <= scattered {
+		t.Errorf("expected prefix match to score higher: %d <= %d", prefix, scattered)
+	}
+}
+
+func TestTableWideCharacters(t *testing.T) {
+	renderer := NewRenderer(NewStyles(createTestRenderer()), 80)
+
+	input := `| Name | Note |
+|------+------|
+| 東京 | *bold* 🍣 |
+| Zürich | café |
+`
+	doc := goorg.New().Parse(strings.NewReader(input), "test.org")
+	output := renderer.RenderNodes(doc.Nodes)
+
+	var widths []int
+	for _, line := range strings.Split(strings.TrimSpace(output), "\n") {
+		if line = strings.TrimSpace(stripANSI(line)); line != "" {
+			widths = append(widths, lipgloss.Width(line))
+		}
+	}
+	for i, w := range widths {
+		if w != widths[0] {
+			t.Errorf("table line %d is %d cells wide, want %d:\n%s", i, w, widths[0], stripANSI(output))
+		}
+	}
+}
+
+func TestLineCells(t *testing.T) {
+	var pen sgrPen
+	cells := lineCells("a\x1b[1m東\x1b[0mb\x1b[38;5;0mc", &pen, ansi.NewParser())
+	want := []cell{{"a", ""}, {"東", "\x1b[1m"}, {"", "\x1b[1m"}, {"b", ""}, {"c", "\x1b[38;5;0m"}}
+	if !slices.Equal(cells, want) {
+		t.Errorf("lineCells = %q, want %q", cells, want)
+	}
+	// Black (38;5;0) isn't a reset, and carries over to the next line
+	if string(pen.seqs) != "\x1b[38;5;0m" {
+		t.Errorf("pen = %q, want %q", pen.seqs, "\x1b[38;5;0m")
+	}
+}
+
+func TestWaveKeepsEscapes(t *testing.T) {
+	m := NewModel(createTestRenderer(), t.TempDir(), "", Options{})
+	m.width, m.height = 40, 1
+	m.animValue = 0.5 // Reveals the middle of the line
+
+	link := "\x1b]8;;https://orgmode.org\x1b\\link\x1b]8;;\x1b\\"
+	content := link + "\x1b[38;5;196m" + strings.Repeat("r", 36) + "\x1b[0m"
+	out := m.applyWaveRipple(content)
+
+	i := strings.Index(out, "rrr")
+	if i < 0 || !strings.HasSuffix(out[:i], "\x1b[38;5;196m") {
+		t.Errorf("revealed content lost its color: %q", out)
+	}
+	if n := strings.Count(out, "\x1b]8;;"); n != 2 {
+		t.Errorf("hidden hyperlink left %d of its 2 sequences: %q", n, out)
+	}
+	if strings.Contains(ansi.Strip(out), "\x1b") {
+		t.Errorf("escape fragments leaked: %q", out)
+	}
+}
+
+func TestPoofFramesReproducible(t *testing.T) {
+	m := NewModel(createTestRenderer(), t.TempDir(), "", Options{})
+	m.width = 40
+	m.animValue = 0.5
+	from := strings.Repeat("old content\n", 10)
+	to := strings.Repeat("new content\n", 10)
+
+	m.animSeed = 1
+	first := m.applyPoofToViewport(from, to)
+	if again := m.applyPoofToViewport(from, to); again != first {
+		t.Error("frame drawn again differs")
+	}
+	m.animSeed = 2
+	if other := m.applyPoofToViewport(from, to); other == first {
+		t.Error("frames with different seeds are the same")
+	}
+}
+
+func TestRevision(t *testing.T) {
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git not installed")
+	}
+	dir := t.TempDir()
+	path := filepath.Join(dir, "notes.org")
+	if err := os.WriteFile(path, []byte("#+TITLE: First\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("GIT_CONFIG_GLOBAL", os.DevNull)
+	for _, args := range [][]string{
+		{"init", "-q"},
+		{"add", "notes.org"},
+		{"-c", "user.name=Ada", "-c", "user.email=ada@example.com", "commit", "-q", "-m", "Start notes"},
+	} {
+		if out, err := exec.Command("git", append([]string{"-C", dir}, args...)...).CombinedOutput(); err != nil {
+			t.Fatalf("git %v: %v: %s", args, err, out)
+		}
+	}
+	if err := os.WriteFile(path, []byte("#+TITLE: Second\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	doc, err := org.Load(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	m := NewModel(createTestRenderer(), dir, "", Options{})
+	m.width, m.height = 80, 24
+	m.resizeViewport()
+	m.openDocument(doc)
+
+	m.handleHistoryLoaded(m.openHistory()().(historyLoadedMsg))
+	if len(m.history.commits) != 1 || m.history.commits[0].Subject != "Start notes" {
+		t.Fatalf("history = %+v", m.history)
+	}
+	msg := m.handleHistoryKey(tea.KeyMsg{Type: tea.KeyEnter})()
+	m.handleRevisionLoaded(msg.(revisionLoadedMsg))
+	if m.revision == nil || m.currentDoc.Title() != "First" {
+		t.Fatalf("showing %q, revision %v", m.currentDoc.Title(), m.revision)
+	}
+	if header := ansi.Strip(m.renderDocumentHeader()); !strings.Contains(header, "First @ "+m.revision.ShortHash()) {
+		t.Errorf("header doesn't name the commit: %q", header)
+	}
+
+	m.closeDocument()
+	if m.revision != nil {
+		t.Error("revision kept after closing the document")
+	}
+}
+
+func TestDescribeModification(t *testing.T) {
+	now := time.Date(2024, 3, 1, 12, 0, 0, 0, time.UTC)
+	for _, tt := range []struct {
+		mod  org.Modification
+		want string
+	}{
+		{org.Modification{Time: now.Add(-2 * time.Hour)}, "modified 2h ago"},
+		{org.Modification{Time: now.Add(-2 * time.Hour), Author: "Ada", Commit: "abc1234"}, "modified 2h ago by Ada"},
+		{org.Modification{Time: now.Add(-time.Minute), Uncommitted: true}, "modified 1m ago, not committed"},
+	} {
+		if got := describeModification(tt.mod, now); got != tt.want {
+			t.Errorf("describeModification(%+v) = %q, want %q", tt.mod, got, tt.want)
+		}
+	}
+}
+
+func TestFormatBacklinks(t *testing.T) {
+	for n, want := range map[int]string{1: "1 backlink", 3: "3 backlinks", 1200: "1,200 backlinks"} {
+		if got := formatBacklinks(n); got != want {
+			t.Errorf("formatBacklinks(%d) = %q, want %q", n, got, want)
+		}
+	}
+}
+
+func TestSizedStyles(t *testing.T) {
+	styles := NewStyles(createTestRenderer())
+	for range 2 {
+		if got, want := styles.Sized(&styles.Quote, 30).Render("text"), styles.Quote.Width(30).Render("text"); got != want {
+			t.Errorf("Sized quote = %q, want %q", got, want)
+		}
+		if got, want := styles.Sized(&styles.Verse, 30).Render("text"), styles.Verse.Width(30).Render("text"); got != want {
+			t.Errorf("Sized verse = %q, want %q", got, want)
+		}
+	}
+	if n := len(styles.sized); n != 2 {
+		t.Errorf("kept %d sized styles, want 2", n)
+	}
+}
+
+func TestTableWrapsToWidth(t *testing.T) {
+	renderer := NewRenderer(NewStyles(createTestRenderer()), 40)
+
+	input := `| Feature | Description |
+|---------+-------------|
+| Wrapping | Long cells are wrapped within their column instead of overflowing the viewport |
+`
+	doc := goorg.New().Parse(strings.NewReader(input), "test.org")
+	output := stripANSI(renderer.RenderNodes(doc.Nodes))
+
+	lines := strings.Split(strings.TrimSpace(output), "\n")
+	for _, line := range lines {
+		if w := lipgloss.Width(line); w > 36 || w != lipgloss.Width(lines[0]) {
+			t.Errorf("table line is %d cells wide, want %d (at most 36):\n%s", w, lipgloss.Width(lines[0]), output)
+			break
+		}
+	}
+	if !strings.Contains(output, "overflowing") {
+		t.Errorf("wrapped table lost cell content:\n%s", output)
+	}
+}
+
+func TestTimestampRanges(t *testing.T) {
+	renderer := NewRenderer(NewStyles(createTestRenderer()), 200)
+
+	input := `Meet <2024-01-15 Mon 10:00-11:30>, trip <2024-01-15 Mon>--<2024-01-17 Wed>, review <2024-02-01 Thu +1w -2d>.
+`
+	doc := goorg.New().Parse(strings.NewReader(input), "test.org")
+	output := stripANSI(renderer.RenderNodes(doc.Nodes))
+
+	for _, want := range []string{
+		"2024-01-15 Mon 10:00–11:30",
+		"2024-01-15 Mon → 2024-01-17 Wed",
+		"2024-02-01 Thu ↻ +1w ⏰ -2d",
+	} {
+		if !strings.Contains(output, want) {
+			t.Errorf("output missing %q:\n%s", want, output)
+		}
+	}
+	if strings.Contains(output, "--") {
+		t.Errorf("range separator left in output:\n%s", output)
+	}
+}
+
+func TestActiveAndInactiveTimestamps(t *testing.T) {
+	styles := NewStyles(createTestRenderer())
+	renderer := NewRenderer(styles, 200)
+
+	input := `Due <2024-01-15 Mon>, logged [2024-01-01 Mon 9:30], see [2020-01-01: Smith et al.].
+`
+	doc := goorg.New().Parse(strings.NewReader(input), "test.org")
+	output := renderer.RenderNodes(doc.Nodes)
+
+	if want := styles.Timestamp.Render("📅 2024-01-15 Mon"); !strings.Contains(output, want) {
+		t.Errorf("active timestamp not styled as active:\n%q", output)
+	}
+	if want := styles.TimestampInactive.Render("[2024-01-01 Mon 09:30]"); !strings.Contains(output, want) {
+		t.Errorf("inactive timestamp not styled as inactive:\n%q", output)
+	}
+	if !strings.Contains(stripANSI(output), "see [2020-01-01: Smith et al.].") || strings.Contains(output, styles.TimestampInactive.Render("[2020")) {
+		t.Errorf("bracketed citation styled as a timestamp:\n%q", output)
+	}
+}
+
+func TestPlanningLine(t *testing.T) {
+	renderer := NewRenderer(NewStyles(createTestRenderer()), 100)
+
+	input := `* TODO Task
+DEADLINE: <2024-03-05 Tue> SCHEDULED: <2024-03-01 Fri>
+Body text mentioning SCHEDULED: casually.
+`
+	doc := goorg.New().Parse(strings.NewReader(input), "test.org")
+	output := stripANSI(renderer.RenderNodes(doc.Nodes))
+	lines := strings.Split(output, "\n")
+
+	if len(lines) < 4 || strings.TrimSpace(lines[2]) != "Scheduled  📅 2024-03-01 Fri   Deadline  📅 2024-03-05 Tue" {
+		t.Errorf("planning row not rendered below the heading:\n%s", output)
+	}
+	if !strings.Contains(output, "Body text mentioning SCHEDULED: casually.") {
+		t.Errorf("body text after the planning line lost:\n%s", output)
+	}
+}
+
+func TestHeadlineTagsRightAligned(t *testing.T) {
+	renderer := NewRenderer(NewStyles(createTestRenderer()), 50)
+
+	input := `** TODO Short title :work:urgent:
+** A title long enough that the tags cannot be aligned at all :x:
+`
+	doc := goorg.New().Parse(strings.NewReader(input), "test.org")
+	output := stripANSI(renderer.RenderNodes(doc.Nodes))
+	lines := strings.Split(output, "\n")
+
+	if !strings.HasSuffix(lines[0], " :work:urgent:") || lipgloss.Width(lines[0]) != 46 {
+		t.Errorf("tags not aligned to column 46: %q", lines[0])
+	}
+	if !strings.Contains(output, "cannot be aligned at all :x:") {
+		t.Errorf("tags of a long headline not kept after the title:\n%s", output)
+	}
+}
+
+func TestOrderedListNumbering(t *testing.T) {
+	renderer := NewRenderer(NewStyles(createTestRenderer()), 60)
+
+	input := `1. one
+1. two
+   1) sub a
+   1) sub b
+1. [@7] seven
+1. eight
+
+
+3. three
+4. four
+
+
+c) gamma
+c) delta
+`
+	doc := goorg.New().Parse(strings.NewReader(input), "test.org")
+	output := stripANSI(renderer.RenderNodes(doc.Nodes))
+
+	var lines []string
+	for _, line := range strings.Split(output, "\n") {
+		if line = strings.TrimRight(line, " "); line != "" {
+			lines = append(lines, line)
+		}
+	}
+	want := []string{"1. one", "2. two", "  1) sub a", "  2) sub b", "7. seven", "8. eight", "3. three", "4. four", "c) gamma", "d) delta"}
+	if strings.Join(lines, "\n") != strings.Join(want, "\n") {
+		t.Errorf("list numbering:\n%s\nwant:\n%s", strings.Join(lines, "\n"), strings.Join(want, "\n"))
+	}
+}
+
+func TestDescriptionListWraps(t *testing.T) {
+	renderer := NewRenderer(NewStyles(createTestRenderer()), 40)
+
+	input := `- Term :: Some *long* details that keep going past the width so they wrap.
+- Short :: ok
+`
+	doc := goorg.New().Parse(strings.NewReader(input), "test.org")
+	output := stripANSI(renderer.RenderNodes(doc.Nodes))
+
+	lines := strings.Split(strings.TrimRight(output, "\n"), "\n")
+	if len(lines) < 3 || !strings.HasPrefix(lines[0], "• Term :: Some long") {
+		t.Fatalf("unexpected description list:\n%s", output)
+	}
+	for _, line := range lines[1 : len(lines)-1] {
+		if !strings.HasPrefix(line, "  ") || strings.HasPrefix(line, "   ") {
+			t.Errorf("continuation line %q not aligned under the term", line)
+		}
+		if w := lipgloss.Width(line); w > 36 {
+			t.Errorf("line %q is %d cells wide, want at most 36", line, w)
+		}
+	}
+	if lines[len(lines)-1] != "• Short :: ok" {
+		t.Errorf("last line = %q, want the short item", lines[len(lines)-1])
+	}
+}
+
+func TestListItemsHangingIndent(t *testing.T) {
+	renderer := NewRenderer(NewStyles(createTestRenderer()), 40)
+
+	input := `1. An ordered item whose text keeps going past the width.
+   - [X] A nested checked item that also wraps onto more lines.
+`
+	doc := goorg.New().Parse(strings.NewReader(input), "test.org")
+	output := stripANSI(renderer.RenderNodes(doc.Nodes))
+
+	lines := strings.Split(strings.TrimRight(output, "\n"), "\n")
+	if len(lines) < 4 || !strings.HasPrefix(lines[0], "1. An ordered") {
+		t.Fatalf("unexpected list:\n%s", output)
+	}
+	pad := "   " // Past "1. "
+	for _, line := range lines[1:] {
+		if strings.HasPrefix(strings.TrimSpace(line), "•") {
+			pad = "        " // Past "  • [✓] "
+			continue
+		}
+		if !strings.HasPrefix(line, pad) || strings.HasPrefix(line, pad+" ") {
+			t.Errorf("continuation line %q not aligned under the item text", line)
+		}
+		if w := lipgloss.Width(line); w > 36 {
+			t.Errorf("line %q is %d cells wide, want at most 36", line, w)
+		}
+	}
+}
+
+func TestFootnoteEndnotes(t *testing.T) {
+	renderer := NewRenderer(NewStyles(createTestRenderer()), 60)
+
+	input := `* Intro
+Some text[fn:second] and more[fn:first].
+
+* Footnotes
+
+[fn:first] The first definition.
+
+[fn:second] The second, citing[fn:third].
+
+[fn:third] Third.
+`
+	doc := goorg.New().Parse(strings.NewReader(input), "test.org")
+	output := stripANSI(renderer.RenderDocument(doc.Nodes))
+
+	if !strings.Contains(output, "Some text[1] and more[2].") {
+		t.Errorf("references not renumbered in order of appearance:\n%s", output)
+	}
+	if strings.Contains(output, "★ Footnotes") {
+		t.Errorf("footnote section heading rendered:\n%s", output)
+	}
+	for _, want := range []string{
+		" 1  The second, citing[3]. ↩ Intro",
+		" 2  The first definition. ↩ Intro",
+		" 3  Third. ↩ footnote 1",
+	} {
+		if !strings.Contains(output, want) {
+			t.Errorf("endnotes missing %q:\n%s", want, output)
+		}
+	}
+	if strings.Index(output, "Footnotes") < strings.Index(output, "Some text") {
+		t.Errorf("endnotes not at the end:\n%s", output)
+	}
+}
+
+func TestInlineFootnotes(t *testing.T) {
+	renderer := NewRenderer(NewStyles(createTestRenderer()), 60)
+
+	input := `Anonymous[fn:: an *inline* note], named[fn:named: a named note], again[fn:named].
+`
+	doc := goorg.New().Parse(strings.NewReader(input), "test.org")
+	output := stripANSI(renderer.RenderDocument(doc.Nodes))
+
+	if !strings.Contains(output, "Anonymous[1], named[2], again[2].") {
+		t.Errorf("inline footnotes not rendered as references:\n%s", output)
+	}
+	for _, want := range []string{" 1  an inline note ↩", " 2  a named note ↩"} {
+		if !strings.Contains(output, want) {
+			t.Errorf("endnotes missing %q:\n%s", want, output)
+		}
+	}
+}
+
+func TestVerseKeepsLines(t *testing.T) {
+	renderer := NewRenderer(NewStyles(createTestRenderer()), 60)
+
+	input := `#+BEGIN_VERSE
+      Great clouds overhead
+Tiny *black* birds rise and fall
+  - and dip
+
+
+Snow covers Emacs
+#+END_VERSE
+`
+	doc := goorg.New().Parse(strings.NewReader(input), "test.org")
+	renderer.SetSource(input, doc.Nodes)
+	output := stripANSI(renderer.RenderNodes(doc.Nodes))
+
+	var lines []string
+	for _, line := range strings.Split(output, "\n") {
+		lines = append(lines, strings.TrimRight(line, " "))
+	}
+	want := "          Great clouds overhead\n    Tiny black birds rise and fall\n      - and dip\n\n\n    Snow covers Emacs"
+	if got := strings.Join(lines, "\n"); !strings.Contains(got, want) {
+		t.Errorf("verse lines = %q, want them to contain %q", got, want)
+	}
+
+	// Verses with the same words keep their own lines, wherever they are
+	input = `* Hidden :noexport:
+#+BEGIN_VERSE
+Roses are red
+violets are blue
+#+END_VERSE
+* Shown
+#+BEGIN_SRC org
+#+BEGIN_VERSE
+Roses are
+red violets are blue
+#+END_VERSE
+#+END_SRC
+#+BEGIN_VERSE
+Roses are red violets
+  are blue
+#+END_VERSE
+`
+	renderer = NewRenderer(NewStyles(createTestRenderer()), 60)
+	doc = goorg.New().Parse(strings.NewReader(input), "test.org")
+	renderer.SetSource(input, doc.Nodes)
+	output = stripANSI(renderer.RenderNodes(doc.Nodes))
+	if !strings.Contains(output, "Roses are red violets ") || !strings.Contains(output, "      are blue") {
+		t.Errorf("verse lines taken from another block:\n%s", output)
+	}
+}
+
+func TestNestedBlocks(t *testing.T) {
+	renderer := NewRenderer(NewStyles(createTestRenderer()), 50)
+
+	input := `#+BEGIN_QUOTE
+Outer *quote*.
+
+#+BEGIN_QUOTE
+Inner quote
+#+END_QUOTE
+#+END_QUOTE
+
+- item
+  #+BEGIN_SRC sh
+  ls
+  #+END_SRC
+`
+	doc := goorg.New().Parse(strings.NewReader(input), "test.org")
+	output := stripANSI(renderer.RenderNodes(doc.Nodes))
+
+	if !strings.Contains(output, "┃  Outer quote.") || strings.Contains(output, "*quote*") {
+		t.Errorf("quote contents not rendered as org:\n%s", output)
+	}
+	if !strings.Contains(output, "┃  ┃  Inner quote") {
+		t.Errorf("nested quote not indented inside its parent:\n%s", output)
+	}
+	if !strings.Contains(output, "• item\n  ┌─ sh") {
+		t.Errorf("block in list item not indented below it:\n%s", output)
+	}
+}
+
+func TestAdmonitionBlock(t *testing.T) {
+	renderer := NewRenderer(NewStyles(createTestRenderer()), 50)
+
+	input := `#+BEGIN_TIP
+Callouts wrap their *paragraphs* to fit inside the box, however long they are.
+
+- and render lists
+#+END_TIP
+`
+	doc := goorg.New().Parse(strings.NewReader(input), "test.org")
+	output := stripANSI(renderer.RenderNodes(doc.Nodes))
+
+	lines := strings.Split(strings.TrimRight(output, "\n "), "\n")
+	if !strings.HasPrefix(lines[0], "╭") || !strings.Contains(lines[1], "💡 Tip") {
+		t.Errorf("admonition lacks its frame or title:\n%s", output)
+	}
+	for _, line := range lines {
+		if w := lipgloss.Width(strings.TrimRight(line, " ")); w > 46 {
+			t.Errorf("admonition line is %d cells wide, want at most 46:\n%s", w, output)
+			break
+		}
+	}
+	if !strings.Contains(output, "• and render lists") || strings.Contains(output, "*paragraphs*") {
+		t.Errorf("admonition contents not rendered as org:\n%s", output)
+	}
+}
+
+func TestNamedSourceBlock(t *testing.T) {
+	renderer := NewRenderer(NewStyles(createTestRenderer()), 60)
+
+	input := `#+NAME: greet
+#+BEGIN_SRC python
+print("hi")
+#+END_SRC
+`
+	doc := goorg.New().Parse(strings.NewReader(input), "test.org")
+	output := renderer.RenderNodes(doc.Nodes)
+
+	if !strings.Contains(stripANSI(output), "─ greet · python ─") {
+		t.Errorf("frame lacks the block name and language:\n%s", stripANSI(output))
+	}
+	blocks := renderer.CodeBlocks()
+	if len(blocks) != 1 || blocks[0].Name != "greet" || blocks[0].Code != `print("hi")` {
+		t.Fatalf("CodeBlocks() = %+v, want the greet block", blocks)
+	}
+	LocateCodeBlocks(output, blocks)
+	if blocks[0].Line != 0 {
+		t.Errorf("block located at line %d, want 0", blocks[0].Line)
+	}
+}
+
+func TestBlockLineNumbers(t *testing.T) {
+	renderer := NewRenderer(NewStyles(createTestRenderer()), 60)
+
+	input := `#+BEGIN_SRC go -n
+func main() {
+}
+#+END_SRC
+
+#+BEGIN_EXAMPLE +n 10
+first
+second
+#+END_EXAMPLE
+
+#+BEGIN_SRC sh -n 98
+echo 98
+echo 99
+echo 100
+#+END_SRC
+`
+	doc := goorg.New().Parse(strings.NewReader(input), "test.org")
+	output := stripANSI(renderer.RenderNodes(doc.Nodes))
+
+	for _, want := range []string{"1 │ func main() {", "2 │ }", "12 │ first", "13 │ second", " 98 │ echo 98", "100 │ echo 100"} {
+		if !strings.Contains(output, want) {
+			t.Errorf("output missing %q:\n%s", want, output)
+		}
+	}
+	if !strings.Contains(output, "─ go ─") {
+		t.Errorf("language label lost next to -n:\n%s", output)
+	}
+}
+
+func TestWideTableScrollsSideways(t *testing.T) {
+	renderer := NewRenderer(NewStyles(createTestRenderer()), 40)
+
+	input := `Intro paragraph.
+
+| Alpha | Beta | Gamma | Delta | Epsilon | Zeta | Eta | Theta | Iota |
+|-------+------+-------+-------+---------+------+-----+-------+------|
+| 1     | 2    | 3     | 4     | 5       | 6    | 7   | 8     | 9    |
+`
+	doc := goorg.New().Parse(strings.NewReader(input), "test.org")
+	output := renderer.RenderNodes(doc.Nodes)
+
+	blocks := renderer.WideBlocks()
+	if len(blocks) != 1 {
+		t.Fatalf("got %d wide blocks, want 1:\n%s", len(blocks), stripANSI(output))
+	}
+	LocateBlocks(output, blocks)
+	lines := strings.Split(stripANSI(output), "\n")
+	block := blocks[0]
+	if block.Height == 0 || !strings.HasPrefix(strings.TrimSpace(lines[block.Line]), "╭") {
+		t.Errorf("block located at line %d, want the table's top border:\n%s", block.Line, stripANSI(output))
+	}
+	if block.Width <= 40 || block.Width != lipgloss.Width(lines[block.Line+1]) {
+		t.Errorf("block width = %d, want the natural table width %d", block.Width, lipgloss.Width(lines[block.Line+1]))
+	}
+}
+
+func TestTableNoWrap(t *testing.T) {
+	input := `| Name | Description         |
+|------+---------------------|
+|      | <10>                |
+| a    | ` + strings.Repeat("long words here ", 8) + `|
+`
+	doc := goorg.New().Parse(strings.NewReader(input), "test.org")
+	renderer := NewRenderer(NewStyles(createTestRenderer()), 60)
+	renderer.SetNoWrap(true)
+	lines := strings.Split(strings.TrimRight(stripANSI(renderer.RenderNodes(doc.Nodes)), "\n"), "\n")
+
+	// Borders, the header and the row: neither the width cookie nor the
+	// viewport wraps the long cell
+	if len(lines) != 5 || !strings.Contains(lines[3], strings.TrimSpace(strings.Repeat("long words here ", 8))) {
+		t.Errorf("table wrapped with wrapping off:\n%s", strings.Join(lines, "\n"))
+	}
+}
+
+func TestTableAlignment(t *testing.T) {
+	renderer := NewRenderer(NewStyles(createTestRenderer()), 80)
+
+	input := `| Item   | Qty | Note |
+|--------+-----+------|
+| <l>    |     | <c>  |
+| Apples |   3 | ok   |
+| Pears  |  12 | fine |
+`
+	doc := goorg.New().Parse(strings.NewReader(input), "test.org")
+	output := stripANSI(renderer.RenderNodes(doc.Nodes))
+
+	if strings.Contains(output, "<l>") || strings.Contains(output, "<c>") {
+		t.Errorf("alignment cookies should not be shown:\n%s", output)
+	}
+	// Numeric columns align right, <c> centers
+	for _, want := range []string{"│ Apples │   3 │  ok  │", "│ Pears  │  12 │ fine │"} {
+		if !strings.Contains(output, want) {
+			t.Errorf("expected row %q in:\n%s", want, output)
+		}
+	}
+}
+
+func TestExcludedSubtrees(t *testing.T) {
+	input := `* Visible
+* COMMENT Draft
+Draft body.
+* Private :noexport:
+** Nested note
+Private body.
+* Also visible
+`
+	doc := goorg.New().Parse(strings.NewReader(input), "test.org")
+
+	renderer := NewRenderer(NewStyles(createTestRenderer()), 80)
+	output := stripANSI(renderer.RenderNodes(doc.Nodes))
+	for _, hidden := range []string{"Draft", "Private", "Nested note"} {
+		if strings.Contains(output, hidden) {
+			t.Errorf("excluded %q rendered:\n%s", hidden, output)
+		}
+	}
+	if !strings.Contains(output, "Visible") || !strings.Contains(output, "Also visible") {
+		t.Errorf("visible headlines missing:\n%s", output)
+	}
+	// Hidden headlines still count towards the source positions
+	if headings := renderer.Headings(); len(headings) != 2 || headings[1].Index != 4 {
+		t.Errorf("headings = %+v, want Also visible at index 4", headings)
+	}
+
+	renderer = NewRenderer(NewStyles(createTestRenderer()), 80)
+	renderer.SetShowExcluded(true)
+	output = stripANSI(renderer.RenderNodes(doc.Nodes))
+	for _, shown := range []string{"COMMENT Draft", "Draft body.", "Private", "Nested note", "Private body."} {
+		if !strings.Contains(output, shown) {
+			t.Errorf("excluded %q not shown:\n%s", shown, output)
+		}
+	}
+}
+
+func TestTableRows(t *testing.T) {
+	renderer := NewRenderer(NewStyles(createTestRenderer()), 40)
+	renderer.SetZebraTables(true)
+
+	input := `Intro paragraph.
+
+| Name | Notes |
+|------+-------|
+| one  | short |
+| two  | a note long enough to wrap onto more lines than one |
+| three | last |
+`
+	doc := goorg.New().Parse(strings.NewReader(input), "test.org")
+	output := renderer.RenderNodes(doc.Nodes)
+
+	tables := renderer.Tables()
+	if len(tables) != 1 {
+		t.Fatalf("got %d tables, want 1", len(tables))
+	}
+	LocateTables(output, tables)
+	table := tables[0]
+	lines := strings.Split(stripANSI(output), "\n")
+	if len(table.Rows) != 3 {
+		t.Fatalf("got %d body rows, want 3:\n%s", len(table.Rows), stripANSI(output))
+	}
+	for i, want := range []string{"one", "two", "three"} {
+		row := table.Rows[i]
+		line := lines[table.Line+row.Offset]
+		if !strings.HasPrefix(strings.TrimSpace(strings.TrimPrefix(strings.TrimSpace(line), "│")), want) {
+			t.Errorf("row %d starts at %q, want %q", i, line, want)
+		}
+	}
+	if table.Rows[1].Height < 2 {
+		t.Errorf("wrapped row height = %d, want at least 2", table.Rows[1].Height)
+	}
+	if last := lines[table.Line+table.Height-1]; !strings.HasPrefix(strings.TrimSpace(last), "╰") {
+		t.Errorf("table ends at %q, want the bottom border", last)
+	}
+}
+
+func TestSmartPunctuation(t *testing.T) {
+	input := `Pages 10--20 --- "quoted" and it's done... but ~a--b~ stays.
+
+Quotes around "*bold*" and '/italics/', *bold*'s "[[https://example.com][link]]".
+`
+	doc := goorg.New().Parse(strings.NewReader(input), "test.org")
+
+	renderer := NewRenderer(NewStyles(createTestRenderer()), 100)
+	output := stripANSI(renderer.RenderNodes(doc.Nodes))
+	if !strings.Contains(output, "Pages 10–20 — “quoted” and it’s done… but") {
+		t.Errorf("special strings not replaced:\n%s", output)
+	}
+	if !strings.Contains(output, "a--b") {
+		t.Errorf("code changed by smart punctuation:\n%s", output)
+	}
+	// Quotes after emphasis and links follow their text
+	if !strings.Contains(output, "Quotes around “bold” and ‘italics’, bold’s “🔗 link”") {
+		t.Errorf("quotes around inline content not curled as text:\n%s", output)
+	}
+
+	renderer = NewRenderer(NewStyles(createTestRenderer()), 100)
+	renderer.SetSmartPunctuation(false)
+	output = stripANSI(renderer.RenderNodes(doc.Nodes))
+	if !strings.Contains(output, `Pages 10--20 --- "quoted" and it's done...`) {
+		t.Errorf("text changed with smart punctuation off:\n%s", output)
+	}
+}
+
+func TestDetectGraphics(t *testing.T) {
+	tests := []struct {
+		term    string
+		environ []string
+		want    GraphicsProtocol
+	}{
+		{"xterm-kitty", nil, GraphicsKitty},
+		{"xterm-256color", []string{"TERM_PROGRAM=ghostty"}, GraphicsKitty},
+		{"xterm-256color", []string{"LANG=C", "LC_TERMINAL=iTerm2"}, GraphicsBlocks},
+		{"foot", nil, GraphicsBlocks},
+		{"xterm-256color", []string{"LANG=C"}, GraphicsBlocks},
+	}
+	for _, tt := range tests {
+		if got := DetectGraphics(tt.term, tt.environ); got != tt.want {
+			t.Errorf("DetectGraphics(%q, %q) = %v, want %v", tt.term, tt.environ, got, tt.want)
+		}
+	}
+}
+
+func TestInlineImages(t *testing.T) {
+	root := t.TempDir()
+	f, err := os.Create(filepath.Join(root, "pic.png"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	pic := image.NewRGBA(image.Rect(0, 0, 80, 32))
+	draw.Draw(pic, pic.Bounds(), image.NewUniform(color.RGBA{0xf7, 0x76, 0x8e, 0xff}), image.Point{}, draw.Src)
+	if err := png.Encode(f, pic); err != nil {
+		t.Fatal(err)
+	}
+	f.Close()
+
+	input := `[[file:pic.png]]
+
+[[../outside.png]]
+`
+	doc := goorg.New().Parse(strings.NewReader(input), "test.org")
+
+	// With graphics none, images are framed links
+	renderer := NewRenderer(NewStyles(createTestRenderer()), 80)
+	renderer.SetImageDir(root, root)
+	renderer.SetGraphics(GraphicsNone)
+	output := stripANSI(renderer.RenderNodes(doc.Nodes))
+	if !strings.Contains(output, "🖼") || !strings.Contains(output, "pic.png") {
+		t.Errorf("image placeholder missing:\n%s", output)
+	}
+	if len(renderer.Links()) != 2 {
+		t.Errorf("got %d links, want the 2 placeholders", len(renderer.Links()))
+	}
+
+	// Kitty draws images inside the org directory with placeholder cells
+	renderer = NewRenderer(NewStyles(createTestRenderer()), 80)
+	renderer.SetImageDir(root, root)
+	renderer.SetGraphics(GraphicsKitty)
+	output = renderer.RenderNodes(doc.Nodes)
+	if !strings.Contains(output, "\x1b_G") || strings.Count(output, "\U0010EEEE") != 10*2 {
+		t.Errorf("kitty image not drawn as 10x2 placeholder cells:\n%q", output)
+	}
+	if !strings.Contains(stripANSI(output), "outside.png") {
+		t.Errorf("image outside the org directory not shown as a placeholder:\n%s", stripANSI(output))
+	}
+
+	// Terminals without a protocol get half-block art, 2 pixels a cell
+	renderer = NewRenderer(NewStyles(createTestRenderer()), 80)
+	renderer.SetImageDir(root, root)
+	renderer.SetGraphics(GraphicsBlocks)
+	output = stripANSI(renderer.RenderNodes(doc.Nodes))
+	art := strings.Repeat("▀", 10)
+	if !strings.HasPrefix(output, art+"\n"+art+"\n") {
+		t.Errorf("image not drawn as 10x2 half blocks:\n%s", output)
+	}
+}
+
+func TestLoadImageTooLarge(t *testing.T) {
+	// A 1x1 PNG declaring itself 100000x100000 pixels
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, image.NewGray(image.Rect(0, 0, 1, 1))); err != nil {
+		t.Fatal(err)
+	}
+	data := buf.Bytes()
+	binary.BigEndian.PutUint32(data[16:], 100000)
+	binary.BigEndian.PutUint32(data[20:], 100000)
+	binary.BigEndian.PutUint32(data[29:], crc32.ChecksumIEEE(data[12:29]))
+	path := filepath.Join(t.TempDir(), "bomb.png")
+	if err := os.WriteFile(path, data, 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := loadImage(path); err == nil || !strings.Contains(err.Error(), "too large") {
+		t.Errorf("loadImage = %v, want an image too large error", err)
+	}
+}
+
+func TestCaptions(t *testing.T) {
+	renderer := NewRenderer(NewStyles(createTestRenderer()), 80)
+
+	input := `#+CAPTION: Sales by region
+#+NAME: sales
+| a | b |
+
+#+CAPTION: A diagram
+[[file:diagram.png]]
+
+#+CAPTION: Hello world
+#+BEGIN_SRC go
+fmt.Println("hi")
+#+END_SRC
+
+#+CAPTION: Totals
+| c | d |
+`
+	doc := goorg.New().Parse(strings.NewReader(input), "test.org")
+	output := stripANSI(renderer.RenderNodes(doc.Nodes))
+	lines := strings.Split(output, "\n")
+
+	find := func(text string) int {
+		for i, line := range lines {
+			if strings.Contains(line, text) {
+				return i
+			}
+		}
+		t.Fatalf("%q not rendered:\n%s", text, output)
+		return -1
+	}
+	if strings.Contains(output, "#+CAPTION") {
+		t.Errorf("caption keyword rendered:\n%s", output)
+	}
+	if table := find("Table 1: Sales by region"); !strings.Contains(lines[table+1], "╭") {
+		t.Errorf("table caption not above the table:\n%s", output)
+	}
+	if figure := find("Figure 1: A diagram"); !strings.Contains(lines[figure-1], "╰") {
+		t.Errorf("figure caption not below the image:\n%s", output)
+	}
+	find("Listing 1: Hello world")
+	find("Table 2: Totals")
+}
+
+func TestCollapsibleDrawers(t *testing.T) {
+	input := `* Task
+:PROPERTIES:
+:ID: abc-123
+:END:
+:NOTES:
+Some notes.
+:END:
+Body text.
+`
+	doc := goorg.New().Parse(strings.NewReader(input), "test.org")
+
+	renderer := NewRenderer(NewStyles(createTestRenderer()), 80)
+	output := stripANSI(renderer.RenderNodes(doc.Nodes))
+	if !strings.Contains(output, ":PROPERTIES: …") || !strings.Contains(output, ":NOTES: …") {
+		t.Errorf("drawers not collapsed to summary lines:\n%s", output)
+	}
+	if strings.Contains(output, "abc-123") || strings.Contains(output, "Some notes.") {
+		t.Errorf("collapsed drawer contents shown:\n%s", output)
+	}
+	if drawers := renderer.Drawers(); len(drawers) != 2 || drawers[0].Height != 1 {
+		t.Errorf("drawers = %+v, want 2 one-line drawers", drawers)
+	}
+
+	// Expanding all drawers but the second
+	renderer = NewRenderer(NewStyles(createTestRenderer()), 80)
+	renderer.SetDrawers(true, map[int]bool{1: true})
+	output = stripANSI(renderer.RenderNodes(doc.Nodes))
+	if !strings.Contains(output, ":ID: abc-123") || !strings.Contains(output, ":NOTES: …") {
+		t.Errorf("drawers not expanded and toggled:\n%s", output)
+	}
+	drawers := renderer.Drawers()
+	if !drawers[0].Open || drawers[1].Open || drawers[0].Height != 3 {
+		t.Errorf("drawers = %+v, want the first expanded over 3 lines", drawers)
+	}
+	LocateDrawers(output, drawers)
+	if lines := strings.Split(output, "\n"); !strings.Contains(lines[drawers[1].Line], ":NOTES:") {
+		t.Errorf("second drawer located at line %d:\n%s", drawers[1].Line, output)
+	}
+}
+
+func TestLogbookTimeline(t *testing.T) {
+	renderer := NewRenderer(NewStyles(createTestRenderer()), 80)
+	renderer.SetDrawers(true, nil)
+
+	input := `* DONE Task
+:LOGBOOK:
+- State "DONE"       from "TODO"       [2024-03-05 Tue 10:12]
+- Note taken on [2024-03-04 Mon 09:00] \\
+  Called the vendor.
+CLOCK: [2024-03-04 Mon 09:00]--[2024-03-04 Mon 10:30] =>  1:30
+CLOCK: [2024-03-05 Tue 09:00]--[2024-03-05 Tue 09:45] =>  0:45
+:END:
+`
+	doc := goorg.New().Parse(strings.NewReader(input), "test.org")
+	output := stripANSI(renderer.RenderNodes(doc.Nodes))
+
+	for _, want := range []string{
+		"• 2024-03-05 Tue 10:12 TODO → DONE",
+		"• 2024-03-04 Mon 09:00 ✎ Called the vendor.",
+		"• 2024-03-04 Mon 09:00 ⏱ 1:30 until 10:30",
+		"Total 2:15",
+	} {
+		// Status keywords are padded
+		if !strings.Contains(strings.Join(strings.Fields(output), " "), want) {
+			t.Errorf("logbook missing %q:\n%s", want, output)
+		}
+	}
+	if strings.Contains(output, "CLOCK:") || strings.Contains(output, `State "DONE"`) {
+		t.Errorf("raw logbook lines rendered:\n%s", output)
+	}
+}
+
+func TestSplitOffset(t *testing.T) {
+	anchors := []splitAnchor{{0, 0}, {10, 4}, {30, 8}}
+	tests := []struct {
+		line, want int
+	}{
+		{0, 0},
+		{5, 2},
+		{10, 4},
+		{20, 6},
+		{30, 8},
+		{35, 13},
+	}
+	for _, tt := range tests {
+		if got := splitOffset(anchors, tt.line); got != tt.want {
+			t.Errorf("splitOffset(%d) = %d, want %d", tt.line, got, tt.want)
+		}
+	}
+}
+
+func TestTableOfContents(t *testing.T) {
+	input := `#+TOC: headlines 1
+
+* Introduction
+** Details
+* COMMENT Draft
+* Usage
+`
+	doc := goorg.New().Parse(strings.NewReader(input), "test.org")
+
+	renderer := NewRenderer(NewStyles(createTestRenderer()), 80)
+	output := stripANSI(renderer.RenderDocument(doc.Nodes))
+	toc, _, _ := strings.Cut(output, "★")
+	if !strings.Contains(toc, "Contents") || !strings.Contains(toc, "→ Usage") {
+		t.Errorf("table of contents missing:\n%s", output)
+	}
+	for _, hidden := range []string{"Details", "Draft"} {
+		if strings.Contains(toc, hidden) {
+			t.Errorf("%q listed in table of contents:\n%s", hidden, toc)
+		}
+	}
+
+	var urls []string
+	for _, link := range renderer.Links() {
+		urls = append(urls, link.URL)
+	}
+	if want := []string{"*Introduction", "*Usage"}; strings.Join(urls, ",") != strings.Join(want, ",") {
+		t.Errorf("links = %v, want %v", urls, want)
+	}
+	if anchors := renderer.Anchors(); len(anchors) == 0 || anchors[0].Name != "*Introduction" {
+		t.Errorf("anchors = %+v, want headline anchors", anchors)
+	}
+}
+
+func TestAnchors(t *testing.T) {
+	input := `* Setup
+:PROPERTIES:
+:CUSTOM_ID: setup
+:END:
+Paragraph with a <<marker>> target.[fn:1]
+
+#+NAME: example
+#+BEGIN_SRC go
+fmt.Println("hi")
+#+END_SRC
+
+[fn:1] The note.
+`
+	doc := goorg.New().Parse(strings.NewReader(input), "test.org")
+
+	renderer := NewRenderer(NewStyles(createTestRenderer()), 80)
+	rendered := renderer.RenderDocument(doc.Nodes)
+	anchors := renderer.Anchors()
+	LocateAnchors(rendered, anchors)
+
+	lines := strings.Split(stripANSI(rendered), "\n")
+	if strings.Contains(stripANSI(rendered), "marker") {
+		t.Errorf("target markup rendered:\n%s", stripANSI(rendered))
+	}
+	want := map[string]string{
+		"*Setup":  "Setup",
+		"#setup":  "Setup",
+		"marker":  "Paragraph with a",
+		"example": "fmt.Println",
+		"fn:1":    "The note.",
+	}
+	found := map[string]bool{}
+	for _, anchor := range anchors {
+		text, ok := want[anchor.Name]
+		if !ok {
+			continue
+		}
+		found[anchor.Name] = true
+		if anchor.Line < 0 || anchor.Line >= len(lines) {
+			t.Errorf("anchor %q not located: %+v", anchor.Name, anchor)
+			continue
+		}
+		if rest := strings.Join(lines[anchor.Line:], "\n"); !strings.Contains(rest, text) {
+			t.Errorf("anchor %q at line %d, want it before %q", anchor.Name, anchor.Line, text)
+		}
+	}
+	for name := range want {
+		if !found[name] {
+			t.Errorf("no anchor %q in %+v", name, anchors)
+		}
+	}
+}
+
+func TestExampleFidelity(t *testing.T) {
+	input := `#+BEGIN_EXAMPLE -n
+    indented
+
+	Tab
+#+END_EXAMPLE
+
+:   fixed   width
+:
+:     deeper
+`
+	doc := goorg.New().Parse(strings.NewReader(input), "test.org")
+	renderer := NewRenderer(NewStyles(createTestRenderer()), 60)
+	output := stripANSI(renderer.RenderNodes(doc.Nodes))
+
+	// column returns the column text starts at on its line, -1 if missing
+	column := func(text string) int {
+		for _, line := range strings.Split(output, "\n") {
+			if i := strings.Index(line, text); i >= 0 {
+				return lipgloss.Width(line[:i])
+			}
+		}
+		return -1
+	}
+	if !strings.Contains(output, "2 │\n") && !strings.Contains(output, "2 │ ") {
+		t.Errorf("blank line not numbered:\n%s", output)
+	}
+	if got := column("Tab") - column("indented"); column("indented") < 0 || got != 4 {
+		t.Errorf("tab indented %d columns past the spaces, want 4:\n%s", got, output)
+	}
+	if column("fixed   width") < 0 || column("deeper")-column("fixed") != 2 {
+		t.Errorf("fixed-width indentation lost:\n%s", output)
+	}
+}
+
+func TestFoldLongCode(t *testing.T) {
+	var b strings.Builder
+	b.WriteString("#+BEGIN_SRC text\n")
+	for i := 1; i <= 12; i++ {
+		fmt.Fprintf(&b, "line %d\n", i)
+	}
+	b.WriteString("#+END_SRC\n\n#+BEGIN_SRC text\nshort\n#+END_SRC\n")
+	doc := goorg.New().Parse(strings.NewReader(b.String()), "test.org")
+
+	renderer := NewRenderer(NewStyles(createTestRenderer()), 80)
+	renderer.SetFoldCode(5, nil)
+	output := stripANSI(renderer.RenderNodes(doc.Nodes))
+	if !strings.Contains(output, "line 5") || strings.Contains(output, "line 6") {
+		t.Errorf("folded block should show 5 lines:\n%s", output)
+	}
+	if !strings.Contains(output, "… 7 more lines (enter to expand)") {
+		t.Errorf("fold hint missing:\n%s", output)
+	}
+	code := renderer.CodeBlocks()
+	if len(code) != 2 || !code[0].Folded || code[1].Fold {
+		t.Fatalf("code blocks = %+v, want the first folded", code)
+	}
+	if !strings.Contains(code[0].Code, "line 12") {
+		t.Errorf("folded block should still copy all its code")
+	}
+
+	renderer = NewRenderer(NewStyles(createTestRenderer()), 80)
+	renderer.SetFoldCode(5, map[int]bool{0: true})
+	output = stripANSI(renderer.RenderNodes(doc.Nodes))
+	if !strings.Contains(output, "line 12") || strings.Contains(output, "more lines") {
+		t.Errorf("expanded block should show all lines:\n%s", output)
+	}
+}
+
+func TestLatexBlocks(t *testing.T) {
+	input := `\begin{equation}
+  E = mc^2 + \alpha
+\end{equation}
+
+#+BEGIN_EXPORT latex
+\newpage
+#+END_EXPORT
+`
+	doc := goorg.New().Parse(strings.NewReader(input), "test.org")
+
+	renderer := NewRenderer(NewStyles(createTestRenderer()), 60)
+	output := stripANSI(renderer.RenderNodes(doc.Nodes))
+	for _, want := range []string{"╭─ equation ─", "E = mc^2 + \\alpha", "╭─ latex export ─", "\\newpage"} {
+		if !strings.Contains(output, want) {
+			t.Errorf("%q missing:\n%s", want, output)
+		}
+	}
+	if strings.Contains(output, "\\begin") {
+		t.Errorf("environment delimiters should move to the frame:\n%s", output)
+	}
+
+	renderer = NewRenderer(NewStyles(createTestRenderer()), 60)
+	renderer.SetLatexUnicode(true)
+	output = stripANSI(renderer.RenderNodes(doc.Nodes))
+	if !strings.Contains(output, "E = mc² + α") {
+		t.Errorf("math not approximated:\n%s", output)
+	}
+}
+
+func TestLatexToUnicode(t *testing.T) {
+	tests := []struct {
+		in, want string
+	}{
+		{`\sum_{i=1}^{n} x_i`, "∑ᵢ₌₁ⁿ xᵢ"},
+		{`a \leq b \in \mathbb{R}`, `a ≤ b ∈ \mathbb{R}`},
+		{`\frac{a+b}{2}`, "(a+b)/2"},
+		{`x^{abc}`, "x^{abc}"},
+		{`\infty \int`, "∞ ∫"},
+	}
+	for _, tt := range tests {
+		if got := latexToUnicode(tt.in); got != tt.want {
+			t.Errorf("latexToUnicode(%q) = %q, want %q", tt.in, got, tt.want)
+		}
+	}
+}
+
+func TestGuessLanguage(t *testing.T) {
+	input := `#+BEGIN_SRC
+#!/bin/bash
+echo "hello"
+#+END_SRC
+`
+	doc := goorg.New().Parse(strings.NewReader(input), "test.org")
+
+	renderer := NewRenderer(NewStyles(createTestRenderer()), 80)
+	renderer.SetGuessLanguage(true)
+	output := renderer.RenderNodes(doc.Nodes)
+	if !strings.Contains(stripANSI(output), "bash (guessed)") {
+		t.Errorf("guessed language not shown:\n%s", stripANSI(output))
+	}
+	if !strings.Contains(output, "\x1b[38;5;") {
+		t.Errorf("guessed language not highlighted:\n%q", output)
+	}
+	if code := renderer.CodeBlocks(); len(code) != 1 || code[0].Lang != "" {
+		t.Errorf("code blocks = %+v, want no language recorded", code)
+	}
+
+	renderer = NewRenderer(NewStyles(createTestRenderer()), 80)
+	if output := stripANSI(renderer.RenderNodes(doc.Nodes)); strings.Contains(output, "guessed") {
+		t.Errorf("language guessed while disabled:\n%s", output)
+	}
+}
+
+func TestBabelHeaders(t *testing.T) {
+	input := `#+HEADER: :var x=1
+#+BEGIN_SRC python :results output :session s
+print(x)
+#+END_SRC
+`
+	doc := goorg.New().Parse(strings.NewReader(input), "test.org")
+
+	renderer := NewRenderer(NewStyles(createTestRenderer()), 80)
+	output := stripANSI(renderer.RenderNodes(doc.Nodes))
+	if !strings.Contains(output, "─ python ─") {
+		t.Errorf("language label missing:\n%s", output)
+	}
+	for _, hidden := range []string{"#+HEADER", ":var", ":results", ":session"} {
+		if strings.Contains(output, hidden) {
+			t.Errorf("header argument %q shown:\n%s", hidden, output)
+		}
+	}
+
+	renderer = NewRenderer(NewStyles(createTestRenderer()), 80)
+	renderer.SetShowHeaders(true)
+	output = stripANSI(renderer.RenderNodes(doc.Nodes))
+	if !strings.Contains(output, ":var x=1 :results output :session s") {
+		t.Errorf("header arguments not shown:\n%s", output)
+	}
+	if code := renderer.CodeBlocks(); len(code) != 1 || code[0].Code != "print(x)" {
+		t.Errorf("code blocks = %+v, want only the code", code)
+	}
+}
+
+func TestChunkedRendering(t *testing.T) {
+	var input strings.Builder
+	input.WriteString("Intro with a note.[fn:1]\n\n")
+	for i := range 30 {
+		fmt.Fprintf(&input, "* Section %d\nText of section %d.[fn:%d]\n#+CAPTION: Numbers\n| a | b |\n\n", i, i, i%3+1)
+	}
+	input.WriteString("[fn:1] One.\n[fn:2] Two.\n[fn:3] Three.\n")
+	doc := goorg.New().Parse(strings.NewReader(input.String()), "test.org")
+
+	whole := NewRenderer(NewStyles(createTestRenderer()), 80).RenderDocument(doc.Nodes)
+
+	chunks := newDocChunks(NewRenderer(NewStyles(createTestRenderer()), 80), "", doc.Nodes, 0)
+	partial, _ := chunks.renderUntil(20)
+	if partial.more == nil {
+		t.Fatal("whole document rendered for 20 lines")
+	}
+	if !strings.HasPrefix(whole, partial.content) {
+		t.Errorf("partial rendering is not the start of the document:\n%s", stripANSI(partial.content))
+	}
+	if n := strings.Count(partial.content, "\n"); n < 20 || n > 40 {
+		t.Errorf("rendered %d lines for 20", n)
+	}
+
+	rest, _ := partial.more.renderUntil(math.MaxInt)
+	if rest.more != nil {
+		t.Error("document not done after rendering the rest")
+	}
+	if rest.content != whole {
+		t.Errorf("chunked rendering differs from rendering at once:\n%s\n---\n%s", stripANSI(rest.content), stripANSI(whole))
+	}
+	if len(rest.headings) != 30 || rest.headings[29].Line <= partial.headings[len(partial.headings)-1].Line {
+		t.Errorf("headings not located through the rest: %d", len(rest.headings))
+	}
+}
+
+func TestWriteNodesInterrupted(t *testing.T) {
+	var input strings.Builder
+	input.WriteString("* Everything\n")
+	for i := range 500 {
+		fmt.Fprintf(&input, "Paragraph %d.\n\n", i)
+	}
+	doc := goorg.New().Parse(strings.NewReader(input.String()), "test.org")
+
+	var whole strings.Builder
+	renderer := NewRenderer(NewStyles(createTestRenderer()), 80)
+	if err := renderer.WriteNodes(&whole, doc.Nodes); err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(whole.String(), "Paragraph 499.") {
+		t.Error("streamed rendering is incomplete")
+	}
+
+	// A single headline holds everything, so the render has to stop
+	// within it
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	var partial strings.Builder
+	renderer = NewRenderer(NewStyles(createTestRenderer()), 80)
+	renderer.SetContext(ctx)
+	if err := renderer.WriteNodes(&partial, doc.Nodes); !errors.Is(err, context.Canceled) {
+		t.Errorf("WriteNodes() = %v, want %v", err, context.Canceled)
+	}
+	if strings.Contains(partial.String(), "Paragraph 1.") {
+		t.Error("render went on after the context was done")
+	}
+}
+
+// BenchmarkRenderNodes renders each testdata/render/*.org file
+// Run with: go test ./ui/... -run '^$' -bench RenderNodes
+func BenchmarkRenderNodes(b *testing.B) {
+	paths, err := filepath.Glob(filepath.Join("testdata", "render", "*.org"))
+	if err != nil || len(paths) == 0 {
+		b.Fatalf("no testdata: %v", err)
+	}
+	styles := NewStyles(createTestRenderer())
+	for _, path := range paths {
+		source, err := os.ReadFile(path)
+		if err != nil {
+			b.Fatal(err)
+		}
+		doc := goorg.New().Parse(strings.NewReader(string(source)), path)
+		b.Run(strings.TrimSuffix(filepath.Base(path), ".org"), func(b *testing.B) {
+			b.ReportAllocs()
+			b.SetBytes(int64(len(source)))
+			for b.Loop() {
+				renderer := NewRenderer(styles, 80)
+				renderer.SetSource(string(source), doc.Nodes)
+				renderer.RenderNodes(doc.Nodes)
+			}
+		})
+	}
+}
+
+func TestRoamLinks(t *testing.T) {
+	if _, err := exec.LookPath("sqlite3"); err != nil {
+		t.Skip("sqlite3 not installed")
+	}
+	dir := t.TempDir()
+	files := map[string]string{
+		"index.org": "#+TITLE: Index\n\nSee [[id:b2][the details]] and [[roam:Topic]].\n",
+		"topic.org": "#+TITLE: Topic\n\n" + strings.Repeat("Filler.\n\n", 40) +
+			"* Details\n:PROPERTIES:\n:ID: b2\n:END:\nHere.\n",
+	}
+	for name, content := range files {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	db := filepath.Join(t.TempDir(), "org-roam.db")
+	cmd := exec.Command("sqlite3", db)
+	cmd.Stdin = strings.NewReader(`
+CREATE TABLE nodes (id NOT NULL PRIMARY KEY, file NOT NULL, level NOT NULL, pos NOT NULL, todo, priority, scheduled, deadline, title, properties, olp);
+CREATE TABLE aliases (node_id NOT NULL, alias);
+CREATE TABLE links (pos NOT NULL, source NOT NULL, dest NOT NULL, type NOT NULL, properties NOT NULL);
+INSERT INTO nodes (id, file, level, pos, title) VALUES
+	('"a1"', '"/notes/index.org"', 0, 1, '"Index"'),
+	('"b1"', '"/notes/topic.org"', 0, 1, '"Topic"'),
+	('"b2"', '"/notes/topic.org"', 1, 400, '"Details"');
+INSERT INTO links (pos, source, dest, type, properties) VALUES (20, '"a1"', '"b2"', '"id"', '()');
+`)
+	if out, err := cmd.CombinedOutput(); err != nil {
+		t.Fatalf("sqlite3: %v: %s", err, out)
+	}
+	library, err := org.NewLibrary(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	roam, err := org.OpenRoam(context.Background(), db, library)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	m := NewModel(createTestRenderer(), dir, "", Options{Library: library, Roam: roam})
+	m.width, m.height = 80, 24
+	m.resizeViewport()
+	topic := filepath.Join(dir, "topic.org")
+	if got := m.backlinkCount(topic); got != 1 {
+		t.Errorf("backlinkCount(topic.org) = %d, want 1", got)
+	}
+	if node, ok := m.roamLink("roam:topic"); !ok || node.ID != "b1" {
+		t.Errorf("roamLink(roam:topic) = %+v, %v", node, ok)
+	}
+
+	// Following the link opens topic.org at the headline with the ID
+	m.followLink(LinkRef{URL: "id:b2"})
+	if m.pendingAnchor != (pendingAnchor{topic, "id:b2"}) {
+		t.Fatalf("pending anchor after following id:b2 = %+v", m.pendingAnchor)
+	}
+	doc, err := org.Load(topic)
+	if err != nil {
+		t.Fatal(err)
+	}
+	m.openDocument(doc)
+	if line, ok := m.anchorLine("id:b2"); !ok || m.viewport.YOffset != line || line == 0 {
+		t.Errorf("opened topic.org at line %d, want the headline's (%d, %v)", m.viewport.YOffset, line, ok)
+	}
+
+	m.openReferences()
+	if !m.references.open || len(m.references.links) != 1 || m.references.links[0].Source.ID != "a1" {
+		t.Errorf("linked references to topic.org = %+v", m.references)
+	}
+}
+
+func TestPopularSection(t *testing.T) {
+	dir := t.TempDir()
+	for _, name := range []string{"a.org", "b.org", "c.org"} {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte("* "+name+"\n"), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	views, _ := analytics.Open("")
+	store, _ := state.NewStore("")
+	view := func(user, name string) Model {
+		t.Helper()
+		m := NewModel(createTestRenderer(), dir, "", Options{Store: store, UserID: user, Views: views})
+		doc, err := org.Load(filepath.Join(dir, name))
+		if err != nil {
+			t.Fatal(err)
+		}
+		m.openDocument(doc)
+		m.refreshFlatList()
+		return m
+	}
+	view("ada", "b.org")
+	view("grace", "b.org")
+	view("grace", "c.org")
+
+	// A user who viewed none of them sees the most viewed first
+	m := view("alan", "a.org")
+	section, start := m.sectionAt(1)
+	if section < 0 || m.sections[section].title != "Popular" {
+		t.Fatalf("sections = %+v", m.sections)
+	}
+	var popular []string
+	for _, e := range m.flatList[start : start+m.sections[section].count] {
+		popular = append(popular, e.Name)
+	}
+	// a.org is in the Recent section already
+	if !slices.Equal(popular, []string{"b.org", "c.org"}) {
+		t.Errorf("popular = %v, want [b.org c.org]", popular)
+	}
+
+	// Without counting views, there's no Popular section
+	m = NewModel(createTestRenderer(), dir, "", Options{})
+	m.refreshFlatList()
+	for _, s := range m.sections {
+		if s.title == "Popular" && s.count > 0 {
+			t.Errorf("popular section without analytics: %+v", s)
+		}
+	}
+}
+
+func TestHorizontalRuleNarrow(t *testing.T) {
+	doc := goorg.New().Parse(strings.NewReader("Above\n\n-----\n\nBelow\n"), "rule.org")
+	for _, width := range []int{1, 3, 4, 10} {
+		out := NewRenderer(NewStyles(createTestRenderer()), width).RenderDocument(doc.Nodes)
+		if !strings.Contains(out, "Below") {
+			t.Errorf("width %d: %q", width, out)
+		}
+	}
+}
+
+func TestScrollBlockKeepsDocumentOpen(t *testing.T) {
+	dir := t.TempDir()
+	content := "* Wide\n\n| Alpha | Beta | Gamma | Delta | Epsilon | Zeta | Eta | Theta | Iota | Kappa |\n|-------+------+-------+-------+---------+------+-----+-------+------+-------|\n| 1 | 2 | 3 | 4 | 5 | 6 | 7 | 8 | 9 | 10 |\n"
+	path := filepath.Join(dir, "wide.org")
+	if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+	var model tea.Model = NewModel(createTestRenderer(), dir, "", Options{})
+	model, _ = model.Update(tea.WindowSizeMsg{Width: 60, Height: 30})
+	m := model.(Model)
+	doc, err := org.Load(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	m.openDocument(doc)
+	if m.focusedBlock() < 0 {
+		t.Fatalf("no wide block in view, blocks %+v", m.docBlocks)
+	}
+	key := func(k string) {
+		t.Helper()
+		model, _ := m.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune(k)})
+		m = model.(Model)
+	}
+
+	// h at the left edge of the block leaves it there, and the document open
+	key("l")
+	key("h")
+	key("h")
+	if m.currentView != ViewDocument {
+		t.Fatal("h at the left edge of a wide block closed the document")
+	}
+	if offset := m.blockOffsets[m.focusedBlock()]; offset != 0 {
+		t.Errorf("block offset = %d, want 0", offset)
+	}
+}
+
+func TestStatusClockTicks(t *testing.T) {
+	m := NewModel(createTestRenderer(), t.TempDir(), "", Options{StatusTemplate: "{scroll} {time}"})
+	if m.clockTick() == nil {
+		t.Fatal("no tick redrawing a status bar showing the time")
+	}
+	if _, cmd := m.Update(clockMsg{}); cmd == nil {
+		t.Error("the clock stopped ticking after a tick")
+	}
+	m = NewModel(createTestRenderer(), t.TempDir(), "", Options{})
+	if m.clockTick() != nil {
+		t.Error("clock ticking for a status bar without the time")
+	}
+}
+
+// cmdMsg runs cmd, and the commands of the batches it returns, and
+// returns the first message of type T they send
+func cmdMsg[T tea.Msg](t *testing.T, cmd tea.Cmd) T {
+	t.Helper()
+	var found []T
+	var run func(cmd tea.Cmd)
+	run = func(cmd tea.Cmd) {
+		if cmd == nil {
+			return
+		}
+		switch msg := cmd().(type) {
+		case tea.BatchMsg:
+			for _, c := range msg {
+				run(c)
+			}
+		case T:
+			found = append(found, msg)
+		}
+	}
+	run(cmd)
+	if len(found) == 0 {
+		var zero T
+		t.Fatalf("no %T sent", zero)
+	}
+	return found[0]
+}
+
+func TestOpenRendersInBackground(t *testing.T) {
+	dir := t.TempDir()
+	for _, name := range []string{"notes.org", "other.org"} {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte("* "+name+"\nSome text.\n"), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	m := NewModel(createTestRenderer(), dir, "", Options{})
+	m.width, m.height = 80, 24
+	m.resizeViewport()
+	entry := func(name string) *org.FileEntry {
+		return m.flatList[slices.IndexFunc(m.flatList, func(e *org.FileEntry) bool { return e.Name == name })]
+	}
+	notes := entry("notes.org")
+
+	// Parsed, then rendered, in the background, with the file list shown
+	cmd := m.openEntry(notes)
+	if m.loading != notes || m.loadingStep != "Opening" || m.currentView != ViewFileList {
+		t.Fatalf("opening: loading %v, step %q, view %v", m.loading, m.loadingStep, m.currentView)
+	}
+	cmd = m.handleDocParsed(cmdMsg[docParsedMsg](t, cmd))
+	if m.loading != notes || m.loadingStep != "Rendering" || m.currentView != ViewFileList {
+		t.Fatalf("parsed: loading %v, step %q, view %v", m.loading, m.loadingStep, m.currentView)
+	}
+	m.handleDocRendered(cmdMsg[docRenderedMsg](t, cmd))
+	if m.loading != nil || m.currentView != ViewDocument || m.currentDoc != notes.OrgFile {
+		t.Fatalf("rendered: loading %v, view %v", m.loading, m.currentView)
+	}
+
+	// Opened again, the document is shown right away
+	m.closeDocument()
+	if cmd := m.openEntry(notes); cmd != nil || m.currentView != ViewDocument {
+		t.Errorf("reopening rendered again, view %v", m.currentView)
+	}
+
+	// A rendering finishing after another document was opened is kept,
+	// without switching to it
+	m.closeDocument()
+	other := entry("other.org")
+	cmd = m.openEntry(other)
+	parsed := cmdMsg[docParsedMsg](t, cmd)
+	cmd = m.handleDocParsed(parsed)
+	rendered := cmdMsg[docRenderedMsg](t, cmd)
+	m.openEntry(notes)
+	m.handleDocRendered(rendered)
+	if m.currentDoc != notes.OrgFile {
+		t.Errorf("late rendering of %s replaced the document opened since", other.Name)
+	}
+	if _, ok := renderCache.documents.Get(rendered.key); !ok {
+		t.Error("late rendering not cached")
+	}
+}
+
+func TestAnimationFramesReuseBuffers(t *testing.T) {
+	m := NewModel(createTestRenderer(), t.TempDir(), "", Options{})
+	m.width, m.height = 60, 20
+	content := strings.Repeat("\x1b[38;5;196mred\x1b[0m text, then 東京 and plain words\n", 20)
+
+	// Frames drawn into the buffer of the last one leave it intact
+	m.animValue = 0.5
+	first := m.applyWaveRipple(content)
+	kept := strings.Clone(first)
+	m.animValue = 0.6
+	if m.applyWaveRipple(content) == kept {
+		t.Error("wave frames at different times are the same")
+	}
+	if first != kept {
+		t.Error("drawing a wave frame changed the one before")
+	}
+	m.animValue = 0.5
+	if again := m.applyWaveRipple(content); again != kept {
+		t.Error("wave frame drawn again differs")
+	}
+	if allocs := testing.AllocsPerRun(10, func() { m.applyWaveRipple(content) }); allocs > 2 && !raceEnabled {
+		t.Errorf("wave frame allocates %v times, want at most 2", allocs)
+	}
+
+	// A poof splits its content into cells once, on its first frame
+	from, to := content, strings.Repeat("new content\n", 20)
+	m.applyPoofToViewport(from, to)
+	cells := m.frame.fromCells
+	m.animValue = 0.7
+	m.applyPoofToViewport(from, to)
+	if &m.frame.fromCells[0] != &cells[0] {
+		t.Error("poof content split again on its second frame")
+	}
+	if allocs := testing.AllocsPerRun(10, func() { m.applyPoofToViewport(from, to) }); allocs > 2 && !raceEnabled {
+		t.Errorf("poof frame allocates %v times, want at most 2", allocs)
+	}
+	m.stopAnimation()
+	if m.frame.fromCells != nil || m.frame.from != "" {
+		t.Error("poof content kept after the animation stopped")
+	}
+}
+
+func TestTooSmallNotice(t *testing.T) {
+	var model tea.Model = NewModel(createTestRenderer(), t.TempDir(), "", Options{})
+	resize := func(width, height int) string {
+		t.Helper()
+		model, _ = model.Update(tea.WindowSizeMsg{Width: width, Height: height})
+		return stripANSI(model.View())
+	}
+
+	for _, size := range [][2]int{{40, 10}, {59, 30}, {100, 19}, {8, 2}} {
+		view := resize(size[0], size[1])
+		lines := strings.Split(view, "\n")
+		if len(lines) > size[1] {
+			t.Errorf("%dx%d: notice is %d lines tall", size[0], size[1], len(lines))
+		}
+		for _, line := range lines {
+			if w := lipgloss.Width(line); w > size[0] {
+				t.Errorf("%dx%d: notice line %q is %d cells wide", size[0], size[1], line, w)
+			}
+		}
+		if size[0] >= 30 && size[1] >= 3 && (!strings.Contains(view, "Terminal too small") || !strings.Contains(view, fmt.Sprintf("(now %d×%d)", size[0], size[1]))) {
+			t.Errorf("%dx%d: no notice:\n%s", size[0], size[1], view)
+		}
+	}
+
+	// The UI comes back once the terminal is large enough
+	if view := resize(minTermWidth, minTermHeight); strings.Contains(view, "Terminal too small") {
+		t.Errorf("notice shown at the minimum size:\n%s", view)
+	}
+}
@@ -0,0 +1,89 @@
+package ui
+
+import (
+	"path/filepath"
+
+	"org-charm/org"
+)
+
+// listSection is a group of shortcut entries (pinned, recently viewed or
+// popular documents) shown above the file tree in the file list
+type listSection struct {
+	title string // Label shown above the entries
+	icon  string // Icon shown in place of the file icon
+	count int    // Number of entries
+}
+
+// shortcutCount returns the number of shortcut entries at the top of flatList
+func (m Model) shortcutCount() int {
+	n := 0
+	for _, s := range m.sections {
+		n += s.count
+	}
+	return n
+}
+
+// sectionAt returns the section flatList index i belongs to and the index
+// of the section's first entry, or -1 and the tree's first index for
+// entries of the file tree
+func (m Model) sectionAt(i int) (section, start int) {
+	for s, sec := range m.sections {
+		if i < start+sec.count {
+			return s, start
+		}
+		start += sec.count
+	}
+	return -1, start
+}
+
+// sectionLabelLines returns how many lines the section labels take up
+func (m Model) sectionLabelLines() int {
+	if m.shortcutCount() == 0 {
+		return 0
+	}
+	lines := 1 // "All files" label
+	for _, s := range m.sections {
+		if s.count > 0 {
+			lines += 2 // Label and blank line before the next one
+		}
+	}
+	return lines
+}
+
+// entriesForPaths maps paths relative to the org directory to entries of
+// the file tree, skipping files that are gone and those in exclude
+func (m Model) entriesForPaths(paths []string, limit int, exclude []*org.FileEntry) []*org.FileEntry {
+	var entries []*org.FileEntry
+	for _, rel := range paths {
+		if limit > 0 && len(entries) == limit {
+			break
+		}
+		path := filepath.Clean(filepath.Join(m.rootDir, filepath.FromSlash(rel)))
+		entry := findEntryByPath(m.fileTree, path)
+		if entry == nil || containsEntry(exclude, entry) {
+			continue
+		}
+		entries = append(entries, entry)
+	}
+	return entries
+}
+
+// containsEntry reports whether entries contains e
+func containsEntry(entries []*org.FileEntry, e *org.FileEntry) bool {
+	for _, x := range entries {
+		if x == e {
+			return true
+		}
+	}
+	return false
+}
+
+// relPath returns path relative to the org directory, in the slash
+// separated form used in the state store
+func (m Model) relPath(path string) (string, bool) {
+	rel, err := filepath.Rel(m.rootDir, path)
+	if err != nil {
+		return "", false
+	}
+	return filepath.ToSlash(rel), true
+}
@@ -0,0 +1,168 @@
+package ui
+
+import (
+	"path/filepath"
+	"time"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/log"
+
+	"org-charm/org"
+	"org-charm/state"
+)
+
+// sessionSaveDelay is how long the UI state has to settle before it is
+// saved, so scrolling doesn't write the state file on every line
+const sessionSaveDelay = time.Second
+
+// saveSessionMsg saves the UI state if no newer change was scheduled
+type saveSessionMsg int
+
+// session returns the current UI state to persist
+func (m Model) session() state.Session {
+	s := state.Session{Sort: m.sortOrder.String()}
+	if m.currentView == ViewDocument && m.currentDoc != nil {
+		if rel, ok := m.relPath(m.currentDoc.Path); ok {
+			s.Document = rel
+			s.Raw = m.rawView
+			s.Offset = m.viewport.YOffset
+		}
+	}
+	if len(m.flatList) > 0 && m.selectedIndex >= m.shortcutCount() {
+		s.Selected = filepath.ToSlash(m.flatList[m.selectedIndex].RelPath)
+	}
+	var walk func(entries []*org.FileEntry)
+	walk = func(entries []*org.FileEntry) {
+		for _, e := range entries {
+			if e.IsDir && e.Expanded {
+				s.Expanded = append(s.Expanded, filepath.ToSlash(e.RelPath))
+				walk(e.Children)
+			}
+		}
+	}
+	walk(m.fileTree)
+	return s
+}
+
+// preferences returns the viewing preferences of the session to persist
+func (m Model) preferences() state.Preferences {
+	return state.Preferences{
+		Motion:        m.motion.String(),
+		ZebraTables:   m.zebraTables,
+		ShowHeaders:   m.showHeaders,
+		LineNumbers:   m.lineNumbers,
+		NoWrap:        m.noWrap,
+		ReadingColumn: m.readingColumn,
+	}
+}
+
+// applyPreferences takes on the viewing preferences a user saved
+func (m *Model) applyPreferences(p state.Preferences) {
+	if motion, err := ParseMotion(p.Motion); err == nil {
+		m.motion = motion
+	}
+	m.zebraTables = p.ZebraTables
+	m.showHeaders = p.ShowHeaders
+	m.lineNumbers = p.LineNumbers
+	m.noWrap = p.NoWrap
+	m.readingColumn = p.ReadingColumn
+}
+
+// scheduleSessionSave returns the command saving the UI state once it
+// settles, or nil if it didn't change since it was last saved
+func (m *Model) scheduleSessionSave() tea.Cmd {
+	if m.savedSession.Equal(m.session()) && m.savedPreferences == m.preferences() {
+		return nil
+	}
+	m.sessionSaveID++
+	id := m.sessionSaveID
+	return tea.Tick(sessionSaveDelay, func(time.Time) tea.Msg {
+		return saveSessionMsg(id)
+	})
+}
+
+// saveSession persists the current UI state for the next connection, and
+// the viewing preferences once they're changed
+func (m *Model) saveSession() {
+	session := m.session()
+	preferences := m.preferences()
+	changed := preferences != m.savedPreferences
+	err := m.store.Update(m.userID, func(u *state.User) {
+		u.Session = &session
+		if changed {
+			u.Preferences = &preferences
+		}
+	})
+	if err != nil {
+		log.Error("Failed to save session state", "error", err)
+	}
+	m.savedSession = session
+	m.savedPreferences = preferences
+}
+
+// restoreSession returns to the state of the user's previous session: the
+// viewing preferences, the sort order, expanded directories and selection
+// of the file list, and the open document with its scroll position. It
+// needs the viewport, so it runs on the first window size message.
+func (m *Model) restoreSession() tea.Cmd {
+	user := m.store.Get(m.userID)
+	if user.Preferences != nil {
+		m.applyPreferences(*user.Preferences)
+	}
+	m.savedPreferences = m.preferences()
+	saved := user.Session
+	if saved == nil {
+		return nil
+	}
+	m.savedSession = *saved
+
+	if order, err := org.ParseSortOrder(saved.Sort); err == nil && saved.Sort != "" {
+		m.sortOrder = order
+		org.SortTree(m.fileTree, order)
+	}
+
+	expanded := make(map[string]bool, len(saved.Expanded))
+	for _, rel := range saved.Expanded {
+		expanded[rel] = true
+	}
+	var walk func(entries []*org.FileEntry)
+	walk = func(entries []*org.FileEntry) {
+		for _, e := range entries {
+			if e.IsDir {
+				e.Expanded = expanded[filepath.ToSlash(e.RelPath)]
+				walk(e.Children)
+			}
+		}
+	}
+	walk(m.fileTree)
+	m.refreshFlatList()
+	m.collectOrgFiles()
+
+	for i := m.shortcutCount(); i < len(m.flatList); i++ {
+		if filepath.ToSlash(m.flatList[i].RelPath) == saved.Selected {
+			m.selectedIndex = i
+			break
+		}
+	}
+	m.ensureSelectedVisible()
+
+	if saved.Document == "" {
+		return nil
+	}
+	entry := findEntryByPath(m.fileTree, filepath.Join(m.rootDir, filepath.FromSlash(saved.Document)))
+	if entry == nil {
+		return nil
+	}
+	orgFile, err := entry.GetOrgFile()
+	if err != nil {
+		return nil
+	}
+	m.openDocument(orgFile)
+	if saved.Raw {
+		m.rawView = true
+		m.refreshDocument()
+	}
+	m.renderAhead(saved.Offset + m.viewport.Height)
+	m.viewport.SetYOffset(saved.Offset)
+	return m.setStatus("Resumed where you left off")
+}
@@ -0,0 +1,48 @@
+package ui
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"org-charm/org"
+	"org-charm/state"
+)
+
+func TestPersistedMarksAndPreferences(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "notes.org")
+	if err := os.WriteFile(path, []byte("* Notes\n"+strings.Repeat("Text.\n\n", 60)), 0644); err != nil {
+		t.Fatal(err)
+	}
+	store, _ := state.NewStore("")
+	open := func() Model {
+		t.Helper()
+		m := NewModel(createTestRenderer(), dir, "", Options{Store: store, UserID: "ada"})
+		m.width, m.height = 80, 24
+		m.resizeViewport()
+		m.restoreSession()
+		doc, err := org.Load(path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		m.openDocument(doc)
+		return m
+	}
+
+	m := open()
+	m.viewport.SetYOffset(12)
+	m.setMark('a')
+	m.zebraTables = true
+	m.saveSession()
+
+	// The next session of the user starts with them
+	m = open()
+	if !m.zebraTables {
+		t.Error("striped tables preference not restored")
+	}
+	if line, ok := m.markSet()['a']; !ok || line != 12 {
+		t.Errorf("mark a in a new session = %d, %v, want 12", line, ok)
+	}
+}
@@ -2,7 +2,9 @@ package ui
 
 import (
 	"bytes"
+	"flag"
 	"os"
+	"path/filepath"
 	"strings"
 	"testing"
 
@@ -177,3 +179,49 @@ Paragraph with /italic/ text.
 		t.Error("Italic code missing from Model output")
 	}
 }
+
+// updateGolden rewrites the golden files with the current rendering
+// Run with: go test ./ui/... -run TestGolden -update
+var updateGolden = flag.Bool("update", false, "rewrite the golden files of TestGolden")
+
+// renderTestdata renders the org file at path as a document at width 80
+func renderTestdata(tb testing.TB, path string) string {
+	tb.Helper()
+	source, err := os.ReadFile(path)
+	if err != nil {
+		tb.Fatal(err)
+	}
+	doc := goorg.New().Parse(bytes.NewReader(source), path)
+	renderer := NewRenderer(NewStyles(createTestRenderer()), 80)
+	renderer.SetSource(string(source), doc.Nodes)
+	return renderer.RenderDocument(doc.Nodes)
+}
+
+// TestGolden compares the rendering of each testdata/render/*.org file,
+// without colors, to the .golden file next to it
+func TestGolden(t *testing.T) {
+	paths, err := filepath.Glob(filepath.Join("testdata", "render", "*.org"))
+	if err != nil || len(paths) == 0 {
+		t.Fatalf("no testdata: %v", err)
+	}
+	for _, path := range paths {
+		name := strings.TrimSuffix(filepath.Base(path), ".org")
+		t.Run(name, func(t *testing.T) {
+			got := stripANSI(renderTestdata(t, path))
+			golden := strings.TrimSuffix(path, ".org") + ".golden"
+			if *updateGolden {
+				if err := os.WriteFile(golden, []byte(got), 0o644); err != nil {
+					t.Fatal(err)
+				}
+				return
+			}
+			want, err := os.ReadFile(golden)
+			if err != nil {
+				t.Fatalf("%v (run with -update to create it)", err)
+			}
+			if got != string(want) {
+				t.Errorf("rendering differs from %s (run with -update to accept it):\n%s", golden, got)
+			}
+		})
+	}
+}
@@ -0,0 +1,122 @@
+package ui
+
+import (
+	"strings"
+
+	"github.com/charmbracelet/x/ansi"
+)
+
+// splitKey shows the org source beside the rendered document
+const splitKey = "|"
+
+// splitGap separates the source pane from the rendered document
+const splitGap = " │ "
+
+// splitAnchor pairs a rendered line with the source line it came from
+type splitAnchor struct {
+	rendered int
+	source   int
+}
+
+// splitActive reports whether the document is shown beside its source
+func (m Model) splitActive() bool {
+	return m.splitView && m.currentView == ViewDocument && !m.rawView
+}
+
+// splitPaneWidth returns the width of the source pane, or 0 when the
+// split view is off
+func (m Model) splitPaneWidth() int {
+	if !m.splitActive() {
+		return 0
+	}
+	return max(0, (m.width-5-len([]rune(splitGap)))/2)
+}
+
+// splitWidth returns the columns taken from the document by the source
+// pane and the gap after it
+func (m Model) splitWidth() int {
+	if !m.splitActive() {
+		return 0
+	}
+	return m.splitPaneWidth() + len([]rune(splitGap))
+}
+
+// resizeViewport fits the viewport beside the source pane, if shown
+func (m *Model) resizeViewport() {
+	m.viewport.Width = m.width - 5 - m.splitWidth()
+}
+
+// toggleSplit shows or hides the source beside the rendered document
+func (m *Model) toggleSplit() {
+	if m.currentView != ViewDocument || m.rawView {
+		return
+	}
+	m.splitView = !m.splitView
+	m.resizeViewport()
+	m.refreshDocument()
+}
+
+// updateSplit prepares the source pane for the rendered document: its
+// lines and the anchors its scrolling follows, the start and end of the
+// document and each headline
+func (m *Model) updateSplit() {
+	if !m.splitActive() {
+		m.splitRaw, m.splitAnchors = nil, nil
+		return
+	}
+	m.splitRaw = strings.Split(m.renderRaw(m.currentDoc), "\n")
+
+	anchors := []splitAnchor{{0, 0}}
+	sources := m.currentDoc.HeadlineLines()
+	for _, h := range m.docHeadings {
+		if h.Index >= len(sources) {
+			break
+		}
+		last := anchors[len(anchors)-1]
+		if h.Line > last.rendered && sources[h.Index] > last.source {
+			anchors = append(anchors, splitAnchor{h.Line, sources[h.Index]})
+		}
+	}
+	end := splitAnchor{len(m.docLines) - 1, len(m.splitRaw) - 1}
+	if last := anchors[len(anchors)-1]; end.rendered > last.rendered && end.source > last.source {
+		anchors = append(anchors, end)
+	}
+	m.splitAnchors = anchors
+}
+
+// splitOffset returns the source line to show at the top of the source
+// pane when the rendered document is scrolled to line, interpolating
+// between the anchors around it
+func splitOffset(anchors []splitAnchor, line int) int {
+	if len(anchors) == 0 {
+		return 0
+	}
+	i := len(anchors) - 1
+	for i > 0 && line < anchors[i].rendered {
+		i--
+	}
+	a := anchors[i]
+	if i == len(anchors)-1 {
+		return max(0, a.source+line-a.rendered)
+	}
+	b := anchors[i+1]
+	return a.source + (line-a.rendered)*(b.source-a.source)/(b.rendered-a.rendered)
+}
+
+// renderSplitPane renders the source pane, scrolled along with the
+// viewport
+func (m Model) renderSplitPane() string {
+	width, height := m.splitPaneWidth(), m.viewport.Height
+	offset := min(splitOffset(m.splitAnchors, m.viewport.YOffset), max(0, len(m.splitRaw)-height))
+
+	gap := m.styles.ScrollTrack.Render(splitGap)
+	lines := make([]string, height)
+	for i := range lines {
+		var line string
+		if n := offset + i; n < len(m.splitRaw) {
+			line = ansi.Truncate(m.splitRaw[n], width, "…")
+		}
+		lines[i] = line + strings.Repeat(" ", max(0, width-ansi.StringWidth(line))) + gap
+	}
+	return strings.Join(lines, "\n")
+}
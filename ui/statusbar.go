@@ -0,0 +1,83 @@
+package ui
+
+import (
+	"fmt"
+	"path/filepath"
+	"strings"
+	"time"
+
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// DefaultStatusTemplate is the status bar template used when none is configured
+const DefaultStatusTemplate = "{scroll}"
+
+// statusTokens lists the tokens available in status bar templates
+var statusTokens = []string{
+	"{file}",    // File name of the open document
+	"{title}",   // Document title
+	"{scroll}",  // Scroll position as a percentage
+	"{words}",   // Word count of the document, excluding code
+	"{reading}", // Estimated reading time
+	"{heading}", // Heading at the top of the viewport
+	"{time}",    // Current server time (HH:MM)
+}
+
+// renderStatusText expands the configured status bar template for the
+// open document
+func (m Model) renderStatusText() string {
+	tmpl := m.options.StatusTemplate
+	if tmpl == "" {
+		tmpl = DefaultStatusTemplate
+	}
+
+	// Only compute the values the template uses
+	values := make([]string, 0, len(statusTokens)*2)
+	for _, token := range statusTokens {
+		if !strings.Contains(tmpl, token) {
+			continue
+		}
+		values = append(values, token, m.statusValue(token))
+	}
+	return strings.NewReplacer(values...).Replace(tmpl)
+}
+
+// statusValue returns the current value for a status bar token
+func (m Model) statusValue(token string) string {
+	switch token {
+	case "{file}":
+		return filepath.Base(m.currentDoc.Path)
+	case "{title}":
+		return m.currentDoc.Title()
+	case "{scroll}":
+		return fmt.Sprintf("%3.f%%", m.viewport.ScrollPercent()*100)
+	case "{words}":
+		return fmt.Sprint(m.currentDoc.Stats().Words)
+	case "{reading}":
+		return fmt.Sprintf("%d min", m.currentDoc.Stats().ReadingMinutes())
+	case "{heading}":
+		if idx := HeadingAt(m.docHeadings, m.viewport.YOffset); idx >= 0 {
+			return m.docHeadings[idx].Title
+		}
+		return m.currentDoc.Title()
+	case "{time}":
+		return time.Now().Format("15:04")
+	}
+	return token
+}
+
+// clockMsg comes on the minute, for status bars showing the time to be
+// drawn again
+type clockMsg struct{}
+
+// clockTick returns the command waking the session up on the next minute
+// when the status bar shows {time}, or nil: nothing else redraws it while
+// the session is idle
+func (m Model) clockTick() tea.Cmd {
+	if !strings.Contains(m.options.StatusTemplate, "{time}") {
+		return nil
+	}
+	return tea.Every(time.Minute, func(time.Time) tea.Msg {
+		return clockMsg{}
+	})
+}
@@ -1,6 +1,8 @@
 package ui
 
 import (
+	"sync"
+
 	"github.com/charmbracelet/lipgloss"
 )
 
@@ -19,6 +21,7 @@ type Styles struct {
 	FileItemActive   lipgloss.Style
 	FileDir          lipgloss.Style
 	FileMeta         lipgloss.Style
+	Preview          lipgloss.Style
 
 	// Document metadata
 	DocTitle  lipgloss.Style
@@ -31,6 +34,9 @@ type Styles struct {
 	Heading3 lipgloss.Style
 	Heading4 lipgloss.Style
 
+	// Subtrees excluded from export, when shown
+	Excluded lipgloss.Style
+
 	// TODO/DONE states
 	Todo     lipgloss.Style
 	Done     lipgloss.Style
@@ -53,16 +59,41 @@ type Styles struct {
 	BlockHeader lipgloss.Style
 	CodeBlock   lipgloss.Style
 	Example     lipgloss.Style
+	LineNumber  lipgloss.Style
+	CodeFold    lipgloss.Style // "… n more lines" of a folded code block
+
+	// LaTeX environments and export blocks, framed with a labeled border
+	LatexBlock lipgloss.Style
+	LatexLabel lipgloss.Style
+
+	// ChromaStyle is the syntax highlighting style matching the palette
+	ChromaStyle string
 
 	// Quotes and verse
 	Quote  lipgloss.Style
 	Verse  lipgloss.Style
 	Center lipgloss.Style
 
+	// Admonition blocks (NOTE, TIP, ...), colored per kind
+	Admonition       lipgloss.Style
+	AdmonitionTitle  lipgloss.Style
+	AdmonitionColors map[string]lipgloss.Color
+
 	// Tables
-	TableBorder lipgloss.Style
-	TableHeader lipgloss.Style
-	TableCell   lipgloss.Style
+	TableBorder  lipgloss.Style
+	TableHeader  lipgloss.Style
+	TableCell    lipgloss.Style
+	TableCellAlt lipgloss.Style // Alternate body rows of striped tables
+
+	// Placeholder of an image the terminal can't draw
+	ImageFrame lipgloss.Style
+
+	// Captions of tables, figures and listings, and their "Table 1:" labels
+	Caption      lipgloss.Style
+	CaptionLabel lipgloss.Style
+
+	// Title of a table of contents placed with #+TOC:
+	TOCTitle lipgloss.Style
 
 	// Inline formatting
 	Bold          lipgloss.Style
@@ -72,14 +103,19 @@ type Styles struct {
 	Verbatim      lipgloss.Style
 	InlineCode    lipgloss.Style
 	Link          lipgloss.Style
+	LinkActive    lipgloss.Style
 
 	// Other elements
 	HRule           lipgloss.Style
 	Keyword         lipgloss.Style
 	KeywordValue    lipgloss.Style
 	DrawerHeader    lipgloss.Style
+	DrawerSummary   lipgloss.Style // Ellipsis of a collapsed drawer
+	LogMarker       lipgloss.Style // Bullet of a LOGBOOK entry
+	LogTime         lipgloss.Style // Time of a LOGBOOK entry
 	Property        lipgloss.Style
 	Timestamp       lipgloss.Style
+	TimestampInactive lipgloss.Style
 	Footnote           lipgloss.Style
 	FootnoteLabel      lipgloss.Style
 	FootnoteContent    lipgloss.Style
@@ -100,6 +136,45 @@ type Styles struct {
 	// Help/hints
 	HelpKey  lipgloss.Style
 	HelpText lipgloss.Style
+
+	// Overlays
+	Popup    lipgloss.Style
+	Notice    lipgloss.Style
+	KeyHints  lipgloss.Style
+	Selection lipgloss.Style
+
+	// Scrollbar
+	ScrollTrack lipgloss.Style
+	ScrollThumb lipgloss.Style
+
+	// Styles set to the widths they were used at (see Sized), shared by the
+	// renderers of the session
+	sizedMu sync.Mutex
+	sized   map[sizedKey]lipgloss.Style
+}
+
+// sizedKey identifies a style of Styles set to a width
+type sizedKey struct {
+	style *lipgloss.Style
+	width int
+}
+
+// Sized returns style, one of the styles of s, set to width. Styles are
+// set to a width once and kept, as documents are laid out at the same few
+// widths over and over.
+func (s *Styles) Sized(style *lipgloss.Style, width int) lipgloss.Style {
+	key := sizedKey{style, width}
+	s.sizedMu.Lock()
+	defer s.sizedMu.Unlock()
+	sized, ok := s.sized[key]
+	if !ok {
+		if s.sized == nil {
+			s.sized = map[sizedKey]lipgloss.Style{}
+		}
+		sized = style.Width(width)
+		s.sized[key] = sized
+	}
+	return sized
 }
 
 // Colors - a cohesive palette
@@ -189,6 +264,12 @@ func NewStyles(r *lipgloss.Renderer) *Styles {
 		Foreground(colorSubtle).
 		Italic(true)
 
+	s.Preview = r.NewStyle().
+		MarginLeft(1).
+		Border(lipgloss.NormalBorder(), false, false, false, true).
+		BorderForeground(colorSubtle).
+		PaddingLeft(2)
+
 	// ═══════════════════════════════════════════════════════════════════
 	// Document Metadata
 	// ═══════════════════════════════════════════════════════════════════
@@ -231,6 +312,10 @@ func NewStyles(r *lipgloss.Renderer) *Styles {
 	s.Heading4 = r.NewStyle().
 		Foreground(colorH4)
 
+	s.Excluded = r.NewStyle().
+		Foreground(colorSubtle).
+		Faint(true)
+
 	// ═══════════════════════════════════════════════════════════════════
 	// TODO States
 	// ═══════════════════════════════════════════════════════════════════
@@ -304,6 +389,24 @@ func NewStyles(r *lipgloss.Renderer) *Styles {
 		MarginTop(0).
 		MarginBottom(0)
 
+	s.LineNumber = r.NewStyle().
+		Foreground(colorSubtle)
+
+	s.CodeFold = r.NewStyle().
+		Foreground(colorHighlight).
+		Italic(true)
+
+	s.LatexBlock = r.NewStyle().
+		Border(lipgloss.RoundedBorder()).
+		BorderForeground(colorSubtle).
+		Foreground(colorSubtle).
+		Padding(0, 1)
+
+	s.LatexLabel = r.NewStyle().
+		Foreground(colorSubtle)
+
+	s.ChromaStyle = "tokyonight-storm"
+
 	s.Example = r.NewStyle().
 		Background(lipgloss.Color("#1f2335")).
 		Foreground(colorCyan).
@@ -336,6 +439,21 @@ func NewStyles(r *lipgloss.Renderer) *Styles {
 		Foreground(colorFg).
 		Align(lipgloss.Center)
 
+	s.Admonition = r.NewStyle().
+		BorderStyle(lipgloss.RoundedBorder()).
+		Padding(0, 1)
+
+	s.AdmonitionTitle = r.NewStyle().
+		Bold(true)
+
+	s.AdmonitionColors = map[string]lipgloss.Color{
+		"NOTE":      colorBlue,
+		"TIP":       colorGreen,
+		"IMPORTANT": colorMagenta,
+		"WARNING":   colorYellow,
+		"CAUTION":   colorRed,
+	}
+
 	// ═══════════════════════════════════════════════════════════════════
 	// Tables
 	// ═══════════════════════════════════════════════════════════════════
@@ -351,6 +469,27 @@ func NewStyles(r *lipgloss.Renderer) *Styles {
 	s.TableCell = r.NewStyle().
 		Foreground(colorFg)
 
+	s.TableCellAlt = r.NewStyle().
+		Foreground(colorFg).
+		Background(lipgloss.Color("#1f2335"))
+
+	s.ImageFrame = r.NewStyle().
+		Border(lipgloss.RoundedBorder()).
+		BorderForeground(colorSubtle).
+		Padding(0, 1)
+
+	s.Caption = r.NewStyle().
+		Foreground(colorSubtle).
+		Italic(true)
+
+	s.CaptionLabel = r.NewStyle().
+		Bold(true).
+		Foreground(colorAccent)
+
+	s.TOCTitle = r.NewStyle().
+		Bold(true).
+		Foreground(colorAccent)
+
 	// ═══════════════════════════════════════════════════════════════════
 	// Inline Formatting - distinct colors for visibility
 	// ═══════════════════════════════════════════════════════════════════
@@ -383,6 +522,11 @@ func NewStyles(r *lipgloss.Renderer) *Styles {
 		Foreground(colorBlue).
 		Underline(true)
 
+	s.LinkActive = r.NewStyle().
+		Foreground(colorBg).
+		Background(colorBlue).
+		Bold(true)
+
 	// ═══════════════════════════════════════════════════════════════════
 	// Other Elements
 	// ═══════════════════════════════════════════════════════════════════
@@ -400,6 +544,15 @@ func NewStyles(r *lipgloss.Renderer) *Styles {
 		Foreground(colorSubtle).
 		Italic(true)
 
+	s.DrawerSummary = r.NewStyle().
+		Foreground(colorHighlight)
+
+	s.LogMarker = r.NewStyle().
+		Foreground(colorAccent)
+
+	s.LogTime = r.NewStyle().
+		Foreground(colorSubtle)
+
 	s.Property = r.NewStyle().
 		Foreground(colorSubtle)
 
@@ -408,6 +561,9 @@ func NewStyles(r *lipgloss.Renderer) *Styles {
 		Background(lipgloss.Color("#24283b")).
 		Padding(0, 1)
 
+	s.TimestampInactive = r.NewStyle().
+		Foreground(colorSubtle)
+
 	s.Footnote = r.NewStyle().
 		Foreground(colorYellow)
 
@@ -489,5 +645,33 @@ func NewStyles(r *lipgloss.Renderer) *Styles {
 	s.HelpText = r.NewStyle().
 		Foreground(colorSubtle)
 
+	// ═══════════════════════════════════════════════════════════════════
+	// Overlays
+	// ═══════════════════════════════════════════════════════════════════
+
+	s.Popup = r.NewStyle().
+		Border(lipgloss.RoundedBorder()).
+		BorderForeground(colorHighlight).
+		Padding(1, 2)
+
+	s.Notice = r.NewStyle().
+		Foreground(colorGreen).
+		Italic(true)
+
+	s.KeyHints = r.NewStyle().
+		Border(lipgloss.RoundedBorder()).
+		BorderForeground(colorSubtle).
+		Padding(0, 1)
+
+	s.Selection = r.NewStyle().
+		Foreground(colorFg).
+		Background(lipgloss.Color("#33467c"))
+
+	s.ScrollTrack = r.NewStyle().
+		Foreground(lipgloss.Color("#292e42"))
+
+	s.ScrollThumb = r.NewStyle().
+		Foreground(colorHighlight)
+
 	return s
 }
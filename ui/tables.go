@@ -0,0 +1,152 @@
+package ui
+
+import (
+	"strings"
+
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// tableFocusKey starts moving a row cursor through the table in view
+const tableFocusKey = "t"
+
+// tableCursor is the row highlighted in row focus mode
+type tableCursor struct {
+	active bool
+	table  int // Index in docTables
+	row    int // Index in the table's body rows
+}
+
+// valid reports whether the cursor still points at a row of tables
+func (c tableCursor) valid(tables []TableRef) bool {
+	return c.table < len(tables) && c.row < len(tables[c.table].Rows)
+}
+
+// tableInView returns the index of the first table with body rows visible
+// in the viewport, or -1 if there is none
+func (m Model) tableInView() int {
+	top, bottom := m.viewport.YOffset, m.viewport.YOffset+m.viewport.Height
+	for i, table := range m.docTables {
+		if len(table.Rows) > 0 && table.Line < bottom && table.Line+table.Height > top {
+			return i
+		}
+	}
+	return -1
+}
+
+// handleTableKey handles row focus mode in the document view: t focuses
+// the table in view, j and k move the row cursor, y copies the row and esc
+// or t leave. It reports whether the key was consumed.
+func (m *Model) handleTableKey(key string) (tea.Cmd, bool) {
+	if m.currentView != ViewDocument || m.rawView || m.visual.active {
+		return nil, false
+	}
+	if !m.tableRow.active {
+		// A pending mark or count owns the key (as in mt)
+		if key != tableFocusKey || m.animType != AnimNone || m.keys != (keySequence{}) {
+			return nil, false
+		}
+		i := m.tableInView()
+		if i < 0 {
+			return func() tea.Msg {
+				return statusMsg("No table in view")
+			}, true
+		}
+		// Start on the first row in view
+		table := m.docTables[i]
+		row := 0
+		for row < len(table.Rows)-1 && table.Line+table.Rows[row].Offset < m.viewport.YOffset {
+			row++
+		}
+		m.tableRow = tableCursor{active: true, table: i}
+		m.moveTableRow(row)
+		return nil, true
+	}
+
+	switch key {
+	case tableFocusKey, "esc":
+		m.tableRow = tableCursor{}
+	case "j", "down":
+		m.moveTableRow(m.tableRow.row + 1)
+	case "k", "up":
+		m.moveTableRow(m.tableRow.row - 1)
+	case "g", "home":
+		m.moveTableRow(0)
+	case "G", "end":
+		m.moveTableRow(len(m.docTables[m.tableRow.table].Rows) - 1)
+	case "y":
+		return m.yankTableRow(), true
+	case "q", "ctrl+c":
+		return nil, false
+	}
+	// Other commands would move the table away from the cursor
+	return nil, true
+}
+
+// moveTableRow moves the row cursor to row, scrolling it into view
+func (m *Model) moveTableRow(row int) {
+	table := m.docTables[m.tableRow.table]
+	row = max(0, min(len(table.Rows)-1, row))
+	m.tableRow.row = row
+
+	top := table.Line + table.Rows[row].Offset
+	bottom := top + table.Rows[row].Height
+	if top < m.viewport.YOffset {
+		m.viewport.SetYOffset(top)
+	} else if bottom > m.viewport.YOffset+m.viewport.Height {
+		m.viewport.SetYOffset(bottom - m.viewport.Height)
+	}
+}
+
+// tableRowLines returns the first and last document line of the row
+// under the cursor
+func (m Model) tableRowLines() (int, int) {
+	table := m.docTables[m.tableRow.table]
+	span := table.Rows[m.tableRow.row]
+	first := table.Line + span.Offset
+	return first, first + span.Height - 1
+}
+
+// tableRowCells returns the text of each cell of the row under the
+// cursor, joining the lines of wrapped cells
+func (m Model) tableRowCells() []string {
+	first, last := m.tableRowLines()
+	var cells []string
+	for n := first; n <= last && n < len(m.docLines); n++ {
+		parts := strings.Split(strings.TrimSpace(stripANSI(m.docLines[n])), "│")
+		if len(parts) < 2 {
+			continue
+		}
+		for i, part := range parts[1 : len(parts)-1] {
+			part = strings.TrimSpace(part)
+			switch {
+			case i >= len(cells):
+				cells = append(cells, part)
+			case part != "":
+				cells[i] = strings.TrimSpace(cells[i] + " " + part)
+			}
+		}
+	}
+	return cells
+}
+
+// yankTableRow copies the cells of the row under the cursor, separated
+// by tabs so they paste into spreadsheets
+func (m Model) yankTableRow() tea.Cmd {
+	return m.copyToClipboard(strings.Join(m.tableRowCells(), "\t"), "table row")
+}
+
+// highlightTableRow paints the lines of the row under the cursor in the
+// viewport view
+func (m Model) highlightTableRow(view string) string {
+	if !m.tableRow.active {
+		return view
+	}
+	first, last := m.tableRowLines()
+	lines := strings.Split(view, "\n")
+	for i, line := range lines {
+		if n := m.viewport.YOffset + i; n >= first && n <= last {
+			lines[i] = m.styles.Selection.Render(stripANSI(line))
+		}
+	}
+	return strings.Join(lines, "\n")
+}
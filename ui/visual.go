@@ -0,0 +1,124 @@
+package ui
+
+import (
+	"fmt"
+	"strings"
+
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// visualSelection is a range of document lines selected in visual mode.
+// The anchor stays where the selection started and the cursor follows
+// the motion keys.
+type visualSelection struct {
+	active bool
+	anchor int
+	cursor int
+}
+
+// bounds returns the first and last selected line
+func (v visualSelection) bounds() (int, int) {
+	return min(v.anchor, v.cursor), max(v.anchor, v.cursor)
+}
+
+// handleVisualKey handles visual line selection in the document view: v
+// starts selecting at the top line, motions extend the selection, o jumps
+// to its other end and y copies it. It reports whether the key was
+// consumed; motion keys are left to handleMotion.
+func (m *Model) handleVisualKey(key string) (tea.Cmd, bool) {
+	if m.currentView != ViewDocument {
+		return nil, false
+	}
+	if !m.visual.active {
+		if key == "v" && m.animType == AnimNone {
+			m.visual = visualSelection{active: true, anchor: m.viewport.YOffset, cursor: m.viewport.YOffset}
+			if m.linkIndex >= 0 {
+				m.linkIndex = -1
+				m.refreshDocument()
+			}
+			return nil, true
+		}
+		return nil, false
+	}
+
+	switch key {
+	case "v", "esc":
+		m.visual = visualSelection{}
+		return nil, true
+	case "o":
+		m.visual.anchor, m.visual.cursor = m.visual.cursor, m.visual.anchor
+		m.moveVisualCursor(m.visual.cursor)
+		return nil, true
+	case "y":
+		return m.yankSelection(), true
+	}
+	return nil, false
+}
+
+// moveVisualCursor moves the end of the selection to line, scrolling it
+// into view
+func (m *Model) moveVisualCursor(line int) {
+	line = max(0, min(len(m.docLines)-1, line))
+	m.visual.cursor = line
+	if line < m.viewport.YOffset {
+		m.viewport.SetYOffset(line)
+	} else if line >= m.viewport.YOffset+m.viewport.Height {
+		m.viewport.SetYOffset(line - m.viewport.Height + 1)
+	}
+}
+
+// selectedText returns the selected lines without styling, with their
+// common indentation and trailing spaces removed
+func (m Model) selectedText() string {
+	first, last := m.visual.bounds()
+	if len(m.docLines) == 0 {
+		return ""
+	}
+	last = min(last, len(m.docLines)-1)
+
+	lines := make([]string, 0, last-first+1)
+	indent := -1
+	for _, line := range m.docLines[first : last+1] {
+		line = strings.TrimRight(stripANSI(line), " ")
+		lines = append(lines, line)
+		if trimmed := strings.TrimLeft(line, " "); trimmed != "" {
+			n := len(line) - len(trimmed)
+			if indent < 0 || n < indent {
+				indent = n
+			}
+		}
+	}
+	for i, line := range lines {
+		if len(line) >= indent && indent > 0 {
+			lines[i] = line[indent:]
+		}
+	}
+	return strings.Join(lines, "\n")
+}
+
+// yankSelection copies the selected lines and leaves visual mode
+func (m *Model) yankSelection() tea.Cmd {
+	first, last := m.visual.bounds()
+	text := m.selectedText()
+	m.visual = visualSelection{}
+	what := "1 line"
+	if n := last - first + 1; n != 1 {
+		what = fmt.Sprintf("%d lines", n)
+	}
+	return m.copyToClipboard(text, what)
+}
+
+// highlightSelection paints the selected lines of the viewport view
+func (m Model) highlightSelection(view string) string {
+	if !m.visual.active {
+		return view
+	}
+	first, last := m.visual.bounds()
+	lines := strings.Split(view, "\n")
+	for i, line := range lines {
+		if n := m.viewport.YOffset + i; n >= first && n <= last {
+			lines[i] = m.styles.Selection.Render(stripANSI(line))
+		}
+	}
+	return strings.Join(lines, "\n")
+}
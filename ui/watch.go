@@ -0,0 +1,68 @@
+package ui
+
+import (
+	"path/filepath"
+	"time"
+
+	"org-charm/org"
+
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// watchInterval is how often the open document is checked for changes
+const watchInterval = 2 * time.Second
+
+// watchMsg comes back from a check of the open document that found it
+// unchanged, or no document open
+type watchMsg struct{}
+
+// docChangedMsg reports the open document doc changed on disk and was
+// parsed again as file
+type docChangedMsg struct {
+	doc  *org.OrgFile
+	file *org.OrgFile
+}
+
+// watchDocument returns the command checking the open document for
+// changes after a while, unless an older version of it is shown. The
+// check and parsing happen in the background; the session keeps checking
+// for as long as it runs.
+func (m Model) watchDocument() tea.Cmd {
+	doc := m.currentDoc
+	if m.revision != nil {
+		// Older versions don't change
+		doc = nil
+	}
+	return tea.Tick(watchInterval, func(time.Time) tea.Msg {
+		if doc == nil || !doc.Changed() {
+			return watchMsg{}
+		}
+		file, err := org.Load(doc.Path)
+		if err != nil {
+			// Removed or unreadable for now; keep showing what was read
+			return watchMsg{}
+		}
+		return docChangedMsg{doc, file}
+	})
+}
+
+// handleDocChanged shows the new version of the open document in place,
+// at the same scroll position, unless another document was opened since
+// it was checked
+func (m *Model) handleDocChanged(msg docChangedMsg) tea.Cmd {
+	if msg.doc != m.currentDoc {
+		return nil
+	}
+	if entry := findEntryByPath(m.fileTree, filepath.Clean(msg.file.Path)); entry != nil {
+		entry.OrgFile = msg.file
+	}
+
+	offset := m.viewport.YOffset
+	m.currentDoc = msg.file
+	m.linkIndex = -1
+	m.visual = visualSelection{}
+	m.refreshDocument()
+	m.renderAhead(offset + m.viewport.Height)
+	m.viewport.SetYOffset(offset)
+	return m.setStatus("Document updated")
+}
@@ -0,0 +1,52 @@
+package ui
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"org-charm/org"
+)
+
+func TestWatchReloadsDocument(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "notes.org")
+	if err := os.WriteFile(path, []byte("* Notes\nFirst draft.\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	doc, err := org.Load(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	m := NewModel(createTestRenderer(), dir, "", Options{})
+	m.width, m.height = 80, 24
+	m.resizeViewport()
+	m.openDocument(doc)
+
+	// Unchanged, the check comes back empty
+	if _, ok := m.watchDocument()().(watchMsg); !ok {
+		t.Fatal("unchanged document reported changed")
+	}
+
+	if err := os.WriteFile(path, []byte("* Notes\nSecond draft.\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	later := doc.ModTime.Add(time.Second)
+	if err := os.Chtimes(path, later, later); err != nil {
+		t.Fatal(err)
+	}
+	changed, ok := m.watchDocument()().(docChangedMsg)
+	if !ok {
+		t.Fatal("rewritten document not reported changed")
+	}
+	model, _ := m.Update(changed)
+	m = model.(Model)
+	if m.currentDoc == doc || m.currentView != ViewDocument {
+		t.Fatal("document not reloaded in place")
+	}
+	if content := stripANSI(strings.Join(m.docLines, "\n")); !strings.Contains(content, "Second draft.") || strings.Contains(content, "First draft.") {
+		t.Errorf("reloaded document shows:\n%s", content)
+	}
+}
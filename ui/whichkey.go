@@ -0,0 +1,125 @@
+package ui
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+)
+
+// whichKeyDelay is how long a key sequence must be pending before its
+// completions are shown, so fast typists never see the panel
+const whichKeyDelay = 400 * time.Millisecond
+
+// whichKeyMsg shows the completions panel for the key sequence with the
+// given id, unless the sequence changed since
+type whichKeyMsg int
+
+// scheduleWhichKey hides the completions panel and, while a key sequence
+// is pending, returns the command that shows it after whichKeyDelay
+func (m *Model) scheduleWhichKey() tea.Cmd {
+	m.keysID++
+	m.whichKey = false
+	if m.keys == (keySequence{}) {
+		return nil
+	}
+	id := m.keysID
+	return tea.Tick(whichKeyDelay, func(time.Time) tea.Msg {
+		return whichKeyMsg(id)
+	})
+}
+
+// whichKeyHints returns the typed prefix and the possible completions of
+// the pending key sequence
+func (m Model) whichKeyHints() (string, []helpItem) {
+	seq := m.keys
+	switch {
+	case seq.pendingMark == 'm':
+		return "m", []helpItem{{"a-z", "set mark here"}}
+
+	case seq.pendingMark != 0:
+		var items []helpItem
+		marks := m.markSet()
+		names := make([]rune, 0, len(marks))
+		for name := range marks {
+			names = append(names, name)
+		}
+		sort.Slice(names, func(i, j int) bool { return names[i] < names[j] })
+		for _, name := range names {
+			desc := fmt.Sprintf("line %d", marks[name]+1)
+			if name == lastJumpMark {
+				desc += " (last jump)"
+			}
+			items = append(items, helpItem{string(name), desc})
+		}
+		if len(items) == 0 {
+			items = append(items, helpItem{"a-z", "no marks set yet"})
+		}
+		return "'", items
+
+	case seq.pendingG:
+		prefix, desc := "g", "go to top"
+		if seq.count > 0 {
+			prefix, desc = fmt.Sprintf("%dg", seq.count), fmt.Sprintf("go to line %d", seq.count)
+		}
+		return prefix, []helpItem{{"g", desc}}
+	}
+
+	n := seq.count
+	return fmt.Sprint(n), []helpItem{
+		{"j / ↓", fmt.Sprintf("down %d", n)},
+		{"k / ↑", fmt.Sprintf("up %d", n)},
+		{"G / gg", fmt.Sprintf("go to line %d", n)},
+		{"Ctrl+d / Ctrl+u", fmt.Sprintf("%d half pages down/up", n)},
+		{"} / {", fmt.Sprintf("%d paragraphs down/up", n)},
+		{"0-9", "extend the count"},
+	}
+}
+
+// renderWhichKey renders the completions panel for the pending key sequence
+func (m Model) renderWhichKey() string {
+	prefix, items := m.whichKeyHints()
+	width := m.width - 4
+	inner := width - m.styles.KeyHints.GetHorizontalFrameSize()
+
+	// Lay the completions out in as many columns as fit
+	colWidth := 0
+	cells := make([]string, len(items))
+	for i, item := range items {
+		cells[i] = m.styles.HelpKey.Render(item.key) + "  " + m.styles.HelpText.Render(item.desc)
+		colWidth = max(colWidth, lipgloss.Width(cells[i])+4)
+	}
+	cols := max(1, inner/colWidth)
+
+	var b strings.Builder
+	b.WriteString(m.styles.HelpKey.Render(prefix+"…") + m.styles.HelpText.Render("  esc cancels"))
+	for i, cell := range cells {
+		if i%cols == 0 {
+			b.WriteString("\n")
+		}
+		if i%cols < cols-1 && i < len(cells)-1 {
+			cell += strings.Repeat(" ", colWidth-lipgloss.Width(cell))
+		}
+		b.WriteString(cell)
+	}
+
+	return m.styles.Sized(&m.styles.KeyHints, width).Render(b.String())
+}
+
+// overlayBottom draws panel over the bottom lines of content, above the
+// bottom padding of the App frame
+func overlayBottom(content, panel string) string {
+	lines := strings.Split(content, "\n")
+	panelLines := strings.Split(panel, "\n")
+	start := len(lines) - appTop - len(panelLines)
+	if start < 0 {
+		return content
+	}
+	for i, line := range panelLines {
+		lines[start+i] = strings.Repeat(" ", appLeft) + line
+	}
+	return strings.Join(lines, "\n")
+}
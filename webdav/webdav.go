@@ -0,0 +1,294 @@
+// Package webdav mirrors the org files of a WebDAV collection, such as a
+// Nextcloud or Fastmail folder, into a local directory for the server to
+// serve. The mirror only reads from the collection: files are never
+// written back.
+package webdav
+
+import (
+	"context"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"io/fs"
+	"net/http"
+	"net/url"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// requestTimeout bounds how long a listing or download may take
+const requestTimeout = time.Minute
+
+// propfindBody asks for the properties a listing needs
+const propfindBody = `<?xml version="1.0" encoding="utf-8"?>
+<d:propfind xmlns:d="DAV:"><d:prop><d:resourcetype/><d:getetag/><d:getlastmodified/></d:prop></d:propfind>`
+
+// Mirror keeps a local directory a copy of the org files of a WebDAV
+// collection and its subcollections
+type Mirror struct {
+	url      *url.URL // Collection, with a trailing slash
+	user     string
+	password string
+	dir      string
+	client   *http.Client
+
+	// ETags of the files as last downloaded, by slash separated path
+	// relative to the collection
+	etags map[string]string
+}
+
+// New creates a mirror of the collection at rawURL in dir, authenticating
+// with user and password unless user is empty
+func New(rawURL, user, password, dir string) (*Mirror, error) {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return nil, err
+	}
+	if (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return nil, fmt.Errorf("%s: want an http or https URL", rawURL)
+	}
+	if !strings.HasSuffix(u.Path, "/") {
+		u.Path += "/"
+		u.RawPath = ""
+	}
+	return &Mirror{
+		url:      u,
+		user:     user,
+		password: password,
+		dir:      dir,
+		client:   &http.Client{Timeout: requestTimeout},
+		etags:    map[string]string{},
+	}, nil
+}
+
+// remoteFile is an org file of the collection
+type remoteFile struct {
+	etag     string
+	modified time.Time
+}
+
+// Sync downloads the org files of the collection added or changed since
+// the last sync and removes those removed from it, and returns how many
+// files changed. Files of the directory that aren't org files are left
+// alone, but any org file not in the collection is removed: the directory
+// should be the mirror's own.
+func (m *Mirror) Sync(ctx context.Context) (int, error) {
+	remote := map[string]remoteFile{}
+	if err := m.list(ctx, "", remote); err != nil {
+		return 0, err
+	}
+
+	changed := 0
+	for rel, file := range remote {
+		local := filepath.Join(m.dir, filepath.FromSlash(rel))
+		if info, err := os.Stat(local); err == nil && m.current(rel, file, info.ModTime()) {
+			m.etags[rel] = file.etag
+			continue
+		}
+		if err := m.download(ctx, rel, local, file.modified); err != nil {
+			return changed, fmt.Errorf("%s: %w", rel, err)
+		}
+		m.etags[rel] = file.etag
+		changed++
+	}
+
+	// Drop the org files gone from the collection
+	err := filepath.WalkDir(m.dir, func(p string, d fs.DirEntry, err error) error {
+		switch {
+		case err != nil:
+			return err
+		case p != m.dir && strings.HasPrefix(d.Name(), "."):
+			// Hidden files aren't mirrored
+			if d.IsDir() {
+				return filepath.SkipDir
+			}
+			return nil
+		case d.IsDir() || !strings.HasSuffix(strings.ToLower(d.Name()), ".org"):
+			return nil
+		}
+		rel, err := filepath.Rel(m.dir, p)
+		if err != nil {
+			return err
+		}
+		rel = filepath.ToSlash(rel)
+		if _, ok := remote[rel]; ok {
+			return nil
+		}
+		delete(m.etags, rel)
+		changed++
+		return os.Remove(p)
+	})
+	return changed, err
+}
+
+// current reports whether the local copy of the file of the collection at
+// rel, modified at modTime, is up to date: it has the ETag of the last
+// download, or, for files not downloaded since the server started, the
+// modification time of the remote file
+func (m *Mirror) current(rel string, file remoteFile, modTime time.Time) bool {
+	if etag, ok := m.etags[rel]; ok {
+		return file.etag != "" && etag == file.etag
+	}
+	return !file.modified.IsZero() && modTime.Equal(file.modified)
+}
+
+// multistatus is the response to a PROPFIND request
+type multistatus struct {
+	Responses []struct {
+		Href     string `xml:"DAV: href"`
+		Propstat []struct {
+			Status string `xml:"DAV: status"`
+			Prop   struct {
+				Collection   *struct{} `xml:"DAV: resourcetype>collection"`
+				ETag         string    `xml:"DAV: getetag"`
+				LastModified string    `xml:"DAV: getlastmodified"`
+			} `xml:"DAV: prop"`
+		} `xml:"DAV: propstat"`
+	} `xml:"DAV: response"`
+}
+
+// list adds the org files of the collection at rel, a slash separated
+// path relative to the mirrored one ending in a slash unless empty, and of
+// its subcollections to files. Collections are listed one level at a
+// time, as servers often refuse listing them whole.
+func (m *Mirror) list(ctx context.Context, rel string, files map[string]remoteFile) error {
+	target := m.url.ResolveReference(&url.URL{Path: rel})
+	body, err := m.do(ctx, "PROPFIND", target, strings.NewReader(propfindBody), map[string]string{
+		"Depth":        "1",
+		"Content-Type": "application/xml; charset=utf-8",
+	})
+	if err != nil {
+		return err
+	}
+	defer body.Close()
+
+	var ms multistatus
+	if err := xml.NewDecoder(body).Decode(&ms); err != nil {
+		return fmt.Errorf("listing %s: %w", target, err)
+	}
+	for _, resp := range ms.Responses {
+		child, ok := m.relative(resp.Href)
+		if !ok || strings.TrimSuffix(child, "/") == strings.TrimSuffix(rel, "/") {
+			// The collection itself, or somewhere else
+			continue
+		}
+		name := path.Base(child)
+		if strings.HasPrefix(name, ".") {
+			continue
+		}
+		for _, ps := range resp.Propstat {
+			if !strings.Contains(ps.Status, " 200 ") {
+				continue
+			}
+			switch {
+			case ps.Prop.Collection != nil:
+				if err := m.list(ctx, strings.TrimSuffix(child, "/")+"/", files); err != nil {
+					return err
+				}
+			case strings.HasSuffix(strings.ToLower(name), ".org"):
+				modified, _ := http.ParseTime(ps.Prop.LastModified)
+				files[child] = remoteFile{etag: ps.Prop.ETag, modified: modified}
+			}
+		}
+	}
+	return nil
+}
+
+// relative returns the path of href relative to the mirrored collection,
+// unescaped, and whether it's inside of it
+func (m *Mirror) relative(href string) (string, bool) {
+	u, err := url.Parse(href)
+	if err != nil {
+		return "", false
+	}
+	u = m.url.ResolveReference(u)
+	if u.Host != m.url.Host || !strings.HasPrefix(u.Path, m.url.Path) {
+		return "", false
+	}
+	rel := strings.TrimPrefix(u.Path, m.url.Path)
+	if trimmed := strings.TrimSuffix(rel, "/"); trimmed != "" && !fs.ValidPath(trimmed) {
+		// Escaping the collection
+		return "", false
+	}
+	return rel, true
+}
+
+// download writes the file of the collection at rel to local, through a
+// temporary file so readers never see it half written, dated modified
+func (m *Mirror) download(ctx context.Context, rel, local string, modified time.Time) error {
+	body, err := m.do(ctx, http.MethodGet, m.url.ResolveReference(&url.URL{Path: rel}), nil, nil)
+	if err != nil {
+		return err
+	}
+	defer body.Close()
+
+	if err := os.MkdirAll(filepath.Dir(local), 0o755); err != nil {
+		return err
+	}
+	tmp, err := os.CreateTemp(filepath.Dir(local), ".webdav-*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+	if _, err := io.Copy(tmp, body); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	if !modified.IsZero() {
+		if err := os.Chtimes(tmp.Name(), modified, modified); err != nil {
+			return err
+		}
+	}
+	if err := os.Chmod(tmp.Name(), 0o644); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), local)
+}
+
+// do sends a request to the server and returns the body of a successful
+// response
+func (m *Mirror) do(ctx context.Context, method string, target *url.URL, body io.Reader, header map[string]string) (io.ReadCloser, error) {
+	ctx, cancel := context.WithTimeout(ctx, requestTimeout)
+	req, err := http.NewRequestWithContext(ctx, method, target.String(), body)
+	if err != nil {
+		cancel()
+		return nil, err
+	}
+	for k, v := range header {
+		req.Header.Set(k, v)
+	}
+	req.Header.Set("User-Agent", "org-charm")
+	if m.user != "" {
+		req.SetBasicAuth(m.user, m.password)
+	}
+	resp, err := m.client.Do(req)
+	if err != nil {
+		cancel()
+		return nil, err
+	}
+	if resp.StatusCode >= 300 {
+		resp.Body.Close()
+		cancel()
+		return nil, fmt.Errorf("%s %s: %s", method, target.Redacted(), resp.Status)
+	}
+	return cancelingBody{resp.Body, cancel}, nil
+}
+
+// cancelingBody is a response body releasing the context of its request
+// when closed
+type cancelingBody struct {
+	io.ReadCloser
+	cancel context.CancelFunc
+}
+
+func (b cancelingBody) Close() error {
+	err := b.ReadCloser.Close()
+	b.cancel()
+	return err
+}
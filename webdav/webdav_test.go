@@ -0,0 +1,174 @@
+package webdav
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// davServer serves files as a WebDAV server under /dav/notes/
+type davServer struct {
+	mu    sync.Mutex
+	files map[string]string // Content by path under the collection
+	etags map[string]string
+	gets  int
+}
+
+func (s *davServer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if user, password, ok := r.BasicAuth(); !ok || user != "ada" || password != "secret" {
+		w.WriteHeader(http.StatusUnauthorized)
+		return
+	}
+	const root = "/dav/notes/"
+	rel := strings.TrimPrefix(r.URL.Path, root)
+	modified := time.Date(2024, 3, 1, 10, 0, 0, 0, time.UTC).Format(http.TimeFormat)
+
+	switch r.Method {
+	case http.MethodGet:
+		content, ok := s.files[rel]
+		if !ok {
+			http.NotFound(w, r)
+			return
+		}
+		s.gets++
+		fmt.Fprint(w, content)
+	case "PROPFIND":
+		if r.Header.Get("Depth") != "1" {
+			w.WriteHeader(http.StatusForbidden)
+			return
+		}
+		var b strings.Builder
+		b.WriteString(`<?xml version="1.0"?><d:multistatus xmlns:d="DAV:">`)
+		response := func(href, props string) {
+			fmt.Fprintf(&b, `<d:response><d:href>%s</d:href><d:propstat><d:prop>%s</d:prop><d:status>HTTP/1.1 200 OK</d:status></d:propstat></d:response>`, href, props)
+		}
+		response((&url.URL{Path: root + rel}).EscapedPath(), `<d:resourcetype><d:collection/></d:resourcetype>`)
+		dirs := map[string]bool{}
+		var names []string
+		for name := range s.files {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		for _, name := range names {
+			if !strings.HasPrefix(name, rel) {
+				continue
+			}
+			child, _, nested := strings.Cut(strings.TrimPrefix(name, rel), "/")
+			href := (&url.URL{Path: root + rel + child}).EscapedPath()
+			if nested {
+				if !dirs[child] {
+					dirs[child] = true
+					response(href+"/", `<d:resourcetype><d:collection/></d:resourcetype>`)
+				}
+				continue
+			}
+			response(href, fmt.Sprintf(`<d:resourcetype/><d:getetag>%s</d:getetag><d:getlastmodified>%s</d:getlastmodified>`, s.etags[name], modified))
+		}
+		b.WriteString(`</d:multistatus>`)
+		w.WriteHeader(http.StatusMultiStatus)
+		fmt.Fprint(w, b.String())
+	default:
+		w.WriteHeader(http.StatusMethodNotAllowed)
+	}
+}
+
+func (s *davServer) set(name, content, etag string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.files[name] = content
+	s.etags[name] = etag
+}
+
+func TestMirror(t *testing.T) {
+	dav := &davServer{files: map[string]string{}, etags: map[string]string{}}
+	dav.set("notes.org", "* Notes\n", `"1"`)
+	dav.set("work/plan b.org", "* Plan\n", `"2"`)
+	dav.set("work/image.png", "png", `"3"`)
+	dav.set(".hidden/secret.org", "* Secret\n", `"4"`)
+	srv := httptest.NewServer(dav)
+	defer srv.Close()
+
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, "README"), []byte("kept"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	mirror, err := New(srv.URL+"/dav/notes", "ada", "secret", dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx := context.Background()
+	syncMirror := func(want int) {
+		t.Helper()
+		changed, err := mirror.Sync(ctx)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if changed != want {
+			t.Errorf("sync changed %d files, want %d", changed, want)
+		}
+	}
+	read := func(name string) string {
+		t.Helper()
+		content, err := os.ReadFile(filepath.Join(dir, filepath.FromSlash(name)))
+		if err != nil {
+			return ""
+		}
+		return string(content)
+	}
+
+	syncMirror(2)
+	if got := read("notes.org"); got != "* Notes\n" {
+		t.Errorf("notes.org = %q", got)
+	}
+	if got := read("work/plan b.org"); got != "* Plan\n" {
+		t.Errorf("work/plan b.org = %q", got)
+	}
+	if read("work/image.png") != "" || read(".hidden/secret.org") != "" {
+		t.Error("mirrored files other than org files, or hidden ones")
+	}
+	info, err := os.Stat(filepath.Join(dir, "notes.org"))
+	if err != nil || !info.ModTime().Equal(time.Date(2024, 3, 1, 10, 0, 0, 0, time.UTC)) {
+		t.Errorf("notes.org not dated as on the server: %v, %v", info, err)
+	}
+
+	syncMirror(0)
+	dav.set("notes.org", "* Notes\nEdited.\n", `"5"`)
+	syncMirror(1)
+	if got := read("notes.org"); got != "* Notes\nEdited.\n" {
+		t.Errorf("edited notes.org = %q", got)
+	}
+
+	dav.mu.Lock()
+	delete(dav.files, "work/plan b.org")
+	gets := dav.gets
+	dav.mu.Unlock()
+	syncMirror(1)
+	if read("work/plan b.org") != "" {
+		t.Error("file removed from the server kept")
+	}
+	if read("README") != "kept" {
+		t.Error("file other than an org file removed")
+	}
+
+	// A new mirror finds the files it would download already there
+	mirror, _ = New(srv.URL+"/dav/notes/", "ada", "secret", dir)
+	syncMirror(0)
+	if dav.gets != gets {
+		t.Errorf("downloaded %d files again after a restart", dav.gets-gets)
+	}
+
+	if _, err := New("ftp://example.com/", "", "", dir); err == nil {
+		t.Error("accepted a URL other than http or https")
+	}
+}
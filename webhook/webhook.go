@@ -0,0 +1,208 @@
+// Package webhook posts the documents added to or changed in the org
+// directory to a URL, for automations such as posting new notes elsewhere
+// or purging caches to react to them
+package webhook
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"hash/fnv"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"org-charm/org"
+
+	"github.com/charmbracelet/log"
+)
+
+// postTimeout bounds how long posting an event may take
+const postTimeout = 10 * time.Second
+
+// queueSize is how many refreshes with changes can wait to be posted;
+// the changes of more are dropped
+const queueSize = 64
+
+// Event is the JSON payload posted for a document added or changed
+type Event struct {
+	Event    string    `json:"event"` // added or modified
+	Path     string    `json:"path"`  // Slash separated, relative to the org directory
+	Title    string    `json:"title"`
+	Added    int       `json:"lines_added"`
+	Removed  int       `json:"lines_removed"`
+	Modified time.Time `json:"modified"`
+}
+
+// Notifier posts an Event to its URL for every org file added or changed
+// in a library, one after the other, in the background
+type Notifier struct {
+	url    string
+	root   string
+	client *http.Client
+	queue  chan []org.Change
+
+	// Hashes of the lines of the files as last seen, to count the lines
+	// changes add and remove; only the posting goroutine uses them
+	lines map[string][]uint64
+}
+
+// New creates a notifier posting to url the changes of the org files of
+// library, counted from their contents as they are now, and starts
+// posting
+func New(url string, library *org.Library) *Notifier {
+	n := &Notifier{
+		url:    url,
+		root:   library.Root(),
+		client: &http.Client{Timeout: postTimeout},
+		queue:  make(chan []org.Change, queueSize),
+		lines:  map[string][]uint64{},
+	}
+	var snapshot func(entries []*org.FileEntry)
+	snapshot = func(entries []*org.FileEntry) {
+		for _, e := range entries {
+			if !e.IsDir {
+				n.lines[e.RelPath], _ = readLines(e.Path)
+			}
+			snapshot(e.Children)
+		}
+	}
+	tree, _ := library.Tree()
+	snapshot(tree)
+	go n.run()
+	return n
+}
+
+// Notify queues the changes of a library refresh to be posted
+func (n *Notifier) Notify(changes []org.Change) {
+	select {
+	case n.queue <- changes:
+	default:
+		log.Warn("Webhook queue full, dropping changes", "changes", len(changes))
+	}
+}
+
+// run posts the events of queued changes
+func (n *Notifier) run() {
+	for changes := range n.queue {
+		for _, change := range changes {
+			event, ok := n.event(change)
+			if !ok {
+				continue
+			}
+			if err := n.post(event); err != nil {
+				log.Error("Failed to post webhook", "path", event.Path, "error", err)
+			}
+		}
+	}
+}
+
+// event returns the event of a change, updating the lines of the file,
+// and whether there's one to post: only org files added or modified are
+// posted
+func (n *Notifier) event(change org.Change) (Event, bool) {
+	if change.IsDir {
+		return Event{}, false
+	}
+	if change.Kind == org.ChangeRemoved {
+		delete(n.lines, change.RelPath)
+		return Event{}, false
+	}
+
+	path := filepath.Join(n.root, change.RelPath)
+	lines, err := readLines(path)
+	if err != nil {
+		// Removed again since the refresh; the next one tells
+		return Event{}, false
+	}
+	before := n.lines[change.RelPath]
+	n.lines[change.RelPath] = lines
+	added, removed := diffstat(before, lines)
+	if change.Kind == org.ChangeModified && added == 0 && removed == 0 {
+		// Touched but not edited
+		return Event{}, false
+	}
+
+	event := Event{
+		Event:   change.Kind.String(),
+		Path:    filepath.ToSlash(change.RelPath),
+		Title:   strings.TrimSuffix(filepath.Base(change.RelPath), ".org"),
+		Added:   added,
+		Removed: removed,
+	}
+	if header, err := org.ScanHeader(path); err == nil && header.Title != "" {
+		event.Title = header.Title
+	}
+	if info, err := os.Stat(path); err == nil {
+		event.Modified = info.ModTime().UTC()
+	}
+	return event, true
+}
+
+// post posts an event to the URL of the notifier
+func (n *Notifier) post(event Event) error {
+	body, err := json.Marshal(event)
+	if err != nil {
+		return err
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), postTimeout)
+	defer cancel()
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, n.url, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", "org-charm")
+	resp, err := n.client.Do(req)
+	if err != nil {
+		return err
+	}
+	resp.Body.Close()
+	if resp.StatusCode >= 300 {
+		return fmt.Errorf("webhook responded %s", resp.Status)
+	}
+	return nil
+}
+
+// readLines returns the hashes of the lines of the file at path
+func readLines(path string) ([]uint64, error) {
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	text := strings.TrimSuffix(string(content), "\n")
+	if text == "" {
+		return nil, nil
+	}
+	var lines []uint64
+	for line := range strings.SplitSeq(text, "\n") {
+		h := fnv.New64a()
+		h.Write([]byte(line))
+		lines = append(lines, h.Sum64())
+	}
+	return lines, nil
+}
+
+// diffstat counts the lines added and removed from before to after.
+// Lines count as kept when the other version has them as many times,
+// wherever they are, so lines moved around aren't counted.
+func diffstat(before, after []uint64) (added, removed int) {
+	counts := map[uint64]int{}
+	for _, line := range before {
+		counts[line]++
+	}
+	for _, line := range after {
+		if counts[line] > 0 {
+			counts[line]--
+		} else {
+			added++
+		}
+	}
+	for _, n := range counts {
+		removed += n
+	}
+	return added, removed
+}
@@ -0,0 +1,82 @@
+package webhook
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"org-charm/org"
+)
+
+func TestNotifier(t *testing.T) {
+	dir := t.TempDir()
+	write := func(name, content string, modified time.Time) {
+		t.Helper()
+		path := filepath.Join(dir, name)
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.Chtimes(path, modified, modified); err != nil {
+			t.Fatal(err)
+		}
+	}
+	start := time.Now().Add(-time.Hour)
+	write("a.org", "#+TITLE: Notes\n* One\n* Two\n", start)
+	write("b.org", "* Untouched\n", start)
+
+	events := make(chan Event, 10)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var event Event
+		if r.Method != http.MethodPost || r.Header.Get("Content-Type") != "application/json" {
+			t.Errorf("request %s with %q", r.Method, r.Header.Get("Content-Type"))
+		}
+		if err := json.NewDecoder(r.Body).Decode(&event); err != nil {
+			t.Error(err)
+		}
+		events <- event
+	}))
+	defer srv.Close()
+
+	library, err := org.NewLibrary(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	n := New(srv.URL, library)
+
+	write("a.org", "#+TITLE: Notes\n* One\n* Three\n* Four\n", start.Add(time.Minute))
+	write("b.org", "* Untouched\n", start.Add(time.Minute))
+	write("sub/c.org", "#+TITLE: New\n* C\n", start.Add(time.Minute))
+	changes, err := library.Refresh()
+	if err != nil {
+		t.Fatal(err)
+	}
+	n.Notify(changes)
+
+	got := map[string]Event{}
+	for range 2 {
+		select {
+		case event := <-events:
+			got[event.Path] = event
+		case <-time.After(5 * time.Second):
+			t.Fatalf("got events %v, want 2", got)
+		}
+	}
+	if e := got["a.org"]; e.Event != "modified" || e.Title != "Notes" || e.Added != 2 || e.Removed != 1 {
+		t.Errorf("modified event = %+v", e)
+	}
+	if e := got["sub/c.org"]; e.Event != "added" || e.Title != "New" || e.Added != 2 || e.Removed != 0 || e.Modified.IsZero() {
+		t.Errorf("added event = %+v", e)
+	}
+	select {
+	case event := <-events:
+		t.Errorf("unexpected event %+v for a file touched but not edited", event)
+	case <-time.After(100 * time.Millisecond):
+	}
+}